@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/adamrobbie/go-support/pkg/chat"
+)
+
+// composeTimeout is how long the local user may take to type a message after
+// `say` before the technicians are told they stopped typing
+const composeTimeout = 2 * time.Minute
+
+// composer tracks a chat message the local user is typing on its own line
+// after `say`, so the technicians can be shown that the user is typing
+type composer struct {
+	mu     sync.Mutex
+	active bool
+	idle   *time.Timer
+}
+
+// initChat creates the chat and registers its handlers. Incoming messages
+// are shown in the terminal in interactive mode and logged otherwise.
+func (a *App) initChat() {
+	a.Chat = chat.New(chatTransport{viewerTransport{a}}, chat.Options{
+		OnMessage: func(entry chat.Entry) {
+			if a.Config.Interactive {
+				fmt.Printf("\n💬 %s: %s\n(reply with: say <message>)\n", sanitizeChat(entry.Sender), sanitizeChat(entry.Text))
+				return
+			}
+			log.Printf("Chat message from %s: %s", sanitizeChat(entry.Sender), sanitizeChat(entry.Text))
+		},
+		OnTyping: func(sender string, typing bool) {
+			if a.Config.Interactive && typing {
+				fmt.Printf("✏️  %s is typing...\n", sanitizeChat(sender))
+			}
+		},
+		OnAck: func(entry chat.Entry) {
+			if a.Config.Verbose {
+				log.Printf("DEBUG: Chat message %s was delivered", entry.ID)
+			}
+		},
+		Verbose: a.Config.Verbose,
+	})
+}
+
+// handleSayCommand sends a chat message typed in the console
+func (a *App) handleSayCommand(args []string) error {
+	if a.Chat == nil || a.WSClient == nil || !a.WSClient.IsConnected() {
+		return fmt.Errorf("not connected to the server")
+	}
+	_, err := a.Chat.Send(strings.Join(args, " "))
+	return err
+}
+
+// startComposing starts a chat message typed on the next console line and
+// tells the technicians that the local user is typing
+func (a *App) startComposing() error {
+	if a.Chat == nil || a.WSClient == nil || !a.WSClient.IsConnected() {
+		return fmt.Errorf("not connected to the server")
+	}
+
+	c := &a.composer
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active {
+		if err := a.Chat.SetTyping(true); err != nil {
+			return err
+		}
+		c.active = true
+		c.idle = time.AfterFunc(composeTimeout, func() { a.stopComposing() })
+	}
+	fmt.Print("Type your message (empty line to cancel): ")
+	return nil
+}
+
+// stopComposing ends the message being typed, if there is one, and tells the
+// technicians that the local user stopped typing
+func (a *App) stopComposing() bool {
+	c := &a.composer
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active {
+		return false
+	}
+	c.active = false
+	c.idle.Stop()
+	if err := a.Chat.SetTyping(false); err != nil {
+		log.Printf("Failed to send typing state: %v", err)
+	}
+	return true
+}
+
+// composeLine sends a console line as the message started with `say`, and
+// reports whether a message was being typed
+func (a *App) composeLine(input string) bool {
+	if !a.stopComposing() {
+		return false
+	}
+	if text := strings.TrimSpace(input); text != "" {
+		if _, err := a.Chat.Send(text); err != nil {
+			log.Printf("Error sending chat message: %v", err)
+		}
+	}
+	return true
+}
+
+// sanitizeChat makes text from the other side safe to print in a terminal.
+// Line breaks and tabs become spaces, and other control characters, such as
+// the escape that starts a terminal control sequence, and invisible
+// formatting characters, such as direction overrides, are removed.
+func sanitizeChat(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\r' || r == '\t':
+			return ' '
+		case unicode.IsControl(r) || unicode.Is(unicode.Cf, r):
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// printChatHistory prints the chat messages of the current session
+func (a *App) printChatHistory() {
+	if a.Chat == nil {
+		fmt.Println("\nChat is not available until the agent connects.")
+		return
+	}
+
+	history := a.Chat.History()
+	fmt.Printf("\nChat history (%d messages):\n", len(history))
+	for _, entry := range history {
+		status := ""
+		if entry.Local {
+			status = " (sending)"
+			if entry.Delivered {
+				status = " ✓"
+			}
+		}
+		fmt.Printf("  [%s] %s: %s%s\n", entry.Time.Format("15:04:05"), sanitizeChat(entry.Sender), sanitizeChat(entry.Text), status)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/clipboard"
+)
+
+// ClipboardMessage carries clipboard content. Text is sent as-is and images
+// as base64-encoded PNG.
+type ClipboardMessage struct {
+	Type      string `json:"type"`
+	Format    string `json:"format"`
+	Data      string `json:"data,omitempty"`
+	Size      int    `json:"size,omitempty"` // Size of the decoded content in bytes
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+// initClipboard creates the clipboard and registers its handlers if clipboard sync is enabled
+func (a *App) initClipboard() error {
+	if !a.Config.ClipboardSync {
+		return nil
+	}
+
+	clip, err := clipboard.New(a.PermManager, a.Config.ClipboardMaxSize, a.Config.Verbose)
+	if err != nil {
+		return err
+	}
+	a.Clipboard = clip
+
+	a.WSClient.RegisterHandler(MessageTypeGetClipboard, a.controlHandler(MessageTypeGetClipboard, func(data []byte) error {
+		log.Println("DEBUG: Received clipboard request from server")
+
+		var msg ClipboardMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return fmt.Errorf("failed to parse clipboard request: %w", err)
+		}
+		if msg.Format == "" {
+			msg.Format = string(clipboard.Text)
+		}
+
+		content, err := a.Clipboard.Read(clipboard.Format(msg.Format))
+		if err != nil {
+			log.Printf("ERROR: Failed to read clipboard: %v", err)
+			return fmt.Errorf("failed to read clipboard: %w", err)
+		}
+		return a.sendClipboard(MessageTypeClipboardContent, *content)
+	}))
+
+	a.WSClient.RegisterHandler(MessageTypeSetClipboard, a.controlHandler(MessageTypeSetClipboard, func(data []byte) error {
+		log.Println("DEBUG: Received set clipboard request from server")
+
+		var msg ClipboardMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return fmt.Errorf("failed to parse set clipboard request: %w", err)
+		}
+
+		content, err := decodeClipboardMessage(msg)
+		if err != nil {
+			return err
+		}
+		if err := a.Clipboard.Write(content); err != nil {
+			log.Printf("ERROR: Failed to write clipboard: %v", err)
+			return fmt.Errorf("failed to write clipboard: %w", err)
+		}
+		return nil
+	}))
+
+	log.Printf("Clipboard sync enabled (limit %d bytes)", a.Clipboard.MaxSize())
+	return nil
+}
+
+// watchClipboard sends a clipboardChanged message whenever the local clipboard changes
+func (a *App) watchClipboard() {
+	err := a.Clipboard.Watch(a.stopClipboardWatch, clipboard.DefaultPollInterval, func(content clipboard.Content) {
+		if a.WSClient == nil || !a.WSClient.IsConnected() {
+			return
+		}
+		// Nothing is shared before a technician has joined
+		if a.Pairing != nil && !a.Pairing.IsPaired() {
+			return
+		}
+		if err := a.sendClipboard(MessageTypeClipboardChanged, content); err != nil {
+			log.Printf("Failed to send clipboard change: %v", err)
+		}
+	})
+	if err != nil {
+		log.Printf("Clipboard watch stopped: %v", err)
+	}
+}
+
+// sendClipboard sends clipboard content to the server
+func (a *App) sendClipboard(messageType string, content clipboard.Content) error {
+	message := ClipboardMessage{
+		Type:      messageType,
+		Format:    string(content.Format),
+		Size:      len(content.Data),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	if content.Format == clipboard.Image {
+		message.Data = base64.StdEncoding.EncodeToString(content.Data)
+	} else {
+		message.Data = string(content.Data)
+	}
+	return a.WSClient.SendJSON(message)
+}
+
+// decodeClipboardMessage converts a clipboard message to clipboard content
+func decodeClipboardMessage(msg ClipboardMessage) (clipboard.Content, error) {
+	content := clipboard.Content{Format: clipboard.Format(msg.Format)}
+
+	switch content.Format {
+	case "", clipboard.Text:
+		content.Format = clipboard.Text
+		content.Data = []byte(msg.Data)
+	case clipboard.Image:
+		data, err := base64.StdEncoding.DecodeString(msg.Data)
+		if err != nil {
+			return content, fmt.Errorf("invalid clipboard image encoding: %w", err)
+		}
+		content.Data = data
+	default:
+		return content, fmt.Errorf("%w: %s", clipboard.ErrUnknownFormat, msg.Format)
+	}
+	return content, nil
+}
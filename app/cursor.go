@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/base64"
+	"image"
+
+	"github.com/adamrobbie/go-support/pkg/screenshot"
+	"github.com/adamrobbie/go-support/pkg/video"
+)
+
+// defaultCursorRate is how many cursor updates per second are sent while streaming
+const defaultCursorRate = 30
+
+// defaultCursor is drawn where the platform cursor image cannot be read
+var defaultCursor = screenshot.DefaultCursorShape()
+
+// CursorUpdateMessage reports the pointer position, and its shape when it changes
+type CursorUpdateMessage struct {
+	Type         string              `json:"type"`
+	X            int                 `json:"x"`
+	Y            int                 `json:"y"`
+	Visible      bool                `json:"visible"`
+	ScreenWidth  int                 `json:"screenWidth,omitempty"`
+	ScreenHeight int                 `json:"screenHeight,omitempty"`
+	Shape        *CursorShapeMessage `json:"shape,omitempty"`
+}
+
+// CursorShapeMessage is the pointer image sent with a cursor update
+type CursorShapeMessage struct {
+	Serial uint64 `json:"serial"`
+	HotX   int    `json:"hotX"`
+	HotY   int    `json:"hotY"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Image  string `json:"image"` // Base64-encoded PNG
+}
+
+// readCursor reads the pointer from the platform, falling back to the
+// position reported by the input backend and the default arrow shape
+func (a *App) readCursor() (*screenshot.Cursor, error) {
+	cursor, err := screenshot.CaptureCursor()
+	if err == nil || a.RemoteController == nil {
+		return cursor, err
+	}
+
+	x, y, err := a.RemoteController.GetMousePosition()
+	if err != nil {
+		return nil, err
+	}
+	cursor = &screenshot.Cursor{X: x, Y: y, Visible: true, Shape: defaultCursor}
+	if width, height, err := a.RemoteController.GetScreenSize(); err == nil {
+		cursor.Screen = image.Rect(0, 0, width, height)
+	}
+	return cursor, nil
+}
+
+// cursorUpdateMessage builds the message for a cursor update
+func cursorUpdateMessage(update video.CursorUpdate) (CursorUpdateMessage, error) {
+	cursor := update.Cursor
+	message := CursorUpdateMessage{
+		Type:         MessageTypeCursorUpdate,
+		X:            cursor.X,
+		Y:            cursor.Y,
+		Visible:      cursor.Visible,
+		ScreenWidth:  cursor.Screen.Dx(),
+		ScreenHeight: cursor.Screen.Dy(),
+	}
+
+	if update.ShapeChanged && cursor.Shape != nil {
+		data, err := cursor.Shape.PNG()
+		if err != nil {
+			return message, err
+		}
+		bounds := cursor.Shape.Image.Bounds()
+		message.Shape = &CursorShapeMessage{
+			Serial: cursor.Shape.Serial,
+			HotX:   cursor.Shape.HotX,
+			HotY:   cursor.Shape.HotY,
+			Width:  bounds.Dx(),
+			Height: bounds.Dy(),
+			Image:  base64.StdEncoding.EncodeToString(data),
+		}
+	}
+	return message, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+	"github.com/adamrobbie/go-support/pkg/diagnostics"
+)
+
+// diagnosticsTimeout limits how long collecting a diagnostics report may take
+const diagnosticsTimeout = 10 * time.Second
+
+// SystemInfoMessage carries a diagnostics report to the server
+type SystemInfoMessage struct {
+	Type      string              `json:"type"`
+	Report    *diagnostics.Report `json:"report"`
+	SendQueue client.SendStats    `json:"sendQueue"` // Outbound queue depth and drops of the connection
+}
+
+// initDiagnostics registers the handler for diagnostics requests
+func (a *App) initDiagnostics() {
+	a.WSClient.RegisterHandler(MessageTypeGetSystemInfo, a.controlHandler(MessageTypeGetSystemInfo, func(data []byte) error {
+		log.Println("DEBUG: Received system info request from server")
+
+		report := a.collectDiagnostics()
+		if a.Config.Verbose && len(report.Errors) > 0 {
+			log.Printf("DEBUG: Diagnostics report is incomplete: %v", report.Errors)
+		}
+		return a.WSClient.SendJSON(SystemInfoMessage{
+			Type:      MessageTypeSystemInfo,
+			Report:    report,
+			SendQueue: a.WSClient.SendStats(),
+		})
+	}))
+}
+
+// collectDiagnostics collects a full diagnostics report
+func (a *App) collectDiagnostics() *diagnostics.Report {
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticsTimeout)
+	defer cancel()
+	return diagnostics.Collect(ctx, diagnostics.DefaultTopProcesses)
+}
+
+// systemSummary collects the summary sent with the client info, or nil if it cannot be read
+func systemSummary() *diagnostics.Summary {
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticsTimeout)
+	defer cancel()
+
+	summary, err := diagnostics.CollectSummary(ctx)
+	if err != nil {
+		log.Printf("WARNING: Failed to collect system summary: %v", err)
+		return nil
+	}
+	return &summary
+}
+
+// printSystemInfo prints a diagnostics report in the terminal
+func (a *App) printSystemInfo() {
+	fmt.Println("\nCollecting system information...")
+	a.collectDiagnostics().Print(os.Stdout)
+	fmt.Println()
+}
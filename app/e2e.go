@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/e2e"
+)
+
+// initE2E creates the end-to-end channel and registers the key exchange
+// handler. Key exchanges are gated like other viewer messages, so with signed
+// commands the viewer's key must be signed by a technician key.
+func (a *App) initE2E() error {
+	if !a.Config.E2EEncryption {
+		return nil
+	}
+
+	channel, err := e2e.NewChannel()
+	if err != nil {
+		return err
+	}
+	a.E2EChannel = channel
+
+	a.WSClient.RegisterHandler(MessageTypeKeyExchange, a.viewerHandler(MessageTypeKeyExchange, a.handleKeyExchange))
+
+	log.Println("End-to-end encryption enabled, screen content will not be sent until a key is agreed")
+	if a.CommandVerifier == nil {
+		log.Println("WARNING: Key exchanges are not signed; compare the key fingerprint with the technician before sharing the screen")
+	}
+	return nil
+}
+
+// handleKeyExchange accepts a key exchange from the viewer and shows the key
+// fingerprint when a new session is established
+func (a *App) handleKeyExchange(data []byte) error {
+	log.Println("DEBUG: Received end-to-end key exchange from server")
+
+	var msg e2e.KeyExchange
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to parse key exchange: %w", err)
+	}
+
+	fingerprint := a.E2EChannel.Fingerprint()
+	reply, err := a.E2EChannel.Accept(msg)
+	if err != nil {
+		return fmt.Errorf("failed to accept key exchange: %w", err)
+	}
+
+	log.Printf("End-to-end key established for session %s (epoch %d)", a.E2EChannel.SessionID(), a.E2EChannel.Epoch())
+	if current := a.E2EChannel.Fingerprint(); current != fingerprint {
+		fmt.Printf("\n🔐 End-to-end encryption key fingerprint: %s\n", current)
+		fmt.Println("Make sure your technician sees the same fingerprint before sharing anything sensitive.")
+	}
+	if reply != nil {
+		return a.WSClient.SendJSON(reply)
+	}
+	return nil
+}
+
+// resetE2E drops the end-to-end keys at the end of a session and, if a new
+// technician has joined, offers a key for a new session
+func (a *App) resetE2E(offer bool) {
+	if a.E2EChannel == nil {
+		return
+	}
+	if err := a.E2EChannel.Reset(); err != nil {
+		log.Printf("Failed to reset end-to-end keys: %v", err)
+		return
+	}
+	if offer && a.WSClient != nil && a.WSClient.IsConnected() {
+		if err := a.offerE2EKey(); err != nil {
+			log.Printf("Failed to offer end-to-end key: %v", err)
+		}
+	}
+}
+
+// offerE2EKey sends a key exchange offer for the next epoch to the viewer
+func (a *App) offerE2EKey() error {
+	offer, err := a.E2EChannel.Offer()
+	if err != nil {
+		return err
+	}
+	return a.WSClient.SendJSON(offer)
+}
+
+// rotateE2EKeys periodically offers a fresh key until the application stops
+func (a *App) rotateE2EKeys() {
+	ticker := time.NewTicker(a.Config.E2ERotateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if a.WSClient == nil || !a.WSClient.IsConnected() {
+				continue
+			}
+			if a.Config.Verbose {
+				log.Println("DEBUG: Rotating end-to-end key")
+			}
+			if err := a.offerE2EKey(); err != nil {
+				log.Printf("Failed to rotate end-to-end key: %v", err)
+			}
+		case <-a.stopE2ERotation:
+			return
+		}
+	}
+}
+
+// sealScreenshot encrypts the image of a screenshot message when end-to-end encryption is enabled
+func (a *App) sealScreenshot(message *ScreenshotMessage) error {
+	if a.E2EChannel == nil {
+		return nil
+	}
+
+	sealed, err := a.E2EChannel.Seal(MessageTypeScreenshot, []byte(message.ImageURL))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt screenshot: %w", err)
+	}
+
+	message.ImageURL = sealed.Ciphertext
+	message.Encrypted = true
+	message.Epoch = sealed.Epoch
+	message.Nonce = sealed.Nonce
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+
+	"github.com/adamrobbie/go-support/pkg/audit"
+	"github.com/adamrobbie/go-support/pkg/command"
+)
+
+// openAudit opens the audit trail if it is not open yet
+func (a *App) openAudit() error {
+	if a.Audit != nil {
+		return nil
+	}
+
+	logger, err := audit.Open(a.Config.AuditLog)
+	if err != nil {
+		return err
+	}
+	a.Audit = logger
+
+	log.Printf("Recording audit trail in %s", a.Config.AuditLog)
+	return nil
+}
+
+// initExec creates the command runner if any commands are allowed
+func (a *App) initExec() error {
+	if len(a.Config.ExecAllowed) == 0 && !a.Config.ExecAllowAny {
+		return nil
+	}
+
+	if err := a.openAudit(); err != nil {
+		return err
+	}
+
+	a.Commands = command.NewRunner(controlTransport{a}, command.Options{
+		Allowed:  a.Config.ExecAllowed,
+		AllowAny: a.Config.ExecAllowAny,
+		Timeout:  a.Config.ExecTimeout,
+		Audit:    a.Audit,
+		Verbose:  a.Config.Verbose,
+	})
+
+	if a.Config.ExecAllowAny {
+		log.Printf("WARNING: Remote command execution is enabled for any command")
+	} else {
+		log.Printf("Remote command execution enabled for %v", a.Config.ExecAllowed)
+	}
+	return nil
+}
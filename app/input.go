@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/adamrobbie/go-support/pkg/input"
+	"github.com/adamrobbie/go-support/pkg/remote"
+	"github.com/adamrobbie/go-support/pkg/session"
+)
+
+// eventExecutor executes mouse and keyboard events on the machine
+type eventExecutor interface {
+	ExecuteMouseEvent(event remote.MouseEvent) error
+	ExecuteKeyboardEvent(event remote.KeyboardEvent) error
+	GetScreenSize() (int, int, error)
+}
+
+// inputExecutor returns the executor of input events: the remote controller
+// unless another executor was set
+func (a *App) inputExecutor() eventExecutor {
+	if a.executor != nil {
+		return a.executor
+	}
+	return a.RemoteController
+}
+
+// inputController executes queued events through the app, so they are
+// recorded into macros like mouse and keyboard events
+type inputController struct {
+	a *App
+}
+
+func (c inputController) ExecuteMouseEvent(event remote.MouseEvent) error {
+	return c.a.executeMouseEvent(event)
+}
+
+func (c inputController) ExecuteKeyboardEvent(event remote.KeyboardEvent) error {
+	return c.a.executeKeyboardEvent(event)
+}
+
+func (c inputController) GetScreenSize() (int, int, error) {
+	return c.a.inputExecutor().GetScreenSize()
+}
+
+func (c inputController) HandleDOMEvent(event remote.DOMEvent, view remote.View) error {
+	return c.a.Input.Handle(event, view)
+}
+
+// initInput creates the DOM event translator and the input queue, and
+// registers the handlers of input events. The handlers only queue events, so
+// a slow event does not hold up other messages.
+func (a *App) initInput() {
+	a.Input = remote.NewTranslator(inputController{a}, a.Config.Verbose)
+	a.InputQueue = input.NewQueue(controlTransport{a}, inputController{a}, input.Options{
+		Authorize: a.authorizeQueuedInput,
+		Verbose:   a.Config.Verbose,
+	})
+	for _, messageType := range []string{MessageTypeMouseEvent, MessageTypeKeyboardEvent, MessageTypeInputEvent} {
+		a.WSClient.RegisterHandler(messageType, a.controlHandler(messageType, a.InputQueue.HandleMessage))
+	}
+}
+
+// authorizeQueuedInput checks, right before a queued event runs, that the
+// viewer that sent it may still control the machine. controlHandler checked
+// it when the event arrived, but control can move while the event waits.
+func (a *App) authorizeQueuedInput(sender string) error {
+	if a.Pairing != nil && !a.Pairing.IsPaired() {
+		return errNotPaired
+	}
+	if a.Viewers != nil && a.Viewers.Controller() != sender {
+		return fmt.Errorf("%w: %s", session.ErrNotController, sender)
+	}
+	return nil
+}
+
+// resetInput drops the queued events and releases the keys and buttons held
+// for the viewer that had control
+func (a *App) resetInput() {
+	if a.InputQueue != nil {
+		a.InputQueue.Clear()
+	}
+	if a.Input != nil {
+		a.Input.Reset()
+	}
+}
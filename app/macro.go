@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/adamrobbie/go-support/pkg/macro"
+	"github.com/adamrobbie/go-support/pkg/remote"
+)
+
+// defaultMacroDir is where macros are saved without MACRO_DIR
+const defaultMacroDir = "macros"
+
+// initMacros creates the macro manager and registers its handlers
+func (a *App) initMacros() {
+	a.Macros = macro.NewManager(controlTransport{a}, a.RemoteController, macro.Options{
+		Dir:     a.Config.MacroDir,
+		Verbose: a.Config.Verbose,
+	})
+}
+
+// executeMouseEvent executes a mouse event and records it if a macro is being recorded
+func (a *App) executeMouseEvent(event remote.MouseEvent) error {
+	if err := a.inputExecutor().ExecuteMouseEvent(event); err != nil {
+		return err
+	}
+	if a.Macros != nil {
+		a.Macros.RecordMouse(event)
+	}
+	return nil
+}
+
+// executeKeyboardEvent executes a keyboard event and records it if a macro is being recorded
+func (a *App) executeKeyboardEvent(event remote.KeyboardEvent) error {
+	if err := a.inputExecutor().ExecuteKeyboardEvent(event); err != nil {
+		return err
+	}
+	if a.Macros != nil {
+		a.Macros.RecordKeyboard(event)
+	}
+	return nil
+}
+
+// handleMacroCommand handles macro commands from the console
+func (a *App) handleMacroCommand(args []string) error {
+	if a.Macros == nil {
+		return fmt.Errorf("macros are not available until the agent connects")
+	}
+
+	switch args[0] {
+	case "record":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: macro record <name>")
+		}
+		if err := a.Macros.StartRecording(args[1]); err != nil {
+			return err
+		}
+		log.Println("Recording macro; use mouse and key commands, then 'macro stop'")
+		return nil
+
+	case "stop":
+		if !a.Macros.Recording() {
+			a.Macros.Stop()
+			return nil
+		}
+		m, path, err := a.Macros.StopRecording(true)
+		if err != nil {
+			return err
+		}
+		log.Printf("Saved macro with %d steps to %s", len(m.Steps), path)
+		return nil
+
+	case "play":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: macro play <name|file> [speed]")
+		}
+		m, err := a.loadMacro(args[1])
+		if err != nil {
+			return err
+		}
+		opts := macro.PlayOptions{Speed: 1, AbortOnInput: true}
+		if len(args) > 2 {
+			if opts.Speed, err = strconv.ParseFloat(args[2], 64); err != nil || opts.Speed <= 0 {
+				return fmt.Errorf("invalid speed: %s", args[2])
+			}
+		}
+		log.Printf("Playing macro with %d steps; move the mouse to stop", len(m.Steps))
+		return a.Macros.Play(m, opts, func(played int, err error) {
+			switch {
+			case errors.Is(err, macro.ErrAborted):
+				log.Printf("Macro stopped by mouse movement after %d steps", played)
+			case err != nil:
+				log.Printf("Macro failed after %d steps: %v", played, err)
+			default:
+				log.Printf("Macro finished after %d steps", played)
+			}
+		})
+
+	case "list":
+		files, err := filepath.Glob(filepath.Join(a.Config.MacroDir, "*.json"))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n%d macros in %s:\n", len(files), a.Config.MacroDir)
+		for _, file := range files {
+			fmt.Printf("  %s\n", strings.TrimSuffix(filepath.Base(file), ".json"))
+		}
+		fmt.Println()
+		return nil
+
+	default:
+		return fmt.Errorf("unknown macro command: %s", args[0])
+	}
+}
+
+// loadMacro loads a macro by name from the macro directory, or from a file path
+func (a *App) loadMacro(nameOrPath string) (*macro.Macro, error) {
+	if _, err := os.Stat(nameOrPath); err == nil {
+		return macro.Load(nameOrPath)
+	}
+	return a.Macros.LoadNamed(nameOrPath)
+}
@@ -18,10 +18,26 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/adamrobbie/go-support/pkg/audit"
+	"github.com/adamrobbie/go-support/pkg/chat"
 	"github.com/adamrobbie/go-support/pkg/client"
+	"github.com/adamrobbie/go-support/pkg/clipboard"
+	"github.com/adamrobbie/go-support/pkg/command"
+	"github.com/adamrobbie/go-support/pkg/diagnostics"
+	"github.com/adamrobbie/go-support/pkg/e2e"
+	"github.com/adamrobbie/go-support/pkg/input"
+	"github.com/adamrobbie/go-support/pkg/macro"
+	"github.com/adamrobbie/go-support/pkg/overlay"
 	"github.com/adamrobbie/go-support/pkg/permissions"
+	"github.com/adamrobbie/go-support/pkg/policy"
+	"github.com/adamrobbie/go-support/pkg/processes"
 	"github.com/adamrobbie/go-support/pkg/remote"
 	"github.com/adamrobbie/go-support/pkg/screenshot"
+	"github.com/adamrobbie/go-support/pkg/script"
+	"github.com/adamrobbie/go-support/pkg/session"
+	"github.com/adamrobbie/go-support/pkg/shell"
+	"github.com/adamrobbie/go-support/pkg/signing"
+	"github.com/adamrobbie/go-support/pkg/transfer"
 	"github.com/adamrobbie/go-support/pkg/video"
 	"github.com/gorilla/websocket"
 	"github.com/joho/godotenv"
@@ -47,6 +63,67 @@ type Config struct {
 	VideoFPS          int    // Frames per second for video streaming
 	VideoRecording    bool   // Whether to enable video recording
 	VideoRecordingDir string // Directory to save video recordings
+	CursorRate        int    // Cursor updates per second while streaming (0 disables them)
+	CompositeCursor   bool   // Whether to draw the pointer into video frames
+
+	// WebSocket security options
+	AuthToken      string   // Bearer token sent with the WebSocket handshake
+	APIKey         string   // API key sent with the WebSocket handshake
+	APIKeyHeader   string   // Header name for the API key
+	CAFile         string   // PEM bundle of additional trusted CAs
+	ClientCertFile string   // Client certificate for mutual TLS
+	ClientKeyFile  string   // Client private key for mutual TLS
+	ServerName     string   // Server name to verify the certificate against, if not the URL host
+	PinnedSPKI     []string // SHA-256 SPKI pins of the server certificate
+
+	// Signed command options
+	RequireSignedCommands bool          // Whether control commands must carry a valid signature
+	CommandPublicKeys     string        // Comma-separated keyId:base64 Ed25519 public keys
+	CommandPublicKeysFile string        // File with one keyId:base64 public key per line
+	CommandMaxSkew        time.Duration // Allowed clock skew for signed command timestamps
+
+	// End-to-end encryption options
+	E2EEncryption     bool          // Whether to encrypt screen frames end-to-end for the viewer
+	E2ERotateInterval time.Duration // How often to rotate the end-to-end key (0 disables rotation)
+
+	// Session options
+	PairingMode bool // Whether to require a technician to join with a pairing code
+	MultiViewer bool // Whether several viewers may join, with one holding the controller role
+
+	// Clipboard options
+	ClipboardSync    bool // Whether to share the clipboard with the technician
+	ClipboardMaxSize int  // Maximum clipboard content size in bytes
+
+	// File transfer options
+	FileTransfer    bool     // Whether the technician may upload and download files
+	TransferDirs    []string // Directories files may be written to and read from
+	TransferMaxSize int64    // Maximum size of a transferred file in bytes
+
+	// Remote command options
+	ExecAllowed  []string      // Commands the technician may run
+	ExecAllowAny bool          // Whether the technician may run any command
+	ExecTimeout  time.Duration // Maximum run time of a command
+	AuditLog     string        // File the audit trail is appended to
+
+	// Remote shell options
+	RemoteShell bool   // Whether the technician may open interactive shells
+	ShellPath   string // Shell to run ($SHELL or /bin/sh if empty)
+
+	// Capability policy options
+	Capabilities  []string // Capabilities granted to the technician, e.g. listProcesses
+	LaunchAllowed []string // Applications the technician may launch
+
+	// Screen overlay options
+	ScreenOverlay bool // Whether viewers may draw pointers and highlights on the screen
+
+	// Macro options
+	MacroDir string // Directory macros are saved to and played from by name
+
+	// Keyboard options
+	KeyboardLayout string // Layout text is typed with, e.g. de, or empty to detect it
+
+	// Script options
+	RunScript string // Script file to run locally instead of connecting, from the run-script subcommand
 }
 
 // App represents the application
@@ -59,6 +136,28 @@ type App struct {
 	Interrupt          chan os.Signal
 	RemoteController   *remote.RemoteController
 	VideoStream        *video.VideoStream
+	CommandVerifier    *signing.Verifier    // Verifies signed control commands when enabled
+	E2EChannel         *e2e.Channel         // End-to-end encryption channel to the viewer
+	stopE2ERotation    chan struct{}        // Channel to stop periodic key rotation
+	Pairing            *session.Pairing     // Pairing-code session state, if pairing mode is enabled
+	Viewers            *session.Roster      // Connected viewers and their roles, if multi-viewer mode is enabled
+	Clipboard          *clipboard.Clipboard // Clipboard access, if clipboard sync is enabled
+	stopClipboardWatch chan struct{}        // Channel to stop watching the clipboard
+	Transfers          *transfer.Manager    // File transfers, if file transfer is enabled
+	Commands           *command.Runner      // Remote command execution, if enabled
+	Shells             *shell.Manager       // Interactive remote shells, if enabled
+	Policy             *policy.Policy       // Capabilities granted to the technician
+	Processes          *processes.Manager   // Process management, if the policy grants it
+	Audit              *audit.Logger        // Audit trail of sensitive actions
+	Overlay            *overlay.Manager     // Pointers and highlights drawn by viewers, if enabled
+	Chat               *chat.Chat           // Chat with the technicians
+	Macros             *macro.Manager       // Input macro recording and playback
+	Scripts            *script.Manager      // Automation scripts run by the server
+	Input              *remote.Translator   // Translates DOM input events from the web viewer
+	InputQueue         *input.Queue         // Executes input events off the message loop
+	prompter           prompter             // Asks the local user for consent
+	composer           composer             // Chat message being typed after `say`
+	executor           eventExecutor        // Executes input events instead of RemoteController, if set
 }
 
 // Message types
@@ -78,6 +177,21 @@ const (
 	MessageTypeScreenRecordingStatus = "screenRecordingStatus" // New message type for screen recording status
 	MessageTypeScreenRecordingSaved  = "screenRecordingSaved"  // New message type for when recording is saved
 	MessageTypeGetRecordingStatus    = "getRecordingStatus"    // New message type for requesting recording status
+	MessageTypeCommandRejected       = "commandRejected"       // Sent when a control command fails signature verification
+	MessageTypeKeyExchange           = e2e.MessageType         // End-to-end encryption key exchange
+	MessageTypeGetClipboard          = "getClipboard"          // Server requests the clipboard content
+	MessageTypeSetClipboard          = "setClipboard"          // Server replaces the clipboard content
+	MessageTypeClipboardContent      = "clipboardContent"      // Reply to getClipboard
+	MessageTypeClipboardChanged      = "clipboardChanged"      // Sent when the local clipboard changes
+	MessageTypeGetSystemInfo         = "getSystemInfo"         // Server requests a diagnostics report
+	MessageTypeSystemInfo            = "systemInfo"            // Reply to getSystemInfo
+	MessageTypeListWindows           = "listWindows"           // Server requests the top-level windows
+	MessageTypeWindowList            = "windowList"            // Reply to listWindows
+	MessageTypeWindowEvent           = "windowEvent"           // Server focuses, raises, minimizes, moves or resizes a window
+	MessageTypeCursorUpdate          = "cursorUpdate"          // Sent when the pointer moves or changes shape while streaming
+	MessageTypeClickImage            = "clickImage"            // Server asks to find an image on screen and click it
+	MessageTypeImageClicked          = "imageClicked"          // Reply to clickImage
+	MessageTypeInputEvent            = "inputEvent"            // DOM mouse, wheel or keyboard event from the web viewer
 )
 
 // ScreenshotMessage represents a screenshot message to be sent to the server
@@ -87,13 +201,17 @@ type ScreenshotMessage struct {
 	Width     int    `json:"width"`
 	Height    int    `json:"height"`
 	Timestamp string `json:"timestamp"`
+	Encrypted bool   `json:"encrypted,omitempty"` // Whether ImageURL is sealed end-to-end
+	Epoch     uint32 `json:"epoch,omitempty"`     // End-to-end key epoch
+	Nonce     string `json:"nonce,omitempty"`     // End-to-end AEAD nonce
 }
 
 // ClientInfoMessage represents client information to be sent to the server
 type ClientInfoMessage struct {
-	Type     string `json:"type"`
-	Platform string `json:"platform"`
-	Version  string `json:"version"`
+	Type     string               `json:"type"`
+	Platform string               `json:"platform"`
+	Version  string               `json:"version"`
+	System   *diagnostics.Summary `json:"system,omitempty"` // Compact system summary
 }
 
 // dumpMessageTypes logs all available message types for debugging
@@ -114,6 +232,23 @@ func dumpMessageTypes() {
 	log.Printf("ScreenRecordingStatus: %s", MessageTypeScreenRecordingStatus)
 	log.Printf("ScreenRecordingSaved:  %s", MessageTypeScreenRecordingSaved)
 	log.Printf("GetRecordingStatus:    %s", MessageTypeGetRecordingStatus)
+	log.Printf("CommandRejected:       %s", MessageTypeCommandRejected)
+	log.Printf("KeyExchange:           %s", MessageTypeKeyExchange)
+	log.Printf("GetClipboard:          %s", MessageTypeGetClipboard)
+	log.Printf("SetClipboard:          %s", MessageTypeSetClipboard)
+	log.Printf("ClipboardContent:      %s", MessageTypeClipboardContent)
+	log.Printf("ClipboardChanged:      %s", MessageTypeClipboardChanged)
+	log.Printf("GetSystemInfo:         %s", MessageTypeGetSystemInfo)
+	log.Printf("SystemInfo:            %s", MessageTypeSystemInfo)
+	log.Printf("ListWindows:           %s", MessageTypeListWindows)
+	log.Printf("WindowList:            %s", MessageTypeWindowList)
+	log.Printf("WindowEvent:           %s", MessageTypeWindowEvent)
+	log.Printf("CursorUpdate:          %s", MessageTypeCursorUpdate)
+	log.Printf("ClickImage:            %s", MessageTypeClickImage)
+	log.Printf("ImageClicked:          %s", MessageTypeImageClicked)
+	log.Printf("InputEvent:            %s", MessageTypeInputEvent)
+	log.Printf("InputBatch:            %s", input.MessageTypeInputBatch)
+	log.Printf("InputBackpressure:     %s", input.MessageTypeInputBackpressure)
 	log.Println("========================================")
 }
 
@@ -135,6 +270,22 @@ func main() {
 	videoFPS := flag.Int("video-fps", 10, "Frames per second for video streaming")
 	videoRecording := flag.Bool("video-recording", false, "Enable video recording")
 	videoRecordingDir := flag.String("video-recording-dir", "recordings", "Directory to save video recordings")
+	compositeCursor := flag.Bool("composite-cursor", false, "Draw the mouse pointer into video frames")
+
+	// Security flags
+	requireSignedCommands := flag.Bool("require-signed-commands", false, "Reject control commands without a valid signature")
+	e2eEncryption := flag.Bool("e2e", false, "Encrypt screen frames end-to-end for the viewer")
+
+	// Session flags
+	pairingMode := flag.Bool("pairing", false, "Require a technician to join with a pairing code before accepting control")
+	clipboardSync := flag.Bool("clipboard", false, "Share the clipboard with the technician")
+	fileTransfer := flag.Bool("file-transfer", false, "Allow the technician to upload and download files in the allowed directories")
+	execAllowAny := flag.Bool("exec-allow-any", false, "Allow the technician to run any command, not just those in EXEC_ALLOWED")
+	capabilities := flag.String("capabilities", "", "Comma-separated capabilities granted to the technician (listProcesses, killProcess, launchApplication or all)")
+	remoteShell := flag.Bool("shell", false, "Allow the technician to open interactive shells")
+	auditLog := flag.String("audit-log", "", "File to append the audit trail to (default audit.log)")
+	screenOverlay := flag.Bool("overlay", false, "Allow viewers to point at and highlight parts of the screen")
+	multiViewer := flag.Bool("multi-viewer", false, "Allow several viewers, accepting input only from the viewer holding the controller role")
 
 	flag.Parse()
 
@@ -156,6 +307,34 @@ func main() {
 	config.VideoFPS = *videoFPS
 	config.VideoRecording = *videoRecording
 	config.VideoRecordingDir = *videoRecordingDir
+	config.CompositeCursor = *compositeCursor
+
+	// Security configuration
+	config.RequireSignedCommands = *requireSignedCommands
+	config.E2EEncryption = *e2eEncryption
+
+	// Session configuration
+	config.PairingMode = *pairingMode
+	config.MultiViewer = *multiViewer
+	config.ClipboardSync = *clipboardSync
+	config.FileTransfer = *fileTransfer
+	config.ExecAllowAny = *execAllowAny
+	config.AuditLog = *auditLog
+	config.RemoteShell = *remoteShell
+	config.Capabilities = splitList(*capabilities)
+	config.ScreenOverlay = *screenOverlay
+
+	// Subcommands
+	switch flag.Arg(0) {
+	case "":
+	case "run-script":
+		if flag.NArg() != 2 {
+			log.Fatal("Usage: go-support [flags] run-script <file>")
+		}
+		config.RunScript = flag.Arg(1)
+	default:
+		log.Fatalf("Unknown command: %s", flag.Arg(0))
+	}
 
 	// Load additional configuration from environment
 	if err := loadConfig(&config); err != nil {
@@ -164,7 +343,7 @@ func main() {
 
 	// Print configuration if verbose
 	if config.Verbose {
-		log.Printf("Configuration: %+v", config)
+		log.Printf("Configuration: %+v", config.redacted())
 		// Dump message types for debugging
 		dumpMessageTypes()
 	}
@@ -202,6 +381,180 @@ func loadConfig(config *Config) error {
 		}
 	}
 
+	// Get cursor options from environment
+	if config.CursorRate == 0 {
+		config.CursorRate = defaultCursorRate
+		if value := os.Getenv("CURSOR_RATE"); value != "" {
+			rate, err := strconv.Atoi(value)
+			if err != nil || rate < 0 {
+				return fmt.Errorf("invalid CURSOR_RATE: %s", value)
+			}
+			config.CursorRate = rate
+		}
+	}
+	if config.CursorRate > video.MaxCursorRate {
+		return fmt.Errorf("CURSOR_RATE %d is above the maximum of %d", config.CursorRate, video.MaxCursorRate)
+	}
+	if !config.CompositeCursor {
+		config.CompositeCursor = os.Getenv("COMPOSITE_CURSOR") == "true"
+	}
+
+	// Get WebSocket security options from environment
+	if config.AuthToken == "" {
+		config.AuthToken = os.Getenv("WEBSOCKET_AUTH_TOKEN")
+	}
+	if config.APIKey == "" {
+		config.APIKey = os.Getenv("WEBSOCKET_API_KEY")
+	}
+	if config.APIKeyHeader == "" {
+		config.APIKeyHeader = os.Getenv("WEBSOCKET_API_KEY_HEADER")
+	}
+	if config.CAFile == "" {
+		config.CAFile = os.Getenv("WEBSOCKET_CA_FILE")
+	}
+	if config.ClientCertFile == "" {
+		config.ClientCertFile = os.Getenv("WEBSOCKET_CLIENT_CERT")
+	}
+	if config.ClientKeyFile == "" {
+		config.ClientKeyFile = os.Getenv("WEBSOCKET_CLIENT_KEY")
+	}
+	if config.ServerName == "" {
+		config.ServerName = os.Getenv("WEBSOCKET_SERVER_NAME")
+	}
+	if len(config.PinnedSPKI) == 0 {
+		config.PinnedSPKI = splitList(os.Getenv("WEBSOCKET_PINNED_SPKI"))
+	}
+
+	// Get signed command options from environment
+	if !config.RequireSignedCommands {
+		config.RequireSignedCommands = os.Getenv("REQUIRE_SIGNED_COMMANDS") == "true"
+	}
+	if config.CommandPublicKeys == "" {
+		config.CommandPublicKeys = os.Getenv("COMMAND_PUBLIC_KEYS")
+	}
+	if config.CommandPublicKeysFile == "" {
+		config.CommandPublicKeysFile = os.Getenv("COMMAND_PUBLIC_KEYS_FILE")
+	}
+	if config.CommandMaxSkew == 0 {
+		if value := os.Getenv("COMMAND_MAX_SKEW"); value != "" {
+			skew, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid COMMAND_MAX_SKEW: %w", err)
+			}
+			config.CommandMaxSkew = skew
+		}
+	}
+
+	// Get end-to-end encryption options from environment
+	if !config.E2EEncryption {
+		config.E2EEncryption = os.Getenv("E2E_ENCRYPTION") == "true"
+	}
+	if config.E2ERotateInterval == 0 {
+		if value := os.Getenv("E2E_ROTATE_INTERVAL"); value != "" {
+			interval, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid E2E_ROTATE_INTERVAL: %w", err)
+			}
+			config.E2ERotateInterval = interval
+		}
+	}
+
+	// Get session options from environment
+	if !config.PairingMode {
+		config.PairingMode = os.Getenv("PAIRING_MODE") == "true"
+	}
+	if !config.MultiViewer {
+		config.MultiViewer = os.Getenv("MULTI_VIEWER") == "true"
+	}
+
+	// Get clipboard options from environment
+	if !config.ClipboardSync {
+		config.ClipboardSync = os.Getenv("CLIPBOARD_SYNC") == "true"
+	}
+	if config.ClipboardMaxSize == 0 {
+		if value := os.Getenv("CLIPBOARD_MAX_SIZE"); value != "" {
+			size, err := strconv.Atoi(value)
+			if err != nil || size <= 0 {
+				return fmt.Errorf("invalid CLIPBOARD_MAX_SIZE: %s", value)
+			}
+			config.ClipboardMaxSize = size
+		}
+	}
+
+	// Get file transfer options from environment
+	if !config.FileTransfer {
+		config.FileTransfer = os.Getenv("FILE_TRANSFER") == "true"
+	}
+	if len(config.TransferDirs) == 0 {
+		config.TransferDirs = splitList(os.Getenv("FILE_TRANSFER_DIRS"))
+	}
+	if config.TransferMaxSize == 0 {
+		if value := os.Getenv("FILE_TRANSFER_MAX_SIZE"); value != "" {
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size <= 0 {
+				return fmt.Errorf("invalid FILE_TRANSFER_MAX_SIZE: %s", value)
+			}
+			config.TransferMaxSize = size
+		}
+	}
+
+	// Get remote command options from environment
+	if len(config.ExecAllowed) == 0 {
+		config.ExecAllowed = splitList(os.Getenv("EXEC_ALLOWED"))
+	}
+	if !config.ExecAllowAny {
+		config.ExecAllowAny = os.Getenv("EXEC_ALLOW_ANY") == "true"
+	}
+	if config.ExecTimeout == 0 {
+		if value := os.Getenv("EXEC_TIMEOUT"); value != "" {
+			timeout, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid EXEC_TIMEOUT: %w", err)
+			}
+			config.ExecTimeout = timeout
+		}
+	}
+	if config.AuditLog == "" {
+		config.AuditLog = os.Getenv("AUDIT_LOG")
+	}
+	if config.AuditLog == "" {
+		config.AuditLog = "audit.log"
+	}
+
+	// Get remote shell options from environment
+	if !config.RemoteShell {
+		config.RemoteShell = os.Getenv("REMOTE_SHELL") == "true"
+	}
+	if config.ShellPath == "" {
+		config.ShellPath = os.Getenv("REMOTE_SHELL_PATH")
+	}
+
+	// Get macro options from environment
+	if config.MacroDir == "" {
+		config.MacroDir = os.Getenv("MACRO_DIR")
+		if config.MacroDir == "" {
+			config.MacroDir = defaultMacroDir
+		}
+	}
+
+	// Get keyboard options from environment
+	if config.KeyboardLayout == "" {
+		config.KeyboardLayout = os.Getenv("KEYBOARD_LAYOUT")
+	}
+
+	// Get screen overlay options from environment
+	if !config.ScreenOverlay {
+		config.ScreenOverlay = os.Getenv("SCREEN_OVERLAY") == "true"
+	}
+
+	// Get the capability policy from environment
+	if len(config.Capabilities) == 0 {
+		config.Capabilities = splitList(os.Getenv("CAPABILITIES"))
+	}
+	if len(config.LaunchAllowed) == 0 {
+		config.LaunchAllowed = splitList(os.Getenv("LAUNCH_ALLOWED"))
+	}
+
 	// Create screenshot directory if it doesn't exist
 	if config.ScreenshotDir == "" {
 		config.ScreenshotDir = "screenshots"
@@ -214,12 +567,36 @@ func loadConfig(config *Config) error {
 	return nil
 }
 
+// redacted returns a copy of the configuration with secrets masked for logging
+func (c Config) redacted() Config {
+	if c.AuthToken != "" {
+		c.AuthToken = "[redacted]"
+	}
+	if c.APIKey != "" {
+		c.APIKey = "[redacted]"
+	}
+	return c
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // NewApp creates a new application instance
 func NewApp(config Config, interrupt chan os.Signal) *App {
 	return &App{
 		Config:             config,
 		Done:               make(chan struct{}),
 		stopAutoScreenshot: make(chan struct{}),
+		stopE2ERotation:    make(chan struct{}),
+		stopClipboardWatch: make(chan struct{}),
 		Interrupt:          interrupt,
 	}
 }
@@ -251,6 +628,11 @@ func (a *App) Run() error {
 		return nil // Exit after test
 	}
 
+	// Run a script locally if requested
+	if a.Config.RunScript != "" {
+		return a.runScriptFile(a.Config.RunScript)
+	}
+
 	// Connect to WebSocket server
 	if err := a.connectWebSocket(); err != nil {
 		return fmt.Errorf("failed to connect to WebSocket server: %w", err)
@@ -335,9 +717,12 @@ func (a *App) captureAndSendScreenshot(quality screenshot.Quality, description s
 		Height:    ss.Height,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	}
+	if err := a.sealScreenshot(&message); err != nil {
+		return err
+	}
 
 	log.Println("Sending screenshot to server...")
-	return a.WSClient.SendJSON(message)
+	return a.WSClient.SendJSONWithPriority(message, client.PriorityBulk)
 }
 
 // captureRegionAndSendScreenshot captures a screenshot of a specific region and sends it to the server
@@ -367,9 +752,12 @@ func (a *App) captureRegionAndSendScreenshot(region screenshot.Region, quality s
 		Height:    ss.Height,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	}
+	if err := a.sealScreenshot(&message); err != nil {
+		return err
+	}
 
 	log.Println("Sending region screenshot to server...")
-	return a.WSClient.SendJSON(message)
+	return a.WSClient.SendJSONWithPriority(message, client.PriorityBulk)
 }
 
 // checkPermissions checks if the application has the required permissions
@@ -442,6 +830,25 @@ func (a *App) checkPermissions() error {
 	return nil
 }
 
+// handleDisconnect stops what the technician started when the connection
+// ends: shells, commands, macros and scripts must not keep running with
+// nobody able to see their output or stop them
+func (a *App) handleDisconnect() {
+	log.Println("DEBUG: Connection closed, stopping remote shells, commands, macros and scripts")
+	if a.Shells != nil {
+		a.Shells.Close()
+	}
+	if a.Commands != nil {
+		a.Commands.Close()
+	}
+	if a.Macros != nil {
+		a.Macros.Stop()
+	}
+	if a.Scripts != nil {
+		a.Scripts.Stop()
+	}
+}
+
 // connectWebSocket connects to the WebSocket server
 func (a *App) connectWebSocket() error {
 	// Determine the WebSocket URL
@@ -456,12 +863,102 @@ func (a *App) connectWebSocket() error {
 
 	// Create a new WebSocket client
 	a.WSClient = client.NewWebSocketClient(url, a.Config.Verbose)
+	a.WSClient.Auth = client.AuthOptions{
+		BearerToken:  a.Config.AuthToken,
+		APIKey:       a.Config.APIKey,
+		APIKeyHeader: a.Config.APIKeyHeader,
+	}
+	a.WSClient.TLS = client.TLSOptions{
+		CAFile:         a.Config.CAFile,
+		ClientCertFile: a.Config.ClientCertFile,
+		ClientKeyFile:  a.Config.ClientKeyFile,
+		ServerName:     a.Config.ServerName,
+		PinnedSPKI:     a.Config.PinnedSPKI,
+	}
+
+	// Run slow requests in pools of their own, so they do not hold up input
+	// and the other messages, which are handled in order in the default pool
+	a.WSClient.SetDispatch(MessageTypeTakeScreenshot, client.DispatchOptions{Pool: "screenshot"})
+	for _, messageType := range []string{MessageTypeGetSystemInfo, MessageTypeListWindows, processes.MessageTypeListProcesses} {
+		a.WSClient.SetDispatch(messageType, client.DispatchOptions{Pool: "queries", Workers: 2})
+	}
 
 	// Create a new remote controller
-	a.RemoteController = remote.NewRemoteController(a.PermManager, a.Config.Verbose)
+	a.RemoteController = a.newRemoteController()
+
+	// Set up signature verification for control commands
+	if err := a.initCommandVerifier(); err != nil {
+		return fmt.Errorf("failed to initialize command verification: %w", err)
+	}
+
+	// Set up end-to-end encryption of screen content
+	if err := a.initE2E(); err != nil {
+		return fmt.Errorf("failed to initialize end-to-end encryption: %w", err)
+	}
+
+	// Set up the pairing-code session
+	a.initPairing()
+
+	// Set up the viewer roster
+	a.initViewers()
 
-	// Register message handlers
-	a.WSClient.RegisterHandler(MessageTypeTakeScreenshot, func(data []byte) error {
+	// Set up clipboard sharing
+	if err := a.initClipboard(); err != nil {
+		return fmt.Errorf("failed to initialize clipboard: %w", err)
+	}
+
+	// Set up file transfers
+	if err := a.initTransfer(); err != nil {
+		return fmt.Errorf("failed to initialize file transfer: %w", err)
+	}
+
+	// Set up remote command execution
+	if err := a.initExec(); err != nil {
+		return fmt.Errorf("failed to initialize remote commands: %w", err)
+	}
+
+	// Set up diagnostics reports
+	a.initDiagnostics()
+
+	// Set up window listing and window control
+	a.initWindows()
+
+	// Set up clicking images found on screen
+	a.initVision()
+
+	// Set up macro recording and playback
+	a.initMacros()
+
+	// Set up DOM input events from the web viewer
+	a.initInput()
+
+	// Set up automation scripts
+	a.initScripts()
+
+	// Set up chat
+	a.initChat()
+
+	// Set up the screen overlay
+	if err := a.initOverlay(); err != nil {
+		return fmt.Errorf("failed to initialize screen overlay: %w", err)
+	}
+
+	// Set up process management
+	if err := a.initProcesses(); err != nil {
+		return fmt.Errorf("failed to initialize process management: %w", err)
+	}
+
+	// Set up remote shells
+	if err := a.initShell(); err != nil {
+		return fmt.Errorf("failed to initialize remote shells: %w", err)
+	}
+
+	// Clean up what the technician left open when the connection drops
+	a.WSClient.OnDisconnect(a.handleDisconnect)
+
+	// Register message handlers. Viewing the screen is open to any viewer;
+	// recording is a control command.
+	a.WSClient.RegisterHandler(MessageTypeTakeScreenshot, a.viewerHandler(MessageTypeTakeScreenshot, func(data []byte) error {
 		log.Println("DEBUG: Received screenshot request from server")
 
 		// Parse the full message for debugging
@@ -471,37 +968,11 @@ func (a *App) connectWebSocket() error {
 		}
 
 		return a.captureAndSendScreenshot(screenshot.High, "Requested screenshot")
-	})
-
-	a.WSClient.RegisterHandler(MessageTypeMouseEvent, func(data []byte) error {
-		log.Println("DEBUG: Received mouse event from server")
-
-		var event remote.MouseEvent
-		if err := json.Unmarshal(data, &event); err != nil {
-			log.Printf("ERROR: Failed to parse mouse event: %v", err)
-			log.Printf("ERROR: Raw mouse event data: %s", string(data))
-			return fmt.Errorf("failed to parse mouse event: %w", err)
-		}
-
-		log.Printf("DEBUG: Mouse event details: %+v", event)
-		return a.RemoteController.ExecuteMouseEvent(event)
-	})
-
-	a.WSClient.RegisterHandler(MessageTypeKeyboardEvent, func(data []byte) error {
-		log.Println("DEBUG: Received keyboard event from server")
+	}))
 
-		var event remote.KeyboardEvent
-		if err := json.Unmarshal(data, &event); err != nil {
-			log.Printf("ERROR: Failed to parse keyboard event: %v", err)
-			log.Printf("ERROR: Raw keyboard event data: %s", string(data))
-			return fmt.Errorf("failed to parse keyboard event: %w", err)
-		}
+	// Mouse, keyboard and DOM input events are queued by initInput
 
-		log.Printf("DEBUG: Keyboard event details: %+v", event)
-		return a.RemoteController.ExecuteKeyboardEvent(event)
-	})
-
-	a.WSClient.RegisterHandler(MessageTypeScreenSize, func(data []byte) error {
+	a.WSClient.RegisterHandler(MessageTypeScreenSize, a.viewerHandler(MessageTypeScreenSize, func(data []byte) error {
 		log.Println("DEBUG: Received screen size request from server")
 
 		width, height, err := a.RemoteController.GetScreenSize()
@@ -518,9 +989,9 @@ func (a *App) connectWebSocket() error {
 		}
 
 		return a.WSClient.SendJSON(message)
-	})
+	}))
 
-	a.WSClient.RegisterHandler(MessageTypeMousePosition, func(data []byte) error {
+	a.WSClient.RegisterHandler(MessageTypeMousePosition, a.viewerHandler(MessageTypeMousePosition, func(data []byte) error {
 		log.Println("DEBUG: Received mouse position request from server")
 
 		x, y, err := a.RemoteController.GetMousePosition()
@@ -537,10 +1008,10 @@ func (a *App) connectWebSocket() error {
 		}
 
 		return a.WSClient.SendJSON(message)
-	})
+	}))
 
 	// Register video streaming handlers
-	a.WSClient.RegisterHandler(MessageTypeStartVideo, func(data []byte) error {
+	a.WSClient.RegisterHandler(MessageTypeStartVideo, a.viewerHandler(MessageTypeStartVideo, func(data []byte) error {
 		log.Println("DEBUG: Received start video streaming request from server")
 
 		// Parse the full message for debugging
@@ -549,6 +1020,17 @@ func (a *App) connectWebSocket() error {
 			log.Printf("DEBUG: Start video request details: %+v", msg)
 		}
 
+		if a.Viewers != nil {
+			if _, err := a.handleViewerVideo(data, true); err != nil {
+				log.Printf("ERROR: Rejected start video request: %v", err)
+				return fmt.Errorf("rejected start video request: %w", err)
+			}
+			// Another viewer already started the stream
+			if a.VideoStream != nil && a.VideoStream.IsStreaming() {
+				return nil
+			}
+		}
+
 		err := a.startVideoStreaming()
 		if err != nil {
 			log.Printf("ERROR: Failed to start video streaming: %v", err)
@@ -556,9 +1038,9 @@ func (a *App) connectWebSocket() error {
 			log.Println("DEBUG: Video streaming started successfully")
 		}
 		return err
-	})
+	}))
 
-	a.WSClient.RegisterHandler(MessageTypeStopVideo, func(data []byte) error {
+	a.WSClient.RegisterHandler(MessageTypeStopVideo, a.viewerHandler(MessageTypeStopVideo, func(data []byte) error {
 		log.Println("DEBUG: Received stop video streaming request from server")
 
 		// Parse the full message for debugging
@@ -567,12 +1049,24 @@ func (a *App) connectWebSocket() error {
 			log.Printf("DEBUG: Stop video request details: %+v", msg)
 		}
 
+		if a.Viewers != nil {
+			watching, err := a.handleViewerVideo(data, false)
+			if err != nil {
+				log.Printf("ERROR: Rejected stop video request: %v", err)
+				return fmt.Errorf("rejected stop video request: %w", err)
+			}
+			if watching > 0 {
+				log.Printf("DEBUG: Keeping video stream running for %d other viewer(s)", watching)
+				return nil
+			}
+		}
+
 		a.stopVideoStreaming()
 		log.Println("DEBUG: Video streaming stopped successfully")
 		return nil
-	})
+	}))
 
-	a.WSClient.RegisterHandler(MessageTypeStartRecording, func(data []byte) error {
+	a.WSClient.RegisterHandler(MessageTypeStartRecording, a.controlHandler(MessageTypeStartRecording, func(data []byte) error {
 		log.Println("DEBUG: Received start video recording request from server")
 
 		// Parse the full message for debugging
@@ -588,9 +1082,9 @@ func (a *App) connectWebSocket() error {
 			log.Println("DEBUG: Video recording started successfully")
 		}
 		return err
-	})
+	}))
 
-	a.WSClient.RegisterHandler(MessageTypeStopRecording, func(data []byte) error {
+	a.WSClient.RegisterHandler(MessageTypeStopRecording, a.controlHandler(MessageTypeStopRecording, func(data []byte) error {
 		log.Println("DEBUG: Received stop video recording request from server")
 
 		// Parse the full message for debugging
@@ -606,10 +1100,10 @@ func (a *App) connectWebSocket() error {
 			log.Println("DEBUG: Video recording stopped successfully")
 		}
 		return err
-	})
+	}))
 
 	// Register recording status request handler
-	a.WSClient.RegisterHandler(MessageTypeGetRecordingStatus, func(data []byte) error {
+	a.WSClient.RegisterHandler(MessageTypeGetRecordingStatus, a.viewerHandler(MessageTypeGetRecordingStatus, func(data []byte) error {
 		log.Println("DEBUG: Received recording status request from server")
 
 		// Parse the full message for debugging
@@ -625,7 +1119,7 @@ func (a *App) connectWebSocket() error {
 			log.Println("DEBUG: Recording status sent successfully")
 		}
 		return err
-	})
+	}))
 
 	// Connect to the server
 	if err := a.WSClient.Connect(); err != nil {
@@ -639,6 +1133,28 @@ func (a *App) connectWebSocket() error {
 		log.Printf("Failed to send client info: %v", err)
 	}
 
+	// Offer an end-to-end key to the viewer
+	if a.E2EChannel != nil {
+		if err := a.offerE2EKey(); err != nil {
+			log.Printf("Failed to offer end-to-end key: %v", err)
+		}
+		if a.Config.E2ERotateInterval > 0 {
+			go a.rotateE2EKeys()
+		}
+	}
+
+	// Report local clipboard changes to the technician
+	if a.Clipboard != nil {
+		go a.watchClipboard()
+	}
+
+	// Request a pairing code for the technician
+	if a.Pairing != nil {
+		if err := a.Pairing.Start(); err != nil {
+			log.Printf("Failed to request pairing code: %v", err)
+		}
+	}
+
 	// Send screen size information
 	width, height, err := a.RemoteController.GetScreenSize()
 	if err != nil {
@@ -695,6 +1211,36 @@ func (a *App) eventLoop() error {
 		if a.Config.AutoScreenshot {
 			close(a.stopAutoScreenshot)
 		}
+		close(a.stopE2ERotation)
+		close(a.stopClipboardWatch)
+		if a.Transfers != nil {
+			a.Transfers.Close()
+		}
+		if a.Commands != nil {
+			a.Commands.Close()
+		}
+		if a.Shells != nil {
+			a.Shells.Close()
+		}
+		if a.Overlay != nil {
+			a.Overlay.Close()
+		}
+		if a.Macros != nil {
+			a.Macros.Close()
+		}
+		if a.Scripts != nil {
+			a.Scripts.Stop()
+		}
+		if a.InputQueue != nil {
+			a.InputQueue.Close()
+		}
+		if a.Input != nil {
+			a.Input.Reset()
+		}
+		a.Audit.Close()
+		if a.Pairing != nil {
+			a.Pairing.End()
+		}
 		if a.WSClient != nil {
 			a.WSClient.Close()
 		}
@@ -718,6 +1264,17 @@ func (a *App) eventLoop() error {
 func (a *App) handleUserInput(scanner *bufio.Scanner) {
 	for scanner.Scan() {
 		input := scanner.Text()
+
+		// Answer a pending consent prompt
+		if a.prompter.answer(input) {
+			continue
+		}
+
+		// Send a chat message started with `say`
+		if a.composeLine(input) {
+			continue
+		}
+
 		args := strings.Fields(input)
 		if len(args) == 0 {
 			continue
@@ -796,6 +1353,42 @@ func (a *App) handleUserInput(scanner *bufio.Scanner) {
 			if err := a.handleRecordCommand(args[1:]); err != nil {
 				log.Printf("Error handling record command: %v", err)
 			}
+		case "pairing":
+			if err := a.handlePairingCommand(args[1:]); err != nil {
+				log.Printf("Error handling pairing command: %v", err)
+			}
+		case "control":
+			if err := a.handleControlCommand(args[1:]); err != nil {
+				log.Printf("Error handling control command: %v", err)
+			}
+		case "sysinfo":
+			a.printSystemInfo()
+		case "say":
+			if len(args) < 2 {
+				// Type the message on its own line, showing the technicians
+				// that the user is typing
+				if err := a.startComposing(); err != nil {
+					log.Printf("Error starting chat message: %v", err)
+				}
+				continue
+			}
+			if err := a.handleSayCommand(args[1:]); err != nil {
+				log.Printf("Error sending chat message: %v", err)
+			}
+		case "chat":
+			a.printChatHistory()
+		case "macro":
+			if len(args) < 2 {
+				log.Println("Usage: macro <record <name>|stop|play <name|file> [speed]|list>")
+				continue
+			}
+			if err := a.handleMacroCommand(args[1:]); err != nil {
+				log.Printf("Error handling macro command: %v", err)
+			}
+		case "windows":
+			if err := a.printWindows(); err != nil {
+				log.Printf("Error listing windows: %v", err)
+			}
 		case "help":
 			a.printHelp()
 		default:
@@ -840,6 +1433,7 @@ func (a *App) sendClientInfo() error {
 		Type:     MessageTypeClientInfo,
 		Platform: runtime.GOOS,
 		Version:  "1.0.0", // Your app version
+		System:   systemSummary(),
 	}
 
 	return a.WSClient.SendJSON(message)
@@ -1155,6 +1749,15 @@ func boolToStatus(granted bool) string {
 	return "❌ Not Granted"
 }
 
+// newRemoteController creates a remote controller that types with the configured keyboard layout
+func (a *App) newRemoteController() *remote.RemoteController {
+	rc := remote.NewRemoteController(a.PermManager, a.Config.Verbose)
+	if err := rc.SetKeyboardLayout(a.Config.KeyboardLayout); err != nil {
+		log.Printf("Warning: %v, detecting the keyboard layout instead", err)
+	}
+	return rc
+}
+
 // initVideoStream initializes the video stream
 func (a *App) initVideoStream() error {
 	// Convert quality string to video.Quality
@@ -1180,11 +1783,44 @@ func (a *App) initVideoStream() error {
 				"frameData": base64.StdEncoding.EncodeToString(frameData),
 				"timestamp": time.Now().Format(time.RFC3339),
 			}
-			return a.WSClient.SendJSON(message)
+			if a.E2EChannel != nil {
+				sealed, err := a.E2EChannel.Seal(MessageTypeVideoFrame, frameData)
+				if err != nil {
+					// Never fall back to plaintext; drop the frame until a key is agreed
+					if a.Config.Verbose {
+						log.Printf("DEBUG: Dropping video frame: %v", err)
+					}
+					return nil
+				}
+				message["frameData"] = sealed.Ciphertext
+				message["encrypted"] = true
+				message["epoch"] = sealed.Epoch
+				message["nonce"] = sealed.Nonce
+			}
+			// Frames wait behind replies and stale ones are dropped
+			return a.WSClient.SendJSONWithPriority(message, client.PriorityStream)
 		}
 		return nil
 	})
 
+	// Send the pointer separately from the frames, at a higher rate
+	a.VideoStream.SetCursorSource(a.readCursor, a.Config.CursorRate)
+	a.VideoStream.SetCompositeCursor(a.Config.CompositeCursor)
+	a.VideoStream.SetOnCursorUpdate(func(update video.CursorUpdate) error {
+		if a.WSClient == nil || !a.WSClient.IsConnected() {
+			return nil
+		}
+		message, err := cursorUpdateMessage(update)
+		if err != nil {
+			return err
+		}
+		// A new shape must arrive, while a stale position can be dropped
+		if message.Shape != nil {
+			return a.WSClient.SendJSON(message)
+		}
+		return a.WSClient.SendJSONWithPriority(message, client.PriorityStream)
+	})
+
 	// Create video recording directory if needed
 	if a.Config.VideoRecording {
 		if err := os.MkdirAll(a.Config.VideoRecordingDir, 0755); err != nil {
@@ -1370,6 +2006,17 @@ func (a *App) printHelp() {
 	fmt.Println("  key <action> [params...]   - Perform a keyboard action")
 	fmt.Println("  video <start|stop|status>  - Control video streaming")
 	fmt.Println("  record <start|stop|status> - Control video recording")
+	fmt.Println("  pairing [status|new|end]   - Show, renew or end the pairing session")
+	fmt.Println("  control [status|grant <id>|revoke] - Show viewers, or grant or revoke remote control")
+	fmt.Println("  sysinfo                    - Show OS, CPU, memory, disk, network and top processes")
+	fmt.Println("  windows                    - List the top-level windows")
+	fmt.Println("  say <message>              - Send a chat message to the technician")
+	fmt.Println("  say                        - Type a chat message on the next line, showing that you are typing")
+	fmt.Println("  chat                       - Show the chat history of this session")
+	fmt.Println("  macro record <name>        - Record mouse and key commands into a macro")
+	fmt.Println("  macro stop                 - Save the recording, or stop the playing macro")
+	fmt.Println("  macro play <name> [speed]  - Play a saved macro (move the mouse to abort)")
+	fmt.Println("  macro list                 - List the saved macros")
 	fmt.Println("  help                       - Show this help message")
 	fmt.Println("  exit, quit                 - Exit the application")
 }
@@ -1394,7 +2041,7 @@ func (a *App) handleMouseCommand(args []string) error {
 		if err != nil {
 			return fmt.Errorf("invalid y coordinate: %w", err)
 		}
-		return a.RemoteController.ExecuteMouseEvent(remote.MouseEvent{
+		return a.executeMouseEvent(remote.MouseEvent{
 			Action: remote.MouseMove,
 			X:      x,
 			Y:      y,
@@ -1409,7 +2056,7 @@ func (a *App) handleMouseCommand(args []string) error {
 				button = remote.MiddleButton
 			}
 		}
-		return a.RemoteController.ExecuteMouseEvent(remote.MouseEvent{
+		return a.executeMouseEvent(remote.MouseEvent{
 			Action: remote.MouseClick,
 			Button: button,
 		})
@@ -1423,7 +2070,7 @@ func (a *App) handleMouseCommand(args []string) error {
 				button = remote.MiddleButton
 			}
 		}
-		return a.RemoteController.ExecuteMouseEvent(remote.MouseEvent{
+		return a.executeMouseEvent(remote.MouseEvent{
 			Action: remote.MouseDown,
 			Button: button,
 		})
@@ -1437,7 +2084,7 @@ func (a *App) handleMouseCommand(args []string) error {
 				button = remote.MiddleButton
 			}
 		}
-		return a.RemoteController.ExecuteMouseEvent(remote.MouseEvent{
+		return a.executeMouseEvent(remote.MouseEvent{
 			Action: remote.MouseUp,
 			Button: button,
 		})
@@ -1465,7 +2112,7 @@ func (a *App) handleKeyCommand(args []string) error {
 		if len(args) < 2 {
 			return fmt.Errorf("usage: key press <key>")
 		}
-		return a.RemoteController.ExecuteKeyboardEvent(remote.KeyboardEvent{
+		return a.executeKeyboardEvent(remote.KeyboardEvent{
 			Action: remote.KeyPress,
 			Key:    args[1],
 		})
@@ -1473,7 +2120,7 @@ func (a *App) handleKeyCommand(args []string) error {
 		if len(args) < 2 {
 			return fmt.Errorf("usage: key down <key>")
 		}
-		return a.RemoteController.ExecuteKeyboardEvent(remote.KeyboardEvent{
+		return a.executeKeyboardEvent(remote.KeyboardEvent{
 			Action: remote.KeyDown,
 			Key:    args[1],
 		})
@@ -1481,7 +2128,7 @@ func (a *App) handleKeyCommand(args []string) error {
 		if len(args) < 2 {
 			return fmt.Errorf("usage: key up <key>")
 		}
-		return a.RemoteController.ExecuteKeyboardEvent(remote.KeyboardEvent{
+		return a.executeKeyboardEvent(remote.KeyboardEvent{
 			Action: remote.KeyUp,
 			Key:    args[1],
 		})
@@ -1490,7 +2137,7 @@ func (a *App) handleKeyCommand(args []string) error {
 			return fmt.Errorf("usage: key type <text>")
 		}
 		text := strings.Join(args[1:], " ")
-		return a.RemoteController.ExecuteKeyboardEvent(remote.KeyboardEvent{
+		return a.executeKeyboardEvent(remote.KeyboardEvent{
 			Action: remote.KeyType,
 			Text:   text,
 		})
@@ -1498,7 +2145,7 @@ func (a *App) handleKeyCommand(args []string) error {
 		if len(args) < 2 {
 			return fmt.Errorf("usage: key combo <key1> <key2> ...")
 		}
-		return a.RemoteController.ExecuteKeyboardEvent(remote.KeyboardEvent{
+		return a.executeKeyboardEvent(remote.KeyboardEvent{
 			Action: remote.KeyCombination,
 			Keys:   args[1:],
 		})
@@ -1,8 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+	"github.com/adamrobbie/go-support/pkg/clipboard"
+	"github.com/adamrobbie/go-support/pkg/e2e"
+	"github.com/adamrobbie/go-support/pkg/policy"
+	"github.com/adamrobbie/go-support/pkg/remote"
+	"github.com/adamrobbie/go-support/pkg/screenshot"
+	"github.com/adamrobbie/go-support/pkg/signing"
+	"github.com/adamrobbie/go-support/pkg/video"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -42,6 +63,15 @@ func TestLoadConfig(t *testing.T) {
 	}
 }
 
+func TestLoadConfigCursorRate(t *testing.T) {
+	for value, valid := range map[string]bool{"0": true, "30": true, "240": true, "241": false, "1000000001": false, "-1": false} {
+		t.Setenv("CURSOR_RATE", value)
+		if err := loadConfig(&Config{}); (err == nil) != valid {
+			t.Errorf("loadConfig() with CURSOR_RATE=%s returned %v", value, err)
+		}
+	}
+}
+
 func TestNewApp(t *testing.T) {
 	// Create a test configuration
 	config := Config{
@@ -95,3 +125,651 @@ func TestNewApp(t *testing.T) {
 		t.Error("Expected Interrupt channel to be the same as the one passed to NewApp")
 	}
 }
+
+func TestLoadConfigSecurity(t *testing.T) {
+	os.Setenv("WEBSOCKET_AUTH_TOKEN", "secret-token")
+	os.Setenv("WEBSOCKET_PINNED_SPKI", "sha256/aaa=, sha256/bbb= ,")
+	defer os.Unsetenv("WEBSOCKET_AUTH_TOKEN")
+	defer os.Unsetenv("WEBSOCKET_PINNED_SPKI")
+	t.Setenv("WEBSOCKET_SERVER_NAME", "support.example.com")
+
+	config := &Config{}
+	if err := loadConfig(config); err != nil {
+		t.Fatalf("loadConfig() returned an error: %v", err)
+	}
+
+	if config.AuthToken != "secret-token" {
+		t.Errorf("Expected AuthToken to be 'secret-token', got '%s'", config.AuthToken)
+	}
+
+	if len(config.PinnedSPKI) != 2 || config.PinnedSPKI[1] != "sha256/bbb=" {
+		t.Errorf("Expected two trimmed pins, got %v", config.PinnedSPKI)
+	}
+
+	if config.ServerName != "support.example.com" {
+		t.Errorf("Expected ServerName to be 'support.example.com', got '%s'", config.ServerName)
+	}
+
+	if config.redacted().AuthToken != "[redacted]" {
+		t.Error("Expected redacted() to mask the auth token")
+	}
+}
+
+func TestSignedHandler(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	app := NewApp(Config{
+		RequireSignedCommands: true,
+		CommandPublicKeys:     "tech:" + base64.StdEncoding.EncodeToString(pub),
+	}, make(chan os.Signal, 1))
+	if err := app.initCommandVerifier(); err != nil {
+		t.Fatalf("initCommandVerifier() returned an error: %v", err)
+	}
+
+	calls := 0
+	handler := app.signedHandler(MessageTypeMouseEvent, func(data []byte) error {
+		calls++
+		return nil
+	})
+
+	if err := handler([]byte(`{"type":"mouseEvent","action":"move","x":1,"y":2}`)); err == nil {
+		t.Error("Expected an unsigned command to be rejected")
+	}
+
+	signed, err := signing.Sign([]byte(`{"type":"mouseEvent","action":"move","x":1,"y":2}`), "tech", priv)
+	if err != nil {
+		t.Fatalf("Sign() returned an error: %v", err)
+	}
+	if err := handler(signed); err != nil {
+		t.Errorf("Expected a signed command to be accepted, got: %v", err)
+	}
+	if err := handler(signed); err == nil {
+		t.Error("Expected a replayed command to be rejected")
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected the handler to run once, ran %d times", calls)
+	}
+}
+
+func TestInitCommandVerifierWithoutKeys(t *testing.T) {
+	app := NewApp(Config{RequireSignedCommands: true}, make(chan os.Signal, 1))
+	if err := app.initCommandVerifier(); err == nil {
+		t.Error("Expected an error when no public keys are configured")
+	}
+}
+
+func TestSealScreenshot(t *testing.T) {
+	app := NewApp(Config{}, make(chan os.Signal, 1))
+
+	// Without end-to-end encryption the message is left untouched
+	message := ScreenshotMessage{Type: MessageTypeScreenshot, ImageURL: "data:image/png;base64,AAAA"}
+	if err := app.sealScreenshot(&message); err != nil || message.Encrypted {
+		t.Fatalf("sealScreenshot() without a channel = %v, encrypted=%v", err, message.Encrypted)
+	}
+
+	agent, _ := e2e.NewChannel()
+	viewer, _ := e2e.NewChannel()
+	app.E2EChannel = agent
+
+	// Before a key is agreed the screenshot must not be sent
+	if err := app.sealScreenshot(&message); err == nil {
+		t.Fatal("Expected sealScreenshot() to fail before the key exchange")
+	}
+
+	offer, _ := agent.Offer()
+	answer, _ := viewer.Accept(*offer)
+	agent.Accept(*answer)
+
+	if err := app.sealScreenshot(&message); err != nil {
+		t.Fatalf("sealScreenshot() returned an error: %v", err)
+	}
+	if !message.Encrypted || message.Nonce == "" {
+		t.Error("Expected the message to be marked as encrypted")
+	}
+
+	plaintext, err := viewer.Open(MessageTypeScreenshot, e2e.Sealed{
+		Epoch:      message.Epoch,
+		Nonce:      message.Nonce,
+		Ciphertext: message.ImageURL,
+	})
+	if err != nil || string(plaintext) != "data:image/png;base64,AAAA" {
+		t.Errorf("viewer Open() = %q, %v", plaintext, err)
+	}
+}
+
+func TestControlHandlerRequiresPairing(t *testing.T) {
+	app := NewApp(Config{PairingMode: true}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	app.initPairing()
+
+	calls := 0
+	handler := app.controlHandler(MessageTypeKeyboardEvent, func(data []byte) error {
+		calls++
+		return nil
+	})
+
+	if err := handler([]byte(`{"type":"keyboardEvent"}`)); err == nil {
+		t.Error("Expected a command to be rejected before pairing")
+	}
+	if calls != 0 {
+		t.Errorf("Expected the handler not to run, ran %d times", calls)
+	}
+}
+
+func TestControlHandlerRequiresController(t *testing.T) {
+	app := NewApp(Config{MultiViewer: true}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	app.initViewers()
+
+	calls := 0
+	handler := app.controlHandler(MessageTypeMouseEvent, func(data []byte) error {
+		calls++
+		return nil
+	})
+
+	if err := handler([]byte(`{"type":"mouseEvent"}`)); err == nil {
+		t.Error("Expected a command without a viewer ID to be rejected")
+	}
+	if err := handler([]byte(`{"type":"mouseEvent","viewerId":"v1"}`)); err == nil {
+		t.Error("Expected a command from a viewer without control to be rejected")
+	}
+	if calls != 0 {
+		t.Errorf("Expected the handler not to run, ran %d times", calls)
+	}
+}
+
+func TestDecodeClipboardMessage(t *testing.T) {
+	content, err := decodeClipboardMessage(ClipboardMessage{Data: "echo hi"})
+	if err != nil || content.Format != clipboard.Text || string(content.Data) != "echo hi" {
+		t.Errorf("decodeClipboardMessage() = %+v, %v", content, err)
+	}
+
+	content, err = decodeClipboardMessage(ClipboardMessage{Format: "image/png", Data: "iVBORw0KGgo="})
+	if err != nil || content.Format != clipboard.Image || len(content.Data) != 8 {
+		t.Errorf("decodeClipboardMessage() = %+v, %v", content, err)
+	}
+
+	if _, err := decodeClipboardMessage(ClipboardMessage{Format: "image/png", Data: "%%%"}); err == nil {
+		t.Error("Expected an error for invalid base64")
+	}
+	if _, err := decodeClipboardMessage(ClipboardMessage{Format: "text/html"}); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	app := NewApp(Config{Interactive: true}, make(chan os.Signal, 1))
+
+	if app.prompter.answer("y") {
+		t.Error("answer() should report no pending prompt")
+	}
+
+	result := make(chan bool)
+	go func() { result <- app.confirm("Allow?") }()
+
+	// Wait for the prompt to be pending
+	for !app.prompter.answer("yes") {
+		time.Sleep(time.Millisecond)
+	}
+	if !<-result {
+		t.Error("Expected confirm() to return true for 'yes'")
+	}
+
+	go func() { result <- app.confirm("Allow?") }()
+	for !app.prompter.answer("n") {
+		time.Sleep(time.Millisecond)
+	}
+	if <-result {
+		t.Error("Expected confirm() to return false for 'n'")
+	}
+}
+
+func TestInitTransfer(t *testing.T) {
+	dir := t.TempDir()
+	app := NewApp(Config{FileTransfer: true, TransferDirs: []string{dir}, PairingMode: true}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	app.initPairing()
+
+	if err := app.initTransfer(); err != nil {
+		t.Fatalf("initTransfer() returned an error: %v", err)
+	}
+	if app.Transfers == nil {
+		t.Fatal("Expected a transfer manager")
+	}
+
+	app = NewApp(Config{FileTransfer: true, TransferDirs: []string{filepath.Join(dir, "missing")}}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	if err := app.initTransfer(); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
+
+func TestInitExec(t *testing.T) {
+	app := NewApp(Config{}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	if err := app.initExec(); err != nil || app.Commands != nil || app.Audit != nil {
+		t.Fatalf("Expected remote commands to be disabled without an allowlist, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	app = NewApp(Config{ExecAllowed: []string{"uptime"}, AuditLog: path}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	if err := app.initExec(); err != nil {
+		t.Fatalf("initExec() returned an error: %v", err)
+	}
+	defer app.Audit.Close()
+
+	if app.Commands == nil || !app.Commands.Allowed("uptime") || app.Commands.Allowed("rm") {
+		t.Error("Expected a runner allowing only uptime")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the audit log to be created: %v", err)
+	}
+}
+
+func TestInitShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Remote shells are not supported on Windows")
+	}
+
+	app := NewApp(Config{RemoteShell: true, AuditLog: filepath.Join(t.TempDir(), "audit.log")}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	if err := app.initShell(); err != nil {
+		t.Fatalf("initShell() returned an error: %v", err)
+	}
+	defer app.Audit.Close()
+
+	if app.Shells == nil || app.Shells.Count() != 0 {
+		t.Error("Expected a shell manager with no open shells")
+	}
+}
+
+func TestClientInfoSummary(t *testing.T) {
+	message := ClientInfoMessage{Type: MessageTypeClientInfo, Platform: runtime.GOOS, System: systemSummary()}
+	if message.System == nil {
+		t.Fatal("Expected a system summary")
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("Failed to encode client info: %v", err)
+	}
+	var decoded map[string]interface{}
+	json.Unmarshal(data, &decoded)
+	system, ok := decoded["system"].(map[string]interface{})
+	if !ok || system["os"] == "" || system["memoryTotal"] == nil {
+		t.Errorf("Expected the summary in the client info, got %s", data)
+	}
+}
+
+func TestInitProcesses(t *testing.T) {
+	app := NewApp(Config{}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	if err := app.initProcesses(); err != nil || app.Processes != nil {
+		t.Fatalf("Expected process management to be disabled without capabilities, got %v", err)
+	}
+
+	app = NewApp(Config{Capabilities: []string{"listProcesses", "killProcess"}, AuditLog: filepath.Join(t.TempDir(), "audit.log")}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	if err := app.initProcesses(); err != nil {
+		t.Fatalf("initProcesses() returned an error: %v", err)
+	}
+	defer app.Audit.Close()
+	if app.Processes == nil || !app.Policy.Allows(policy.KillProcess) || app.Policy.Allows(policy.LaunchApplication) {
+		t.Error("Expected process management with listProcesses and killProcess")
+	}
+
+	app = NewApp(Config{Capabilities: []string{"rebootMachine"}}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	if err := app.initProcesses(); err == nil {
+		t.Error("Expected an error for an unknown capability")
+	}
+}
+
+// stubWindowManager is a window manager with a fixed window list
+type stubWindowManager struct {
+	windows []remote.Window
+	err     error
+}
+
+func (s stubWindowManager) List() ([]remote.Window, error)            { return s.windows, s.err }
+func (s stubWindowManager) Focus(id uint64) error                     { return nil }
+func (s stubWindowManager) Raise(id uint64) error                     { return nil }
+func (s stubWindowManager) Minimize(id uint64) error                  { return nil }
+func (s stubWindowManager) Move(id uint64, x, y int) error            { return nil }
+func (s stubWindowManager) Resize(id uint64, width, height int) error { return nil }
+
+func TestWindowList(t *testing.T) {
+	app := NewApp(Config{}, make(chan os.Signal, 1))
+	app.RemoteController = remote.NewRemoteController(nil, false)
+	app.RemoteController.SetWindowManager(stubWindowManager{windows: []remote.Window{{ID: 7, Title: "Editor", Focused: true}}})
+
+	reply := app.windowList("req-1")
+	if reply.Type != MessageTypeWindowList || reply.RequestID != "req-1" || reply.Error != "" {
+		t.Fatalf("Unexpected reply: %+v", reply)
+	}
+	if len(reply.Windows) != 1 || reply.Windows[0].Title != "Editor" {
+		t.Errorf("Expected the Editor window, got %+v", reply.Windows)
+	}
+
+	app.RemoteController.SetWindowManager(stubWindowManager{err: remote.ErrWindowsUnsupported})
+	reply = app.windowList("req-2")
+	if reply.Error == "" || reply.Windows == nil {
+		t.Errorf("Expected an error and an empty window list, got %+v", reply)
+	}
+}
+
+func TestCursorUpdateMessage(t *testing.T) {
+	cursor := &screenshot.Cursor{X: 10, Y: 20, Visible: true, Shape: screenshot.DefaultCursorShape(), Screen: image.Rect(0, 0, 800, 600)}
+
+	message, err := cursorUpdateMessage(video.CursorUpdate{Cursor: cursor, ShapeChanged: true})
+	if err != nil {
+		t.Fatalf("cursorUpdateMessage() returned an error: %v", err)
+	}
+	if message.Type != MessageTypeCursorUpdate || message.X != 10 || message.Y != 20 || message.ScreenWidth != 800 {
+		t.Errorf("Unexpected cursor update: %+v", message)
+	}
+	if message.Shape == nil || message.Shape.Image == "" || message.Shape.Width == 0 {
+		t.Fatalf("Expected the cursor shape, got %+v", message.Shape)
+	}
+
+	message, err = cursorUpdateMessage(video.CursorUpdate{Cursor: cursor})
+	if err != nil || message.Shape != nil {
+		t.Errorf("Expected a position-only update, got %+v, %v", message, err)
+	}
+}
+
+func TestViewerHandler(t *testing.T) {
+	app := NewApp(Config{MultiViewer: true}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	app.initViewers()
+
+	calls := 0
+	handler := app.viewerHandler("showPointer", func(data []byte) error {
+		calls++
+		return nil
+	})
+
+	if err := handler([]byte(`{"type":"showPointer"}`)); err == nil {
+		t.Error("Expected a command without a viewer ID to be rejected")
+	}
+	if err := handler([]byte(`{"type":"showPointer","viewerId":"v1"}`)); err == nil {
+		t.Error("Expected a command from an unknown viewer to be rejected")
+	}
+	if calls != 0 {
+		t.Errorf("Expected the handler not to run, ran %d times", calls)
+	}
+
+	app = NewApp(Config{}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	if err := app.initOverlay(); err != nil || app.Overlay != nil {
+		t.Errorf("Expected the overlay to be disabled by default, got %v", err)
+	}
+
+	// An overlay that cannot be opened is left out instead of stopping the agent
+	t.Setenv("DISPLAY", "")
+	app = NewApp(Config{ScreenOverlay: true}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	if err := app.initOverlay(); err != nil || app.Overlay != nil {
+		t.Errorf("Expected the agent to run without the overlay, got %v", err)
+	}
+}
+
+func TestInitChat(t *testing.T) {
+	app := NewApp(Config{}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	app.initChat()
+
+	if app.Chat == nil {
+		t.Fatal("Expected chat to be created")
+	}
+	if err := app.handleSayCommand([]string{"hello"}); err == nil {
+		t.Error("Expected an error when sending chat without a connection")
+	}
+	if n := len(app.Chat.History()); n != 0 {
+		t.Errorf("Expected no messages in the history, got %d", n)
+	}
+	if err := app.startComposing(); err == nil {
+		t.Error("Expected an error when typing a chat message without a connection")
+	}
+	if app.composeLine("not a chat message") {
+		t.Error("Expected a console line to be a command when no message is being typed")
+	}
+}
+
+func TestChatAcksFromServer(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	app := NewApp(Config{
+		MultiViewer:           true,
+		RequireSignedCommands: true,
+		CommandPublicKeys:     "tech:" + base64.StdEncoding.EncodeToString(pub),
+	}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	if err := app.initCommandVerifier(); err != nil {
+		t.Fatalf("initCommandVerifier() returned an error: %v", err)
+	}
+	app.initViewers()
+	app.initChat()
+
+	// Sending fails without a connection, but the message is kept in the history
+	entry, _ := app.Chat.Send("hello")
+
+	// Acknowledgments carry neither a viewer ID nor a signature
+	ack := app.WSClient.Handlers["chatAck"]
+	if err := ack([]byte(`{"type":"chatAck","id":"` + entry.ID + `"}`)); err != nil {
+		t.Fatalf("Expected the acknowledgment to be accepted, got: %v", err)
+	}
+	if history := app.Chat.History(); len(history) != 1 || !history[0].Delivered {
+		t.Errorf("Expected the message to be delivered, got %+v", history)
+	}
+
+	// Messages from viewers are still gated
+	if err := app.WSClient.Handlers["chat"]([]byte(`{"type":"chat","message":"hi"}`)); err == nil {
+		t.Error("Expected a chat message without a viewer ID to be rejected")
+	}
+}
+
+func TestSanitizeChat(t *testing.T) {
+	tests := map[string]string{
+		"hello":                     "hello",
+		"two\nlines\tand a tab":     "two lines and a tab",
+		"\x1b[2J\x1b]0;pwned\x07hi": "[2J]0;pwnedhi",
+		"\u202egnp.exe":             "gnp.exe",
+		"bell\x07 and null\x00":     "bell and null",
+		"émoji 👍 and ümlauts":       "émoji 👍 and ümlauts",
+	}
+	for input, want := range tests {
+		if got := sanitizeChat(input); got != want {
+			t.Errorf("sanitizeChat(%q) = %q, expected %q", input, got, want)
+		}
+	}
+}
+
+func TestMacroCommand(t *testing.T) {
+	app := NewApp(Config{MacroDir: t.TempDir()}, make(chan os.Signal, 1))
+	if err := app.handleMacroCommand([]string{"record", "demo"}); err == nil {
+		t.Error("Expected an error before the macro manager is created")
+	}
+
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	app.RemoteController = remote.NewRemoteController(nil, false)
+	app.initMacros()
+
+	if err := app.handleMacroCommand([]string{"record", "../demo"}); err == nil {
+		t.Error("Expected an invalid macro name to be rejected")
+	}
+	if err := app.handleMacroCommand([]string{"record", "demo"}); err != nil {
+		t.Fatalf("macro record returned an error: %v", err)
+	}
+	if err := app.handleMacroCommand([]string{"stop"}); err != nil {
+		t.Fatalf("macro stop returned an error: %v", err)
+	}
+
+	m, err := app.loadMacro("demo")
+	if err != nil {
+		t.Fatalf("Failed to load the saved macro: %v", err)
+	}
+	if m.Name != "demo" || len(m.Steps) != 0 {
+		t.Errorf("Unexpected macro: %+v", m)
+	}
+	if _, err := app.loadMacro("missing"); err == nil {
+		t.Error("Expected an error loading a missing macro")
+	}
+}
+
+func TestRunScriptFile(t *testing.T) {
+	app := NewApp(Config{ScreenshotDir: t.TempDir()}, make(chan os.Signal, 1))
+	dir := t.TempDir()
+
+	bad := filepath.Join(dir, "bad.txt")
+	os.WriteFile(bad, []byte("wait 1ms\n\nfly 1 2\n"), 0644)
+	if err := app.runScriptFile(bad); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected an error on line 3, got %v", err)
+	}
+
+	good := filepath.Join(dir, "good.txt")
+	os.WriteFile(good, []byte("# just wait\nwait 1ms\n"), 0644)
+	if err := app.runScriptFile(good); err != nil {
+		t.Errorf("runScriptFile() returned an error: %v", err)
+	}
+}
+
+func TestClickImage(t *testing.T) {
+	app := NewApp(Config{}, make(chan os.Signal, 1))
+	app.RemoteController = remote.NewRemoteController(nil, false)
+	app.RemoteController.SetScreenCapture(func() (image.Image, error) {
+		return image.NewRGBA(image.Rect(0, 0, 200, 100)), nil
+	})
+
+	reply := app.clickImage(ClickImageRequest{RequestID: "req-1", Image: "not base64!"})
+	if reply.Type != MessageTypeImageClicked || reply.RequestID != "req-1" || reply.Error == "" || reply.Found {
+		t.Errorf("Expected an error for an invalid image, got %+v", reply)
+	}
+
+	// A checkerboard that is not on the blank screen
+	template := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := 0; i < len(template.Pix); i += 8 {
+		template.Pix[i], template.Pix[i+3] = 255, 255
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, template)
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	reply = app.clickImage(ClickImageRequest{RequestID: "req-2", Image: dataURL})
+	if reply.Found || reply.Match != nil || !strings.Contains(reply.Error, "not found") {
+		t.Errorf("Expected the image not to be found, got %+v", reply)
+	}
+}
+
+// fakeExecutor records the input events it is asked to execute
+type fakeExecutor struct {
+	mu       sync.Mutex
+	keyboard []remote.KeyboardEvent
+}
+
+func (f *fakeExecutor) ExecuteMouseEvent(event remote.MouseEvent) error {
+	return nil
+}
+
+func (f *fakeExecutor) ExecuteKeyboardEvent(event remote.KeyboardEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keyboard = append(f.keyboard, event)
+	return nil
+}
+
+func (f *fakeExecutor) GetScreenSize() (int, int, error) {
+	return 1000, 500, nil
+}
+
+func TestInputEvent(t *testing.T) {
+	app := NewApp(Config{MacroDir: t.TempDir()}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	executor := &fakeExecutor{}
+	app.executor = executor
+	app.initMacros()
+	app.initInput()
+
+	handler, ok := app.WSClient.Handlers[MessageTypeInputEvent]
+	if !ok {
+		t.Fatal("Expected a handler for input events")
+	}
+	if err := app.Macros.StartRecording("input"); err != nil {
+		t.Fatal(err)
+	}
+	msg := `{"type":"inputEvent","viewWidth":100,"viewHeight":50,"event":{"type":"keydown","key":"x","code":"KeyX"}}`
+	if err := handler([]byte(msg)); err != nil {
+		t.Fatalf("Input event returned an error: %v", err)
+	}
+	if err := handler([]byte(`{"type":"inputEvent","event":{"type":"touchstart"}}`)); err == nil {
+		t.Error("Expected an unsupported event type to be rejected")
+	}
+	if err := handler([]byte(`{"type":"inputEvent"}`)); err == nil {
+		t.Error("Expected an input event without an event to be rejected")
+	}
+	app.InputQueue.Flush()
+	defer app.InputQueue.Close()
+
+	executor.mu.Lock()
+	typed := executor.keyboard
+	executor.mu.Unlock()
+	if len(typed) != 1 || typed[0].Action != remote.KeyType || typed[0].Text != "x" {
+		t.Errorf("Expected the key to be typed as x, got %+v", typed)
+	}
+
+	m, _, err := app.Macros.StopRecording(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Steps) != 1 || m.Steps[0].Keyboard == nil || m.Steps[0].Keyboard.Action != remote.KeyType || m.Steps[0].Keyboard.Text != "x" {
+		t.Errorf("Expected the translated key to be recorded as typing, got %+v", m.Steps)
+	}
+}
+
+func TestKeyExchangeRequiresPairing(t *testing.T) {
+	app := NewApp(Config{PairingMode: true, E2EEncryption: true}, make(chan os.Signal, 1))
+	app.WSClient = client.NewWebSocketClient("ws://example.com", false)
+	if err := app.initE2E(); err != nil {
+		t.Fatalf("initE2E() returned an error: %v", err)
+	}
+	app.initPairing()
+
+	viewer, _ := e2e.NewChannel()
+	offer, _ := viewer.Offer()
+	data, _ := json.Marshal(offer)
+	if err := app.WSClient.Handlers[MessageTypeKeyExchange](data); err == nil {
+		t.Error("Expected a key exchange to be rejected before pairing")
+	}
+	if app.E2EChannel.Ready() {
+		t.Error("Expected no key to be installed before pairing")
+	}
+}
+
+func TestScreenHandlersRequirePairing(t *testing.T) {
+	app := NewApp(Config{PairingMode: true, WebSocketURL: "ws://127.0.0.1:1", ScreenshotDir: t.TempDir()}, make(chan os.Signal, 1))
+	if err := app.connectWebSocket(); err == nil {
+		t.Fatal("Expected connecting to a closed port to fail")
+	}
+
+	for _, messageType := range []string{
+		MessageTypeTakeScreenshot, MessageTypeScreenSize, MessageTypeMousePosition,
+		MessageTypeStartVideo, MessageTypeStopVideo,
+		MessageTypeStartRecording, MessageTypeStopRecording, MessageTypeGetRecordingStatus,
+	} {
+		handler, ok := app.WSClient.Handlers[messageType]
+		if !ok {
+			t.Errorf("No handler registered for %s", messageType)
+			continue
+		}
+		if err := handler([]byte(`{"type":"` + messageType + `"}`)); !errors.Is(err, errNotPaired) {
+			t.Errorf("Expected %s to be rejected before pairing, got: %v", messageType, err)
+		}
+	}
+}
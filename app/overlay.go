@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log"
+
+	"github.com/adamrobbie/go-support/pkg/overlay"
+)
+
+// initOverlay opens the screen overlay and registers its handlers if it is enabled
+func (a *App) initOverlay() error {
+	if !a.Config.ScreenOverlay {
+		return nil
+	}
+
+	// The overlay is an extra; without it the agent still works
+	o, err := overlay.New()
+	if err != nil {
+		log.Printf("WARNING: Screen overlay is not available: %v", err)
+		return nil
+	}
+	a.Overlay = overlay.NewManager(viewerTransport{a}, o, overlay.Options{Verbose: a.Config.Verbose})
+
+	log.Println("Screen overlay enabled")
+	return nil
+}
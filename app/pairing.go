@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+	"github.com/adamrobbie/go-support/pkg/session"
+)
+
+// errNotPaired is returned for control commands received before a technician has joined
+var errNotPaired = errors.New("no technician has joined with the pairing code")
+
+// initPairing creates the pairing state machine if pairing mode is enabled
+func (a *App) initPairing() {
+	if !a.Config.PairingMode {
+		return
+	}
+
+	a.Pairing = session.NewPairing(a.WSClient, a.Config.Verbose)
+	a.Pairing.OnStateChange(func(state session.State) {
+		switch state {
+		case session.Waiting:
+			a.displayPairingCode()
+		case session.Paired:
+			technician := a.Pairing.Technician()
+			name := technician.Name
+			if name == "" {
+				name = technician.ID
+			}
+			fmt.Printf("\n✅ Technician %s joined the session. Remote control is now enabled.\n\n", name)
+			a.resetE2E(true)
+		case session.Expired:
+			fmt.Println("\n⚠️ The pairing code expired. Requesting a new one...")
+			go func() {
+				if err := a.Pairing.Start(); err != nil {
+					log.Printf("Failed to request a new pairing code: %v", err)
+				}
+			}()
+		case session.Ended:
+			fmt.Println("\nThe support session has ended. Remote control is disabled.")
+			if a.Shells != nil {
+				a.Shells.Close()
+			}
+			if a.Overlay != nil {
+				a.Overlay.Clear()
+			}
+			if a.Chat != nil {
+				a.Chat.Reset()
+			}
+			if a.Macros != nil {
+				a.Macros.Stop()
+			}
+			if a.Scripts != nil {
+				a.Scripts.Stop()
+			}
+			a.resetInput()
+			a.resetE2E(false)
+		}
+	})
+}
+
+// displayPairingCode prints the pairing code in the terminal
+func (a *App) displayPairingCode() {
+	code, expiresAt := a.Pairing.Code()
+	fmt.Println("=================================================================")
+	fmt.Println("🔑 SUPPORT PAIRING CODE 🔑")
+	fmt.Println("=================================================================")
+	fmt.Printf("   %s\n", code)
+	fmt.Println("-----------------------------------------------------------------")
+	fmt.Println("Give this code to your support technician.")
+	fmt.Printf("It expires at %s.\n", expiresAt.Local().Format(time.Kitchen))
+	fmt.Println("=================================================================")
+}
+
+// controlHandler wraps a handler for a message that controls the machine. The
+// message is rejected unless a technician has paired (in pairing mode), it was
+// sent by the viewer holding the controller role (in multi-viewer mode) and it
+// passes signature verification (when signed commands are required).
+func (a *App) controlHandler(messageType string, handler client.MessageHandler) client.MessageHandler {
+	signed := a.signedHandler(messageType, handler)
+	return func(data []byte) error {
+		if a.Pairing != nil && !a.Pairing.IsPaired() {
+			log.Printf("WARNING: Rejected %s command: %v", messageType, errNotPaired)
+			a.sendCommandRejected(messageType, errNotPaired)
+			return fmt.Errorf("rejected %s command: %w", messageType, errNotPaired)
+		}
+		if a.Viewers != nil {
+			if err := a.Viewers.AuthorizeInput(data); err != nil {
+				log.Printf("WARNING: Rejected %s command: %v", messageType, err)
+				a.sendCommandRejected(messageType, err)
+				return fmt.Errorf("rejected %s command: %w", messageType, err)
+			}
+		}
+		return signed(data)
+	}
+}
+
+// viewerHandler wraps a handler for a message any viewer may send, such as
+// pointing at the screen. It is gated like controlHandler, except that in
+// multi-viewer mode any connected viewer is accepted, not just the controller.
+func (a *App) viewerHandler(messageType string, handler client.MessageHandler) client.MessageHandler {
+	signed := a.signedHandler(messageType, handler)
+	return func(data []byte) error {
+		if a.Pairing != nil && !a.Pairing.IsPaired() {
+			log.Printf("WARNING: Rejected %s command: %v", messageType, errNotPaired)
+			a.sendCommandRejected(messageType, errNotPaired)
+			return fmt.Errorf("rejected %s command: %w", messageType, errNotPaired)
+		}
+		if a.Viewers != nil {
+			if err := a.Viewers.AuthorizeViewer(data); err != nil {
+				log.Printf("WARNING: Rejected %s command: %v", messageType, err)
+				a.sendCommandRejected(messageType, err)
+				return fmt.Errorf("rejected %s command: %w", messageType, err)
+			}
+		}
+		return signed(data)
+	}
+}
+
+// pairedHandler wraps a handler for a message the server sends on its own
+// account, such as a delivery acknowledgment. Such messages carry no viewer ID
+// or signature, so they are only rejected before a technician has paired.
+func (a *App) pairedHandler(messageType string, handler client.MessageHandler) client.MessageHandler {
+	return func(data []byte) error {
+		if a.Pairing != nil && !a.Pairing.IsPaired() {
+			log.Printf("WARNING: Rejected %s message: %v", messageType, errNotPaired)
+			return fmt.Errorf("rejected %s message: %w", messageType, errNotPaired)
+		}
+		return handler(data)
+	}
+}
+
+// handlePairingCommand handles pairing commands from the console
+func (a *App) handlePairingCommand(args []string) error {
+	if a.Pairing == nil {
+		return fmt.Errorf("pairing mode is not enabled")
+	}
+
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "status":
+		log.Printf("Pairing state: %s", a.Pairing.State())
+		if code, expiresAt := a.Pairing.Code(); code != "" {
+			log.Printf("Pairing code: %s (expires %s)", code, expiresAt.Format(time.RFC3339))
+		}
+		if a.Pairing.IsPaired() {
+			log.Printf("Technician: %+v", a.Pairing.Technician())
+		}
+		return nil
+	case "new":
+		return a.Pairing.Start()
+	case "end":
+		return a.Pairing.End()
+	default:
+		return fmt.Errorf("unknown pairing command: %s", action)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log"
+
+	"github.com/adamrobbie/go-support/pkg/policy"
+	"github.com/adamrobbie/go-support/pkg/processes"
+)
+
+// initProcesses parses the capability policy and creates the process manager
+// if the policy grants any capabilities
+func (a *App) initProcesses() error {
+	p, err := policy.Parse(a.Config.Capabilities)
+	if err != nil {
+		return err
+	}
+	a.Policy = p
+
+	if len(p.Capabilities()) == 0 {
+		return nil
+	}
+
+	if err := a.openAudit(); err != nil {
+		return err
+	}
+
+	a.Processes = processes.NewManager(controlTransport{a}, processes.Options{
+		Policy:       a.Policy,
+		PermManager:  a.PermManager,
+		Applications: a.Config.LaunchAllowed,
+		Audit:        a.Audit,
+		Verbose:      a.Config.Verbose,
+	})
+
+	log.Printf("Process management enabled for %v", p.Capabilities())
+	return nil
+}
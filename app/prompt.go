@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// confirmTimeout is how long the local user has to answer a prompt
+const confirmTimeout = 60 * time.Second
+
+// prompter asks the local user yes/no questions in the terminal. In interactive
+// mode the console reader passes lines to answer; otherwise stdin is read directly.
+type prompter struct {
+	mu        sync.Mutex // Held while a question is waiting for an answer
+	pendingMu sync.Mutex
+	pending   chan string
+	stdinOnce sync.Once
+}
+
+// confirm asks the local user a yes/no question. It returns false if the
+// question is not answered within confirmTimeout.
+func (a *App) confirm(question string) bool {
+	p := &a.prompter
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !a.Config.Interactive {
+		p.stdinOnce.Do(func() {
+			go func() {
+				scanner := bufio.NewScanner(os.Stdin)
+				for scanner.Scan() {
+					p.answer(scanner.Text())
+				}
+			}()
+		})
+	}
+
+	answers := make(chan string, 1)
+	p.pendingMu.Lock()
+	p.pending = answers
+	p.pendingMu.Unlock()
+
+	defer func() {
+		p.pendingMu.Lock()
+		p.pending = nil
+		p.pendingMu.Unlock()
+	}()
+
+	fmt.Printf("\n❓ %s (y/n, %s to answer): ", question, confirmTimeout)
+
+	select {
+	case input := <-answers:
+		input = strings.ToLower(strings.TrimSpace(input))
+		return input == "y" || input == "yes"
+	case <-time.After(confirmTimeout):
+		fmt.Println("\nNo answer, declined.")
+		return false
+	}
+}
+
+// answer passes a line of input to the pending question and reports whether one was waiting
+func (p *prompter) answer(input string) bool {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+
+	if p.pending == nil {
+		return false
+	}
+	p.pending <- input
+	p.pending = nil
+	return true
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/screenshot"
+	"github.com/adamrobbie/go-support/pkg/script"
+)
+
+// initScripts creates the script manager and registers its handlers
+func (a *App) initScripts() {
+	env := a.scriptEnv()
+	env.Screenshot = func(name string) error {
+		return a.captureAndSendScreenshot(screenshot.High, "Script screenshot "+name)
+	}
+	a.Scripts = script.NewManager(controlTransport{a}, env)
+}
+
+// scriptEnv returns the environment scripts run against, without a screenshot handler
+func (a *App) scriptEnv() script.Env {
+	env := script.Env{
+		Controller: a.RemoteController,
+		Capture: func() (image.Image, error) {
+			return screenshot.CaptureScreen()
+		},
+		ClickImage: a.clickImageFile,
+		Verbose:    a.Config.Verbose,
+	}
+	if ocr, err := script.NewTesseract(); err == nil {
+		env.OCR = ocr
+	} else if a.Config.Verbose {
+		log.Printf("DEBUG: %v", err)
+	}
+	return env
+}
+
+// runScriptFile runs a script file locally, saving its screenshots to the screenshot directory
+func (a *App) runScriptFile(path string) error {
+	s, err := script.Load(path)
+	if err != nil {
+		return err
+	}
+
+	if a.RemoteController == nil {
+		a.RemoteController = a.newRemoteController()
+	}
+	env := a.scriptEnv()
+	env.Screenshot = func(name string) error {
+		ss, err := screenshot.Capture(screenshot.High)
+		if err != nil {
+			return fmt.Errorf("failed to capture screenshot: %w", err)
+		}
+		if name == "" {
+			name = "script-" + time.Now().Format("20060102-150405")
+		}
+		filename := filepath.Join(a.Config.ScreenshotDir, filepath.Base(name)+".png")
+		if err := ss.SaveToFile(filename); err != nil {
+			return fmt.Errorf("failed to save screenshot: %w", err)
+		}
+		log.Printf("Screenshot saved to: %s", filename)
+		return nil
+	}
+
+	// Stop the script on Ctrl+C
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-a.Interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	log.Printf("Running %s with %d commands", path, len(s.Commands))
+	executed, err := script.Run(ctx, s, env)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	log.Printf("Script finished after %d commands", executed)
+	return nil
+}
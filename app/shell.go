@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log"
+	"runtime"
+
+	"github.com/adamrobbie/go-support/pkg/shell"
+)
+
+// initShell creates the shell manager if remote shells are enabled
+func (a *App) initShell() error {
+	if !a.Config.RemoteShell {
+		return nil
+	}
+	if runtime.GOOS == "windows" {
+		return shell.ErrUnsupported
+	}
+
+	if err := a.openAudit(); err != nil {
+		return err
+	}
+
+	a.Shells = shell.NewManager(controlTransport{a}, shell.Options{
+		Shell:   a.Config.ShellPath,
+		Audit:   a.Audit,
+		Verbose: a.Config.Verbose,
+	})
+
+	log.Printf("WARNING: Remote shells are enabled")
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+	"github.com/adamrobbie/go-support/pkg/signing"
+)
+
+// initCommandVerifier sets up the command verifier if signed commands are required
+func (a *App) initCommandVerifier() error {
+	if !a.Config.RequireSignedCommands {
+		return nil
+	}
+
+	keys := make(map[string]ed25519.PublicKey)
+	if a.Config.CommandPublicKeys != "" {
+		parsed, err := signing.ParsePublicKeys(a.Config.CommandPublicKeys)
+		if err != nil {
+			return err
+		}
+		for id, key := range parsed {
+			keys[id] = key
+		}
+	}
+	if a.Config.CommandPublicKeysFile != "" {
+		loaded, err := signing.LoadPublicKeys(a.Config.CommandPublicKeysFile)
+		if err != nil {
+			return err
+		}
+		for id, key := range loaded {
+			keys[id] = key
+		}
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("signed commands are required but no public keys are configured")
+	}
+
+	a.CommandVerifier = signing.NewVerifier(keys, a.Config.CommandMaxSkew)
+	log.Printf("Signed commands required, %d technician key(s) loaded", len(keys))
+	return nil
+}
+
+// signedHandler wraps a control command handler so it only runs for messages
+// that pass signature verification. Without a verifier the handler runs as is.
+func (a *App) signedHandler(messageType string, handler client.MessageHandler) client.MessageHandler {
+	return func(data []byte) error {
+		if a.CommandVerifier == nil {
+			return handler(data)
+		}
+
+		if err := a.CommandVerifier.Verify(data); err != nil {
+			log.Printf("WARNING: Rejected %s command: %v", messageType, err)
+			a.sendCommandRejected(messageType, err)
+			return fmt.Errorf("rejected %s command: %w", messageType, err)
+		}
+
+		return handler(data)
+	}
+}
+
+// sendCommandRejected notifies the server that a command was rejected
+func (a *App) sendCommandRejected(messageType string, reason error) {
+	if a.WSClient == nil || !a.WSClient.IsConnected() {
+		return
+	}
+
+	message := map[string]interface{}{
+		"type":        MessageTypeCommandRejected,
+		"commandType": messageType,
+		"reason":      reason.Error(),
+		"timestamp":   time.Now().Format(time.RFC3339),
+	}
+	if err := a.WSClient.SendJSON(message); err != nil {
+		log.Printf("Failed to send command rejection: %v", err)
+	}
+}
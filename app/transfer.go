@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/adamrobbie/go-support/pkg/transfer"
+)
+
+// initTransfer creates the file transfer manager if file transfer is enabled
+func (a *App) initTransfer() error {
+	if !a.Config.FileTransfer {
+		return nil
+	}
+
+	dirs := a.Config.TransferDirs
+	if len(dirs) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("no FILE_TRANSFER_DIRS configured and no home directory: %w", err)
+		}
+		dirs = []string{filepath.Join(home, "Downloads")}
+	}
+
+	manager, err := transfer.NewManager(controlTransport{a}, transfer.Options{
+		AllowedDirs: dirs,
+		MaxFileSize: a.Config.TransferMaxSize,
+		Consent:     a.confirmTransfer,
+		Verbose:     a.Config.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	a.Transfers = manager
+
+	log.Printf("File transfer enabled for %v", manager.AllowedDirs())
+	return nil
+}
+
+// confirmTransfer asks the local user to approve a file transfer
+func (a *App) confirmTransfer(request transfer.Request) bool {
+	var question string
+	switch request.Direction {
+	case transfer.Upload:
+		question = fmt.Sprintf("The technician wants to save a file to %s (%d bytes). Allow?", request.Path, request.Size)
+	default:
+		question = fmt.Sprintf("The technician wants to copy %s (%d bytes) from this computer. Allow?", request.Path, request.Size)
+	}
+
+	if !a.confirm(question) {
+		log.Printf("File transfer %s of %s declined", request.Direction, request.Path)
+		return false
+	}
+	log.Printf("File transfer %s of %s allowed", request.Direction, request.Path)
+	return true
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/adamrobbie/go-support/pkg/chat"
+	"github.com/adamrobbie/go-support/pkg/client"
+)
+
+// controlTransport registers handlers on the WebSocket client wrapped with
+// controlHandler, so subsystems that register their own handlers are gated
+// like mouse and keyboard events
+type controlTransport struct {
+	app *App
+}
+
+// SendJSON sends a message through the WebSocket client
+func (t controlTransport) SendJSON(message interface{}) error {
+	return t.app.WSClient.SendJSON(message)
+}
+
+// SendJSONWithPriority sends a JSON message through the WebSocket client with a priority
+func (t controlTransport) SendJSONWithPriority(message interface{}, priority client.Priority) error {
+	return t.app.WSClient.SendJSONWithPriority(message, priority)
+}
+
+// SendBinary sends a binary message through the WebSocket client
+func (t controlTransport) SendBinary(data []byte) error {
+	return t.app.WSClient.SendBinary(data)
+}
+
+// RegisterHandler registers a handler that is only run for accepted control commands
+func (t controlTransport) RegisterHandler(messageType string, handler client.MessageHandler) {
+	t.app.WSClient.RegisterHandler(messageType, t.app.controlHandler(messageType, handler))
+}
+
+// viewerTransport registers handlers on the WebSocket client wrapped with
+// viewerHandler, for subsystems any viewer may use
+type viewerTransport struct {
+	app *App
+}
+
+// SendJSON sends a message through the WebSocket client
+func (t viewerTransport) SendJSON(message interface{}) error {
+	return t.app.WSClient.SendJSON(message)
+}
+
+// RegisterHandler registers a handler that is only run for accepted viewer commands
+func (t viewerTransport) RegisterHandler(messageType string, handler client.MessageHandler) {
+	t.app.WSClient.RegisterHandler(messageType, t.app.viewerHandler(messageType, handler))
+}
+
+// chatTransport registers chat handlers like viewerTransport, except for
+// acknowledgments, which come from the server rather than a viewer
+type chatTransport struct {
+	viewerTransport
+}
+
+// RegisterHandler registers a handler for accepted viewer messages, or for
+// acknowledgments received while paired
+func (t chatTransport) RegisterHandler(messageType string, handler client.MessageHandler) {
+	if messageType == chat.MessageTypeAck {
+		t.app.WSClient.RegisterHandler(messageType, t.app.pairedHandler(messageType, handler))
+		return
+	}
+	t.viewerTransport.RegisterHandler(messageType, handler)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/adamrobbie/go-support/pkg/session"
+)
+
+// initViewers creates the viewer roster if multi-viewer mode is enabled
+func (a *App) initViewers() {
+	if !a.Config.MultiViewer {
+		return
+	}
+
+	a.Viewers = session.NewRoster(a.WSClient, a.Config.Verbose)
+
+	a.Viewers.OnChange(func(change session.Change) {
+		if change.ControllerChanged() {
+			// Input from the previous controller must not run after it lost control
+			a.resetInput()
+			if a.Macros != nil {
+				a.Macros.Stop()
+			}
+			if a.Scripts != nil {
+				a.Scripts.Stop()
+			}
+			if change.Controller == "" {
+				fmt.Println("\nNo viewer has remote control.")
+			} else {
+				fmt.Printf("\n🖱️ Viewer %s now has remote control.\n", a.viewerName(change.Controller))
+			}
+		}
+
+		// Stop streaming once the last viewer watching it has gone
+		if change.PreviousWatching > 0 && change.Watching == 0 {
+			a.stopVideoStreaming()
+		}
+	})
+}
+
+// viewerName returns a display name for a viewer
+func (a *App) viewerName(viewerID string) string {
+	for _, viewer := range a.Viewers.Viewers() {
+		if viewer.ID == viewerID && viewer.Name != "" {
+			return viewer.Name
+		}
+	}
+	return viewerID
+}
+
+// viewerID extracts the ID of the viewer that sent a message
+func viewerID(data []byte) string {
+	var msg struct {
+		ViewerID string `json:"viewerId"`
+	}
+	json.Unmarshal(data, &msg)
+	return msg.ViewerID
+}
+
+// handleViewerVideo records that a viewer started or stopped watching the
+// stream and returns the number of viewers still watching
+func (a *App) handleViewerVideo(data []byte, watching bool) (int, error) {
+	id := viewerID(data)
+	if id == "" {
+		return 0, session.ErrNoViewerID
+	}
+	return a.Viewers.SetWatching(id, watching)
+}
+
+// handleControlCommand handles viewer control commands from the console
+func (a *App) handleControlCommand(args []string) error {
+	if a.Viewers == nil {
+		return fmt.Errorf("multi-viewer mode is not enabled")
+	}
+
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "status":
+		viewers := a.Viewers.Viewers()
+		if len(viewers) == 0 {
+			log.Println("No viewers connected")
+			return nil
+		}
+		for _, viewer := range viewers {
+			log.Printf("Viewer %s (%s): role=%s watching=%t", viewer.ID, viewer.Name, viewer.Role, viewer.Watching)
+		}
+		return nil
+	case "grant":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: control grant <viewerId>")
+		}
+		return a.Viewers.Grant(args[1])
+	case "revoke":
+		return a.Viewers.Revoke()
+	default:
+		return fmt.Errorf("unknown control command: %s", action)
+	}
+}
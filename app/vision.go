@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"log"
+	"strings"
+
+	"github.com/adamrobbie/go-support/pkg/remote"
+	"github.com/adamrobbie/go-support/pkg/vision"
+)
+
+// ClickImageRequest asks the agent to find an image on screen and click it
+type ClickImageRequest struct {
+	RequestID string             `json:"requestId,omitempty"`
+	Image     string             `json:"image"`               // PNG or JPEG template, base64 or as a data URL
+	Threshold float64            `json:"threshold,omitempty"` // Minimum match score from 0 to 1
+	Button    remote.MouseButton `json:"button,omitempty"`
+	Double    bool               `json:"double,omitempty"`
+}
+
+// ImageClickedMessage is the reply to clickImage
+type ImageClickedMessage struct {
+	Type      string             `json:"type"`
+	RequestID string             `json:"requestId,omitempty"`
+	Found     bool               `json:"found"`
+	Match     *remote.ImageMatch `json:"match,omitempty"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// initVision registers the handler for clicking images on screen
+func (a *App) initVision() {
+	a.WSClient.RegisterHandler(MessageTypeClickImage, a.controlHandler(MessageTypeClickImage, func(data []byte) error {
+		log.Println("DEBUG: Received click image request from server")
+
+		var req ClickImageRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fmt.Errorf("failed to parse click image request: %w", err)
+		}
+
+		reply := a.clickImage(req)
+		if err := a.WSClient.SendJSON(reply); err != nil {
+			return fmt.Errorf("failed to send click image result: %w", err)
+		}
+		return nil
+	}))
+}
+
+// clickImage clicks the template of a clickImage request and builds the reply
+func (a *App) clickImage(req ClickImageRequest) ImageClickedMessage {
+	reply := ImageClickedMessage{Type: MessageTypeImageClicked, RequestID: req.RequestID}
+
+	template, err := decodeImageData(req.Image)
+	if err != nil {
+		reply.Error = err.Error()
+		return reply
+	}
+
+	match, err := a.RemoteController.ClickImage(template, vision.Options{Threshold: req.Threshold}, req.Button, req.Double)
+	if err != nil {
+		reply.Error = err.Error()
+	}
+	// The image may have been found even if clicking it failed
+	if match.Width > 0 {
+		reply.Found = true
+		reply.Match = &match
+	}
+	return reply
+}
+
+// clickImageFile clicks the image in a template file, for the clickImage script command
+func (a *App) clickImageFile(path string, threshold float64) error {
+	template, err := vision.LoadTemplate(path)
+	if err != nil {
+		return err
+	}
+	_, err = a.RemoteController.ClickImage(template, vision.Options{Threshold: threshold}, remote.LeftButton, false)
+	return err
+}
+
+// decodeImageData decodes a base64 image, with or without a data URL prefix
+func decodeImageData(data string) (image.Image, error) {
+	if data == "" {
+		return nil, fmt.Errorf("no image given")
+	}
+	if strings.HasPrefix(data, "data:") {
+		if i := strings.Index(data, ","); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return vision.DecodeTemplate(raw)
+}
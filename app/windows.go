@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/adamrobbie/go-support/pkg/remote"
+)
+
+// WindowListMessage is the reply to listWindows
+type WindowListMessage struct {
+	Type      string          `json:"type"`
+	RequestID string          `json:"requestId,omitempty"`
+	Windows   []remote.Window `json:"windows"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// initWindows registers the handlers for window listing and window events
+func (a *App) initWindows() {
+	a.WSClient.RegisterHandler(MessageTypeListWindows, a.controlHandler(MessageTypeListWindows, func(data []byte) error {
+		log.Println("DEBUG: Received window list request from server")
+
+		var req struct {
+			RequestID string `json:"requestId"`
+		}
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fmt.Errorf("failed to parse window list request: %w", err)
+		}
+
+		reply := a.windowList(req.RequestID)
+		if err := a.WSClient.SendJSON(reply); err != nil {
+			return fmt.Errorf("failed to send window list: %w", err)
+		}
+		return nil
+	}))
+
+	a.WSClient.RegisterHandler(MessageTypeWindowEvent, a.controlHandler(MessageTypeWindowEvent, func(data []byte) error {
+		log.Println("DEBUG: Received window event from server")
+
+		var event remote.WindowEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Printf("ERROR: Failed to parse window event: %v", err)
+			return fmt.Errorf("failed to parse window event: %w", err)
+		}
+
+		log.Printf("DEBUG: Window event details: %+v", event)
+		return a.RemoteController.ExecuteWindowEvent(event)
+	}))
+}
+
+// windowList lists the windows for a listWindows reply
+func (a *App) windowList(requestID string) WindowListMessage {
+	reply := WindowListMessage{Type: MessageTypeWindowList, RequestID: requestID, Windows: []remote.Window{}}
+
+	windows, err := a.RemoteController.ListWindows()
+	if err != nil {
+		reply.Error = err.Error()
+		return reply
+	}
+	if windows != nil {
+		reply.Windows = windows
+	}
+	return reply
+}
+
+// printWindows prints the top-level windows in the terminal
+func (a *App) printWindows() error {
+	windows, err := a.RemoteController.ListWindows()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d windows:\n", len(windows))
+	for _, w := range windows {
+		state := ""
+		if w.Focused {
+			state = " [focused]"
+		}
+		if w.Minimized {
+			state += " [minimized]"
+		}
+		fmt.Printf("  %-12d %dx%d+%d+%d %s (%s, PID %d)%s\n", w.ID, w.Width, w.Height, w.X, w.Y, w.Title, w.Process, w.PID, state)
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event is an entry in the audit trail
+type Event struct {
+	Time    time.Time              `json:"time"`
+	Action  string                 `json:"action"`
+	Actor   string                 `json:"actor,omitempty"` // Viewer or technician that caused the event
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Logger appends events to the audit trail as JSON lines.
+// A nil Logger discards events, so callers do not need to check for one.
+type Logger struct {
+	w      io.Writer
+	closer io.Closer
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+// Open opens an audit trail file for appending, creating it if needed
+func Open(path string) (*Logger, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	logger := New(file)
+	logger.closer = file
+	return logger, nil
+}
+
+// New creates a logger writing to w
+func New(w io.Writer) *Logger {
+	return &Logger{w: w, now: time.Now}
+}
+
+// Record appends an event. Write errors are logged rather than returned so
+// that auditing never interrupts the action being audited.
+func (l *Logger) Record(action, actor string, details map[string]interface{}) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	line, err := json.Marshal(Event{
+		Time:    l.now().UTC(),
+		Action:  action,
+		Actor:   actor,
+		Details: details,
+	})
+	if err != nil {
+		log.Printf("Failed to encode audit event %s: %v", action, err)
+		return
+	}
+	if _, err := l.w.Write(append(line, '\n')); err != nil {
+		log.Printf("Failed to write audit event %s: %v", action, err)
+	}
+}
+
+// Close closes the underlying file, if any
+func (l *Logger) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
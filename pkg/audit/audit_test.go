@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	logger, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() returned an error: %v", err)
+	}
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	logger.now = func() time.Time { return fixed }
+
+	logger.Record("exec.started", "v1", map[string]interface{}{"command": "ls"})
+	logger.Record("exec.exited", "v1", map[string]interface{}{"exitCode": 0})
+	logger.Close()
+
+	// Reopening appends to the existing trail
+	logger, _ = Open(path)
+	logger.Record("exec.started", "", nil)
+	logger.Close()
+
+	file, _ := os.Open(path)
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Invalid audit line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	if events[0].Action != "exec.started" || events[0].Actor != "v1" || !events[0].Time.Equal(fixed) || events[0].Details["command"] != "ls" {
+		t.Errorf("Unexpected first event: %+v", events[0])
+	}
+
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected audit log mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	var logger *Logger
+	logger.Record("ignored", "", nil)
+	if err := logger.Close(); err != nil {
+		t.Errorf("Close() on a nil logger returned an error: %v", err)
+	}
+}
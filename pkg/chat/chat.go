@@ -0,0 +1,237 @@
+package chat
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+)
+
+// Message types used by chat
+const (
+	MessageTypeChat   = string(client.ChatMessage) // A chat message in either direction
+	MessageTypeAck    = "chatAck"                  // Acknowledges delivery of a chat message
+	MessageTypeTyping = "typing"                   // Reports that a participant started or stopped typing
+)
+
+const (
+	// MaxMessageLength limits the length of a chat message in bytes
+	MaxMessageLength = 4096
+	// MaxHistory limits the number of messages kept in the history
+	MaxHistory = 500
+)
+
+// Message is a chat, acknowledgment or typing message
+type Message struct {
+	Type      string `json:"type"`
+	ID        string `json:"id,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Sender    string `json:"sender,omitempty"`
+	ViewerID  string `json:"viewerId,omitempty"`
+	Typing    bool   `json:"typing,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+// Entry is a message in the chat history
+type Entry struct {
+	ID        string
+	Local     bool // Sent by the local user
+	Sender    string
+	Text      string
+	Time      time.Time
+	Delivered bool // For local messages, whether the server acknowledged them
+}
+
+// Options configures chat
+type Options struct {
+	LocalName string             // Name the local user's messages are sent with ("User" if empty)
+	OnMessage func(Entry)        // Called for each incoming message
+	OnTyping  func(string, bool) // Called when a remote participant starts or stops typing
+	OnAck     func(Entry)        // Called when a local message is acknowledged
+	Verbose   bool
+}
+
+// Chat exchanges messages between the local user and the technicians and
+// keeps the history of the current session
+type Chat struct {
+	transport client.Transport
+	opts      Options
+	history   []Entry
+	mu        sync.Mutex
+}
+
+// New creates a chat and registers its handlers on the transport
+func New(transport client.Transport, opts Options) *Chat {
+	if opts.LocalName == "" {
+		opts.LocalName = "User"
+	}
+	c := &Chat{transport: transport, opts: opts}
+
+	transport.RegisterHandler(MessageTypeChat, c.handleChat)
+	transport.RegisterHandler(MessageTypeAck, c.handleAck)
+	transport.RegisterHandler(MessageTypeTyping, c.handleTyping)
+
+	return c
+}
+
+// Send sends a message from the local user and adds it to the history
+func (c *Chat) Send(text string) (Entry, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Entry{}, fmt.Errorf("chat message is empty")
+	}
+	if len(text) > MaxMessageLength {
+		return Entry{}, fmt.Errorf("chat message is longer than %d bytes", MaxMessageLength)
+	}
+
+	entry := Entry{ID: newID(), Local: true, Sender: c.opts.LocalName, Text: text, Time: time.Now()}
+	c.mu.Lock()
+	c.appendLocked(entry)
+	c.mu.Unlock()
+
+	err := c.transport.SendJSON(Message{
+		Type:      MessageTypeChat,
+		ID:        entry.ID,
+		Message:   entry.Text,
+		Sender:    entry.Sender,
+		Timestamp: entry.Time.Format(time.RFC3339),
+	})
+	if err != nil {
+		return entry, fmt.Errorf("failed to send chat message: %w", err)
+	}
+	return entry, nil
+}
+
+// SetTyping tells the technicians that the local user started or stopped typing
+func (c *Chat) SetTyping(typing bool) error {
+	return c.transport.SendJSON(Message{Type: MessageTypeTyping, Sender: c.opts.LocalName, Typing: typing})
+}
+
+// History returns the messages of the current session, oldest first
+func (c *Chat) History() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Entry(nil), c.history...)
+}
+
+// Reset clears the history when a session ends
+func (c *Chat) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.history = nil
+}
+
+// handleChat records an incoming message, acknowledges it and shows it to the local user
+func (c *Chat) handleChat(data []byte) error {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to parse chat message: %w", err)
+	}
+	if strings.TrimSpace(msg.Message) == "" {
+		return fmt.Errorf("chat message is empty")
+	}
+	if len(msg.Message) > MaxMessageLength {
+		return fmt.Errorf("chat message is longer than %d bytes", MaxMessageLength)
+	}
+
+	entry := Entry{ID: msg.ID, Sender: senderName(msg), Text: msg.Message, Time: time.Now()}
+	if entry.ID == "" {
+		entry.ID = newID()
+	}
+
+	c.mu.Lock()
+	c.appendLocked(entry)
+	c.mu.Unlock()
+
+	if c.opts.Verbose {
+		log.Printf("DEBUG: Received chat message %s from %s", entry.ID, entry.Sender)
+	}
+	if c.opts.OnMessage != nil {
+		c.opts.OnMessage(entry)
+	}
+
+	// Only messages with an ID from the server can be acknowledged
+	if msg.ID == "" {
+		return nil
+	}
+	ack := Message{Type: MessageTypeAck, ID: msg.ID, ViewerID: msg.ViewerID}
+	if err := c.transport.SendJSON(ack); err != nil {
+		return fmt.Errorf("failed to acknowledge chat message: %w", err)
+	}
+	return nil
+}
+
+// handleAck marks a local message as delivered
+func (c *Chat) handleAck(data []byte) error {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to parse chat acknowledgment: %w", err)
+	}
+
+	c.mu.Lock()
+	var acked *Entry
+	for i := range c.history {
+		if c.history[i].Local && c.history[i].ID == msg.ID {
+			c.history[i].Delivered = true
+			entry := c.history[i]
+			acked = &entry
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	if acked == nil {
+		return fmt.Errorf("acknowledgment for unknown chat message %q", msg.ID)
+	}
+	if c.opts.OnAck != nil {
+		c.opts.OnAck(*acked)
+	}
+	return nil
+}
+
+// handleTyping forwards a typing indicator to the local user
+func (c *Chat) handleTyping(data []byte) error {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to parse typing indicator: %w", err)
+	}
+	if c.opts.OnTyping != nil {
+		c.opts.OnTyping(senderName(msg), msg.Typing)
+	}
+	return nil
+}
+
+// appendLocked adds an entry, dropping the oldest beyond MaxHistory. Must be called with the lock held.
+func (c *Chat) appendLocked(entry Entry) {
+	c.history = append(c.history, entry)
+	if len(c.history) > MaxHistory {
+		c.history = append([]Entry(nil), c.history[len(c.history)-MaxHistory:]...)
+	}
+}
+
+// senderName returns the display name of a remote participant
+func senderName(msg Message) string {
+	switch {
+	case msg.Sender != "":
+		return msg.Sender
+	case msg.ViewerID != "":
+		return msg.ViewerID
+	default:
+		return "Technician"
+	}
+}
+
+// newID returns a random message ID
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,106 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adamrobbie/go-support/pkg/client/clienttest"
+)
+
+// last returns the last message sent through a transport
+func last(transport *clienttest.Transport) Message {
+	messages := transport.Messages()
+	return messages[len(messages)-1].(Message)
+}
+
+func TestIncomingMessage(t *testing.T) {
+	transport := clienttest.New()
+	var received []Entry
+	var typing []string
+	c := New(transport, Options{
+		OnMessage: func(entry Entry) { received = append(received, entry) },
+		OnTyping: func(sender string, isTyping bool) {
+			if isTyping {
+				typing = append(typing, sender)
+			}
+		},
+	})
+
+	transport.Deliver(t, `{"type":"typing","sender":"Alice","typing":true}`)
+	if len(typing) != 1 || typing[0] != "Alice" {
+		t.Errorf("Expected Alice's typing indicator, got %v", typing)
+	}
+
+	if err := transport.Deliver(t, `{"type":"chat","id":"m1","message":"Hello","viewerId":"v1"}`); err != nil {
+		t.Fatalf("chat handler returned an error: %v", err)
+	}
+	if len(received) != 1 || received[0].Text != "Hello" || received[0].Sender != "v1" || received[0].Local {
+		t.Fatalf("Unexpected received messages: %+v", received)
+	}
+	if ack := last(transport); ack.Type != MessageTypeAck || ack.ID != "m1" || ack.ViewerID != "v1" {
+		t.Errorf("Expected an acknowledgment for m1, got %+v", ack)
+	}
+
+	if err := transport.Deliver(t, `{"type":"chat","message":"  "}`); err == nil {
+		t.Error("Expected an error for an empty message")
+	}
+	if len(c.History()) != 1 {
+		t.Errorf("Expected one message in the history, got %d", len(c.History()))
+	}
+}
+
+func TestSendAndAck(t *testing.T) {
+	transport := clienttest.New()
+	var acked []Entry
+	c := New(transport, Options{LocalName: "Bob", OnAck: func(entry Entry) { acked = append(acked, entry) }})
+
+	entry, err := c.Send(" Thanks! ")
+	if err != nil {
+		t.Fatalf("Send() returned an error: %v", err)
+	}
+	sent := last(transport)
+	if sent.Type != MessageTypeChat || sent.ID != entry.ID || sent.Message != "Thanks!" || sent.Sender != "Bob" {
+		t.Errorf("Unexpected chat message: %+v", sent)
+	}
+	if history := c.History(); len(history) != 1 || history[0].Delivered {
+		t.Fatalf("Expected an undelivered message in the history, got %+v", history)
+	}
+
+	if err := transport.Deliver(t, `{"type":"chatAck","id":"`+entry.ID+`"}`); err != nil {
+		t.Fatalf("chatAck handler returned an error: %v", err)
+	}
+	if history := c.History(); !history[0].Delivered || len(acked) != 1 {
+		t.Errorf("Expected the message to be delivered, got %+v", history)
+	}
+	if err := transport.Deliver(t, `{"type":"chatAck","id":"unknown"}`); err == nil {
+		t.Error("Expected an error for an unknown acknowledgment")
+	}
+
+	if _, err := c.Send(""); err == nil {
+		t.Error("Expected an error for an empty message")
+	}
+	if _, err := c.Send(strings.Repeat("x", MaxMessageLength+1)); err == nil {
+		t.Error("Expected an error for a long message")
+	}
+
+	if err := c.SetTyping(true); err != nil || !last(transport).Typing {
+		t.Errorf("Expected a typing indicator, got %+v, %v", last(transport), err)
+	}
+}
+
+func TestHistoryLimitAndReset(t *testing.T) {
+	transport := clienttest.New()
+	c := New(transport, Options{})
+
+	for i := 0; i < MaxHistory+10; i++ {
+		c.Send("message")
+	}
+	if n := len(c.History()); n != MaxHistory {
+		t.Errorf("Expected %d messages in the history, got %d", MaxHistory, n)
+	}
+
+	c.Reset()
+	if n := len(c.History()); n != 0 {
+		t.Errorf("Expected an empty history after Reset(), got %d", n)
+	}
+}
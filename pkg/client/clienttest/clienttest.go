@@ -0,0 +1,207 @@
+// Package clienttest provides a fake client.Transport for testing the
+// packages that send and receive messages through the WebSocket client.
+package clienttest
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+)
+
+// Timeout limits how long the Wait methods wait for a message
+const Timeout = 10 * time.Second
+
+// Transport records the messages sent through it and lets tests deliver
+// messages to the registered handlers
+type Transport struct {
+	mu         sync.Mutex
+	messages   []interface{}     // As passed to SendJSON
+	encoded    [][]byte          // As encoded when they were sent
+	priorities []client.Priority // The priority each message was sent with
+	binary     [][]byte
+	handlers   map[string]client.MessageHandler
+	taken      map[string]int // Messages of each type returned by Next
+	sendErr    error
+}
+
+// New creates a transport without handlers
+func New() *Transport {
+	return &Transport{
+		handlers: make(map[string]client.MessageHandler),
+		taken:    make(map[string]int),
+	}
+}
+
+// SendJSON records a message sent with the control priority, or returns the
+// error set with SetSendError
+func (f *Transport) SendJSON(message interface{}) error {
+	return f.SendJSONWithPriority(message, client.PriorityControl)
+}
+
+// SendJSONWithPriority records a message and its priority, or returns the
+// error set with SetSendError
+func (f *Transport) SendJSONWithPriority(message interface{}, priority client.Priority) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sendErr != nil {
+		return f.sendErr
+	}
+	f.messages = append(f.messages, message)
+	f.encoded = append(f.encoded, data)
+	f.priorities = append(f.priorities, priority)
+	return nil
+}
+
+// SendBinary records a binary message, or returns the error set with SetSendError
+func (f *Transport) SendBinary(data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sendErr != nil {
+		return f.sendErr
+	}
+	f.binary = append(f.binary, append([]byte(nil), data...))
+	return nil
+}
+
+// RegisterHandler registers the handler of a message type
+func (f *Transport) RegisterHandler(messageType string, handler client.MessageHandler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[messageType] = handler
+}
+
+// SetSendError makes sends fail with err, or succeed again if err is nil
+func (f *Transport) SetSendError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sendErr = err
+}
+
+// Deliver passes a message to the handler registered for its type and
+// returns the handler's error. The test fails if no handler is registered.
+func (f *Transport) Deliver(t testing.TB, message string) error {
+	t.Helper()
+	var envelope struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(message), &envelope); err != nil {
+		t.Fatalf("Invalid test message: %v", err)
+	}
+	f.mu.Lock()
+	handler, ok := f.handlers[envelope.Type]
+	f.mu.Unlock()
+	if !ok {
+		t.Fatalf("No handler registered for %s", envelope.Type)
+	}
+	return handler([]byte(message))
+}
+
+// Messages returns the messages sent with SendJSON, as they were passed to it
+func (f *Transport) Messages() []interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]interface{}(nil), f.messages...)
+}
+
+// Sent returns the messages sent with SendJSON, decoded from JSON
+func (f *Transport) Sent() []map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sentLocked()
+}
+
+// sentLocked decodes the sent messages. Must be called with the lock held.
+func (f *Transport) sentLocked() []map[string]interface{} {
+	sent := make([]map[string]interface{}, len(f.encoded))
+	for i, data := range f.encoded {
+		json.Unmarshal(data, &sent[i])
+	}
+	return sent
+}
+
+// Last returns the last message sent with SendJSON decoded from JSON, or nil
+func (f *Transport) Last() map[string]interface{} {
+	sent := f.Sent()
+	if len(sent) == 0 {
+		return nil
+	}
+	return sent[len(sent)-1]
+}
+
+// LastType returns the type of the last message sent with SendJSON, or ""
+func (f *Transport) LastType() string {
+	messageType, _ := f.Last()["type"].(string)
+	return messageType
+}
+
+// Priorities returns the priority of each message in Sent
+func (f *Transport) Priorities() []client.Priority {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]client.Priority(nil), f.priorities...)
+}
+
+// Binary returns the messages sent with SendBinary
+func (f *Transport) Binary() [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]byte(nil), f.binary...)
+}
+
+// Next waits for the next message of a type that Next has not returned yet
+func (f *Transport) Next(t testing.TB, messageType string) map[string]interface{} {
+	t.Helper()
+	var next map[string]interface{}
+	f.wait(t, "a "+messageType+" message", func() bool {
+		seen := 0
+		for _, message := range f.sentLocked() {
+			if message["type"] != messageType {
+				continue
+			}
+			if seen == f.taken[messageType] {
+				f.taken[messageType]++
+				next = message
+				return true
+			}
+			seen++
+		}
+		return false
+	})
+	return next
+}
+
+// WaitSent waits until at least n messages have been sent with SendJSON and returns them
+func (f *Transport) WaitSent(t testing.TB, n int) []map[string]interface{} {
+	t.Helper()
+	f.wait(t, "messages to be sent", func() bool { return len(f.encoded) >= n })
+	return f.Sent()
+}
+
+// WaitBinary waits until done reports true for the messages sent with SendBinary
+func (f *Transport) WaitBinary(t testing.TB, what string, done func([][]byte) bool) {
+	t.Helper()
+	f.wait(t, what, func() bool { return done(f.binary) })
+}
+
+// wait polls ready with the lock held until it returns true, failing the test after Timeout
+func (f *Transport) wait(t testing.TB, what string, ready func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(Timeout)
+	for time.Now().Before(deadline) {
+		f.mu.Lock()
+		ok := ready()
+		f.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s", what)
+}
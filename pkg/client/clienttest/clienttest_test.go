@@ -0,0 +1,46 @@
+package clienttest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+)
+
+// The fake must stay usable wherever the WebSocket client is
+var _ client.Transport = (*Transport)(nil)
+
+func TestTransport(t *testing.T) {
+	transport := New()
+	var handled string
+	transport.RegisterHandler("ping", func(data []byte) error {
+		handled = string(data)
+		return transport.SendJSON(map[string]string{"type": "pong", "n": "1"})
+	})
+
+	if err := transport.Deliver(t, `{"type":"ping"}`); err != nil || handled != `{"type":"ping"}` {
+		t.Fatalf("Deliver() = %v, handler saw %q", err, handled)
+	}
+	transport.SendJSONWithPriority(map[string]string{"type": "pong", "n": "2"}, client.PriorityBulk)
+	if transport.LastType() != "pong" || len(transport.Sent()) != 2 {
+		t.Errorf("Unexpected messages %v", transport.Sent())
+	}
+	if priorities := transport.Priorities(); len(priorities) != 2 || priorities[0] != client.PriorityControl || priorities[1] != client.PriorityBulk {
+		t.Errorf("Unexpected priorities %v", priorities)
+	}
+
+	// Next returns each message of a type once
+	for _, want := range []string{"1", "2"} {
+		if got := transport.Next(t, "pong")["n"]; got != want {
+			t.Errorf("Expected pong %s, got %v", want, got)
+		}
+	}
+
+	transport.SetSendError(errors.New("offline"))
+	if transport.SendJSON(map[string]string{"type": "pong"}) == nil || transport.SendBinary([]byte{1}) == nil {
+		t.Error("Expected sends to fail after SetSendError")
+	}
+	if len(transport.Messages()) != 2 || len(transport.Binary()) != 0 {
+		t.Error("Expected failed sends not to be recorded")
+	}
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+)
+
+// DefaultDispatchQueue is how many messages wait for a worker of a pool
+// before reading pauses, when the options give no queue size
+const DefaultDispatchQueue = 64
+
+// defaultPool is the pool of message types without dispatch options
+const defaultPool = "default"
+
+// DispatchOptions configures the worker pool that runs the handlers of a
+// message type. Types without options share one pool with one worker, so
+// they are handled in the order received, as they were before pools.
+type DispatchOptions struct {
+	Pool    string // Pool shared with other message types; the message type if empty
+	Workers int    // Handlers run at once; 1, the default, runs them in the order received
+	Queue   int    // Messages waiting for a worker before reading pauses (DefaultDispatchQueue if 0)
+}
+
+// ContextHandler handles a message with a context that is cancelled when the
+// connection it arrived on closes
+type ContextHandler func(ctx context.Context, data []byte) error
+
+// HandlerFailure is sent to the server when a handler panics
+type HandlerFailure struct {
+	Type        MessageType `json:"type"`
+	MessageType string      `json:"messageType"`
+	Error       string      `json:"error"`
+}
+
+// dispatchJob is a message waiting for a worker
+type dispatchJob struct {
+	messageType string
+	data        []byte
+	handler     ContextHandler
+}
+
+// dispatcher runs the handlers of the messages of one connection in worker
+// pools. Pools are started on their first message and stop with the connection.
+type dispatcher struct {
+	client *WebSocketClient
+	ctx    context.Context
+	cancel context.CancelFunc
+	pools  map[string]chan dispatchJob
+	wg     sync.WaitGroup
+}
+
+// newDispatcher creates a dispatcher for a new connection
+func newDispatcher(c *WebSocketClient) *dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &dispatcher{
+		client: c,
+		ctx:    ctx,
+		cancel: cancel,
+		pools:  make(map[string]chan dispatchJob),
+	}
+}
+
+// dispatch queues a message for the pool of its type. It blocks while the
+// pool's queue is full, which pauses reading, until the connection closes.
+func (d *dispatcher) dispatch(messageType string, data []byte, handler ContextHandler) {
+	opts, ok := d.client.dispatchOptions(messageType)
+	name := opts.Pool
+	if !ok {
+		name = defaultPool
+	} else if name == "" {
+		name = messageType
+	}
+
+	jobs, started := d.pools[name]
+	if !started {
+		workers, queue := max(opts.Workers, 1), opts.Queue
+		if queue <= 0 {
+			queue = DefaultDispatchQueue
+		}
+		jobs = make(chan dispatchJob, queue)
+		d.pools[name] = jobs
+		for i := 0; i < workers; i++ {
+			d.wg.Add(1)
+			go d.work(jobs)
+		}
+	}
+
+	select {
+	case jobs <- dispatchJob{messageType: messageType, data: data, handler: handler}:
+	case <-d.ctx.Done():
+	}
+}
+
+// work runs the handlers of a pool's messages; messages still waiting when
+// the connection closes are dropped
+func (d *dispatcher) work(jobs <-chan dispatchJob) {
+	defer d.wg.Done()
+	for job := range jobs {
+		if d.ctx.Err() != nil {
+			continue
+		}
+		d.run(job)
+	}
+}
+
+// run runs one handler, recovering from a panic and reporting it to the server
+func (d *dispatcher) run(job dispatchJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("ERROR: Handler for message type %s panicked: %v\n%s", job.messageType, r, debug.Stack())
+			failure := HandlerFailure{
+				Type:        HandlerFailedMessage,
+				MessageType: job.messageType,
+				Error:       fmt.Sprintf("handler panicked: %v", r),
+			}
+			if err := d.client.SendJSON(failure); err != nil {
+				log.Printf("ERROR: Failed to report handler failure: %v", err)
+			}
+		}
+	}()
+
+	if err := job.handler(d.ctx, job.data); err != nil {
+		if d.client.Verbose {
+			log.Printf("Error handling message of type %s: %v", job.messageType, err)
+		}
+	} else if d.client.Verbose {
+		log.Printf("Successfully handled message of type: %s", job.messageType)
+	}
+}
+
+// stop cancels the context of running handlers and stops the workers once
+// they have finished
+func (d *dispatcher) stop() {
+	d.cancel()
+	for _, jobs := range d.pools {
+		close(jobs)
+	}
+	d.wg.Wait()
+}
+
+// SetDispatch sets how the handlers of a message type are run. It applies to
+// pools started after the call, so set it before connecting.
+func (c *WebSocketClient) SetDispatch(messageType string, opts DispatchOptions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dispatch == nil {
+		c.dispatch = make(map[string]DispatchOptions)
+	}
+	c.dispatch[messageType] = opts
+}
+
+// dispatchOptions returns the dispatch options of a message type, and false
+// if none were set
+func (c *WebSocketClient) dispatchOptions(messageType string) (DispatchOptions, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	opts, ok := c.dispatch[messageType]
+	return opts, ok
+}
+
+// RegisterContextHandler registers a handler for a specific message type that
+// is told when the connection closes through its context
+func (c *WebSocketClient) RegisterContextHandler(messageType string, handler ContextHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.contextHandlers == nil {
+		c.contextHandlers = make(map[string]ContextHandler)
+	}
+	c.contextHandlers[messageType] = handler
+	delete(c.Handlers, messageType)
+}
+
+// handler returns the handler of a message type
+func (c *WebSocketClient) handler(messageType string) (ContextHandler, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if handler, ok := c.contextHandlers[messageType]; ok {
+		return handler, true
+	}
+	handler, ok := c.Handlers[messageType]
+	if !ok {
+		return nil, false
+	}
+	return func(ctx context.Context, data []byte) error {
+		return handler(data)
+	}, true
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// scriptServer is a WebSocket server that sends messages to the client and
+// passes on the messages it receives
+type scriptServer struct {
+	*httptest.Server
+	send     chan string
+	received chan []byte
+	drop     chan struct{}
+}
+
+func newScriptServer(t *testing.T) *scriptServer {
+	t.Helper()
+	s := &scriptServer{
+		send:     make(chan string, 16),
+		received: make(chan []byte, 16),
+		drop:     make(chan struct{}),
+	}
+	upgrader := websocket.Upgrader{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go func() {
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				s.received <- data
+			}
+		}()
+		for {
+			select {
+			case message := <-s.send:
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+					return
+				}
+			case <-s.drop:
+				return
+			}
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// connect connects a client to the server
+func (s *scriptServer) connect(t *testing.T, client *WebSocketClient) {
+	t.Helper()
+	client.URL = "ws" + strings.TrimPrefix(s.URL, "http")
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() returned an error: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+}
+
+// waitFor fails the test if a value does not arrive in time
+func waitFor[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for %s", what)
+		var zero T
+		return zero
+	}
+}
+
+func TestDispatchSlowHandlerDoesNotBlockOtherPools(t *testing.T) {
+	server := newScriptServer(t)
+	client := NewWebSocketClient("", false)
+	client.SetDispatch("slow", DispatchOptions{})
+
+	release := make(chan struct{})
+	handled := make(chan string, 2)
+	client.RegisterHandler("slow", func(data []byte) error {
+		<-release
+		handled <- "slow"
+		return nil
+	})
+	client.RegisterHandler("fast", func(data []byte) error {
+		handled <- "fast"
+		return nil
+	})
+	server.connect(t, client)
+
+	server.send <- `{"type":"slow"}`
+	server.send <- `{"type":"fast"}`
+	if got := waitFor(t, handled, "the fast handler"); got != "fast" {
+		t.Errorf("Expected the fast handler to run while the slow one waits, got %s", got)
+	}
+	close(release)
+	waitFor(t, handled, "the slow handler")
+}
+
+func TestDispatchOrderedPool(t *testing.T) {
+	server := newScriptServer(t)
+	client := NewWebSocketClient("", false)
+	for _, messageType := range []string{"mouse", "key"} {
+		client.SetDispatch(messageType, DispatchOptions{Pool: "input", Workers: 1})
+	}
+
+	var mu sync.Mutex
+	var order []string
+	done := make(chan struct{})
+	record := func(data []byte) error {
+		var message struct {
+			Type string `json:"type"`
+			N    int    `json:"n"`
+		}
+		json.Unmarshal(data, &message)
+		// Sleeping on early messages would let later ones overtake them if
+		// the pool ran more than one at a time
+		time.Sleep(time.Duration(5-message.N) * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, message.Type)
+		if len(order) == 5 {
+			close(done)
+		}
+		return nil
+	}
+	client.RegisterHandler("mouse", record)
+	client.RegisterHandler("key", record)
+	server.connect(t, client)
+
+	for i, messageType := range []string{"mouse", "key", "mouse", "key", "key"} {
+		server.send <- fmt.Sprintf(`{"type":%q,"n":%d}`, messageType, i)
+	}
+	waitFor(t, done, "the input messages")
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"mouse", "key", "mouse", "key", "key"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("Expected %q, got %q", want, order)
+	}
+}
+
+func TestDispatchRecoversPanics(t *testing.T) {
+	server := newScriptServer(t)
+	client := NewWebSocketClient("", false)
+	handled := make(chan struct{}, 1)
+	client.RegisterHandler("broken", func(data []byte) error {
+		panic("boom")
+	})
+	client.RegisterHandler("ping", func(data []byte) error {
+		handled <- struct{}{}
+		return nil
+	})
+	server.connect(t, client)
+
+	server.send <- `{"type":"broken"}`
+	var failure HandlerFailure
+	if err := json.Unmarshal(waitFor(t, server.received, "the failure report"), &failure); err != nil {
+		t.Fatalf("Failed to parse the failure report: %v", err)
+	}
+	want := HandlerFailure{Type: HandlerFailedMessage, MessageType: "broken", Error: "handler panicked: boom"}
+	if failure != want {
+		t.Errorf("Expected %+v, got %+v", want, failure)
+	}
+
+	// The read loop and the pool keep going after the panic
+	server.send <- `{"type":"ping"}`
+	waitFor(t, handled, "the handler after the panic")
+}
+
+func TestDispatchCancelsContextOnDisconnect(t *testing.T) {
+	server := newScriptServer(t)
+	client := NewWebSocketClient("", false)
+	started, cancelled := make(chan struct{}), make(chan error, 1)
+	client.RegisterContextHandler("long", func(ctx context.Context, data []byte) error {
+		close(started)
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return ctx.Err()
+	})
+	disconnected := make(chan struct{})
+	client.OnDisconnect(func() { close(disconnected) })
+	server.connect(t, client)
+
+	server.send <- `{"type":"long"}`
+	waitFor(t, started, "the handler to start")
+	close(server.drop)
+	if err := waitFor(t, cancelled, "the context to be cancelled"); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	waitFor(t, disconnected, "the disconnect callback")
+
+	// A plain handler replaces the context handler of the type
+	client.RegisterHandler("long", func(data []byte) error { return nil })
+	if handler, ok := client.handler("long"); !ok || handler(context.Background(), nil) != nil {
+		t.Error("Expected the plain handler to replace the context handler")
+	}
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DefaultAPIKeyHeader is the header used to send the API key when none is configured
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// AuthOptions holds the credentials sent with the WebSocket handshake
+type AuthOptions struct {
+	BearerToken  string // Sent as "Authorization: Bearer <token>"
+	APIKey       string // Sent in APIKeyHeader
+	APIKeyHeader string // Header name for the API key (default: X-API-Key)
+}
+
+// TLSOptions holds the TLS settings used when dialing a wss:// URL
+type TLSOptions struct {
+	CAFile         string   // PEM bundle of additional trusted CAs
+	ClientCertFile string   // PEM client certificate for mutual TLS
+	ClientKeyFile  string   // PEM private key for the client certificate
+	ServerName     string   // Overrides the server name used for verification
+	PinnedSPKI     []string // SHA-256 pins of the server public key ("sha256/<base64>")
+}
+
+// PinError is returned when the server certificate chain matches none of the configured pins
+type PinError struct {
+	Expected []string // Configured pins
+	Got      []string // Pins of the certificates presented by the server
+}
+
+// Error implements the error interface
+func (e *PinError) Error() string {
+	return fmt.Sprintf("server public key pin mismatch: expected one of [%s], server presented [%s]",
+		strings.Join(e.Expected, ", "), strings.Join(e.Got, ", "))
+}
+
+// IsZero returns true if no TLS options are set
+func (o TLSOptions) IsZero() bool {
+	return o.CAFile == "" && o.ClientCertFile == "" && o.ClientKeyFile == "" &&
+		o.ServerName == "" && len(o.PinnedSPKI) == 0
+}
+
+// Config builds a *tls.Config from the options
+func (o TLSOptions) Config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: o.ServerName,
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle %s: %w", o.CAFile, err)
+		}
+
+		// Start from the system pool so the bundle adds to, rather than replaces, the defaults
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in CA bundle %s", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.ClientCertFile != "" || o.ClientKeyFile != "" {
+		if o.ClientCertFile == "" || o.ClientKeyFile == "" {
+			return nil, fmt.Errorf("both a client certificate and a client key are required for mutual TLS")
+		}
+		cert, err := tls.LoadX509KeyPair(o.ClientCertFile, o.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(o.PinnedSPKI) > 0 {
+		pins := make(map[string]bool, len(o.PinnedSPKI))
+		expected := make([]string, 0, len(o.PinnedSPKI))
+		for _, pin := range o.PinnedSPKI {
+			normalized, err := normalizePin(pin)
+			if err != nil {
+				return nil, err
+			}
+			pins[normalized] = true
+			expected = append(expected, "sha256/"+normalized)
+		}
+
+		// VerifyConnection runs after the standard chain verification, so pinning
+		// narrows the set of trusted servers rather than replacing verification
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			got := make([]string, 0, len(cs.PeerCertificates))
+			for _, cert := range cs.PeerCertificates {
+				pin := SPKIPin(cert)
+				if pins[pin] {
+					return nil
+				}
+				got = append(got, "sha256/"+pin)
+			}
+			return &PinError{Expected: expected, Got: got}
+		}
+	}
+
+	return cfg, nil
+}
+
+// SPKIPin returns the base64-encoded SHA-256 hash of a certificate's SubjectPublicKeyInfo
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// normalizePin strips the optional "sha256/" prefix and validates the pin
+func normalizePin(pin string) (string, error) {
+	pin = strings.TrimSpace(pin)
+	pin = strings.TrimPrefix(pin, "sha256/")
+	decoded, err := base64.StdEncoding.DecodeString(pin)
+	if err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid SPKI pin %q: expected base64-encoded SHA-256 hash", pin)
+	}
+	return pin, nil
+}
+
+// Header builds the HTTP headers carrying the credentials
+func (a AuthOptions) Header() http.Header {
+	header := http.Header{}
+	if a.BearerToken != "" {
+		header.Set("Authorization", "Bearer "+a.BearerToken)
+	}
+	if a.APIKey != "" {
+		name := a.APIKeyHeader
+		if name == "" {
+			name = DefaultAPIKeyHeader
+		}
+		header.Set(name, a.APIKey)
+	}
+	return header
+}
+
+// describeDialError turns TLS and handshake failures into actionable messages
+func describeDialError(err error, resp *http.Response) string {
+	var pinErr *PinError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &pinErr):
+		return "the server certificate does not match any configured SPKI pin; check WEBSOCKET_PINNED_SPKI"
+	case errors.As(err, &unknownAuthority):
+		return "the server certificate is signed by an unknown authority; set WEBSOCKET_CA_FILE to trust it"
+	case errors.As(err, &hostnameErr):
+		return fmt.Sprintf("the server certificate is not valid for host %q", hostnameErr.Host)
+	case errors.As(err, &invalidErr):
+		return fmt.Sprintf("the server certificate is invalid: %v", invalidErr)
+	case resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden):
+		return "the server rejected the credentials; check WEBSOCKET_AUTH_TOKEN or WEBSOCKET_API_KEY"
+	case strings.Contains(err.Error(), "certificate required") || strings.Contains(err.Error(), "bad certificate"):
+		return "the server requires a valid client certificate; check WEBSOCKET_CLIENT_CERT and WEBSOCKET_CLIENT_KEY"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestWSServer starts a TLS WebSocket server that records the handshake headers
+func newTestWSServer(t *testing.T, headers chan<- http.Header) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if headers != nil {
+			headers <- r.Header.Clone()
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writeServerCA writes the test server certificate to a PEM file and returns its path
+func writeServerCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+	return path
+}
+
+func wsURL(server *httptest.Server) string {
+	return "wss" + strings.TrimPrefix(server.URL, "https")
+}
+
+func TestAuthOptionsHeader(t *testing.T) {
+	header := AuthOptions{BearerToken: "abc", APIKey: "key"}.Header()
+	if got := header.Get("Authorization"); got != "Bearer abc" {
+		t.Errorf("Expected Authorization header 'Bearer abc', got '%s'", got)
+	}
+	if got := header.Get(DefaultAPIKeyHeader); got != "key" {
+		t.Errorf("Expected %s header 'key', got '%s'", DefaultAPIKeyHeader, got)
+	}
+
+	header = AuthOptions{APIKey: "key", APIKeyHeader: "X-Custom"}.Header()
+	if got := header.Get("X-Custom"); got != "key" {
+		t.Errorf("Expected X-Custom header 'key', got '%s'", got)
+	}
+
+	if len(AuthOptions{}.Header()) != 0 {
+		t.Error("Expected no headers when no credentials are set")
+	}
+}
+
+func TestConnectSendsAuthHeaders(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	server := newTestWSServer(t, headers)
+
+	client := NewWebSocketClient(wsURL(server), false)
+	client.Auth = AuthOptions{BearerToken: "token123"}
+	client.TLS = TLSOptions{CAFile: writeServerCA(t, server)}
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	got := <-headers
+	if got.Get("Authorization") != "Bearer token123" {
+		t.Errorf("Expected bearer token in handshake, got '%s'", got.Get("Authorization"))
+	}
+}
+
+func TestConnectUnknownAuthority(t *testing.T) {
+	server := newTestWSServer(t, nil)
+
+	client := NewWebSocketClient(wsURL(server), false)
+	err := client.Connect()
+	if err == nil {
+		client.Close()
+		t.Fatal("Connect() should fail when the server CA is not trusted")
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	if !errors.As(err, &unknownAuthority) {
+		t.Errorf("Expected an unknown authority error, got: %v", err)
+	}
+}
+
+func TestConnectSPKIPinning(t *testing.T) {
+	server := newTestWSServer(t, nil)
+	caFile := writeServerCA(t, server)
+	pin := "sha256/" + SPKIPin(server.Certificate())
+
+	// Matching pin connects
+	client := NewWebSocketClient(wsURL(server), false)
+	client.TLS = TLSOptions{CAFile: caFile, PinnedSPKI: []string{pin}}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() with matching pin returned an error: %v", err)
+	}
+	client.Close()
+
+	// Mismatched pin is rejected with a PinError
+	wrongPin := "sha256/" + strings.Repeat("A", 43) + "="
+	client = NewWebSocketClient(wsURL(server), false)
+	client.TLS = TLSOptions{CAFile: caFile, PinnedSPKI: []string{wrongPin}}
+	err := client.Connect()
+	if err == nil {
+		client.Close()
+		t.Fatal("Connect() should fail when no pin matches")
+	}
+
+	var pinErr *PinError
+	if !errors.As(err, &pinErr) {
+		t.Fatalf("Expected a PinError, got: %v", err)
+	}
+	if len(pinErr.Got) == 0 || pinErr.Got[0] != pin {
+		t.Errorf("Expected PinError to report server pin %s, got %v", pin, pinErr.Got)
+	}
+}
+
+func TestTLSOptionsInvalid(t *testing.T) {
+	if _, err := (TLSOptions{PinnedSPKI: []string{"not-a-pin"}}).Config(); err == nil {
+		t.Error("Expected an error for a malformed pin")
+	}
+
+	if _, err := (TLSOptions{ClientCertFile: "cert.pem"}).Config(); err == nil {
+		t.Error("Expected an error when the client key is missing")
+	}
+
+	if _, err := (TLSOptions{CAFile: filepath.Join(t.TempDir(), "missing.pem")}).Config(); err == nil {
+		t.Error("Expected an error for a missing CA bundle")
+	}
+}
+
+func TestTLSOptionsClientCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "go-support-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	// Require the generated certificate on the server side
+	pool := x509.NewCertPool()
+	cert, _ := x509.ParseCertificate(der)
+	pool.AddCert(cert)
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	server.StartTLS()
+	defer server.Close()
+
+	client := NewWebSocketClient(wsURL(server), false)
+	client.TLS = TLSOptions{
+		CAFile:         writeServerCA(t, server),
+		ClientCertFile: certFile,
+		ClientKeyFile:  keyFile,
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() with client certificate returned an error: %v", err)
+	}
+	client.Close()
+}
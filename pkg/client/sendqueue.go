@@ -0,0 +1,246 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Priority orders outgoing messages; messages of a lower priority are only
+// written when no message of a higher one is waiting
+type Priority int
+
+const (
+	// PriorityControl is for replies and control messages, written first
+	PriorityControl Priority = iota
+	// PriorityBulk is for file chunks, shell output and screenshots
+	PriorityBulk
+	// PriorityStream is for video frames and cursor updates
+	PriorityStream
+
+	numPriorities = iota
+)
+
+// String returns the name of a priority
+func (p Priority) String() string {
+	switch p {
+	case PriorityControl:
+		return "control"
+	case PriorityBulk:
+		return "bulk"
+	case PriorityStream:
+		return "stream"
+	default:
+		return "unknown"
+	}
+}
+
+// DropPolicy says what happens to a message sent while its queue is full
+type DropPolicy int
+
+const (
+	// Block waits until the writer makes room or the connection closes
+	Block DropPolicy = iota
+	// DropOldest drops the oldest waiting message to make room
+	DropOldest
+	// DropNewest rejects the new message with ErrSendQueueFull
+	DropNewest
+)
+
+// ErrSendQueueFull is returned when a message is rejected because its queue is full
+var ErrSendQueueFull = errors.New("send queue is full")
+
+// errNotConnected is returned when a message is sent without a connection
+var errNotConnected = errors.New("not connected to WebSocket server")
+
+// QueueOptions configures the queue of one priority
+type QueueOptions struct {
+	Size   int           // Messages waiting to be written before the policy applies
+	Policy DropPolicy    // What happens to messages sent while the queue is full
+	MaxAge time.Duration // Messages waiting longer are dropped instead of written; 0 keeps them
+}
+
+// DefaultQueueOptions returns the default options of a priority: control
+// and bulk messages wait for room and are never dropped, while only the
+// newest few stream messages are kept and those older than a second are
+// dropped as stale
+func DefaultQueueOptions(priority Priority) QueueOptions {
+	switch priority {
+	case PriorityBulk:
+		return QueueOptions{Size: 16, Policy: Block}
+	case PriorityStream:
+		return QueueOptions{Size: 4, Policy: DropOldest, MaxAge: time.Second}
+	default:
+		return QueueOptions{Size: 256, Policy: Block}
+	}
+}
+
+// QueueStats reports the state of the queue of one priority
+type QueueStats struct {
+	Depth   int    `json:"depth"`   // Messages waiting to be written
+	Peak    int    `json:"peak"`    // Highest depth since the connection opened
+	Written uint64 `json:"written"` // Messages written
+	Dropped uint64 `json:"dropped"` // Messages dropped because the queue was full or they were stale
+}
+
+// SendStats reports the state of the outbound queues of a connection
+type SendStats struct {
+	Control QueueStats `json:"control"`
+	Bulk    QueueStats `json:"bulk"`
+	Stream  QueueStats `json:"stream"`
+}
+
+// outbound is a message waiting to be written
+type outbound struct {
+	kind   int // websocket.TextMessage, BinaryMessage or CloseMessage
+	data   []byte
+	queued time.Time
+}
+
+// sendQueue holds the messages of a connection until its writer writes them,
+// highest priority first
+type sendQueue struct {
+	mu      sync.Mutex
+	changed *sync.Cond // Signalled when a message is queued or taken, or the queue closes
+	options [numPriorities]QueueOptions
+	queues  [numPriorities][]outbound
+	stats   [numPriorities]QueueStats
+	closing bool // No more messages are accepted; the control queue is still written
+	closed  bool // Nothing more is written
+	err     error
+}
+
+// newSendQueue creates a send queue with the options of each priority
+func newSendQueue(options [numPriorities]QueueOptions) *sendQueue {
+	q := &sendQueue{options: options}
+	for i := range q.options {
+		if q.options[i].Size <= 0 {
+			q.options[i].Size = DefaultQueueOptions(Priority(i)).Size
+		}
+	}
+	q.changed = sync.NewCond(&q.mu)
+	return q
+}
+
+// push queues a message, applying the drop policy of its priority when the
+// queue is full
+func (q *sendQueue) push(priority Priority, message outbound) error {
+	if priority < 0 || priority >= numPriorities {
+		priority = PriorityControl
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	opts, stats := q.options[priority], &q.stats[priority]
+	for !q.closing && len(q.queues[priority]) >= opts.Size {
+		switch opts.Policy {
+		case DropOldest:
+			q.queues[priority] = q.queues[priority][1:]
+			stats.Dropped++
+		case DropNewest:
+			stats.Dropped++
+			return ErrSendQueueFull
+		default:
+			q.changed.Wait()
+		}
+	}
+	if q.closing {
+		if q.err != nil {
+			return q.err
+		}
+		return errNotConnected
+	}
+
+	message.queued = time.Now()
+	q.queues[priority] = append(q.queues[priority], message)
+	stats.Depth = len(q.queues[priority])
+	stats.Peak = max(stats.Peak, stats.Depth)
+	q.changed.Broadcast()
+	return nil
+}
+
+// next waits for the next message to write and takes it from the queue.
+// Stale messages are dropped on the way. It returns false once the queue is
+// closed, or closing and out of control messages.
+func (q *sendQueue) next() (outbound, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		if q.closed {
+			return outbound{}, false
+		}
+		for priority := range q.queues {
+			if q.closing && priority != int(PriorityControl) {
+				break
+			}
+			for len(q.queues[priority]) > 0 {
+				message := q.queues[priority][0]
+				q.queues[priority] = q.queues[priority][1:]
+				stats := &q.stats[priority]
+				stats.Depth = len(q.queues[priority])
+				q.changed.Broadcast()
+
+				if maxAge := q.options[priority].MaxAge; maxAge > 0 && time.Since(message.queued) > maxAge {
+					stats.Dropped++
+					continue
+				}
+				stats.Written++
+				return message, true
+			}
+		}
+		if q.closing {
+			return outbound{}, false
+		}
+		q.changed.Wait()
+	}
+}
+
+// finish stops accepting messages. The writer writes the waiting control
+// messages and then message, if it is not empty, before stopping.
+func (q *sendQueue) finish(message outbound) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closing {
+		return
+	}
+	if message.kind != 0 {
+		q.queues[PriorityControl] = append(q.queues[PriorityControl], message)
+	}
+	q.closing = true
+	q.changed.Broadcast()
+}
+
+// abort drops every waiting message and stops the writer. A non-nil err is
+// recorded as the failure returned by sends from now on.
+func (q *sendQueue) abort(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.err == nil {
+		q.err = err
+	}
+	for i := range q.queues {
+		q.stats[i].Dropped += uint64(len(q.queues[i]))
+		q.queues[i] = nil
+		q.stats[i].Depth = 0
+	}
+	q.closing, q.closed = true, true
+	q.changed.Broadcast()
+}
+
+// failure returns why the writer stopped early, or nil
+func (q *sendQueue) failure() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.err
+}
+
+// snapshot returns the statistics of the queues
+func (q *sendQueue) snapshot() SendStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return SendStats{
+		Control: q.stats[PriorityControl],
+		Bulk:    q.stats[PriorityBulk],
+		Stream:  q.stats[PriorityStream],
+	}
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// text returns a text message for a send queue
+func text(s string) outbound {
+	return outbound{kind: websocket.TextMessage, data: []byte(s)}
+}
+
+// drain takes the messages a writer would write until the queue has none left
+func drain(q *sendQueue) []string {
+	q.finish(outbound{})
+	var got []string
+	for {
+		message, ok := q.next()
+		if !ok {
+			return got
+		}
+		got = append(got, string(message.data))
+	}
+}
+
+func TestSendQueuePriority(t *testing.T) {
+	q := newSendQueue([numPriorities]QueueOptions{{Size: 8}, {Size: 8}, {Size: 8}})
+	q.push(PriorityStream, text("frame 1"))
+	q.push(PriorityBulk, text("chunk 1"))
+	q.push(PriorityControl, text("reply 1"))
+	q.push(PriorityStream, text("frame 2"))
+	q.push(PriorityBulk, text("chunk 2"))
+	q.push(PriorityControl, text("reply 2"))
+
+	// Control messages jump ahead of the chunks and frames queued before
+	// them, and chunks ahead of frames
+	var got []string
+	for i := 0; i < 6; i++ {
+		message, _ := q.next()
+		got = append(got, string(message.data))
+	}
+	want := []string{"reply 1", "reply 2", "chunk 1", "chunk 2", "frame 1", "frame 2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	stats := q.snapshot()
+	if stats.Control.Written != 2 || stats.Bulk.Written != 2 || stats.Stream.Written != 2 || stats.Stream.Peak != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestSendQueueDropPolicies(t *testing.T) {
+	q := newSendQueue([numPriorities]QueueOptions{
+		PriorityControl: {Size: 2, Policy: DropNewest},
+		PriorityStream:  {Size: 2, Policy: DropOldest},
+	})
+	for i := 1; i <= 4; i++ {
+		if err := q.push(PriorityStream, text(fmt.Sprintf("frame %d", i))); err != nil {
+			t.Fatalf("push() returned an error for a stream message: %v", err)
+		}
+	}
+	q.push(PriorityControl, text("reply 1"))
+	q.push(PriorityControl, text("reply 2"))
+	if err := q.push(PriorityControl, text("reply 3")); !errors.Is(err, ErrSendQueueFull) {
+		t.Errorf("Expected ErrSendQueueFull, got %v", err)
+	}
+
+	stats := q.snapshot()
+	want := SendStats{
+		Control: QueueStats{Depth: 2, Peak: 2, Dropped: 1},
+		Stream:  QueueStats{Depth: 2, Peak: 2, Dropped: 2},
+	}
+	if stats != want {
+		t.Errorf("Expected stats %+v, got %+v", want, stats)
+	}
+
+	// Closing writes the control messages and drops the frames
+	if got := drain(q); !reflect.DeepEqual(got, []string{"reply 1", "reply 2"}) {
+		t.Errorf("Expected only the replies to be written on close, got %q", got)
+	}
+	if err := q.push(PriorityControl, text("late")); err == nil {
+		t.Error("Expected pushing to a closed queue to fail")
+	}
+}
+
+func TestSendQueueDropsStaleMessages(t *testing.T) {
+	q := newSendQueue([numPriorities]QueueOptions{PriorityStream: {Size: 8, MaxAge: 20 * time.Millisecond}})
+	q.push(PriorityStream, text("old frame"))
+	time.Sleep(30 * time.Millisecond)
+	q.push(PriorityStream, text("new frame"))
+
+	message, _ := q.next()
+	if string(message.data) != "new frame" {
+		t.Errorf("Expected the stale frame to be skipped, got %q", message.data)
+	}
+	if stats := q.snapshot().Stream; stats.Dropped != 1 || stats.Written != 1 {
+		t.Errorf("Unexpected stream stats %+v", stats)
+	}
+}
+
+func TestSendQueueBlocksUntilRoom(t *testing.T) {
+	q := newSendQueue([numPriorities]QueueOptions{PriorityBulk: {Size: 1, Policy: Block}})
+	q.push(PriorityBulk, text("first"))
+
+	// A bulk message waits for room instead of being dropped
+	pushed := make(chan error, 1)
+	go func() { pushed <- q.push(PriorityBulk, text("second")) }()
+	select {
+	case err := <-pushed:
+		t.Fatalf("Expected push() to wait for room, it returned %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	q.next()
+	if err := waitFor(t, pushed, "the blocked push"); err != nil {
+		t.Errorf("push() returned an error: %v", err)
+	}
+
+	// A push waiting for room fails when the connection drops
+	go func() { pushed <- q.push(PriorityBulk, text("third")) }()
+	time.Sleep(10 * time.Millisecond)
+	q.abort(nil)
+	if err := waitFor(t, pushed, "the aborted push"); !errors.Is(err, errNotConnected) {
+		t.Errorf("Expected errNotConnected, got %v", err)
+	}
+}
+
+func TestConcurrentSendsAreWrittenWhole(t *testing.T) {
+	server := newScriptServer(t)
+	client := NewWebSocketClient("", false)
+	server.connect(t, client)
+
+	const senders, messages = 8, 10
+	var wg sync.WaitGroup
+	for s := 0; s < senders; s++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < messages; i++ {
+				var err error
+				if i%2 == 0 {
+					err = client.SendJSON(map[string]int{"sender": s, "n": i})
+				} else {
+					err = client.SendMessage(Message{Type: CustomMessage, Message: fmt.Sprint(s, i)})
+				}
+				if err != nil {
+					t.Errorf("Send returned an error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < senders*messages; i++ {
+		data := waitFor(t, server.received, "a message")
+		if !json.Valid(data) {
+			t.Fatalf("Received a garbled message: %s", data)
+		}
+	}
+	if stats := client.SendStats(); stats.Control.Written != senders*messages {
+		t.Errorf("Expected %d control messages written, got %+v", senders*messages, stats.Control)
+	}
+}
+
+func TestCloseWritesQueuedMessages(t *testing.T) {
+	server := newScriptServer(t)
+	client := NewWebSocketClient("", false)
+	server.connect(t, client)
+
+	if err := client.SendJSON(map[string]string{"type": "bye"}); err != nil {
+		t.Fatalf("SendJSON() returned an error: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+	if got := string(waitFor(t, server.received, "the queued message")); got != `{"type":"bye"}` {
+		t.Errorf("Expected the queued message before closing, got %s", got)
+	}
+	if err := client.SendJSONWithPriority(map[string]string{"type": "frame"}, PriorityStream); err == nil {
+		t.Error("Expected sending after Close to fail")
+	}
+}
@@ -24,6 +24,8 @@ const (
 	CustomMessage MessageType = "custom"
 	// ScreenshotMessage is a screenshot message
 	ScreenshotMessage MessageType = "screenshot"
+	// HandlerFailedMessage reports a handler that panicked
+	HandlerFailedMessage MessageType = "handlerFailed"
 )
 
 // Message represents a message to be sent to the WebSocket server
@@ -44,6 +46,13 @@ type Message struct {
 // MessageHandler is a function that handles a specific type of message
 type MessageHandler func(data []byte) error
 
+// Transport is the part of the WebSocket client that subsystems use to send
+// messages and handle the messages of their types
+type Transport interface {
+	SendJSON(message interface{}) error
+	RegisterHandler(messageType string, handler MessageHandler)
+}
+
 // WebSocketClient represents a WebSocket client
 type WebSocketClient struct {
 	URL            string
@@ -52,17 +61,33 @@ type WebSocketClient struct {
 	Connected      bool
 	ConnectTimeout time.Duration
 	Verbose        bool
+	Auth           AuthOptions // Credentials sent with the handshake
+	TLS            TLSOptions  // TLS settings for wss:// connections
 	mu             sync.Mutex
+
+	contextHandlers map[string]ContextHandler
+	dispatch        map[string]DispatchOptions
+	sendOptions     [numPriorities]QueueOptions
+	sendQueue       *sendQueue
+	writerDone      chan struct{}
+	onDisconnect    func()
 }
 
+// closeTimeout limits how long Close waits for queued control messages to be written
+const closeTimeout = 5 * time.Second
+
 // NewWebSocketClient creates a new WebSocket client
 func NewWebSocketClient(url string, verbose bool) *WebSocketClient {
-	return &WebSocketClient{
+	c := &WebSocketClient{
 		URL:            url,
 		Handlers:       make(map[string]MessageHandler),
 		ConnectTimeout: 10 * time.Second,
 		Verbose:        verbose,
 	}
+	for i := range c.sendOptions {
+		c.sendOptions[i] = DefaultQueueOptions(Priority(i))
+	}
+	return c
 }
 
 // Connect connects to the WebSocket server
@@ -81,15 +106,26 @@ func (c *WebSocketClient) Connect() error {
 		HandshakeTimeout: c.ConnectTimeout,
 	}
 
+	if !c.TLS.IsZero() {
+		tlsConfig, err := c.TLS.Config()
+		if err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		dialer.TLSClientConfig = tlsConfig
+	}
+
 	if c.Verbose {
 		log.Printf("DEBUG: Attempting to connect to WebSocket server at %s...", c.URL)
 	}
 
-	conn, resp, err := dialer.Dial(c.URL, nil)
+	conn, resp, err := dialer.Dial(c.URL, c.Auth.Header())
 	if err != nil {
 		if resp != nil {
 			log.Printf("ERROR: Failed to connect to WebSocket server. Status code: %d", resp.StatusCode)
 		}
+		if hint := describeDialError(err, resp); hint != "" {
+			log.Printf("ERROR: %s", hint)
+		}
 		return fmt.Errorf("failed to connect to WebSocket server: %w", err)
 	}
 
@@ -102,35 +138,46 @@ func (c *WebSocketClient) Connect() error {
 			conn.LocalAddr().String(), conn.RemoteAddr().String())
 	}
 
+	// Start the writer, which is the only goroutine writing to the connection
+	c.sendQueue = newSendQueue(c.sendOptions)
+	c.writerDone = make(chan struct{})
+	go c.writeMessages(conn, c.sendQueue, c.writerDone)
+
 	// Start message handler
-	go c.handleMessages()
+	go c.handleMessages(newDispatcher(c))
 
 	return nil
 }
 
-// Close closes the WebSocket connection
+// Close writes the queued control messages and a close message, and closes
+// the WebSocket connection. Queued stream messages are dropped.
 func (c *WebSocketClient) Close() error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if !c.Connected || c.Conn == nil {
+		c.mu.Unlock()
 		return nil
 	}
-
-	err := c.Conn.WriteMessage(
-		websocket.CloseMessage,
-		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
-	)
-	if err != nil {
+	c.Connected = false
+	conn, queue, done := c.Conn, c.sendQueue, c.writerDone
+	c.mu.Unlock()
+
+	queue.finish(outbound{
+		kind: websocket.CloseMessage,
+		data: websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+	})
+	select {
+	case <-done:
+	case <-time.After(closeTimeout):
+		queue.abort(fmt.Errorf("timed out writing queued messages"))
+	}
+	if err := queue.failure(); err != nil {
+		conn.Close()
 		return fmt.Errorf("error sending close message: %w", err)
 	}
 
-	err = c.Conn.Close()
-	if err != nil {
+	if err := conn.Close(); err != nil {
 		return fmt.Errorf("error closing connection: %w", err)
 	}
-
-	c.Connected = false
 	return nil
 }
 
@@ -146,15 +193,85 @@ func (c *WebSocketClient) RegisterHandler(messageType string, handler MessageHan
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.Handlers[messageType] = handler
+	delete(c.contextHandlers, messageType)
 }
 
-// SendJSON sends a JSON message to the server
-func (c *WebSocketClient) SendJSON(message interface{}) error {
+// OnDisconnect sets a callback invoked when a connection ends, whether it
+// dropped or was closed. It runs on the goroutine that read the connection,
+// once the running handlers have been cancelled and have returned.
+func (c *WebSocketClient) OnDisconnect(callback func()) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.onDisconnect = callback
+}
 
-	if !c.Connected || c.Conn == nil {
-		return fmt.Errorf("not connected to WebSocket server")
+// SetSendQueue sets the options of the outbound queue of a priority. It
+// applies from the next connection, so set it before connecting.
+func (c *WebSocketClient) SetSendQueue(priority Priority, opts QueueOptions) {
+	if priority < 0 || priority >= numPriorities {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sendOptions[priority] = opts
+}
+
+// SendStats returns the state of the outbound queues of the current or last connection
+func (c *WebSocketClient) SendStats() SendStats {
+	c.mu.Lock()
+	queue := c.sendQueue
+	c.mu.Unlock()
+	if queue == nil {
+		return SendStats{}
+	}
+	return queue.snapshot()
+}
+
+// send queues a message for the writer
+func (c *WebSocketClient) send(priority Priority, kind int, data []byte) error {
+	c.mu.Lock()
+	queue, connected := c.sendQueue, c.Connected
+	c.mu.Unlock()
+
+	if !connected || queue == nil {
+		return errNotConnected
+	}
+	return queue.push(priority, outbound{kind: kind, data: data})
+}
+
+// writeMessages writes queued messages to the connection until the queue
+// stops or a write fails
+func (c *WebSocketClient) writeMessages(conn *websocket.Conn, queue *sendQueue, done chan struct{}) {
+	defer close(done)
+	for {
+		message, ok := queue.next()
+		if !ok {
+			return
+		}
+		if err := conn.WriteMessage(message.kind, message.data); err != nil {
+			log.Printf("ERROR: Failed to write message: %v", err)
+			queue.abort(fmt.Errorf("error writing message: %w", err))
+			conn.Close()
+			return
+		}
+	}
+}
+
+// SendJSON queues a JSON message for the server ahead of stream messages
+func (c *WebSocketClient) SendJSON(message interface{}) error {
+	return c.SendJSONWithPriority(message, PriorityControl)
+}
+
+// SendJSONWithPriority queues a JSON message for the server with a priority.
+// Errors writing the message are logged and close the connection.
+func (c *WebSocketClient) SendJSONWithPriority(message interface{}, priority Priority) error {
+	if !c.IsConnected() {
+		return errNotConnected
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("error marshaling message: %w", err)
 	}
 
 	if c.Verbose {
@@ -167,11 +284,23 @@ func (c *WebSocketClient) SendJSON(message interface{}) error {
 		}
 	}
 
-	return c.Conn.WriteJSON(message)
+	return c.send(priority, websocket.TextMessage, data)
 }
 
-// handleMessages handles incoming WebSocket messages
-func (c *WebSocketClient) handleMessages() {
+// SendBinary queues a binary message for the server behind control messages
+func (c *WebSocketClient) SendBinary(data []byte) error {
+	if c.Verbose {
+		log.Printf("DEBUG: Sending binary message of %d bytes", len(data))
+	}
+
+	return c.send(PriorityBulk, websocket.BinaryMessage, data)
+}
+
+// handleMessages reads incoming WebSocket messages and dispatches them to
+// the worker pools of their handlers, until the connection closes
+func (c *WebSocketClient) handleMessages(d *dispatcher) {
+	defer c.disconnected()
+	defer d.stop()
 	for {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
@@ -180,7 +309,9 @@ func (c *WebSocketClient) handleMessages() {
 			}
 			c.mu.Lock()
 			c.Connected = false
+			queue := c.sendQueue
 			c.mu.Unlock()
+			queue.abort(nil)
 			return
 		}
 
@@ -212,26 +343,32 @@ func (c *WebSocketClient) handleMessages() {
 			log.Printf("Received message of type: %s with content: %+v", msgType, data)
 		}
 
-		// Call handler for message type
-		c.mu.Lock()
-		handler, ok := c.Handlers[msgType]
-		c.mu.Unlock()
-		if ok {
-			if err := handler(message); err != nil {
-				if c.Verbose {
-					log.Printf("Error handling message of type %s: %v", msgType, err)
-				}
-			} else if c.Verbose {
-				log.Printf("Successfully handled message of type: %s", msgType)
-			}
+		// Queue the message for the handler of its type
+		if handler, ok := c.handler(msgType); ok {
+			d.dispatch(msgType, message, handler)
 		} else if c.Verbose {
 			log.Printf("No handler registered for message type: %s", msgType)
 		}
 	}
 }
 
+// disconnected runs the disconnect callback
+func (c *WebSocketClient) disconnected() {
+	c.mu.Lock()
+	callback := c.onDisconnect
+	c.mu.Unlock()
+	if callback != nil {
+		callback()
+	}
+}
+
 // SendMessage sends a message to the WebSocket server
 func (c *WebSocketClient) SendMessage(msg Message) error {
+	return c.sendMessage(msg, PriorityControl)
+}
+
+// sendMessage sends a message to the WebSocket server with a priority
+func (c *WebSocketClient) sendMessage(msg Message, priority Priority) error {
 	if msg.Timestamp == "" {
 		msg.Timestamp = time.Now().Format(time.RFC3339)
 	}
@@ -251,15 +388,14 @@ func (c *WebSocketClient) SendMessage(msg Message) error {
 		}
 	}
 
-	err = c.Conn.WriteMessage(websocket.TextMessage, data)
-	if err != nil {
+	if err := c.send(priority, websocket.TextMessage, data); err != nil {
 		return fmt.Errorf("error writing message: %w", err)
 	}
 
 	return nil
 }
 
-// SendScreenshot sends a screenshot through the WebSocket connection
+// SendScreenshot sends a screenshot through the WebSocket connection as a bulk message
 func (c *WebSocketClient) SendScreenshot(screenshotData, format string, width, height int, description string) error {
 	msg := Message{
 		Type:           ScreenshotMessage,
@@ -275,5 +411,5 @@ func (c *WebSocketClient) SendScreenshot(screenshotData, format string, width, h
 		},
 	}
 
-	return c.SendMessage(msg)
+	return c.sendMessage(msg, PriorityBulk)
 }
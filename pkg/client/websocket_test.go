@@ -203,6 +203,15 @@ func TestSendJSON(t *testing.T) {
 	}
 }
 
+// TestSendBinary tests the SendBinary method
+func TestSendBinary(t *testing.T) {
+	client := NewWebSocketClient("ws://example.com", false)
+
+	if err := client.SendBinary([]byte{1, 2, 3}); err == nil {
+		t.Error("SendBinary() should return an error when not connected")
+	}
+}
+
 // TestClose tests the Close method
 func TestClose(t *testing.T) {
 	client := NewWebSocketClient("ws://example.com", false)
@@ -0,0 +1,226 @@
+package clipboard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// For testing purposes, we can replace these with mocks
+var (
+	execLookPath = exec.LookPath
+	osGetenv     = os.Getenv
+)
+
+// command is an external command that reads or writes one clipboard format
+type command struct {
+	args   []string                     // Program and arguments
+	encode func([]byte) []byte          // Converts data before it is written to stdin
+	decode func([]byte) ([]byte, error) // Converts the output of a read
+}
+
+// commandBackend accesses the clipboard through platform command-line tools
+type commandBackend struct {
+	name   string
+	read   map[Format]command
+	write  map[Format]command
+	output func(args []string) ([]byte, error)     // Runs a command and returns stdout
+	input  func(args []string, stdin []byte) error // Runs a command with data on stdin
+}
+
+// Name implements the Backend interface
+func (b *commandBackend) Name() string {
+	return b.name
+}
+
+// Read implements the Backend interface
+func (b *commandBackend) Read(format Format) ([]byte, error) {
+	cmd, ok := b.read[format]
+	if !ok {
+		return nil, fmt.Errorf("%s backend cannot read %s", b.name, format)
+	}
+
+	data, err := b.output(cmd.args)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", cmd.args[0], err)
+	}
+	if cmd.decode != nil {
+		return cmd.decode(data)
+	}
+	return data, nil
+}
+
+// Write implements the Backend interface
+func (b *commandBackend) Write(format Format, data []byte) error {
+	cmd, ok := b.write[format]
+	if !ok {
+		return fmt.Errorf("%s backend cannot write %s", b.name, format)
+	}
+
+	if cmd.encode != nil {
+		data = cmd.encode(data)
+	}
+	if err := b.input(cmd.args, data); err != nil {
+		return fmt.Errorf("%s failed: %w", cmd.args[0], err)
+	}
+	return nil
+}
+
+// DetectBackend returns the clipboard backend for the current platform
+func DetectBackend() (Backend, error) {
+	return detectBackend(runtime.GOOS)
+}
+
+// detectBackend returns the clipboard backend for a platform
+func detectBackend(goos string) (Backend, error) {
+	var backend *commandBackend
+
+	switch goos {
+	case "darwin":
+		backend = macOSBackend()
+	case "windows":
+		backend = windowsBackend()
+	case "linux", "freebsd", "openbsd", "netbsd":
+		_, wlErr := execLookPath("wl-paste")
+		_, xclipErr := execLookPath("xclip")
+		wayland := osGetenv("WAYLAND_DISPLAY") != ""
+
+		switch {
+		case wlErr == nil && (wayland || xclipErr != nil):
+			backend = waylandBackend()
+		case xclipErr == nil:
+			backend = xclipBackend()
+		default:
+			return nil, fmt.Errorf("%w: install xclip or wl-clipboard", ErrUnsupported)
+		}
+	default:
+		return nil, fmt.Errorf("%w on %s", ErrUnsupported, goos)
+	}
+
+	backend.output = runOutput
+	backend.input = runInput
+	return backend, nil
+}
+
+// xclipBackend uses xclip on X11
+func xclipBackend() *commandBackend {
+	return &commandBackend{
+		name: "xclip",
+		read: map[Format]command{
+			Text:  {args: []string{"xclip", "-selection", "clipboard", "-o"}},
+			Image: {args: []string{"xclip", "-selection", "clipboard", "-o", "-t", "image/png"}},
+		},
+		write: map[Format]command{
+			Text:  {args: []string{"xclip", "-selection", "clipboard", "-i"}},
+			Image: {args: []string{"xclip", "-selection", "clipboard", "-i", "-t", "image/png"}},
+		},
+	}
+}
+
+// waylandBackend uses wl-clipboard on Wayland
+func waylandBackend() *commandBackend {
+	return &commandBackend{
+		name: "wl-clipboard",
+		read: map[Format]command{
+			Text:  {args: []string{"wl-paste", "--no-newline"}},
+			Image: {args: []string{"wl-paste", "--type", "image/png"}},
+		},
+		write: map[Format]command{
+			Text:  {args: []string{"wl-copy"}},
+			Image: {args: []string{"wl-copy", "--type", "image/png"}},
+		},
+	}
+}
+
+// macOSBackend uses pbcopy and pbpaste for text and AppleScript for images
+func macOSBackend() *commandBackend {
+	return &commandBackend{
+		name: "pbcopy",
+		read: map[Format]command{
+			Text: {args: []string{"pbpaste"}},
+			Image: {
+				args:   []string{"osascript", "-e", "the clipboard as «class PNGf»"},
+				decode: decodeAppleScriptData,
+			},
+		},
+		write: map[Format]command{
+			Text:  {args: []string{"pbcopy"}},
+			Image: {args: []string{"osascript", "-e", `set the clipboard to (read (POSIX file "/dev/stdin") as «class PNGf»)`}},
+		},
+	}
+}
+
+// windowsBackend uses PowerShell
+func windowsBackend() *commandBackend {
+	const forms = "Add-Type -AssemblyName System.Windows.Forms; Add-Type -AssemblyName System.Drawing; "
+	return &commandBackend{
+		name: "powershell",
+		read: map[Format]command{
+			Text: {
+				args:   []string{"powershell", "-NoProfile", "-Command", "[Console]::OutputEncoding = [Text.Encoding]::UTF8; Get-Clipboard -Raw"},
+				decode: trimCRLF,
+			},
+			Image: {
+				args: []string{"powershell", "-NoProfile", "-STA", "-Command", forms +
+					"$i = [Windows.Forms.Clipboard]::GetImage(); if ($i) { $m = New-Object IO.MemoryStream; " +
+					"$i.Save($m, [Drawing.Imaging.ImageFormat]::Png); [Convert]::ToBase64String($m.ToArray()) }"},
+				decode: decodeBase64,
+			},
+		},
+		write: map[Format]command{
+			Text: {args: []string{"powershell", "-NoProfile", "-Command",
+				"[Console]::InputEncoding = [Text.Encoding]::UTF8; Set-Clipboard -Value ([Console]::In.ReadToEnd())"}},
+			Image: {
+				args: []string{"powershell", "-NoProfile", "-STA", "-Command", forms +
+					"$b = [Convert]::FromBase64String([Console]::In.ReadToEnd()); $m = New-Object IO.MemoryStream(,$b); " +
+					"[Windows.Forms.Clipboard]::SetImage([Drawing.Image]::FromStream($m))"},
+				encode: func(data []byte) []byte { return []byte(base64.StdEncoding.EncodeToString(data)) },
+			},
+		},
+	}
+}
+
+// decodeAppleScriptData decodes AppleScript output of the form «data PNGf89504E47...»
+func decodeAppleScriptData(output []byte) ([]byte, error) {
+	text := strings.TrimSpace(string(output))
+	text = strings.TrimPrefix(text, "«data PNGf")
+	text = strings.TrimSuffix(text, "»")
+
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected AppleScript image data: %w", err)
+	}
+	return data, nil
+}
+
+// decodeBase64 decodes base64 output, treating empty output as an empty clipboard
+func decodeBase64(output []byte) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(output)))
+	if err != nil {
+		return nil, fmt.Errorf("unexpected base64 image data: %w", err)
+	}
+	return data, nil
+}
+
+// trimCRLF removes the line ending PowerShell adds to its output
+func trimCRLF(output []byte) ([]byte, error) {
+	return bytes.TrimSuffix(output, []byte("\r\n")), nil
+}
+
+// runOutput runs a command and returns its stdout
+func runOutput(args []string) ([]byte, error) {
+	return exec.Command(args[0], args[1:]...).Output()
+}
+
+// runInput runs a command with data on stdin. Output is not captured because
+// tools such as xclip keep running in the background to serve the selection.
+func runInput(args []string, stdin []byte) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	return cmd.Run()
+}
@@ -0,0 +1,249 @@
+package clipboard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/permissions"
+)
+
+// Format is the format of clipboard content
+type Format string
+
+const (
+	// Text is UTF-8 text
+	Text Format = "text"
+	// Image is a PNG image
+	Image Format = "image/png"
+)
+
+const (
+	// DefaultMaxSize is the default limit for clipboard content in bytes
+	DefaultMaxSize = 4 << 20
+	// DefaultPollInterval is the default interval for watching the clipboard
+	DefaultPollInterval = time.Second
+)
+
+var (
+	// ErrTooLarge is returned for content larger than the size limit
+	ErrTooLarge = errors.New("clipboard content exceeds the size limit")
+	// ErrUnsupported is returned when no clipboard backend is available
+	ErrUnsupported = errors.New("no clipboard backend available")
+	// ErrPermissionDenied is returned when clipboard access is not permitted
+	ErrPermissionDenied = errors.New("clipboard permission not granted")
+	// ErrUnknownFormat is returned for formats other than Text and Image
+	ErrUnknownFormat = errors.New("unknown clipboard format")
+)
+
+// pngSignature is the signature at the start of every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Content is a snapshot of the clipboard
+type Content struct {
+	Format Format
+	Data   []byte
+}
+
+// Backend reads and writes the system clipboard
+type Backend interface {
+	// Name returns a short description of the backend
+	Name() string
+	// Read returns the clipboard content in the given format
+	Read(format Format) ([]byte, error)
+	// Write replaces the clipboard content
+	Write(format Format, data []byte) error
+}
+
+// Clipboard gives size-limited, permission-gated access to the system clipboard
+type Clipboard struct {
+	backend     Backend
+	permManager permissions.Manager
+	maxSize     int
+	last        [sha256.Size]byte // Hash of the content last seen or written
+	verbose     bool
+	mu          sync.Mutex
+}
+
+// New creates a clipboard using the backend for the current platform
+func New(permManager permissions.Manager, maxSize int, verbose bool) (*Clipboard, error) {
+	backend, err := DetectBackend()
+	if err != nil {
+		return nil, err
+	}
+	if verbose {
+		log.Printf("DEBUG: Using %s clipboard backend", backend.Name())
+	}
+	return NewWithBackend(backend, permManager, maxSize, verbose), nil
+}
+
+// NewWithBackend creates a clipboard using the given backend
+func NewWithBackend(backend Backend, permManager permissions.Manager, maxSize int, verbose bool) *Clipboard {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	return &Clipboard{
+		backend:     backend,
+		permManager: permManager,
+		maxSize:     maxSize,
+		verbose:     verbose,
+	}
+}
+
+// MaxSize returns the size limit in bytes
+func (c *Clipboard) MaxSize() int {
+	return c.maxSize
+}
+
+// Read returns the clipboard content in the given format
+func (c *Clipboard) Read(format Format) (*Content, error) {
+	if err := validFormat(format); err != nil {
+		return nil, err
+	}
+	if err := c.checkPermission(); err != nil {
+		return nil, err
+	}
+
+	data, err := c.backend.Read(format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clipboard: %w", err)
+	}
+	if len(data) > c.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrTooLarge, len(data), c.maxSize)
+	}
+
+	return &Content{Format: format, Data: data}, nil
+}
+
+// Write replaces the clipboard content
+func (c *Clipboard) Write(content Content) error {
+	if err := validFormat(content.Format); err != nil {
+		return err
+	}
+	if len(content.Data) > c.maxSize {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrTooLarge, len(content.Data), c.maxSize)
+	}
+	if content.Format == Image && !bytes.HasPrefix(content.Data, pngSignature) {
+		return fmt.Errorf("clipboard image is not a PNG")
+	}
+	if err := c.checkPermission(); err != nil {
+		return err
+	}
+
+	if err := c.backend.Write(content.Format, content.Data); err != nil {
+		return fmt.Errorf("failed to write clipboard: %w", err)
+	}
+
+	// Remember what we wrote so Watch does not report it back as a change
+	c.mu.Lock()
+	c.last = content.hash()
+	c.mu.Unlock()
+
+	if c.verbose {
+		log.Printf("DEBUG: Wrote %d bytes of %s to the clipboard", len(content.Data), content.Format)
+	}
+	return nil
+}
+
+// Watch polls the clipboard until stop is closed and calls onChange when the
+// content changes. Content written with Write and content over the size limit
+// is not reported.
+func (c *Clipboard) Watch(stop <-chan struct{}, interval time.Duration, onChange func(Content)) error {
+	if err := c.checkPermission(); err != nil {
+		return err
+	}
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	// The content present when watching starts is not a change
+	if content := c.poll(); content != nil {
+		c.mu.Lock()
+		c.last = content.hash()
+		c.mu.Unlock()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			content := c.poll()
+			if content == nil {
+				continue
+			}
+
+			hash := content.hash()
+			c.mu.Lock()
+			changed := hash != c.last
+			c.last = hash
+			c.mu.Unlock()
+
+			if !changed {
+				continue
+			}
+			if len(content.Data) > c.maxSize {
+				if c.verbose {
+					log.Printf("DEBUG: Ignoring clipboard change of %d bytes, limit %d", len(content.Data), c.maxSize)
+				}
+				continue
+			}
+			onChange(*content)
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// poll reads the clipboard as text, falling back to an image
+func (c *Clipboard) poll() *Content {
+	if data, err := c.backend.Read(Text); err == nil && len(data) > 0 {
+		return &Content{Format: Text, Data: data}
+	}
+	if data, err := c.backend.Read(Image); err == nil && len(data) > 0 {
+		return &Content{Format: Image, Data: data}
+	}
+	return nil
+}
+
+// checkPermission checks that clipboard access is permitted
+func (c *Clipboard) checkPermission() error {
+	if c.permManager == nil {
+		// If no permission manager is provided, assume permissions are granted
+		return nil
+	}
+
+	granted, err := c.permManager.EnsurePermission(permissions.Clipboard)
+	if err != nil {
+		return fmt.Errorf("failed to check clipboard permission: %w", err)
+	}
+	if !granted {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// hash returns a hash identifying the content
+func (c Content) hash() [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(c.Format))
+	h.Write([]byte{0})
+	h.Write(c.Data)
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// validFormat checks that a format is supported
+func validFormat(format Format) error {
+	if format != Text && format != Image {
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	return nil
+}
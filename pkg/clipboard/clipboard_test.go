@@ -0,0 +1,232 @@
+package clipboard
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/permissions"
+)
+
+// fakeBackend is an in-memory clipboard
+type fakeBackend struct {
+	mu      sync.Mutex
+	content map[Format][]byte
+	writes  int
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{content: make(map[Format][]byte)}
+}
+
+func (f *fakeBackend) Name() string { return "fake" }
+
+func (f *fakeBackend) Read(format Format) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.content[format]
+	if !ok {
+		return nil, errors.New("format not available")
+	}
+	return data, nil
+}
+
+func (f *fakeBackend) Write(format Format, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.content = map[Format][]byte{format: data}
+	f.writes++
+	return nil
+}
+
+// set changes the clipboard as if the local user copied something
+func (f *fakeBackend) set(format Format, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.content = map[Format][]byte{format: data}
+}
+
+func TestReadWrite(t *testing.T) {
+	backend := newFakeBackend()
+	clip := NewWithBackend(backend, nil, 16, false)
+
+	if err := clip.Write(Content{Format: Text, Data: []byte("echo hello")}); err != nil {
+		t.Fatalf("Write() returned an error: %v", err)
+	}
+	content, err := clip.Read(Text)
+	if err != nil || string(content.Data) != "echo hello" {
+		t.Fatalf("Read() = %v, %v", content, err)
+	}
+
+	if err := clip.Write(Content{Format: Text, Data: make([]byte, 17)}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got: %v", err)
+	}
+	backend.set(Text, make([]byte, 17))
+	if _, err := clip.Read(Text); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge on read, got: %v", err)
+	}
+
+	if err := clip.Write(Content{Format: Image, Data: []byte("not a png")}); err == nil {
+		t.Error("Expected an error for an image that is not a PNG")
+	}
+	if err := clip.Write(Content{Format: Image, Data: pngSignature}); err != nil {
+		t.Errorf("Write() of a PNG returned an error: %v", err)
+	}
+	if _, err := clip.Read("application/pdf"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got: %v", err)
+	}
+}
+
+func TestPermissionDenied(t *testing.T) {
+	backend := newFakeBackend()
+	manager := permissions.NewMockManager()
+	manager.SetPermission(permissions.Clipboard, permissions.Denied)
+	clip := NewWithBackend(backend, manager, 0, false)
+
+	if _, err := clip.Read(Text); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied on read, got: %v", err)
+	}
+	if err := clip.Write(Content{Format: Text, Data: []byte("x")}); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected ErrPermissionDenied on write, got: %v", err)
+	}
+	if backend.writes != 0 {
+		t.Error("Backend should not be written without permission")
+	}
+
+	manager.SetPermission(permissions.Clipboard, permissions.Granted)
+	if err := clip.Write(Content{Format: Text, Data: []byte("x")}); err != nil {
+		t.Errorf("Write() with permission returned an error: %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	backend := newFakeBackend()
+	backend.set(Text, []byte("already there"))
+	clip := NewWithBackend(backend, nil, 32, false)
+
+	changes := make(chan Content, 10)
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- clip.Watch(stop, 5*time.Millisecond, func(c Content) { changes <- c })
+	}()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case c := <-changes:
+			if string(c.Data) != want {
+				t.Errorf("Change = %q, want %q", c.Data, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("No change reported for %q", want)
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	// Content written through the clipboard is not echoed back
+	clip.Write(Content{Format: Text, Data: []byte("from technician")})
+	time.Sleep(20 * time.Millisecond)
+
+	// Oversized content is skipped
+	backend.set(Text, make([]byte, 33))
+	time.Sleep(20 * time.Millisecond)
+
+	backend.set(Text, []byte("copied by user"))
+	expect("copied by user")
+
+	backend.set(Image, pngSignature)
+	expect(string(pngSignature))
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("Watch() returned an error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Unexpected extra changes: %d", len(changes))
+	}
+}
+
+func TestDetectBackend(t *testing.T) {
+	originalLookPath, originalGetenv := execLookPath, osGetenv
+	defer func() { execLookPath, osGetenv = originalLookPath, originalGetenv }()
+
+	tools := map[string]bool{}
+	env := map[string]string{}
+	execLookPath = func(file string) (string, error) {
+		if tools[file] {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+	osGetenv = func(key string) string { return env[key] }
+
+	if _, err := detectBackend("linux"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported without tools, got: %v", err)
+	}
+
+	tools["xclip"], tools["wl-paste"] = true, true
+	if b, _ := detectBackend("linux"); b.Name() != "xclip" {
+		t.Errorf("Expected xclip on X11, got %s", b.Name())
+	}
+	env["WAYLAND_DISPLAY"] = "wayland-0"
+	if b, _ := detectBackend("linux"); b.Name() != "wl-clipboard" {
+		t.Errorf("Expected wl-clipboard on Wayland, got %s", b.Name())
+	}
+
+	for goos, want := range map[string]string{"darwin": "pbcopy", "windows": "powershell"} {
+		if b, err := detectBackend(goos); err != nil || b.Name() != want {
+			t.Errorf("detectBackend(%s) = %v, %v; want %s", goos, b, err, want)
+		}
+	}
+	if _, err := detectBackend("plan9"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported for plan9, got: %v", err)
+	}
+}
+
+func TestCommandBackend(t *testing.T) {
+	backend := windowsBackend()
+
+	var ran [][]string
+	var stdin []byte
+	backend.output = func(args []string) ([]byte, error) {
+		ran = append(ran, args)
+		if args[3] == backend.read[Image].args[3] {
+			return []byte("iVBORw0KGgo=\r\n"), nil
+		}
+		return []byte("hello\r\n"), nil
+	}
+	backend.input = func(args []string, data []byte) error {
+		ran = append(ran, args)
+		stdin = data
+		return nil
+	}
+
+	text, err := backend.Read(Text)
+	if err != nil || string(text) != "hello" {
+		t.Errorf("Read(Text) = %q, %v", text, err)
+	}
+	image, err := backend.Read(Image)
+	if err != nil || !reflect.DeepEqual(image, pngSignature) {
+		t.Errorf("Read(Image) = %v, %v", image, err)
+	}
+	if err := backend.Write(Image, pngSignature); err != nil || string(stdin) != "iVBORw0KGgo=" {
+		t.Errorf("Write(Image) sent %q, %v", stdin, err)
+	}
+	if len(ran) != 3 {
+		t.Errorf("Expected 3 commands, ran %d", len(ran))
+	}
+}
+
+func TestDecodeAppleScriptData(t *testing.T) {
+	data, err := decodeAppleScriptData([]byte("«data PNGf89504E470D0A1A0A»\n"))
+	if err != nil || !reflect.DeepEqual(data, pngSignature) {
+		t.Errorf("decodeAppleScriptData() = %v, %v", data, err)
+	}
+	if _, err := decodeAppleScriptData([]byte("«data TIFFxyz»")); err == nil {
+		t.Error("Expected an error for non-PNG data")
+	}
+}
@@ -0,0 +1,356 @@
+package command
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/audit"
+	"github.com/adamrobbie/go-support/pkg/client"
+)
+
+// Message types used by remote command execution
+const (
+	MessageTypeExec        = "exec"        // Server asks the agent to run a command
+	MessageTypeExecCancel  = "execCancel"  // Server cancels a running command
+	MessageTypeExecStarted = "execStarted" // Agent started a command
+	MessageTypeExecOutput  = "execOutput"  // Agent sends a chunk of output
+	MessageTypeExecExit    = "execExit"    // Agent reports that a command finished
+)
+
+const (
+	// DefaultTimeout is the default limit for how long a command may run
+	DefaultTimeout = 5 * time.Minute
+	// DefaultMaxOutput is the default limit for the output streamed per command in bytes
+	DefaultMaxOutput = 1 << 20
+	// chunkSize is the largest output chunk sent in one message
+	chunkSize = 4096
+	// waitDelay is how long to wait for output after a command is killed
+	waitDelay = 2 * time.Second
+)
+
+var (
+	// ErrNotAllowed is returned for commands that are not on the allowlist
+	ErrNotAllowed = errors.New("command is not allowed")
+	// ErrAlreadyRunning is returned when an exec ID is reused while its command runs
+	ErrAlreadyRunning = errors.New("exec ID already in use")
+)
+
+// Options configures command execution
+type Options struct {
+	Allowed   []string      // Commands that may be run, by name or absolute path
+	AllowAny  bool          // Whether any command may be run
+	Timeout   time.Duration // Default and maximum run time (DefaultTimeout if zero)
+	MaxOutput int           // Output streamed per command (DefaultMaxOutput if zero)
+	Audit     *audit.Logger // Audit trail for every request and result
+	Verbose   bool
+}
+
+// Request asks the agent to run a command. The command is run directly, not through a shell.
+type Request struct {
+	Type     string   `json:"type"`
+	ExecID   string   `json:"execId"`
+	Command  string   `json:"command"`
+	Args     []string `json:"args,omitempty"`
+	Dir      string   `json:"dir,omitempty"`
+	Timeout  int      `json:"timeout,omitempty"` // Seconds, capped at the configured timeout
+	ViewerID string   `json:"viewerId,omitempty"`
+}
+
+// Started reports that a command started
+type Started struct {
+	Type   string `json:"type"`
+	ExecID string `json:"execId"`
+	PID    int    `json:"pid"`
+}
+
+// Output is a chunk of a command's stdout or stderr
+type Output struct {
+	Type   string `json:"type"`
+	ExecID string `json:"execId"`
+	Stream string `json:"stream"` // "stdout" or "stderr"
+	Seq    int    `json:"seq"`    // Order of the chunk across both streams
+	Data   string `json:"data"`   // Base64-encoded output bytes
+}
+
+// Exit reports how a command finished
+type Exit struct {
+	Type      string `json:"type"`
+	ExecID    string `json:"execId"`
+	ExitCode  int    `json:"exitCode"` // -1 if the command did not run or was killed
+	Error     string `json:"error,omitempty"`
+	Cancelled bool   `json:"cancelled,omitempty"`
+	TimedOut  bool   `json:"timedOut,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"` // Output beyond the limit was dropped
+	Duration  int64  `json:"durationMs"`
+}
+
+// Transport is the part of the WebSocket client used to run commands
+type Transport interface {
+	client.Transport
+	SendJSONWithPriority(message interface{}, priority client.Priority) error
+}
+
+// Runner runs commands requested by the server and streams their output
+type Runner struct {
+	transport Transport
+	allowed   map[string]bool
+	allowAny  bool
+	timeout   time.Duration
+	maxOutput int
+	audit     *audit.Logger
+	running   map[string]context.CancelFunc
+	verbose   bool
+	mu        sync.Mutex
+}
+
+// NewRunner creates a runner and registers its handlers on the transport
+func NewRunner(transport Transport, opts Options) *Runner {
+	r := &Runner{
+		transport: transport,
+		allowed:   make(map[string]bool),
+		allowAny:  opts.AllowAny,
+		timeout:   opts.Timeout,
+		maxOutput: opts.MaxOutput,
+		audit:     opts.Audit,
+		running:   make(map[string]context.CancelFunc),
+		verbose:   opts.Verbose,
+	}
+	if r.timeout <= 0 {
+		r.timeout = DefaultTimeout
+	}
+	if r.maxOutput <= 0 {
+		r.maxOutput = DefaultMaxOutput
+	}
+	for _, name := range opts.Allowed {
+		r.allowed[name] = true
+	}
+
+	transport.RegisterHandler(MessageTypeExec, r.handleExec)
+	transport.RegisterHandler(MessageTypeExecCancel, r.handleCancel)
+
+	return r
+}
+
+// Allowed reports whether a command may be run. Allowlist entries match the
+// command exactly, so "ping" does not allow "/tmp/ping".
+func (r *Runner) Allowed(command string) bool {
+	return r.allowAny || r.allowed[command]
+}
+
+// Close cancels every running command
+func (r *Runner) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, cancel := range r.running {
+		cancel()
+		delete(r.running, id)
+	}
+}
+
+// handleExec validates a request and starts the command
+func (r *Runner) handleExec(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse exec request: %w", err)
+	}
+	if req.ExecID == "" || req.Command == "" {
+		return fmt.Errorf("exec request needs an execId and a command")
+	}
+
+	r.audit.Record("exec.requested", req.ViewerID, map[string]interface{}{
+		"execId":  req.ExecID,
+		"command": req.Command,
+		"args":    req.Args,
+		"dir":     req.Dir,
+	})
+
+	if !r.Allowed(req.Command) {
+		err := fmt.Errorf("%w: %s", ErrNotAllowed, req.Command)
+		r.finish(req, Exit{ExitCode: -1, Error: err.Error()})
+		return err
+	}
+
+	timeout := r.timeout
+	if req.Timeout > 0 && time.Duration(req.Timeout)*time.Second < timeout {
+		timeout = time.Duration(req.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	r.mu.Lock()
+	if _, exists := r.running[req.ExecID]; exists {
+		r.mu.Unlock()
+		cancel()
+		return fmt.Errorf("%w: %s", ErrAlreadyRunning, req.ExecID)
+	}
+	r.running[req.ExecID] = cancel
+	r.mu.Unlock()
+
+	go r.run(ctx, cancel, req)
+	return nil
+}
+
+// handleCancel cancels a running command
+func (r *Runner) handleCancel(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse exec cancel request: %w", err)
+	}
+
+	r.mu.Lock()
+	cancel, ok := r.running[req.ExecID]
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no running command with execId %s", req.ExecID)
+	}
+
+	r.audit.Record("exec.cancelled", req.ViewerID, map[string]interface{}{"execId": req.ExecID})
+	cancel()
+	return nil
+}
+
+// run runs a command, streams its output and reports its exit
+func (r *Runner) run(ctx context.Context, cancel context.CancelFunc, req Request) {
+	defer func() {
+		cancel()
+		r.mu.Lock()
+		delete(r.running, req.ExecID)
+		r.mu.Unlock()
+	}()
+
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, req.Command, req.Args...)
+	cmd.Dir = req.Dir
+	cmd.WaitDelay = waitDelay
+
+	stream := &outputStream{runner: r, execID: req.ExecID, remaining: r.maxOutput}
+	cmd.Stdout = streamWriter{stream, "stdout"}
+	cmd.Stderr = streamWriter{stream, "stderr"}
+
+	if err := cmd.Start(); err != nil {
+		r.finish(req, contextExit(ctx, Exit{ExitCode: -1, Error: err.Error()}))
+		return
+	}
+
+	r.audit.Record("exec.started", req.ViewerID, map[string]interface{}{
+		"execId": req.ExecID,
+		"pid":    cmd.Process.Pid,
+	})
+	if err := r.transport.SendJSON(Started{Type: MessageTypeExecStarted, ExecID: req.ExecID, PID: cmd.Process.Pid}); err != nil {
+		log.Printf("Failed to send exec started: %v", err)
+	}
+
+	waitErr := cmd.Wait()
+	exit := Exit{
+		ExitCode:  cmd.ProcessState.ExitCode(),
+		Truncated: stream.truncated,
+		Duration:  time.Since(start).Milliseconds(),
+	}
+	if waitErr != nil && exit.ExitCode < 0 {
+		exit.Error = waitErr.Error()
+	}
+
+	r.finish(req, contextExit(ctx, exit))
+}
+
+// contextExit marks an exit as cancelled or timed out if the command's context ended
+func contextExit(ctx context.Context, exit Exit) Exit {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		exit.TimedOut = true
+		exit.Error = "command timed out"
+	case errors.Is(ctx.Err(), context.Canceled):
+		exit.Cancelled = true
+		exit.Error = "command cancelled"
+	}
+	return exit
+}
+
+// finish sends the exit message and records it in the audit trail
+func (r *Runner) finish(req Request, exit Exit) {
+	exit.Type = MessageTypeExecExit
+	exit.ExecID = req.ExecID
+
+	r.audit.Record("exec.exited", req.ViewerID, map[string]interface{}{
+		"execId":     req.ExecID,
+		"command":    req.Command,
+		"exitCode":   exit.ExitCode,
+		"error":      exit.Error,
+		"truncated":  exit.Truncated,
+		"durationMs": exit.Duration,
+	})
+
+	if r.verbose {
+		log.Printf("DEBUG: Command %s (%s) exited with code %d", req.ExecID, req.Command, exit.ExitCode)
+	}
+
+	if err := r.transport.SendJSON(exit); err != nil {
+		log.Printf("Failed to send exec exit: %v", err)
+	}
+}
+
+// outputStream sends output chunks from both streams of one command
+type outputStream struct {
+	runner    *Runner
+	execID    string
+	seq       int
+	remaining int
+	truncated bool
+	mu        sync.Mutex
+}
+
+// streamWriter sends what a command writes to one of its streams. Output
+// beyond the limit is dropped rather than failing the write, so the command
+// does not block or die on a broken pipe.
+type streamWriter struct {
+	stream *outputStream
+	name   string
+}
+
+// Write sends p in chunks of at most chunkSize bytes
+func (w streamWriter) Write(p []byte) (int, error) {
+	for offset := 0; offset < len(p); offset += chunkSize {
+		end := offset + chunkSize
+		if end > len(p) {
+			end = len(p)
+		}
+		w.stream.send(w.name, p[offset:end])
+	}
+	return len(p), nil
+}
+
+// send sends a chunk of output, truncating it at the output limit. Output is
+// sent with the bulk priority so a chatty command cannot hold up replies and
+// input, which are sent with the control priority.
+func (s *outputStream) send(name string, chunk []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.remaining <= 0 {
+		s.truncated = true
+		return
+	}
+	if len(chunk) > s.remaining {
+		chunk = chunk[:s.remaining]
+		s.truncated = true
+	}
+	s.remaining -= len(chunk)
+
+	if err := s.runner.transport.SendJSONWithPriority(Output{
+		Type:   MessageTypeExecOutput,
+		ExecID: s.execID,
+		Stream: name,
+		Seq:    s.seq,
+		Data:   base64.StdEncoding.EncodeToString(chunk),
+	}, client.PriorityBulk); err != nil {
+		log.Printf("Failed to send exec output: %v", err)
+	}
+	s.seq++
+}
@@ -0,0 +1,165 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/adamrobbie/go-support/pkg/audit"
+	"github.com/adamrobbie/go-support/pkg/client"
+	"github.com/adamrobbie/go-support/pkg/client/clienttest"
+)
+
+// output joins the decoded output of one stream sent through a transport, checking that chunks are in order
+func output(t *testing.T, transport *clienttest.Transport, stream string) string {
+	t.Helper()
+	var out strings.Builder
+	seq := 0.0
+	priorities := transport.Priorities()
+	for i, message := range transport.Sent() {
+		if message["type"] != MessageTypeExecOutput {
+			continue
+		}
+		if priorities[i] != client.PriorityBulk {
+			t.Errorf("Expected output to be sent with the bulk priority, got %v", priorities[i])
+		}
+		if message["seq"].(float64) != seq {
+			t.Errorf("Expected output seq %v, got %v", seq, message["seq"])
+		}
+		seq++
+		if message["stream"] != stream {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(message["data"].(string))
+		if err != nil {
+			t.Fatalf("Invalid output data: %v", err)
+		}
+		out.Write(data)
+	}
+	return out.String()
+}
+
+func skipWithoutShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Tests use sh")
+	}
+}
+
+func TestExecStreamsOutput(t *testing.T) {
+	skipWithoutShell(t)
+
+	var trail bytes.Buffer
+	transport := clienttest.New()
+	NewRunner(transport, Options{Allowed: []string{"sh"}, Audit: audit.New(&trail)})
+
+	if err := transport.Deliver(t, `{"type":"exec","execId":"e1","command":"sh","args":["-c","echo hello; echo oops >&2; exit 3"],"viewerId":"v1"}`); err != nil {
+		t.Fatalf("exec handler returned an error: %v", err)
+	}
+
+	exit := transport.Next(t, MessageTypeExecExit)
+	if exit["execId"] != "e1" || exit["exitCode"].(float64) != 3 || exit["error"] != nil {
+		t.Errorf("Unexpected exit: %v", exit)
+	}
+	if out := output(t, transport, "stdout"); out != "hello\n" {
+		t.Errorf("Expected stdout %q, got %q", "hello\n", out)
+	}
+	if out := output(t, transport, "stderr"); out != "oops\n" {
+		t.Errorf("Expected stderr %q, got %q", "oops\n", out)
+	}
+	if transport.Sent()[0]["type"] != MessageTypeExecStarted {
+		t.Errorf("Expected execStarted first, got %v", transport.Sent()[0]["type"])
+	}
+
+	var actions []string
+	scanner := bufio.NewScanner(&trail)
+	for scanner.Scan() {
+		var event audit.Event
+		json.Unmarshal(scanner.Bytes(), &event)
+		if event.Actor != "v1" {
+			t.Errorf("Expected actor v1, got %q", event.Actor)
+		}
+		actions = append(actions, event.Action)
+	}
+	if strings.Join(actions, ",") != "exec.requested,exec.started,exec.exited" {
+		t.Errorf("Unexpected audit trail: %v", actions)
+	}
+}
+
+func TestExecAllowlist(t *testing.T) {
+	transport := clienttest.New()
+	runner := NewRunner(transport, Options{Allowed: []string{"echo"}})
+
+	if !runner.Allowed("echo") || runner.Allowed("/bin/echo") || runner.Allowed("rm") {
+		t.Error("Allowlist should only match exact commands")
+	}
+
+	err := transport.Deliver(t, `{"type":"exec","execId":"e1","command":"rm","args":["-rf","/"]}`)
+	if !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("Expected ErrNotAllowed, got: %v", err)
+	}
+	exit := transport.Next(t, MessageTypeExecExit)
+	if exit["exitCode"].(float64) != -1 || !strings.Contains(exit["error"].(string), "not allowed") {
+		t.Errorf("Unexpected exit for a rejected command: %v", exit)
+	}
+
+	if err := transport.Deliver(t, `{"type":"exec","execId":"e2"}`); err == nil {
+		t.Error("Expected an error for a request without a command")
+	}
+
+	anyRunner := NewRunner(clienttest.New(), Options{AllowAny: true})
+	if !anyRunner.Allowed("/usr/bin/anything") {
+		t.Error("AllowAny should allow any command")
+	}
+}
+
+func TestExecCancelAndTimeout(t *testing.T) {
+	skipWithoutShell(t)
+
+	transport := clienttest.New()
+	runner := NewRunner(transport, Options{Allowed: []string{"sleep"}})
+	defer runner.Close()
+
+	if err := transport.Deliver(t, `{"type":"exec","execId":"e1","command":"sleep","args":["30"]}`); err != nil {
+		t.Fatalf("exec handler returned an error: %v", err)
+	}
+	if err := transport.Deliver(t, `{"type":"exec","execId":"e1","command":"sleep","args":["30"]}`); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("Expected ErrAlreadyRunning, got: %v", err)
+	}
+	if err := transport.Deliver(t, `{"type":"execCancel","execId":"e1"}`); err != nil {
+		t.Fatalf("execCancel handler returned an error: %v", err)
+	}
+	if exit := transport.Next(t, MessageTypeExecExit); exit["cancelled"] != true {
+		t.Errorf("Expected a cancelled exit, got %v", exit)
+	}
+	if err := transport.Deliver(t, `{"type":"execCancel","execId":"e9"}`); err == nil {
+		t.Error("Expected an error cancelling an unknown command")
+	}
+
+	if err := transport.Deliver(t, `{"type":"exec","execId":"e2","command":"sleep","args":["30"],"timeout":1}`); err != nil {
+		t.Fatalf("exec handler returned an error: %v", err)
+	}
+	if exit := transport.Next(t, MessageTypeExecExit); exit["timedOut"] != true {
+		t.Errorf("Expected a timed out exit, got %v", exit)
+	}
+}
+
+func TestExecTruncatesOutput(t *testing.T) {
+	skipWithoutShell(t)
+
+	transport := clienttest.New()
+	NewRunner(transport, Options{Allowed: []string{"sh"}, MaxOutput: 10})
+
+	transport.Deliver(t, `{"type":"exec","execId":"e1","command":"sh","args":["-c","echo 0123456789abcdef"]}`)
+	exit := transport.Next(t, MessageTypeExecExit)
+	if exit["truncated"] != true || exit["exitCode"].(float64) != 0 {
+		t.Errorf("Expected a truncated successful exit, got %v", exit)
+	}
+	if out := output(t, transport, "stdout"); out != "0123456789" {
+		t.Errorf("Expected output truncated to 10 bytes, got %q", out)
+	}
+}
@@ -0,0 +1,388 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const (
+	// DefaultTopProcesses is the number of processes included in a report
+	DefaultTopProcesses = 10
+	// cpuSampleInterval is how long CPU usage is measured for
+	cpuSampleInterval = 200 * time.Millisecond
+)
+
+// Report is a snapshot of the state of the machine
+type Report struct {
+	CollectedAt time.Time   `json:"collectedAt"`
+	Host        Host        `json:"host"`
+	CPU         CPU         `json:"cpu"`
+	Memory      Memory      `json:"memory"`
+	Disks       []Disk      `json:"disks"`
+	Network     []Interface `json:"network"`
+	Processes   []Process   `json:"processes"`        // Top processes by CPU usage
+	Errors      []string    `json:"errors,omitempty"` // Sections that could not be collected
+}
+
+// Host describes the operating system
+type Host struct {
+	Hostname        string `json:"hostname"`
+	OS              string `json:"os"`
+	Platform        string `json:"platform"`
+	PlatformVersion string `json:"platformVersion"`
+	KernelVersion   string `json:"kernelVersion"`
+	Arch            string `json:"arch"`
+	Uptime          uint64 `json:"uptime"` // Seconds
+}
+
+// CPU describes the processor and its current load
+type CPU struct {
+	Model        string  `json:"model"`
+	Cores        int     `json:"cores"`
+	LogicalCores int     `json:"logicalCores"`
+	UsagePercent float64 `json:"usagePercent"`
+}
+
+// Memory describes physical memory and swap in bytes
+type Memory struct {
+	Total       uint64  `json:"total"`
+	Used        uint64  `json:"used"`
+	Available   uint64  `json:"available"`
+	UsedPercent float64 `json:"usedPercent"`
+	SwapTotal   uint64  `json:"swapTotal"`
+	SwapUsed    uint64  `json:"swapUsed"`
+}
+
+// Disk describes a mounted file system in bytes
+type Disk struct {
+	Mountpoint  string  `json:"mountpoint"`
+	Device      string  `json:"device"`
+	Fstype      string  `json:"fstype"`
+	Total       uint64  `json:"total"`
+	Free        uint64  `json:"free"`
+	UsedPercent float64 `json:"usedPercent"`
+}
+
+// Interface describes a network interface
+type Interface struct {
+	Name      string   `json:"name"`
+	MAC       string   `json:"mac,omitempty"`
+	Addresses []string `json:"addresses"`
+	Up        bool     `json:"up"`
+	Loopback  bool     `json:"loopback,omitempty"`
+}
+
+// Process describes a running process
+type Process struct {
+	PID           int32   `json:"pid"`
+	PPID          int32   `json:"ppid,omitempty"`
+	Name          string  `json:"name"`
+	User          string  `json:"user,omitempty"`
+	CPUPercent    float64 `json:"cpuPercent"`
+	MemoryRSS     uint64  `json:"memoryRss"` // Bytes
+	MemoryPercent float32 `json:"memoryPercent"`
+	Status        string  `json:"status,omitempty"`
+}
+
+// Summary is the compact part of a report sent with the client info
+type Summary struct {
+	Hostname        string `json:"hostname"`
+	OS              string `json:"os"`
+	Platform        string `json:"platform"`
+	PlatformVersion string `json:"platformVersion"`
+	Arch            string `json:"arch"`
+	CPUModel        string `json:"cpuModel"`
+	LogicalCores    int    `json:"logicalCores"`
+	MemoryTotal     uint64 `json:"memoryTotal"`
+}
+
+// Collect builds a full report. Sections that fail are listed in Errors
+// rather than failing the whole report.
+func Collect(ctx context.Context, topProcesses int) *Report {
+	if topProcesses <= 0 {
+		topProcesses = DefaultTopProcesses
+	}
+
+	report := &Report{CollectedAt: time.Now().UTC()}
+	fail := func(section string, err error) {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", section, err))
+	}
+
+	var err error
+	if report.Host, err = hostInfo(ctx); err != nil {
+		fail("host", err)
+	}
+	if report.CPU, err = cpuInfo(ctx); err != nil {
+		fail("cpu", err)
+	}
+	if percent, err := cpu.PercentWithContext(ctx, cpuSampleInterval, false); err != nil {
+		fail("cpu usage", err)
+	} else if len(percent) > 0 {
+		report.CPU.UsagePercent = percent[0]
+	}
+	if report.Memory, err = memoryInfo(ctx); err != nil {
+		fail("memory", err)
+	}
+	if report.Disks, err = disks(ctx); err != nil {
+		fail("disks", err)
+	}
+	if report.Network, err = interfaces(ctx); err != nil {
+		fail("network", err)
+	}
+	if processes, err := Processes(ctx); err != nil {
+		fail("processes", err)
+	} else {
+		report.Processes = Top(processes, topProcesses)
+	}
+
+	return report
+}
+
+// CollectSummary builds the compact summary without the slower sections
+func CollectSummary(ctx context.Context) (Summary, error) {
+	var report Report
+	var err error
+	if report.Host, err = hostInfo(ctx); err != nil {
+		return Summary{}, err
+	}
+	if report.CPU, err = cpuInfo(ctx); err != nil {
+		return Summary{}, err
+	}
+	if report.Memory, err = memoryInfo(ctx); err != nil {
+		return Summary{}, err
+	}
+	return report.Summary(), nil
+}
+
+// Processes lists the running processes. Processes that exit while they are
+// being read, or whose details are not readable, are listed with what is known.
+func Processes(ctx context.Context) ([]Process, error) {
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	result := make([]Process, 0, len(procs))
+	for _, p := range procs {
+		info := Process{PID: p.Pid}
+		info.Name, _ = p.NameWithContext(ctx)
+		info.PPID, _ = p.PpidWithContext(ctx)
+		info.User, _ = p.UsernameWithContext(ctx)
+		info.CPUPercent, _ = p.CPUPercentWithContext(ctx)
+		info.MemoryPercent, _ = p.MemoryPercentWithContext(ctx)
+		if memory, err := p.MemoryInfoWithContext(ctx); err == nil {
+			info.MemoryRSS = memory.RSS
+		}
+		if status, err := p.StatusWithContext(ctx); err == nil && len(status) > 0 {
+			info.Status = status[0]
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// Top returns the n processes using the most CPU, then the most memory
+func Top(processes []Process, n int) []Process {
+	sorted := append([]Process(nil), processes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].CPUPercent != sorted[j].CPUPercent {
+			return sorted[i].CPUPercent > sorted[j].CPUPercent
+		}
+		return sorted[i].MemoryRSS > sorted[j].MemoryRSS
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// hostInfo reads the operating system details
+func hostInfo(ctx context.Context) (Host, error) {
+	info, err := host.InfoWithContext(ctx)
+	if err != nil {
+		return Host{OS: runtime.GOOS, Arch: runtime.GOARCH}, fmt.Errorf("failed to read host info: %w", err)
+	}
+	arch := info.KernelArch
+	if arch == "" {
+		arch = runtime.GOARCH
+	}
+	return Host{
+		Hostname:        info.Hostname,
+		OS:              info.OS,
+		Platform:        info.Platform,
+		PlatformVersion: info.PlatformVersion,
+		KernelVersion:   info.KernelVersion,
+		Arch:            arch,
+		Uptime:          info.Uptime,
+	}, nil
+}
+
+// cpuInfo reads the processor model and core counts
+func cpuInfo(ctx context.Context) (CPU, error) {
+	result := CPU{LogicalCores: runtime.NumCPU()}
+
+	infos, err := cpu.InfoWithContext(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to read CPU info: %w", err)
+	}
+	if len(infos) > 0 {
+		result.Model = strings.TrimSpace(infos[0].ModelName)
+	}
+	if cores, err := cpu.CountsWithContext(ctx, false); err == nil {
+		result.Cores = cores
+	}
+	if logical, err := cpu.CountsWithContext(ctx, true); err == nil && logical > 0 {
+		result.LogicalCores = logical
+	}
+	return result, nil
+}
+
+// memoryInfo reads physical memory and swap usage
+func memoryInfo(ctx context.Context) (Memory, error) {
+	virtual, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return Memory{}, fmt.Errorf("failed to read memory: %w", err)
+	}
+	result := Memory{
+		Total:       virtual.Total,
+		Used:        virtual.Used,
+		Available:   virtual.Available,
+		UsedPercent: virtual.UsedPercent,
+	}
+	if swap, err := mem.SwapMemoryWithContext(ctx); err == nil {
+		result.SwapTotal = swap.Total
+		result.SwapUsed = swap.Used
+	}
+	return result, nil
+}
+
+// disks reads the usage of physical file systems
+func disks(ctx context.Context) ([]Disk, error) {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	var result []Disk
+	seen := make(map[string]bool)
+	for _, partition := range partitions {
+		if seen[partition.Mountpoint] {
+			continue
+		}
+		seen[partition.Mountpoint] = true
+
+		usage, err := disk.UsageWithContext(ctx, partition.Mountpoint)
+		if err != nil || usage.Total == 0 {
+			continue
+		}
+		result = append(result, Disk{
+			Mountpoint:  partition.Mountpoint,
+			Device:      partition.Device,
+			Fstype:      partition.Fstype,
+			Total:       usage.Total,
+			Free:        usage.Free,
+			UsedPercent: usage.UsedPercent,
+		})
+	}
+	return result, nil
+}
+
+// interfaces reads the network interfaces and their addresses
+func interfaces(ctx context.Context) ([]Interface, error) {
+	stats, err := net.InterfacesWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	result := make([]Interface, 0, len(stats))
+	for _, stat := range stats {
+		iface := Interface{Name: stat.Name, MAC: stat.HardwareAddr, Addresses: []string{}}
+		for _, flag := range stat.Flags {
+			switch flag {
+			case "up":
+				iface.Up = true
+			case "loopback":
+				iface.Loopback = true
+			}
+		}
+		for _, addr := range stat.Addrs {
+			iface.Addresses = append(iface.Addresses, addr.Addr)
+		}
+		result = append(result, iface)
+	}
+	return result, nil
+}
+
+// Summary returns the compact summary of a report
+func (r *Report) Summary() Summary {
+	return Summary{
+		Hostname:        r.Host.Hostname,
+		OS:              r.Host.OS,
+		Platform:        r.Host.Platform,
+		PlatformVersion: r.Host.PlatformVersion,
+		Arch:            r.Host.Arch,
+		CPUModel:        r.CPU.Model,
+		LogicalCores:    r.CPU.LogicalCores,
+		MemoryTotal:     r.Memory.Total,
+	}
+}
+
+// Print writes the report in a human-readable form
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "Host:     %s (%s %s %s, kernel %s, %s)\n", r.Host.Hostname, r.Host.OS, r.Host.Platform, r.Host.PlatformVersion, r.Host.KernelVersion, r.Host.Arch)
+	fmt.Fprintf(w, "Uptime:   %s\n", time.Duration(r.Host.Uptime)*time.Second)
+	fmt.Fprintf(w, "CPU:      %s, %d cores (%d logical), %.1f%% used\n", r.CPU.Model, r.CPU.Cores, r.CPU.LogicalCores, r.CPU.UsagePercent)
+	fmt.Fprintf(w, "Memory:   %s of %s used (%.1f%%), swap %s of %s\n",
+		FormatBytes(r.Memory.Used), FormatBytes(r.Memory.Total), r.Memory.UsedPercent,
+		FormatBytes(r.Memory.SwapUsed), FormatBytes(r.Memory.SwapTotal))
+
+	fmt.Fprintln(w, "Disks:")
+	for _, d := range r.Disks {
+		fmt.Fprintf(w, "  %-20s %s free of %s (%.1f%% used, %s)\n", d.Mountpoint, FormatBytes(d.Free), FormatBytes(d.Total), d.UsedPercent, d.Fstype)
+	}
+
+	fmt.Fprintln(w, "Network:")
+	for _, iface := range r.Network {
+		state := "down"
+		if iface.Up {
+			state = "up"
+		}
+		fmt.Fprintf(w, "  %-20s %-4s %s\n", iface.Name, state, strings.Join(iface.Addresses, ", "))
+	}
+
+	fmt.Fprintln(w, "Top processes:")
+	fmt.Fprintf(w, "  %7s %6s %9s  %s\n", "PID", "CPU%", "MEMORY", "NAME")
+	for _, p := range r.Processes {
+		fmt.Fprintf(w, "  %7d %6.1f %9s  %s\n", p.PID, p.CPUPercent, FormatBytes(p.MemoryRSS), p.Name)
+	}
+
+	for _, err := range r.Errors {
+		fmt.Fprintf(w, "Error: %s\n", err)
+	}
+}
+
+// FormatBytes formats a byte count with a binary unit, e.g. "1.5 GiB"
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,102 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCollect(t *testing.T) {
+	report := Collect(context.Background(), 5)
+
+	if report.Host.OS == "" || report.Host.Arch == "" {
+		t.Errorf("Expected host details, got %+v", report.Host)
+	}
+	if report.CPU.LogicalCores == 0 {
+		t.Error("Expected at least one logical core")
+	}
+	if report.Memory.Total == 0 {
+		t.Error("Expected total memory to be reported")
+	}
+	if len(report.Processes) == 0 || len(report.Processes) > 5 {
+		t.Errorf("Expected 1 to 5 top processes, got %d", len(report.Processes))
+	}
+
+	// The test process itself is listed
+	processes, err := Processes(context.Background())
+	if err != nil {
+		t.Fatalf("Processes() returned an error: %v", err)
+	}
+	found := false
+	for _, p := range processes {
+		if int(p.PID) == os.Getpid() {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected the test process in the process list")
+	}
+
+	// The report is sent as JSON
+	data, err := json.Marshal(report)
+	if err != nil || !strings.Contains(string(data), `"processes"`) {
+		t.Errorf("Failed to encode the report: %v", err)
+	}
+
+	var out bytes.Buffer
+	report.Print(&out)
+	for _, section := range []string{"Host:", "CPU:", "Memory:", "Disks:", "Network:", "Top processes:"} {
+		if !strings.Contains(out.String(), section) {
+			t.Errorf("Printed report is missing %q", section)
+		}
+	}
+
+	summary := report.Summary()
+	if summary.OS != report.Host.OS || summary.MemoryTotal != report.Memory.Total {
+		t.Errorf("Summary does not match the report: %+v", summary)
+	}
+}
+
+func TestCollectSummary(t *testing.T) {
+	summary, err := CollectSummary(context.Background())
+	if err != nil {
+		t.Fatalf("CollectSummary() returned an error: %v", err)
+	}
+	if summary.OS == "" || summary.LogicalCores == 0 || summary.MemoryTotal == 0 {
+		t.Errorf("Incomplete summary: %+v", summary)
+	}
+}
+
+func TestTop(t *testing.T) {
+	processes := []Process{
+		{PID: 1, CPUPercent: 1, MemoryRSS: 100},
+		{PID: 2, CPUPercent: 50},
+		{PID: 3, CPUPercent: 1, MemoryRSS: 500},
+		{PID: 4},
+	}
+
+	top := Top(processes, 3)
+	if len(top) != 3 || top[0].PID != 2 || top[1].PID != 3 || top[2].PID != 1 {
+		t.Errorf("Unexpected order: %+v", top)
+	}
+	if processes[0].PID != 1 {
+		t.Error("Top() should not reorder its input")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[uint64]string{
+		0:       "0 B",
+		1023:    "1023 B",
+		1536:    "1.5 KiB",
+		8 << 30: "8.0 GiB",
+	}
+	for n, want := range tests {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package e2e
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MessageType is the WebSocket message type used for the key exchange
+const MessageType = "keyExchange"
+
+// protocolInfo binds derived keys to this protocol and version
+const protocolInfo = "go-support e2e v1"
+
+var (
+	// ErrNotReady is returned when sealing or opening before a key has been agreed
+	ErrNotReady = errors.New("end-to-end key exchange not complete")
+	// ErrUnknownEpoch is returned when a payload was sealed with a key that is no longer held
+	ErrUnknownEpoch = errors.New("unknown key epoch")
+	// ErrSessionMismatch is returned when a key exchange names another session
+	// while a key is established
+	ErrSessionMismatch = errors.New("key exchange is for another session")
+	// ErrUnauthenticated is returned when a key exchange after the first one is
+	// not authenticated with the current key
+	ErrUnauthenticated = errors.New("key exchange is not authenticated with the current key")
+)
+
+// KeyExchange is the message exchanged through the server to agree on a key
+type KeyExchange struct {
+	Type      string `json:"type"`
+	SessionID string `json:"sessionId"`
+	Epoch     uint32 `json:"epoch"`
+	PublicKey string `json:"publicKey"`     // Base64-encoded X25519 public key
+	MAC       string `json:"mac,omitempty"` // Base64-encoded HMAC with the current key, once one is established
+}
+
+// Sealed is an encrypted payload
+type Sealed struct {
+	Epoch      uint32 `json:"epoch"`
+	Nonce      string `json:"nonce"`      // Base64-encoded AEAD nonce
+	Ciphertext string `json:"ciphertext"` // Base64-encoded ciphertext and tag
+}
+
+// Channel holds the key agreement state for one end of an encrypted session.
+// Each epoch uses fresh ephemeral keys; the previous epoch is kept so that
+// payloads in flight during a rotation can still be opened. Once a key is
+// established, key exchanges must be authenticated with it, so only the first
+// exchange of a session has to be checked with its fingerprint or signature.
+type Channel struct {
+	sessionID     string
+	pending       *ecdh.PrivateKey // Our key for an offer not yet answered
+	pendingEpoch  uint32
+	epoch         uint32
+	current       cipher.AEAD
+	authKey       []byte // Authenticates key exchanges with the current key
+	previous      cipher.AEAD
+	previousEpoch uint32
+	fingerprint   string // Of the first exchange of the session
+	mu            sync.Mutex
+}
+
+// NewChannel creates a channel for a new session with a random session ID
+func NewChannel() (*Channel, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	return &Channel{sessionID: id}, nil
+}
+
+// newSessionID generates a random session ID
+func newSessionID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Reset drops the keys and starts a new session, for a new viewer
+func (c *Channel) Reset() error {
+	id, err := newSessionID()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessionID = id
+	c.pending, c.pendingEpoch = nil, 0
+	c.current, c.authKey, c.previous, c.epoch, c.previousEpoch = nil, nil, nil, 0, 0
+	c.fingerprint = ""
+	return nil
+}
+
+// Fingerprint returns a short digest of the session ID and the public keys of
+// the first key exchange, or "" before it. Both ends show the same value, so
+// users can compare it to make sure nobody sits between them.
+func (c *Channel) Fingerprint() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fingerprint
+}
+
+// SessionID returns the session identifier
+func (c *Channel) SessionID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionID
+}
+
+// Ready returns true once a key has been agreed
+func (c *Channel) Ready() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current != nil
+}
+
+// Epoch returns the epoch of the key currently used for sealing
+func (c *Channel) Epoch() uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.epoch
+}
+
+// Offer starts a key exchange for the next epoch. The returned message must be
+// delivered to the peer, whose answer is passed to Accept. The current key stays
+// in use until then, so Offer is also how keys are rotated.
+func (c *Channel) Offer() (*KeyExchange, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key pair: %w", err)
+	}
+
+	c.pending = private
+	c.pendingEpoch = c.epoch + 1
+
+	return c.exchange(c.pendingEpoch, private), nil
+}
+
+// exchange builds a key exchange message for our key, authenticated with the
+// current key if there is one. Must be called with the lock held.
+func (c *Channel) exchange(epoch uint32, private *ecdh.PrivateKey) *KeyExchange {
+	public := private.PublicKey().Bytes()
+	msg := &KeyExchange{
+		Type:      MessageType,
+		SessionID: c.sessionID,
+		Epoch:     epoch,
+		PublicKey: base64.StdEncoding.EncodeToString(public),
+	}
+	if c.authKey != nil {
+		msg.MAC = base64.StdEncoding.EncodeToString(c.exchangeMAC(epoch, public))
+	}
+	return msg
+}
+
+// exchangeMAC authenticates a key exchange with the current key. Must be
+// called with the lock held.
+func (c *Channel) exchangeMAC(epoch uint32, public []byte) []byte {
+	mac := hmac.New(sha256.New, c.authKey)
+	mac.Write([]byte(protocolInfo))
+	mac.Write([]byte{0})
+	mac.Write([]byte(c.sessionID))
+	mac.Write([]byte{0})
+	mac.Write(binary.BigEndian.AppendUint32(nil, epoch))
+	mac.Write(public)
+	return mac.Sum(nil)
+}
+
+// Accept processes a key exchange message from the peer. If it answers our
+// pending offer the new key is installed and nil is returned. If it is an offer
+// from the peer, the key is installed and the answer to send back is returned.
+// Once a key is established, the message must be for the same session and
+// authenticated with that key, so the relay cannot take over the session.
+func (c *Channel) Accept(msg KeyExchange) (*KeyExchange, error) {
+	raw, err := base64.StdEncoding.DecodeString(msg.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer public key encoding: %w", err)
+	}
+	peer, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer public key: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current != nil {
+		if msg.SessionID != c.sessionID {
+			return nil, fmt.Errorf("%w: %q", ErrSessionMismatch, msg.SessionID)
+		}
+		mac, err := base64.StdEncoding.DecodeString(msg.MAC)
+		if err != nil || !hmac.Equal(mac, c.exchangeMAC(msg.Epoch, raw)) {
+			return nil, ErrUnauthenticated
+		}
+	}
+
+	// Answer to our own offer
+	if c.pending != nil && msg.SessionID == c.sessionID && msg.Epoch == c.pendingEpoch {
+		if err := c.install(c.pending, peer, msg.Epoch); err != nil {
+			return nil, err
+		}
+		c.pending = nil
+		return nil, nil
+	}
+
+	// Offer from the peer: before the first key, adopt its session and epoch numbering
+	if msg.SessionID != c.sessionID {
+		if msg.SessionID == "" {
+			return nil, fmt.Errorf("key exchange is missing a session ID")
+		}
+		c.sessionID = msg.SessionID
+		c.current, c.previous, c.epoch = nil, nil, 0
+	}
+	if msg.Epoch <= c.epoch {
+		return nil, fmt.Errorf("key exchange epoch %d is not newer than %d", msg.Epoch, c.epoch)
+	}
+
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key pair: %w", err)
+	}
+	// The answer is authenticated with the key the peer still holds
+	answer := c.exchange(msg.Epoch, private)
+	if err := c.install(private, peer, msg.Epoch); err != nil {
+		return nil, err
+	}
+	c.pending = nil
+
+	return answer, nil
+}
+
+// install derives the AEAD for an epoch and makes it current. Must be called with the lock held.
+func (c *Channel) install(private *ecdh.PrivateKey, peer *ecdh.PublicKey, epoch uint32) error {
+	shared, err := private.ECDH(peer)
+	if err != nil {
+		return fmt.Errorf("key agreement failed: %w", err)
+	}
+
+	// Bind the key to both public keys in a role-independent order
+	ours, theirs := private.PublicKey().Bytes(), peer.Bytes()
+	if bytes.Compare(ours, theirs) > 0 {
+		ours, theirs = theirs, ours
+	}
+	info := make([]byte, 0, len(protocolInfo)+4+len(ours)+len(theirs))
+	info = append(info, protocolInfo...)
+	info = binary.BigEndian.AppendUint32(info, epoch)
+	info = append(info, ours...)
+	info = append(info, theirs...)
+
+	// The first 32 bytes encrypt payloads, the rest authenticate key exchanges
+	material := hkdfSHA256(shared, []byte(c.sessionID), info, 64)
+	key := material[:32]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("failed to create AEAD: %w", err)
+	}
+
+	if c.current == nil {
+		digest := sha256.Sum256(append(append([]byte(c.sessionID), ours...), theirs...))
+		c.fingerprint = formatFingerprint(digest[:10])
+	}
+	c.previous, c.previousEpoch = c.current, c.epoch
+	c.current = aead
+	c.authKey = material[32:]
+	c.epoch = epoch
+	return nil
+}
+
+// formatFingerprint formats a digest as groups of four hex digits
+func formatFingerprint(digest []byte) string {
+	encoded := hex.EncodeToString(digest)
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, " ")
+}
+
+// Seal encrypts a payload with the current key. The kind (such as the message
+// type) is authenticated so a ciphertext cannot be replayed as another kind.
+func (c *Channel) Seal(kind string, plaintext []byte) (*Sealed, error) {
+	c.mu.Lock()
+	aead, epoch, sessionID := c.current, c.epoch, c.sessionID
+	c.mu.Unlock()
+
+	if aead == nil {
+		return nil, ErrNotReady
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	ciphertext := aead.Seal(nil, nonce, plaintext, additionalData(sessionID, kind, epoch))
+	return &Sealed{
+		Epoch:      epoch,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	}, nil
+}
+
+// Open decrypts a payload sealed by the peer
+func (c *Channel) Open(kind string, sealed Sealed) ([]byte, error) {
+	c.mu.Lock()
+	sessionID := c.sessionID
+	var aead cipher.AEAD
+	switch {
+	case c.current == nil:
+		c.mu.Unlock()
+		return nil, ErrNotReady
+	case sealed.Epoch == c.epoch:
+		aead = c.current
+	case sealed.Epoch == c.previousEpoch && c.previous != nil:
+		aead = c.previous
+	}
+	c.mu.Unlock()
+
+	if aead == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownEpoch, sealed.Epoch)
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(sealed.Nonce)
+	if err != nil || len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext encoding: %w", err)
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData(sessionID, kind, sealed.Epoch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+	}
+	return plaintext, nil
+}
+
+// additionalData builds the authenticated data for a payload
+func additionalData(sessionID, kind string, epoch uint32) []byte {
+	ad := make([]byte, 0, len(sessionID)+len(kind)+6)
+	ad = append(ad, sessionID...)
+	ad = append(ad, 0)
+	ad = append(ad, kind...)
+	ad = append(ad, 0)
+	return binary.BigEndian.AppendUint32(ad, epoch)
+}
+
+// hkdfSHA256 implements HKDF (RFC 5869) extract-and-expand with SHA-256
+func hkdfSHA256(secret, salt, info []byte, length int) []byte {
+	extractor := hmac.New(sha256.New, salt)
+	extractor.Write(secret)
+	prk := extractor.Sum(nil)
+
+	var out, block []byte
+	for counter := byte(1); len(out) < length; counter++ {
+		expander := hmac.New(sha256.New, prk)
+		expander.Write(block)
+		expander.Write(info)
+		expander.Write([]byte{counter})
+		block = expander.Sum(nil)
+		out = append(out, block...)
+	}
+	return out[:length]
+}
@@ -0,0 +1,195 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// pair performs a key exchange between an agent and a viewer channel
+func pair(t *testing.T) (*Channel, *Channel) {
+	t.Helper()
+	agent, err := NewChannel()
+	if err != nil {
+		t.Fatalf("NewChannel() returned an error: %v", err)
+	}
+	viewer, err := NewChannel()
+	if err != nil {
+		t.Fatalf("NewChannel() returned an error: %v", err)
+	}
+
+	offer, err := agent.Offer()
+	if err != nil {
+		t.Fatalf("Offer() returned an error: %v", err)
+	}
+	answer, err := viewer.Accept(*offer)
+	if err != nil || answer == nil {
+		t.Fatalf("viewer Accept() = %v, %v; want an answer", answer, err)
+	}
+	reply, err := agent.Accept(*answer)
+	if err != nil || reply != nil {
+		t.Fatalf("agent Accept() = %v, %v; want no reply", reply, err)
+	}
+	return agent, viewer
+}
+
+func TestSealAndOpen(t *testing.T) {
+	agent, viewer := pair(t)
+
+	if agent.SessionID() != viewer.SessionID() {
+		t.Fatalf("Expected both ends to share a session ID")
+	}
+
+	frame := []byte("jpeg frame bytes")
+	sealed, err := agent.Seal("videoFrame", frame)
+	if err != nil {
+		t.Fatalf("Seal() returned an error: %v", err)
+	}
+	if bytes.Contains([]byte(sealed.Ciphertext), frame) {
+		t.Error("Ciphertext should not contain the plaintext")
+	}
+
+	opened, err := viewer.Open("videoFrame", *sealed)
+	if err != nil {
+		t.Fatalf("Open() returned an error: %v", err)
+	}
+	if !bytes.Equal(opened, frame) {
+		t.Errorf("Open() = %q, want %q", opened, frame)
+	}
+
+	// The kind is authenticated
+	if _, err := viewer.Open("screenshot", *sealed); err == nil {
+		t.Error("Expected Open() to fail for a different kind")
+	}
+}
+
+func TestNotReady(t *testing.T) {
+	channel, _ := NewChannel()
+	if channel.Ready() {
+		t.Error("New channel should not be ready")
+	}
+	if _, err := channel.Seal("videoFrame", []byte("x")); !errors.Is(err, ErrNotReady) {
+		t.Errorf("Expected ErrNotReady, got: %v", err)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	agent, viewer := pair(t)
+
+	before, _ := agent.Seal("videoFrame", []byte("old"))
+
+	offer, _ := agent.Offer()
+	// Until the viewer answers, the agent keeps sealing with the old key
+	if agent.Epoch() != 1 {
+		t.Errorf("Expected epoch 1 before the answer, got %d", agent.Epoch())
+	}
+	answer, err := viewer.Accept(*offer)
+	if err != nil {
+		t.Fatalf("Accept() returned an error: %v", err)
+	}
+	if _, err := agent.Accept(*answer); err != nil {
+		t.Fatalf("Accept() returned an error: %v", err)
+	}
+	if agent.Epoch() != 2 || viewer.Epoch() != 2 {
+		t.Errorf("Expected both ends at epoch 2, got %d and %d", agent.Epoch(), viewer.Epoch())
+	}
+
+	after, _ := agent.Seal("videoFrame", []byte("new"))
+	if got, err := viewer.Open("videoFrame", *after); err != nil || string(got) != "new" {
+		t.Errorf("Open() after rotation = %q, %v", got, err)
+	}
+
+	// A frame sealed with the previous key is still accepted during the rotation
+	if got, err := viewer.Open("videoFrame", *before); err != nil || string(got) != "old" {
+		t.Errorf("Open() of previous epoch = %q, %v", got, err)
+	}
+
+	// Replayed offers for old epochs are refused
+	if _, err := viewer.Accept(*offer); err == nil {
+		t.Error("Expected Accept() to reject a stale epoch")
+	}
+}
+
+func TestAcceptInvalidKey(t *testing.T) {
+	channel, _ := NewChannel()
+	if _, err := channel.Accept(KeyExchange{SessionID: "s", Epoch: 1, PublicKey: "AAAA"}); err == nil {
+		t.Error("Expected an error for a short public key")
+	}
+}
+
+func TestHKDF(t *testing.T) {
+	// RFC 5869 test case 1
+	ikm, _ := hex.DecodeString("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	want := "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"
+
+	if got := hex.EncodeToString(hkdfSHA256(ikm, salt, info, 42)); got != want {
+		t.Errorf("hkdfSHA256() = %s, want %s", got, want)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	agent, viewer := pair(t)
+
+	fingerprint := agent.Fingerprint()
+	if fingerprint == "" || fingerprint != viewer.Fingerprint() {
+		t.Fatalf("Expected both ends to show the same fingerprint, got %q and %q", fingerprint, viewer.Fingerprint())
+	}
+
+	// Rotations are authenticated by the current key, so the fingerprint stays
+	offer, _ := agent.Offer()
+	answer, _ := viewer.Accept(*offer)
+	agent.Accept(*answer)
+	if agent.Fingerprint() != fingerprint {
+		t.Errorf("Expected the fingerprint to survive a rotation, got %q", agent.Fingerprint())
+	}
+
+	// A relay answering with its own key shows a different fingerprint
+	relay, _ := NewChannel()
+	fresh, _ := NewChannel()
+	offer, _ = fresh.Offer()
+	answer, _ = relay.Accept(*offer)
+	fresh.Accept(*answer)
+	if fresh.Fingerprint() == fingerprint {
+		t.Error("Expected another key exchange to have another fingerprint")
+	}
+}
+
+func TestEstablishedSessionRejectsTakeover(t *testing.T) {
+	agent, viewer := pair(t)
+	relay, _ := NewChannel()
+
+	// A new session from anyone else is refused once a key is established
+	offer, _ := relay.Offer()
+	if _, err := agent.Accept(*offer); !errors.Is(err, ErrSessionMismatch) {
+		t.Errorf("Expected ErrSessionMismatch, got: %v", err)
+	}
+
+	// A rotation without the current key is refused
+	offer.SessionID = agent.SessionID()
+	offer.Epoch = agent.Epoch() + 1
+	if _, err := agent.Accept(*offer); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated, got: %v", err)
+	}
+
+	// So is a genuine rotation whose public key was swapped in transit
+	genuine, _ := viewer.Offer()
+	genuine.PublicKey = offer.PublicKey
+	if _, err := agent.Accept(*genuine); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated for a swapped key, got: %v", err)
+	}
+
+	// After a reset the next viewer can start a new session
+	if err := agent.Reset(); err != nil {
+		t.Fatalf("Reset() returned an error: %v", err)
+	}
+	if agent.Ready() || agent.Fingerprint() != "" {
+		t.Error("Expected Reset() to drop the keys")
+	}
+	offer, _ = relay.Offer()
+	if _, err := agent.Accept(*offer); err != nil {
+		t.Errorf("Expected a new session after Reset(), got: %v", err)
+	}
+}
@@ -0,0 +1,382 @@
+// Package input executes input events from the server in order on a
+// goroutine of their own, so slow events do not hold up other messages.
+package input
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+	"github.com/adamrobbie/go-support/pkg/remote"
+)
+
+// Message types used by the input queue
+const (
+	MessageTypeMouseEvent        = "mouseEvent"        // A mouse event, alone or in a batch
+	MessageTypeKeyboardEvent     = "keyboardEvent"     // A keyboard event, alone or in a batch
+	MessageTypeInputEvent        = "inputEvent"        // A DOM input event, alone or in a batch
+	MessageTypeInputBatch        = "inputBatch"        // Server sends several input events at once
+	MessageTypeInputBackpressure = "inputBackpressure" // Sent when the queue fills up and when it drains again
+)
+
+// DefaultCapacity is how many events the queue holds when no capacity is given
+const DefaultCapacity = 256
+
+// ErrQueueFull is returned when events do not fit in the queue
+var ErrQueueFull = errors.New("input queue is full")
+
+// Executor executes the events taken from the queue
+type Executor interface {
+	ExecuteMouseEvent(event remote.MouseEvent) error
+	ExecuteKeyboardEvent(event remote.KeyboardEvent) error
+	HandleDOMEvent(event remote.DOMEvent, view remote.View) error
+}
+
+// Options configures the input queue
+type Options struct {
+	Capacity int // Events the queue holds before rejecting more (DefaultCapacity if 0)
+	// Authorize is called with the sender of each event right before it runs;
+	// events it returns an error for are dropped. Senders can lose the right to
+	// send input while their events wait in the queue.
+	Authorize func(sender string) error
+	Verbose   bool
+}
+
+// Event is one queued input event; exactly one of its events is set
+type Event struct {
+	Mouse    *remote.MouseEvent
+	Keyboard *remote.KeyboardEvent
+	DOM      *remote.DOMEvent
+	View     remote.View // Display size for the DOM event
+	Sender   string      // Viewer that sent the event, if the message named one
+}
+
+// Batch is an inputBatch message
+type Batch struct {
+	Type     string            `json:"type"`
+	ViewerID string            `json:"viewerId,omitempty"` // Sender of events that do not name one
+	Events   []json.RawMessage `json:"events"`             // mouseEvent, keyboardEvent and inputEvent messages
+}
+
+// Backpressure tells the server how full the queue is
+type Backpressure struct {
+	Type      string `json:"type"`
+	Congested bool   `json:"congested"` // Whether the server should slow down
+	Queued    int    `json:"queued"`
+	Capacity  int    `json:"capacity"`
+	Dropped   int    `json:"dropped"` // Events rejected since the queue last drained
+}
+
+// ParseEvent parses a mouseEvent, keyboardEvent or inputEvent message
+func ParseEvent(data []byte) (Event, error) {
+	var header struct {
+		Type     string           `json:"type"`
+		ViewerID string           `json:"viewerId"`
+		Event    *remote.DOMEvent `json:"event"`
+		remote.View
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return Event{}, fmt.Errorf("failed to parse input event: %w", err)
+	}
+
+	switch header.Type {
+	case MessageTypeMouseEvent:
+		var event remote.MouseEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return Event{}, fmt.Errorf("failed to parse mouse event: %w", err)
+		}
+		return Event{Mouse: &event, Sender: header.ViewerID}, nil
+	case MessageTypeKeyboardEvent:
+		var event remote.KeyboardEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return Event{}, fmt.Errorf("failed to parse keyboard event: %w", err)
+		}
+		return Event{Keyboard: &event, Sender: header.ViewerID}, nil
+	case MessageTypeInputEvent:
+		if header.Event == nil {
+			return Event{}, errors.New("input event has no event")
+		}
+		if !remote.SupportsDOMEvent(header.Event.Type) {
+			return Event{}, fmt.Errorf("unsupported DOM event type: %q", header.Event.Type)
+		}
+		return Event{DOM: header.Event, View: header.View, Sender: header.ViewerID}, nil
+	default:
+		return Event{}, fmt.Errorf("unsupported input event type %q", header.Type)
+	}
+}
+
+// Queue executes input events one at a time in the order they arrive
+type Queue struct {
+	transport client.Transport
+	executor  Executor
+	capacity  int
+	authorize func(sender string) error
+	verbose   bool
+
+	mu        sync.Mutex
+	idle      *sync.Cond // Signalled when the executor finishes the last event
+	events    []Event
+	busy      bool // Whether the executor is running an event
+	congested bool
+	pressed   int // Mouse buttons held down by the mouseEvent messages pushed so far
+	dropped   int
+	closed    bool
+	wake      chan struct{}
+	done      chan struct{}
+}
+
+// NewQueue creates an input queue, starts its executor and registers the
+// inputBatch handler on the transport
+func NewQueue(transport client.Transport, executor Executor, opts Options) *Queue {
+	if opts.Capacity <= 0 {
+		opts.Capacity = DefaultCapacity
+	}
+	q := &Queue{
+		transport: transport,
+		executor:  executor,
+		capacity:  opts.Capacity,
+		authorize: opts.Authorize,
+		verbose:   opts.Verbose,
+		wake:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	q.idle = sync.NewCond(&q.mu)
+
+	transport.RegisterHandler(MessageTypeInputBatch, q.handleBatch)
+	go q.run()
+	return q
+}
+
+// HandleMessage queues a mouseEvent, keyboardEvent or inputEvent message
+func (q *Queue) HandleMessage(data []byte) error {
+	event, err := ParseEvent(data)
+	if err != nil {
+		return err
+	}
+	return q.Push(event)
+}
+
+// handleBatch queues the events of an inputBatch message
+func (q *Queue) handleBatch(data []byte) error {
+	var batch Batch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return fmt.Errorf("failed to parse input batch: %w", err)
+	}
+	events := make([]Event, len(batch.Events))
+	for i, raw := range batch.Events {
+		event, err := ParseEvent(raw)
+		if err != nil {
+			return fmt.Errorf("event %d of input batch: %w", i, err)
+		}
+		if event.Sender == "" {
+			event.Sender = batch.ViewerID
+		}
+		events[i] = event
+	}
+	if q.verbose {
+		log.Printf("DEBUG: Received input batch of %d events", len(events))
+	}
+	return q.Push(events...)
+}
+
+// Push queues events behind those already queued. Consecutive plain pointer
+// moves are coalesced into the last one. The events are queued together or,
+// if they do not fit, rejected together with ErrQueueFull.
+func (q *Queue) Push(events ...Event) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return errors.New("input queue is closed")
+	}
+
+	queued, pressed := append([]Event(nil), q.events...), q.pressed
+	for _, event := range events {
+		// Moves while a button is held are a drag path, which is kept whole
+		if n := len(queued); n > 0 && pressed == 0 && coalescible(queued[n-1], event) {
+			queued[n-1] = event
+			continue
+		}
+		queued = append(queued, event)
+		if event.Mouse != nil {
+			switch event.Mouse.Action {
+			case remote.MouseDown:
+				pressed++
+			case remote.MouseUp:
+				pressed = max(pressed-1, 0)
+			}
+		}
+	}
+
+	if len(queued) > q.capacity {
+		q.dropped += len(events)
+		report := q.setCongested(true, true)
+		q.mu.Unlock()
+		q.send(report)
+		return fmt.Errorf("%w, rejected %d events", ErrQueueFull, len(events))
+	}
+
+	q.events, q.pressed = queued, pressed
+	var report *Backpressure
+	if len(q.events) >= q.capacity*3/4 {
+		report = q.setCongested(true, false)
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	q.mu.Unlock()
+	q.send(report)
+	return nil
+}
+
+// Flush waits until every queued event has been executed
+func (q *Queue) Flush() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for (len(q.events) > 0 || q.busy) && !q.closed {
+		q.idle.Wait()
+	}
+}
+
+// Clear drops the queued events and waits for the running one to finish
+func (q *Queue) Clear() {
+	q.mu.Lock()
+	q.events, q.pressed = nil, 0
+	report := q.setCongested(false, false)
+	for q.busy {
+		q.idle.Wait()
+	}
+	q.mu.Unlock()
+	q.send(report)
+}
+
+// Close drops the queued events and stops the executor
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	q.events = nil
+	q.idle.Broadcast()
+	close(q.wake)
+	q.mu.Unlock()
+	<-q.done
+}
+
+// Len returns the number of queued events
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.events)
+}
+
+// run executes queued events until the queue is closed
+func (q *Queue) run() {
+	defer close(q.done)
+	for range q.wake {
+		for {
+			q.mu.Lock()
+			if q.closed || len(q.events) == 0 {
+				q.busy = false
+				q.idle.Broadcast()
+				q.mu.Unlock()
+				break
+			}
+			event := q.events[0]
+			q.events = q.events[1:]
+			q.busy = true
+			var report *Backpressure
+			if len(q.events) <= q.capacity/4 {
+				report = q.setCongested(false, false)
+			}
+			q.mu.Unlock()
+			q.send(report)
+
+			if q.authorize != nil {
+				if err := q.authorize(event.Sender); err != nil {
+					log.Printf("WARNING: Dropped queued input event: %v", err)
+					continue
+				}
+			}
+			if err := q.execute(event); err != nil {
+				log.Printf("ERROR: Failed to execute input event: %v", err)
+			}
+		}
+	}
+}
+
+// execute runs one event through the executor
+func (q *Queue) execute(event Event) error {
+	switch {
+	case event.Mouse != nil:
+		return q.executor.ExecuteMouseEvent(*event.Mouse)
+	case event.Keyboard != nil:
+		return q.executor.ExecuteKeyboardEvent(*event.Keyboard)
+	case event.DOM != nil:
+		return q.executor.HandleDOMEvent(*event.DOM, event.View)
+	default:
+		return errors.New("empty input event")
+	}
+}
+
+// setCongested records a change of congestion and returns the report to send
+// for it, or nil if nothing changed and force is not set. The lock must be held.
+func (q *Queue) setCongested(congested, force bool) *Backpressure {
+	if q.congested == congested && !force {
+		return nil
+	}
+	q.congested = congested
+	report := &Backpressure{
+		Type:      MessageTypeInputBackpressure,
+		Congested: congested,
+		Queued:    len(q.events),
+		Capacity:  q.capacity,
+		Dropped:   q.dropped,
+	}
+	if !congested {
+		q.dropped = 0
+	}
+	return report
+}
+
+// send sends a backpressure report, if there is one
+func (q *Queue) send(report *Backpressure) {
+	if report == nil {
+		return
+	}
+	if q.verbose {
+		log.Printf("DEBUG: Input queue congested=%v with %d of %d events queued, %d dropped",
+			report.Congested, report.Queued, report.Capacity, report.Dropped)
+	}
+	if err := q.transport.SendJSON(report); err != nil {
+		log.Printf("ERROR: Failed to send input backpressure: %v", err)
+	}
+}
+
+// coalescible returns true if next replaces last in the queue: both are plain
+// pointer moves with no button held, so only the final position matters
+func coalescible(last, next Event) bool {
+	if last.Sender != next.Sender {
+		return false
+	}
+	switch {
+	case last.Mouse != nil && next.Mouse != nil:
+		return plainMove(*last.Mouse) && plainMove(*next.Mouse)
+	case last.DOM != nil && next.DOM != nil:
+		a, b := *last.DOM, *next.DOM
+		return a.Type == "mousemove" && b.Type == "mousemove" && last.View == next.View &&
+			a.Buttons == 0 && b.Buttons == 0 && a.ShiftKey == b.ShiftKey && a.CtrlKey == b.CtrlKey &&
+			a.AltKey == b.AltKey && a.MetaKey == b.MetaKey
+	}
+	return false
+}
+
+// plainMove returns true for a move that jumps to its position
+func plainMove(event remote.MouseEvent) bool {
+	return event.Action == remote.MouseMove && event.Duration == 0 && len(event.Path) == 0
+}
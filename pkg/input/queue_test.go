@@ -0,0 +1,267 @@
+package input
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client/clienttest"
+	"github.com/adamrobbie/go-support/pkg/remote"
+)
+
+// reports returns the backpressure reports sent through a transport
+func reports(transport *clienttest.Transport) []Backpressure {
+	var sent []Backpressure
+	for _, message := range transport.Messages() {
+		sent = append(sent, *message.(*Backpressure))
+	}
+	return sent
+}
+
+// fakeExecutor records executed events; it blocks while gate is held
+type fakeExecutor struct {
+	mu     sync.Mutex
+	gate   sync.Mutex
+	events []string
+}
+
+func (f *fakeExecutor) record(event string) error {
+	f.gate.Lock()
+	defer f.gate.Unlock()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *fakeExecutor) ExecuteMouseEvent(event remote.MouseEvent) error {
+	return f.record(fmt.Sprintf("%s %d,%d", event.Action, event.X, event.Y))
+}
+
+func (f *fakeExecutor) ExecuteKeyboardEvent(event remote.KeyboardEvent) error {
+	if event.Key == "fail" {
+		return errors.New("failed")
+	}
+	return f.record(fmt.Sprintf("key %s %s", event.Action, event.Key))
+}
+
+func (f *fakeExecutor) HandleDOMEvent(event remote.DOMEvent, view remote.View) error {
+	return f.record(fmt.Sprintf("dom %s %g,%g", event.Type, event.OffsetX, event.OffsetY))
+}
+
+func (f *fakeExecutor) executed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.events...)
+}
+
+func move(x, y int) Event {
+	return Event{Mouse: &remote.MouseEvent{Action: remote.MouseMove, X: x, Y: y}}
+}
+
+func TestQueueOrderAndCoalescing(t *testing.T) {
+	transport, executor := clienttest.New(), &fakeExecutor{}
+	q := NewQueue(transport, executor, Options{})
+	defer q.Close()
+
+	// Hold the executor on the first event so the rest queue up behind it
+	executor.gate.Lock()
+	q.Push(Event{Keyboard: &remote.KeyboardEvent{Action: remote.KeyDown, Key: "shift"}})
+	for q.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	q.Push(move(1, 1), move(2, 2))
+	q.Push(move(3, 3))
+	q.Push(Event{Mouse: &remote.MouseEvent{Action: remote.MouseClick, Button: remote.LeftButton}})
+	q.Push(move(4, 4), Event{Mouse: &remote.MouseEvent{Action: remote.MouseMove, X: 5, Y: 5, Duration: 100}})
+	if n := q.Len(); n != 4 {
+		t.Errorf("Expected 4 queued events after coalescing, got %d", n)
+	}
+	executor.gate.Unlock()
+	q.Flush()
+
+	want := []string{"key down shift", "move 3,3", "click 0,0", "move 4,4", "move 5,5"}
+	if got := executor.executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestQueueMessages(t *testing.T) {
+	transport, executor := clienttest.New(), &fakeExecutor{}
+	q := NewQueue(transport, executor, Options{})
+	defer q.Close()
+
+	if err := q.HandleMessage([]byte(`{"type":"keyboardEvent","action":"press","key":"a"}`)); err != nil {
+		t.Fatalf("HandleMessage() returned an error: %v", err)
+	}
+	batch := `{"type":"inputBatch","events":[
+		{"type":"mouseEvent","action":"move","x":10,"y":20},
+		{"type":"inputEvent","viewWidth":100,"viewHeight":50,"event":{"type":"mousedown","offsetX":5,"offsetY":6}},
+		{"type":"keyboardEvent","action":"press","key":"fail"},
+		{"type":"keyboardEvent","action":"press","key":"b"}]}`
+	if err := transport.Deliver(t, batch); err != nil {
+		t.Fatalf("inputBatch returned an error: %v", err)
+	}
+	q.Flush()
+
+	// A failing event is logged and the rest still run
+	want := []string{"key press a", "move 10,20", "dom mousedown 5,6", "key press b"}
+	if got := executor.executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	bad := `{"type":"inputBatch","events":[{"type":"mouseEvent","action":"move"},{"type":"chat"}]}`
+	if err := transport.Deliver(t, bad); err == nil {
+		t.Error("Expected a batch with an unknown event to be rejected")
+	}
+	if err := q.HandleMessage([]byte(`{"type":"inputEvent"}`)); err == nil {
+		t.Error("Expected an input event without an event to be rejected")
+	}
+	if err := q.HandleMessage([]byte(`{"type":"inputEvent","event":{"type":"touchstart"}}`)); err == nil {
+		t.Error("Expected an unsupported DOM event type to be rejected before it is queued")
+	}
+}
+
+func TestQueueBackpressure(t *testing.T) {
+	transport, executor := clienttest.New(), &fakeExecutor{}
+	q := NewQueue(transport, executor, Options{Capacity: 4})
+	defer q.Close()
+
+	executor.gate.Lock()
+	key := func(k string) Event {
+		return Event{Keyboard: &remote.KeyboardEvent{Action: remote.KeyPress, Key: k}}
+	}
+	q.Push(key("a"))
+	for q.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := q.Push(key("b"), key("c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(key("d")); err != nil {
+		t.Fatal(err)
+	}
+	// The whole batch is rejected if it does not fit
+	if err := q.Push(key("e"), key("f")); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+	executor.gate.Unlock()
+	q.Flush()
+
+	if got := executor.executed(); len(got) != 4 {
+		t.Errorf("Expected the 4 accepted events to run, got %q", got)
+	}
+	want := []Backpressure{
+		{Type: MessageTypeInputBackpressure, Congested: true, Queued: 3, Capacity: 4},
+		{Type: MessageTypeInputBackpressure, Congested: true, Queued: 3, Capacity: 4, Dropped: 2},
+		{Type: MessageTypeInputBackpressure, Congested: false, Queued: 1, Capacity: 4, Dropped: 2},
+	}
+	if got := reports(transport); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected reports %+v, got %+v", want, got)
+	}
+	if data, _ := json.Marshal(want[1]); string(data) != `{"type":"inputBackpressure","congested":true,"queued":3,"capacity":4,"dropped":2}` {
+		t.Errorf("Unexpected backpressure JSON: %s", data)
+	}
+}
+
+func TestQueueClearAndClose(t *testing.T) {
+	transport, executor := clienttest.New(), &fakeExecutor{}
+	q := NewQueue(transport, executor, Options{})
+
+	executor.gate.Lock()
+	q.Push(move(1, 1))
+	for q.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	q.Push(Event{Mouse: &remote.MouseEvent{Action: remote.MouseClick}})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		executor.gate.Unlock()
+	}()
+	q.Clear()
+	if got := executor.executed(); !reflect.DeepEqual(got, []string{"move 1,1"}) {
+		t.Errorf("Expected Clear to drop the queued click and wait for the move, got %q", got)
+	}
+
+	q.Close()
+	q.Close()
+	if err := q.Push(move(2, 2)); err == nil {
+		t.Error("Expected pushing to a closed queue to fail")
+	}
+}
+
+func TestQueueAuthorizesWhenEventsRun(t *testing.T) {
+	transport, executor := clienttest.New(), &fakeExecutor{}
+	var mu sync.Mutex
+	controller := "v1"
+	q := NewQueue(transport, executor, Options{Authorize: func(sender string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if sender != controller {
+			return errors.New("not the controller")
+		}
+		return nil
+	}})
+	defer q.Close()
+
+	executor.gate.Lock()
+	q.HandleMessage([]byte(`{"type":"keyboardEvent","action":"press","key":"a","viewerId":"v1"}`))
+	for q.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	transport.Deliver(t, `{"type":"inputBatch","viewerId":"v1","events":[
+		{"type":"keyboardEvent","action":"press","key":"b"},
+		{"type":"mouseEvent","action":"move","x":1,"y":1}]}`)
+	q.HandleMessage([]byte(`{"type":"mouseEvent","action":"move","x":2,"y":2,"viewerId":"v2"}`))
+	if n := q.Len(); n != 3 {
+		t.Errorf("Expected moves from different viewers not to be coalesced, got %d events", n)
+	}
+
+	// Control moves to v2 while v1's events wait
+	mu.Lock()
+	controller = "v2"
+	mu.Unlock()
+	executor.gate.Unlock()
+	q.Flush()
+
+	want := []string{"key press a", "move 2,2"}
+	if got := executor.executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestQueueKeepsDragPaths(t *testing.T) {
+	transport, executor := clienttest.New(), &fakeExecutor{}
+	q := NewQueue(transport, executor, Options{})
+	defer q.Close()
+
+	executor.gate.Lock()
+	q.Push(move(0, 0))
+	for q.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	button := func(action remote.MouseAction) Event {
+		return Event{Mouse: &remote.MouseEvent{Action: action, Button: remote.LeftButton}}
+	}
+	q.Push(button(remote.MouseDown), move(1, 1), move(2, 2))
+	q.Push(move(3, 3), button(remote.MouseUp), move(4, 4), move(5, 5))
+
+	domMove := func(x float64, buttons int) Event {
+		return Event{DOM: &remote.DOMEvent{Type: "mousemove", OffsetX: x, Buttons: buttons}}
+	}
+	q.Push(domMove(1, 1), domMove(2, 1), domMove(3, 0), domMove(4, 0))
+	executor.gate.Unlock()
+	q.Flush()
+
+	want := []string{
+		"move 0,0", "down 0,0", "move 1,1", "move 2,2", "move 3,3", "up 0,0", "move 5,5",
+		"dom mousemove 1,0", "dom mousemove 2,0", "dom mousemove 4,0",
+	}
+	if got := executor.executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
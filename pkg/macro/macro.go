@@ -0,0 +1,307 @@
+package macro
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/remote"
+)
+
+// FormatVersion is the version of the macro file format
+const FormatVersion = 1
+
+const (
+	// MaxSteps limits the number of steps in a macro
+	MaxSteps = 10000
+	// MaxDelay limits the delay before a single step
+	MaxDelay = time.Minute
+)
+
+// ErrAborted is returned when playback stops because the local user moved the mouse
+var ErrAborted = errors.New("macro playback aborted by user input")
+
+// Step is one input event of a macro and the delay before it
+type Step struct {
+	Delay    int64                 `json:"delay"` // Milliseconds since the previous step
+	Mouse    *remote.MouseEvent    `json:"mouse,omitempty"`
+	Keyboard *remote.KeyboardEvent `json:"keyboard,omitempty"`
+}
+
+// Macro is a recorded sequence of input events
+type Macro struct {
+	Version int       `json:"version"`
+	Name    string    `json:"name,omitempty"`
+	Created time.Time `json:"created"`
+	Steps   []Step    `json:"steps"`
+}
+
+// Parse parses and validates a JSON macro
+func Parse(data []byte) (*Macro, error) {
+	var m Macro
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse macro: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Load reads a macro file
+func Load(path string) (*Macro, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read macro: %w", err)
+	}
+	return Parse(data)
+}
+
+// Save writes the macro to a file
+func (m *Macro) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode macro: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save macro: %w", err)
+	}
+	return nil
+}
+
+// Validate checks the version, the number of steps and that each step has exactly one event
+func (m *Macro) Validate() error {
+	if m.Version != FormatVersion {
+		return fmt.Errorf("unsupported macro version %d", m.Version)
+	}
+	if len(m.Steps) > MaxSteps {
+		return fmt.Errorf("macro has %d steps, more than the limit of %d", len(m.Steps), MaxSteps)
+	}
+	for i, step := range m.Steps {
+		if (step.Mouse == nil) == (step.Keyboard == nil) {
+			return fmt.Errorf("step %d must have exactly one mouse or keyboard event", i+1)
+		}
+		if step.Delay < 0 || time.Duration(step.Delay)*time.Millisecond > MaxDelay {
+			return fmt.Errorf("step %d has an invalid delay of %dms", i+1, step.Delay)
+		}
+	}
+	return nil
+}
+
+// Duration returns the total delay of the macro at normal speed
+func (m *Macro) Duration() time.Duration {
+	var total time.Duration
+	for _, step := range m.Steps {
+		total += time.Duration(step.Delay) * time.Millisecond
+	}
+	return total
+}
+
+// Recorder records input events with the time between them
+type Recorder struct {
+	macro     *Macro
+	last      time.Time
+	now       func() time.Time
+	recording bool
+	mu        sync.Mutex
+}
+
+// NewRecorder creates a recorder
+func NewRecorder() *Recorder {
+	return &Recorder{now: time.Now}
+}
+
+// Start starts recording a new macro, discarding an unfinished one
+func (r *Recorder) Start(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.macro = &Macro{Version: FormatVersion, Name: name, Created: now.UTC(), Steps: []Step{}}
+	r.last = now
+	r.recording = true
+}
+
+// Stop stops recording and returns the macro
+func (r *Recorder) Stop() (*Macro, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.recording {
+		return nil, fmt.Errorf("no macro is being recorded")
+	}
+	r.recording = false
+	return r.macro, nil
+}
+
+// Recording returns true if a macro is being recorded
+func (r *Recorder) Recording() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.recording
+}
+
+// RecordMouse adds a mouse event if a macro is being recorded
+func (r *Recorder) RecordMouse(event remote.MouseEvent) {
+	r.record(Step{Mouse: &event})
+}
+
+// RecordKeyboard adds a keyboard event if a macro is being recorded
+func (r *Recorder) RecordKeyboard(event remote.KeyboardEvent) {
+	r.record(Step{Keyboard: &event})
+}
+
+// record adds a step with the time since the previous step
+func (r *Recorder) record(step Step) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.recording || len(r.macro.Steps) >= MaxSteps {
+		return
+	}
+	now := r.now()
+	delay := now.Sub(r.last)
+	if delay > MaxDelay {
+		delay = MaxDelay
+	}
+	step.Delay = delay.Milliseconds()
+	r.last = now
+	r.macro.Steps = append(r.macro.Steps, step)
+}
+
+// Controller executes input events and reads the pointer position; RemoteController implements it
+type Controller interface {
+	ExecuteMouseEvent(event remote.MouseEvent) error
+	ExecuteKeyboardEvent(event remote.KeyboardEvent) error
+	GetMousePosition() (int, int, error)
+}
+
+// PlayOptions configures playback
+type PlayOptions struct {
+	Speed        float64       // Playback speed, e.g. 2 for twice as fast (1 if 0)
+	AbortOnInput bool          // Stop if the local user moves the mouse during playback
+	PollInterval time.Duration // How often the pointer is checked while waiting (50ms if 0)
+	Tolerance    int           // Pixels the pointer may drift before it counts as user input (3 if 0)
+}
+
+// Play executes the steps of a macro. It returns the number of steps played,
+// ErrAborted if the local user moved the mouse, or the context's error if it
+// was cancelled.
+func Play(ctx context.Context, controller Controller, m *Macro, opts PlayOptions) (int, error) {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 50 * time.Millisecond
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 3
+	}
+
+	p := player{ctx: ctx, controller: controller, opts: opts}
+	if opts.AbortOnInput {
+		if err := p.updatePosition(); err != nil {
+			return 0, err
+		}
+	}
+
+	for i, step := range m.Steps {
+		delay := time.Duration(float64(step.Delay)*float64(time.Millisecond)/opts.Speed + 0.5)
+		if err := p.wait(delay); err != nil {
+			return i, err
+		}
+
+		var err error
+		if step.Mouse != nil {
+			err = controller.ExecuteMouseEvent(*step.Mouse)
+		} else {
+			err = controller.ExecuteKeyboardEvent(*step.Keyboard)
+		}
+		if err != nil {
+			return i, fmt.Errorf("step %d failed: %w", i+1, err)
+		}
+
+		// The step may have moved the pointer, so that is where it should stay
+		if opts.AbortOnInput {
+			if err := p.updatePosition(); err != nil {
+				return i + 1, err
+			}
+		}
+	}
+	return len(m.Steps), nil
+}
+
+// player holds the state of one playback
+type player struct {
+	ctx        context.Context
+	controller Controller
+	opts       PlayOptions
+	x, y       int // Pointer position after the last step
+}
+
+// updatePosition remembers where the pointer is
+func (p *player) updatePosition() error {
+	x, y, err := p.controller.GetMousePosition()
+	if err != nil {
+		return fmt.Errorf("failed to read mouse position: %w", err)
+	}
+	p.x, p.y = x, y
+	return nil
+}
+
+// wait sleeps for a delay, checking for cancellation and user input
+func (p *player) wait(delay time.Duration) error {
+	deadline := time.Now().Add(delay)
+	for {
+		if err := p.checkInput(); err != nil {
+			return err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil
+		}
+		if p.opts.AbortOnInput && remaining > p.opts.PollInterval {
+			remaining = p.opts.PollInterval
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-p.ctx.Done():
+			timer.Stop()
+			return p.ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// checkInput returns ErrAborted if the pointer moved since the last step
+func (p *player) checkInput() error {
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	if !p.opts.AbortOnInput {
+		return nil
+	}
+
+	x, y, err := p.controller.GetMousePosition()
+	if err != nil {
+		return fmt.Errorf("failed to read mouse position: %w", err)
+	}
+	if abs(x-p.x) > p.opts.Tolerance || abs(y-p.y) > p.opts.Tolerance {
+		return fmt.Errorf("%w: pointer moved from (%d,%d) to (%d,%d)", ErrAborted, p.x, p.y, x, y)
+	}
+	return nil
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,171 @@
+package macro
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/remote"
+)
+
+// fakeController records executed events and reports a settable pointer position
+type fakeController struct {
+	mu     sync.Mutex
+	events []string
+	x, y   int
+	onStep func(n int) // Called after each executed event
+}
+
+func (f *fakeController) ExecuteMouseEvent(event remote.MouseEvent) error {
+	f.mu.Lock()
+	f.events = append(f.events, "mouse:"+string(event.Action))
+	if event.Action == remote.MouseMove {
+		f.x, f.y = event.X, event.Y
+	}
+	n, onStep := len(f.events), f.onStep
+	f.mu.Unlock()
+	if onStep != nil {
+		onStep(n)
+	}
+	return nil
+}
+
+func (f *fakeController) ExecuteKeyboardEvent(event remote.KeyboardEvent) error {
+	f.mu.Lock()
+	f.events = append(f.events, "key:"+string(event.Action))
+	n, onStep := len(f.events), f.onStep
+	f.mu.Unlock()
+	if onStep != nil {
+		onStep(n)
+	}
+	return nil
+}
+
+func (f *fakeController) GetMousePosition() (int, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.x, f.y, nil
+}
+
+func (f *fakeController) setPosition(x, y int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.x, f.y = x, y
+}
+
+func (f *fakeController) executed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.events...)
+}
+
+// testMacro returns a macro that moves, clicks and types with the given delay between steps
+func testMacro(delay int64) *Macro {
+	return &Macro{Version: FormatVersion, Name: "test", Steps: []Step{
+		{Mouse: &remote.MouseEvent{Action: remote.MouseMove, X: 10, Y: 20}},
+		{Delay: delay, Mouse: &remote.MouseEvent{Action: remote.MouseClick, Button: remote.LeftButton}},
+		{Delay: delay, Keyboard: &remote.KeyboardEvent{Action: remote.KeyType, Text: "hello"}},
+	}}
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	r.RecordMouse(remote.MouseEvent{Action: remote.MouseMove})
+	r.Start("setup")
+	now = now.Add(250 * time.Millisecond)
+	r.RecordMouse(remote.MouseEvent{Action: remote.MouseMove, X: 1, Y: 2})
+	now = now.Add(time.Second)
+	r.RecordKeyboard(remote.KeyboardEvent{Action: remote.KeyPress, Key: "enter"})
+
+	m, err := r.Stop()
+	if err != nil {
+		t.Fatalf("Stop() returned an error: %v", err)
+	}
+	if m.Name != "setup" || len(m.Steps) != 2 {
+		t.Fatalf("Expected two recorded steps, got %+v", m)
+	}
+	if m.Steps[0].Delay != 250 || m.Steps[1].Delay != 1000 || m.Steps[1].Keyboard.Key != "enter" {
+		t.Errorf("Unexpected steps: %+v, %+v", m.Steps[0], m.Steps[1])
+	}
+	if _, err := r.Stop(); err == nil {
+		t.Error("Expected an error when stopping without recording")
+	}
+}
+
+func TestSaveLoadAndValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.json")
+	if err := testMacro(100).Save(path); err != nil {
+		t.Fatalf("Save() returned an error: %v", err)
+	}
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+	if len(m.Steps) != 3 || m.Steps[2].Keyboard.Text != "hello" || m.Duration() != 200*time.Millisecond {
+		t.Errorf("Unexpected loaded macro: %+v", m)
+	}
+
+	for name, data := range map[string]string{
+		"version":    `{"version":2,"steps":[]}`,
+		"empty step": `{"version":1,"steps":[{"delay":0}]}`,
+		"two events": `{"version":1,"steps":[{"mouse":{"action":"move"},"keyboard":{"action":"press"}}]}`,
+		"delay":      `{"version":1,"steps":[{"delay":-1,"mouse":{"action":"move"}}]}`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Expected an error for an invalid %s", name)
+		}
+	}
+}
+
+func TestPlay(t *testing.T) {
+	controller := &fakeController{}
+	start := time.Now()
+	played, err := Play(context.Background(), controller, testMacro(100), PlayOptions{Speed: 4, AbortOnInput: true, PollInterval: 5 * time.Millisecond})
+	if err != nil || played != 3 {
+		t.Fatalf("Play() = %d, %v", played, err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected playback at 4x speed to take about 50ms, took %v", elapsed)
+	}
+	want := []string{"mouse:move", "mouse:click", "key:type"}
+	if got := controller.executed(); len(got) != len(want) || got[0] != want[0] || got[2] != want[2] {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestPlayAbortsOnUserInput(t *testing.T) {
+	controller := &fakeController{}
+	// The user moves the mouse right after the first step
+	controller.onStep = func(n int) {
+		if n == 1 {
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				controller.setPosition(300, 300)
+			}()
+		}
+	}
+
+	played, err := Play(context.Background(), controller, testMacro(200), PlayOptions{AbortOnInput: true, PollInterval: 5 * time.Millisecond})
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("Expected ErrAborted, got %v", err)
+	}
+	if played != 1 {
+		t.Errorf("Expected one step to be played, got %d", played)
+	}
+}
+
+func TestPlayCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	played, err := Play(ctx, &fakeController{}, testMacro(5000), PlayOptions{})
+	if !errors.Is(err, context.Canceled) || played != 1 {
+		t.Errorf("Expected cancellation after one step, got %d, %v", played, err)
+	}
+}
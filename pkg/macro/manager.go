@@ -0,0 +1,275 @@
+package macro
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+	"github.com/adamrobbie/go-support/pkg/remote"
+)
+
+// Message types used by macros
+const (
+	MessageTypePlayMacro          = "playMacro"          // Server plays a macro
+	MessageTypeStopMacro          = "stopMacro"          // Server stops the playing macro
+	MessageTypeMacroFinished      = "macroFinished"      // Sent when playback ends
+	MessageTypeRecordMacro        = "recordMacro"        // Server starts recording a macro
+	MessageTypeStopMacroRecording = "stopMacroRecording" // Server stops recording
+	MessageTypeMacroRecorded      = "macroRecorded"      // Reply to stopMacroRecording with the macro
+)
+
+var (
+	// ErrAlreadyPlaying is returned when a macro is played while another one is playing
+	ErrAlreadyPlaying = errors.New("a macro is already playing")
+	// ErrInvalidName is returned for macro names that cannot be used as file names
+	ErrInvalidName = errors.New("macro names may only contain letters, digits, '-' and '_'")
+)
+
+// validName matches macro names that are safe to use as file names
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Options configures the macro manager
+type Options struct {
+	Dir     string // Directory macros are saved to and loaded from by name
+	Verbose bool
+}
+
+// Request is a macro message from the server
+type Request struct {
+	Type         string          `json:"type"`
+	RequestID    string          `json:"requestId,omitempty"`
+	Name         string          `json:"name,omitempty"`  // Saved macro to play, or name of the recording
+	Macro        json.RawMessage `json:"macro,omitempty"` // Inline macro to play
+	Speed        float64         `json:"speed,omitempty"`
+	AbortOnInput *bool           `json:"abortOnInput,omitempty"` // Defaults to true
+	Save         bool            `json:"save,omitempty"`         // Save the recording in the macro directory
+}
+
+// Result is sent when playback ends or a recording stops
+type Result struct {
+	Type      string `json:"type"`
+	RequestID string `json:"requestId,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Steps     int    `json:"steps"`
+	Played    int    `json:"played,omitempty"`
+	Aborted   bool   `json:"aborted,omitempty"` // Stopped by local user input
+	Macro     *Macro `json:"macro,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Manager records macros and plays them back through a controller
+type Manager struct {
+	transport  client.Transport
+	controller Controller
+	recorder   *Recorder
+	dir        string
+	cancel     context.CancelFunc // Stops the playing macro, nil when idle
+	verbose    bool
+	mu         sync.Mutex
+}
+
+// NewManager creates a macro manager and registers its handlers on the transport
+func NewManager(transport client.Transport, controller Controller, opts Options) *Manager {
+	m := &Manager{
+		transport:  transport,
+		controller: controller,
+		recorder:   NewRecorder(),
+		dir:        opts.Dir,
+		verbose:    opts.Verbose,
+	}
+
+	transport.RegisterHandler(MessageTypePlayMacro, m.handlePlay)
+	transport.RegisterHandler(MessageTypeStopMacro, m.handleStop)
+	transport.RegisterHandler(MessageTypeRecordMacro, m.handleRecord)
+	transport.RegisterHandler(MessageTypeStopMacroRecording, m.handleStopRecording)
+
+	return m
+}
+
+// RecordMouse records an executed mouse event if a macro is being recorded
+func (m *Manager) RecordMouse(event remote.MouseEvent) {
+	m.recorder.RecordMouse(event)
+}
+
+// RecordKeyboard records an executed keyboard event if a macro is being recorded
+func (m *Manager) RecordKeyboard(event remote.KeyboardEvent) {
+	m.recorder.RecordKeyboard(event)
+}
+
+// StartRecording starts recording a macro
+func (m *Manager) StartRecording(name string) error {
+	if name != "" && !validName.MatchString(name) {
+		return ErrInvalidName
+	}
+	m.recorder.Start(name)
+	if m.verbose {
+		log.Printf("DEBUG: Recording macro %q", name)
+	}
+	return nil
+}
+
+// StopRecording stops recording and saves the macro in the macro directory if
+// save is set. It returns the macro and the path it was saved to.
+func (m *Manager) StopRecording(save bool) (*Macro, string, error) {
+	macro, err := m.recorder.Stop()
+	if err != nil || !save {
+		return macro, "", err
+	}
+	if macro.Name == "" {
+		return macro, "", fmt.Errorf("a macro needs a name to be saved")
+	}
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return macro, "", fmt.Errorf("failed to create macro directory: %w", err)
+	}
+	path := filepath.Join(m.dir, macro.Name+".json")
+	return macro, path, macro.Save(path)
+}
+
+// Recording returns true if a macro is being recorded
+func (m *Manager) Recording() bool {
+	return m.recorder.Recording()
+}
+
+// LoadNamed loads a macro saved in the macro directory
+func (m *Manager) LoadNamed(name string) (*Macro, error) {
+	if !validName.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+	return Load(filepath.Join(m.dir, name+".json"))
+}
+
+// Play starts playing a macro in the background and calls done when it ends
+func (m *Manager) Play(macro *Macro, opts PlayOptions, done func(played int, err error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		return ErrAlreadyPlaying
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	if m.verbose {
+		log.Printf("DEBUG: Playing macro %q with %d steps at %.2gx speed", macro.Name, len(macro.Steps), opts.Speed)
+	}
+
+	go func() {
+		played, err := Play(ctx, m.controller, macro, opts)
+
+		m.mu.Lock()
+		m.cancel = nil
+		m.mu.Unlock()
+		cancel()
+
+		if done != nil {
+			done(played, err)
+		}
+	}()
+	return nil
+}
+
+// Stop stops the playing macro, if any
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// Close stops playback and discards an unfinished recording
+func (m *Manager) Close() {
+	m.Stop()
+	m.recorder.Stop()
+}
+
+// handlePlay plays an inline or saved macro and reports when it ends
+func (m *Manager) handlePlay(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse play request: %w", err)
+	}
+	result := Result{Type: MessageTypeMacroFinished, RequestID: req.RequestID, Name: req.Name}
+
+	var macro *Macro
+	var err error
+	switch {
+	case len(req.Macro) > 0:
+		macro, err = Parse(req.Macro)
+	case req.Name != "":
+		macro, err = m.LoadNamed(req.Name)
+	default:
+		err = fmt.Errorf("play request needs a macro or a name")
+	}
+	if err != nil {
+		return m.reply(result, err)
+	}
+	if result.Name == "" {
+		result.Name = macro.Name
+	}
+	result.Steps = len(macro.Steps)
+
+	opts := PlayOptions{Speed: req.Speed, AbortOnInput: req.AbortOnInput == nil || *req.AbortOnInput}
+	err = m.Play(macro, opts, func(played int, err error) {
+		result.Played = played
+		result.Aborted = errors.Is(err, ErrAborted)
+		m.reply(result, err)
+	})
+	if err != nil {
+		return m.reply(result, err)
+	}
+	return nil
+}
+
+// handleStop stops the playing macro
+func (m *Manager) handleStop(data []byte) error {
+	m.Stop()
+	return nil
+}
+
+// handleRecord starts recording the input events executed for the server
+func (m *Manager) handleRecord(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse record request: %w", err)
+	}
+	return m.StartRecording(req.Name)
+}
+
+// handleStopRecording stops recording and replies with the macro
+func (m *Manager) handleStopRecording(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse stop recording request: %w", err)
+	}
+
+	macro, path, err := m.StopRecording(req.Save)
+	result := Result{Type: MessageTypeMacroRecorded, RequestID: req.RequestID, Macro: macro, Path: path}
+	if macro != nil {
+		result.Name = macro.Name
+		result.Steps = len(macro.Steps)
+	}
+	return m.reply(result, err)
+}
+
+// reply sends a result, marking it successful if err is nil
+func (m *Manager) reply(result Result, err error) error {
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if sendErr := m.transport.SendJSON(result); sendErr != nil {
+		log.Printf("Failed to send %s: %v", result.Type, sendErr)
+	}
+	return err
+}
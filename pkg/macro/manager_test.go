@@ -0,0 +1,68 @@
+package macro
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamrobbie/go-support/pkg/client/clienttest"
+	"github.com/adamrobbie/go-support/pkg/remote"
+)
+
+func TestPlayMacroMessage(t *testing.T) {
+	transport := clienttest.New()
+	controller := &fakeController{}
+	NewManager(transport, controller, Options{Dir: t.TempDir()})
+
+	data, _ := json.Marshal(testMacro(1))
+	if err := transport.Deliver(t, `{"type":"playMacro","requestId":"r1","speed":2,"macro":`+string(data)+`}`); err != nil {
+		t.Fatalf("playMacro handler returned an error: %v", err)
+	}
+
+	result := transport.Next(t, MessageTypeMacroFinished)
+	if result["requestId"] != "r1" || result["success"] != true || result["played"] != float64(3) || result["name"] != "test" {
+		t.Errorf("Unexpected result: %v", result)
+	}
+	if len(controller.executed()) != 3 {
+		t.Errorf("Expected three executed events, got %v", controller.executed())
+	}
+}
+
+func TestRecordMacroMessages(t *testing.T) {
+	transport := clienttest.New()
+	dir := t.TempDir()
+	manager := NewManager(transport, &fakeController{}, Options{Dir: dir})
+
+	if err := transport.Deliver(t, `{"type":"recordMacro","name":"../escape"}`); err == nil {
+		t.Error("Expected an error for an invalid macro name")
+	}
+	if err := transport.Deliver(t, `{"type":"recordMacro","name":"login"}`); err != nil {
+		t.Fatalf("recordMacro handler returned an error: %v", err)
+	}
+	manager.RecordMouse(remote.MouseEvent{Action: remote.MouseClick, X: 5, Y: 6})
+	manager.RecordKeyboard(remote.KeyboardEvent{Action: remote.KeyType, Text: "secret"})
+
+	if err := transport.Deliver(t, `{"type":"stopMacroRecording","requestId":"r2","save":true}`); err != nil {
+		t.Fatalf("stopMacroRecording handler returned an error: %v", err)
+	}
+	result := transport.Next(t, MessageTypeMacroRecorded)
+	if result["requestId"] != "r2" || result["steps"] != float64(2) || result["success"] != true {
+		t.Errorf("Unexpected result: %v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "login.json")); err != nil {
+		t.Errorf("Expected the macro to be saved: %v", err)
+	}
+
+	// The saved macro can be played by name
+	if err := transport.Deliver(t, `{"type":"playMacro","name":"login","abortOnInput":false}`); err != nil {
+		t.Fatalf("playMacro handler returned an error: %v", err)
+	}
+	if result := transport.Next(t, MessageTypeMacroFinished); result["success"] != true || result["played"] != float64(2) {
+		t.Errorf("Unexpected result: %v", result)
+	}
+
+	if err := transport.Deliver(t, `{"type":"playMacro","name":"missing"}`); err == nil {
+		t.Error("Expected an error for a missing macro")
+	}
+}
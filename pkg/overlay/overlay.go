@@ -0,0 +1,334 @@
+package overlay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+)
+
+// Message types used by the overlay
+const (
+	MessageTypeShowPointer   = "showPointer"   // Technician points at a position on the screen
+	MessageTypeDrawHighlight = "drawHighlight" // Technician outlines a region of the screen
+	MessageTypeClearOverlay  = "clearOverlay"  // Technician removes the pointers and highlights
+)
+
+const (
+	// DefaultPointerTimeout is how long a pointer stays after its last update
+	DefaultPointerTimeout = 3 * time.Second
+	// DefaultHighlightDuration is how long a highlight stays without a duration
+	DefaultHighlightDuration = 10 * time.Second
+	// MaxHighlights limits the number of highlights shown at once
+	MaxHighlights = 16
+)
+
+var (
+	// DefaultPointerColor is the color of a pointer without a color
+	DefaultPointerColor = color.RGBA{255, 0, 0, 255}
+	// DefaultHighlightColor is the color of a highlight without a color
+	DefaultHighlightColor = color.RGBA{255, 200, 0, 255}
+)
+
+// ErrUnsupported is returned when the overlay cannot be shown on this platform or display
+var ErrUnsupported = errors.New("screen overlay is not supported on this platform")
+
+// Pointer is a laser-pointer dot at a screen position
+type Pointer struct {
+	X     int
+	Y     int
+	Color color.RGBA
+}
+
+// Highlight is a rectangle outlined on the screen
+type Highlight struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+	Color  color.RGBA
+}
+
+// Scene is everything drawn on the overlay
+type Scene struct {
+	Pointers   []Pointer
+	Highlights []Highlight
+}
+
+// Empty reports whether the scene draws nothing
+func (s Scene) Empty() bool {
+	return len(s.Pointers) == 0 && len(s.Highlights) == 0
+}
+
+// Overlay draws on a transparent, always-on-top layer above the user's screen
+// that does not take mouse or keyboard input
+type Overlay interface {
+	// Render replaces what is drawn with the scene
+	Render(scene Scene) error
+	// Close removes the overlay
+	Close() error
+}
+
+// Noop is an overlay that draws nothing, e.g. for headless tests
+type Noop struct{}
+
+// Render does nothing
+func (Noop) Render(Scene) error { return nil }
+
+// Close does nothing
+func (Noop) Close() error { return nil }
+
+// Options configures the overlay manager
+type Options struct {
+	PointerTimeout time.Duration // How long a pointer stays after its last update (DefaultPointerTimeout if 0)
+	Verbose        bool
+}
+
+// Request is an overlay message from the server
+type Request struct {
+	Type     string `json:"type"`
+	ID       string `json:"id,omitempty"` // Highlight ID; a highlight with the same ID is replaced
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	Color    string `json:"color,omitempty"`    // #rrggbb
+	Duration int    `json:"duration,omitempty"` // Milliseconds a highlight stays
+	ViewerID string `json:"viewerId,omitempty"`
+}
+
+// timedPointer is a pointer and when it disappears
+type timedPointer struct {
+	Pointer
+	expires time.Time
+}
+
+// timedHighlight is a highlight and when it disappears
+type timedHighlight struct {
+	Highlight
+	id      string
+	expires time.Time
+}
+
+// Manager keeps the pointers and highlights requested by the server and
+// renders them on the overlay. Each viewer has its own pointer.
+type Manager struct {
+	overlay        Overlay
+	pointerTimeout time.Duration
+	pointers       map[string]timedPointer // By viewer ID
+	highlights     []timedHighlight        // In drawing order
+	timer          *time.Timer             // Fires when the next pointer or highlight expires
+	closed         bool
+	verbose        bool
+	mu             sync.Mutex
+}
+
+// NewManager creates an overlay manager and registers its handlers on the transport
+func NewManager(transport client.Transport, overlay Overlay, opts Options) *Manager {
+	if opts.PointerTimeout <= 0 {
+		opts.PointerTimeout = DefaultPointerTimeout
+	}
+	m := &Manager{
+		overlay:        overlay,
+		pointerTimeout: opts.PointerTimeout,
+		pointers:       make(map[string]timedPointer),
+		verbose:        opts.Verbose,
+	}
+
+	transport.RegisterHandler(MessageTypeShowPointer, m.handleShowPointer)
+	transport.RegisterHandler(MessageTypeDrawHighlight, m.handleDrawHighlight)
+	transport.RegisterHandler(MessageTypeClearOverlay, m.handleClear)
+
+	return m
+}
+
+// Clear removes all pointers and highlights
+func (m *Manager) Clear() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pointers = make(map[string]timedPointer)
+	m.highlights = nil
+	return m.renderLocked()
+}
+
+// Close clears and removes the overlay
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	return m.overlay.Close()
+}
+
+// handleShowPointer shows or moves the pointer of a viewer
+func (m *Manager) handleShowPointer(data []byte) error {
+	req, rgba, err := parseRequest(data, DefaultPointerColor)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pointers[req.ViewerID] = timedPointer{
+		Pointer: Pointer{X: req.X, Y: req.Y, Color: rgba},
+		expires: time.Now().Add(m.pointerTimeout),
+	}
+	return m.renderLocked()
+}
+
+// handleDrawHighlight outlines a region of the screen
+func (m *Manager) handleDrawHighlight(data []byte) error {
+	req, rgba, err := parseRequest(data, DefaultHighlightColor)
+	if err != nil {
+		return err
+	}
+	if req.Width <= 0 || req.Height <= 0 {
+		return fmt.Errorf("invalid highlight size: %dx%d", req.Width, req.Height)
+	}
+	duration := DefaultHighlightDuration
+	if req.Duration > 0 {
+		duration = time.Duration(req.Duration) * time.Millisecond
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	highlight := timedHighlight{
+		Highlight: Highlight{X: req.X, Y: req.Y, Width: req.Width, Height: req.Height, Color: rgba},
+		id:        req.ID,
+		expires:   time.Now().Add(duration),
+	}
+	if req.ID != "" {
+		for i, h := range m.highlights {
+			if h.id == req.ID {
+				m.highlights = append(m.highlights[:i], m.highlights[i+1:]...)
+				break
+			}
+		}
+	}
+	m.highlights = append(m.highlights, highlight)
+	if len(m.highlights) > MaxHighlights {
+		m.highlights = m.highlights[len(m.highlights)-MaxHighlights:]
+	}
+	return m.renderLocked()
+}
+
+// handleClear removes all pointers and highlights
+func (m *Manager) handleClear(data []byte) error {
+	if m.verbose {
+		log.Println("DEBUG: Clearing screen overlay")
+	}
+	return m.Clear()
+}
+
+// expire drops the pointers and highlights that have timed out
+func (m *Manager) expire() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.renderLocked(); err != nil {
+		log.Printf("Failed to update screen overlay: %v", err)
+	}
+}
+
+// renderLocked drops expired items, draws the rest and schedules the next
+// expiry. Must be called with the lock held.
+func (m *Manager) renderLocked() error {
+	if m.closed {
+		return nil
+	}
+
+	now := time.Now()
+	var scene Scene
+	var next time.Time
+	schedule := func(expires time.Time) {
+		if next.IsZero() || expires.Before(next) {
+			next = expires
+		}
+	}
+
+	// Draw pointers in a stable order so overlapping pointers do not flicker
+	viewers := make([]string, 0, len(m.pointers))
+	for viewer, p := range m.pointers {
+		if !now.Before(p.expires) {
+			delete(m.pointers, viewer)
+			continue
+		}
+		viewers = append(viewers, viewer)
+	}
+	sort.Strings(viewers)
+	for _, viewer := range viewers {
+		p := m.pointers[viewer]
+		scene.Pointers = append(scene.Pointers, p.Pointer)
+		schedule(p.expires)
+	}
+
+	kept := m.highlights[:0]
+	for _, h := range m.highlights {
+		if !now.Before(h.expires) {
+			continue
+		}
+		kept = append(kept, h)
+		scene.Highlights = append(scene.Highlights, h.Highlight)
+		schedule(h.expires)
+	}
+	m.highlights = kept
+
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	if !next.IsZero() {
+		m.timer = time.AfterFunc(next.Sub(now), m.expire)
+	}
+
+	if m.verbose {
+		log.Printf("DEBUG: Rendering overlay with %d pointers and %d highlights", len(scene.Pointers), len(scene.Highlights))
+	}
+	if err := m.overlay.Render(scene); err != nil {
+		return fmt.Errorf("failed to draw screen overlay: %w", err)
+	}
+	return nil
+}
+
+// parseRequest parses an overlay request and its color
+func parseRequest(data []byte, defaultColor color.RGBA) (Request, color.RGBA, error) {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, defaultColor, fmt.Errorf("failed to parse overlay request: %w", err)
+	}
+	if req.Color == "" {
+		return req, defaultColor, nil
+	}
+	rgba, err := ParseColor(req.Color)
+	return req, rgba, err
+}
+
+// ParseColor parses a #rrggbb color
+func ParseColor(value string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q: expected #rrggbb", value)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q: %w", value, err)
+	}
+	return color.RGBA{uint8(n >> 16), uint8(n >> 8), uint8(n), 255}, nil
+}
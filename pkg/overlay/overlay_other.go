@@ -0,0 +1,9 @@
+//go:build !linux && !freebsd && !openbsd && !netbsd && !dragonfly
+// +build !linux,!freebsd,!openbsd,!netbsd,!dragonfly
+
+package overlay
+
+// New returns ErrUnsupported; only X11 has an overlay backend so far
+func New() (Overlay, error) {
+	return nil, ErrUnsupported
+}
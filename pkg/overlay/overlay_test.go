@@ -0,0 +1,138 @@
+package overlay
+
+import (
+	"image/color"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client/clienttest"
+)
+
+// recordingOverlay keeps the last rendered scene
+type recordingOverlay struct {
+	scene   Scene
+	renders int
+	closed  bool
+	mu      sync.Mutex
+}
+
+func (r *recordingOverlay) Render(scene Scene) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scene = scene
+	r.renders++
+	return nil
+}
+
+func (r *recordingOverlay) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+func (r *recordingOverlay) last() Scene {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.scene
+}
+
+func TestPointersAndHighlights(t *testing.T) {
+	transport := clienttest.New()
+	rec := &recordingOverlay{}
+	manager := NewManager(transport, rec, Options{})
+	defer manager.Close()
+
+	if err := transport.Deliver(t, `{"type":"showPointer","viewerId":"v1","x":10,"y":20}`); err != nil {
+		t.Fatalf("showPointer handler returned an error: %v", err)
+	}
+	transport.Deliver(t, `{"type":"showPointer","viewerId":"v2","x":30,"y":40,"color":"#00ff00"}`)
+	// Moving a viewer's pointer replaces it
+	transport.Deliver(t, `{"type":"showPointer","viewerId":"v1","x":11,"y":21}`)
+
+	scene := rec.last()
+	if len(scene.Pointers) != 2 {
+		t.Fatalf("Expected one pointer per viewer, got %+v", scene.Pointers)
+	}
+	if scene.Pointers[0] != (Pointer{X: 11, Y: 21, Color: DefaultPointerColor}) {
+		t.Errorf("Unexpected pointer of v1: %+v", scene.Pointers[0])
+	}
+	if scene.Pointers[1].Color != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("Expected a green pointer for v2, got %+v", scene.Pointers[1])
+	}
+
+	transport.Deliver(t, `{"type":"drawHighlight","id":"h1","x":1,"y":2,"width":30,"height":40}`)
+	transport.Deliver(t, `{"type":"drawHighlight","id":"h1","x":5,"y":6,"width":30,"height":40}`)
+	if highlights := rec.last().Highlights; len(highlights) != 1 || highlights[0].X != 5 || highlights[0].Color != DefaultHighlightColor {
+		t.Errorf("Expected the highlight to be replaced, got %+v", highlights)
+	}
+
+	if err := transport.Deliver(t, `{"type":"drawHighlight","x":1,"y":2,"width":0,"height":40}`); err == nil {
+		t.Error("Expected an error for an empty highlight")
+	}
+	if err := transport.Deliver(t, `{"type":"showPointer","x":1,"y":2,"color":"red"}`); err == nil {
+		t.Error("Expected an error for an invalid color")
+	}
+
+	transport.Deliver(t, `{"type":"clearOverlay"}`)
+	if !rec.last().Empty() {
+		t.Errorf("Expected an empty scene after clearOverlay, got %+v", rec.last())
+	}
+
+	manager.Close()
+	if !rec.closed {
+		t.Error("Expected the overlay to be closed")
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	transport := clienttest.New()
+	rec := &recordingOverlay{}
+	manager := NewManager(transport, rec, Options{PointerTimeout: 20 * time.Millisecond})
+	defer manager.Close()
+
+	transport.Deliver(t, `{"type":"showPointer","viewerId":"v1","x":10,"y":20}`)
+	transport.Deliver(t, `{"type":"drawHighlight","x":1,"y":2,"width":30,"height":40,"duration":60}`)
+
+	waitFor := func(what string, done func(Scene) bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !done(rec.last()) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s, scene: %+v", what, rec.last())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitFor("the pointer to expire", func(s Scene) bool { return len(s.Pointers) == 0 })
+	if len(rec.last().Highlights) != 1 {
+		t.Error("Expected the highlight to outlast the pointer")
+	}
+	waitFor("the highlight to expire", func(s Scene) bool { return s.Empty() })
+}
+
+func TestMaxHighlights(t *testing.T) {
+	transport := clienttest.New()
+	rec := &recordingOverlay{}
+	manager := NewManager(transport, rec, Options{})
+	defer manager.Close()
+
+	for i := 0; i < MaxHighlights+5; i++ {
+		transport.Deliver(t, `{"type":"drawHighlight","x":1,"y":2,"width":3,"height":4}`)
+	}
+	if n := len(rec.last().Highlights); n != MaxHighlights {
+		t.Errorf("Expected at most %d highlights, got %d", MaxHighlights, n)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	if c, err := ParseColor("#1a2B3c"); err != nil || c != (color.RGBA{0x1a, 0x2b, 0x3c, 255}) {
+		t.Errorf("ParseColor() = %v, %v", c, err)
+	}
+	for _, value := range []string{"", "#fff", "#gggggg", "red"} {
+		if _, err := ParseColor(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
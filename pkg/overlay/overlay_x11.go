@@ -0,0 +1,262 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+// +build linux freebsd openbsd netbsd dragonfly
+
+package overlay
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math/bits"
+	"os"
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/shape"
+	"github.com/jezek/xgb/xproto"
+)
+
+const (
+	// pointerRadius is the radius of a pointer dot in pixels
+	pointerRadius = 10
+	// highlightWidth is the outline width of a highlight in pixels
+	highlightWidth = 4
+)
+
+// x11Overlay draws into an override-redirect window covering the screen. The
+// window is shaped to the drawn pixels and has an empty input shape, so the
+// rest of the screen stays visible and clicks go through to the windows
+// below. This works without a compositing window manager.
+type x11Overlay struct {
+	conn   *xgb.Conn
+	screen *xproto.ScreenInfo
+	visual *xproto.VisualInfo
+	window xproto.Window
+	gc     xproto.Gcontext
+	mask   xproto.Pixmap // 1-bit bitmap of the drawn pixels
+	maskGC xproto.Gcontext
+	mapped bool
+	scene  Scene // Last scene, redrawn when the window is exposed
+	mu     sync.Mutex
+}
+
+// New opens the overlay on the X server in $DISPLAY
+func New() (Overlay, error) {
+	if os.Getenv("DISPLAY") == "" {
+		return nil, fmt.Errorf("%w: DISPLAY is not set", ErrUnsupported)
+	}
+	return newX11Overlay("")
+}
+
+// newX11Overlay opens the overlay on an X display ("" for $DISPLAY)
+func newX11Overlay(display string) (*x11Overlay, error) {
+	conn, err := xgb.NewConnDisplay(display)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X server: %w", err)
+	}
+	if err := shape.Init(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+
+	o := &x11Overlay{conn: conn, screen: xproto.Setup(conn).DefaultScreen(conn)}
+	if err := o.create(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go o.handleEvents()
+	return o, nil
+}
+
+// create creates the overlay window, its graphics contexts and the shape bitmap
+func (o *x11Overlay) create() error {
+	screen := o.screen
+	for _, depth := range screen.AllowedDepths {
+		for i, visual := range depth.Visuals {
+			if visual.VisualId == screen.RootVisual {
+				o.visual = &depth.Visuals[i]
+			}
+		}
+	}
+
+	var err error
+	if o.window, err = xproto.NewWindowId(o.conn); err != nil {
+		return fmt.Errorf("failed to allocate overlay window: %w", err)
+	}
+	if err := xproto.CreateWindowChecked(o.conn, screen.RootDepth, o.window, screen.Root,
+		0, 0, screen.WidthInPixels, screen.HeightInPixels, 0,
+		xproto.WindowClassInputOutput, screen.RootVisual,
+		xproto.CwBackPixel|xproto.CwOverrideRedirect|xproto.CwEventMask,
+		[]uint32{screen.BlackPixel, 1, xproto.EventMaskExposure}).Check(); err != nil {
+		return fmt.Errorf("failed to create overlay window: %w", err)
+	}
+	name := "go-support overlay"
+	xproto.ChangeProperty(o.conn, xproto.PropModeReplace, o.window, xproto.AtomWmName, xproto.AtomString, 8, uint32(len(name)), []byte(name))
+
+	// An empty input shape lets clicks through; it needs SHAPE 1.1
+	if version, err := shape.QueryVersion(o.conn).Reply(); err == nil && (version.MajorVersion > 1 || version.MinorVersion >= 1) {
+		shape.Rectangles(o.conn, shape.SoSet, shape.SkInput, xproto.ClipOrderingUnsorted, o.window, 0, 0, nil)
+	}
+
+	if o.gc, err = xproto.NewGcontextId(o.conn); err != nil {
+		return fmt.Errorf("failed to allocate graphics context: %w", err)
+	}
+	xproto.CreateGC(o.conn, o.gc, xproto.Drawable(o.window), xproto.GcLineWidth, []uint32{highlightWidth})
+
+	if o.mask, err = xproto.NewPixmapId(o.conn); err != nil {
+		return fmt.Errorf("failed to allocate shape bitmap: %w", err)
+	}
+	xproto.CreatePixmap(o.conn, 1, o.mask, xproto.Drawable(screen.Root), screen.WidthInPixels, screen.HeightInPixels)
+	if o.maskGC, err = xproto.NewGcontextId(o.conn); err != nil {
+		return fmt.Errorf("failed to allocate graphics context: %w", err)
+	}
+	return xproto.CreateGCChecked(o.conn, o.maskGC, xproto.Drawable(o.mask), xproto.GcLineWidth, []uint32{highlightWidth}).Check()
+}
+
+// Render shapes the window to the scene and draws it, or hides the window for an empty scene
+func (o *x11Overlay) Render(scene Scene) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.scene = scene
+	if scene.Empty() {
+		if o.mapped {
+			o.mapped = false
+			return xproto.UnmapWindowChecked(o.conn, o.window).Check()
+		}
+		return nil
+	}
+
+	// Clear the bitmap and set the pixels that will be drawn
+	xproto.ChangeGC(o.conn, o.maskGC, xproto.GcForeground, []uint32{0})
+	xproto.PolyFillRectangle(o.conn, xproto.Drawable(o.mask), o.maskGC, []xproto.Rectangle{
+		{Width: o.screen.WidthInPixels, Height: o.screen.HeightInPixels},
+	})
+	o.drawScene(xproto.Drawable(o.mask), o.maskGC, func(color.RGBA) uint32 { return 1 })
+	if err := shape.MaskChecked(o.conn, shape.SoSet, shape.SkBounding, o.window, 0, 0, o.mask).Check(); err != nil {
+		return fmt.Errorf("failed to shape overlay window: %w", err)
+	}
+
+	if !o.mapped {
+		xproto.MapWindow(o.conn, o.window)
+		o.mapped = true
+	}
+	// Stay above windows raised since the last render
+	xproto.ConfigureWindow(o.conn, o.window, xproto.ConfigWindowStackMode, []uint32{xproto.StackModeAbove})
+
+	// Errors of the unchecked drawing requests are logged by handleEvents
+	o.drawScene(xproto.Drawable(o.window), o.gc, o.pixel)
+	return nil
+}
+
+// Close destroys the overlay window and disconnects from the X server
+func (o *x11Overlay) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	xproto.DestroyWindow(o.conn, o.window)
+	xproto.FreePixmap(o.conn, o.mask)
+	xproto.FreeGC(o.conn, o.gc)
+	xproto.FreeGC(o.conn, o.maskGC)
+	o.conn.Close()
+	return nil
+}
+
+// drawScene draws the highlights and then the pointers on top of them
+func (o *x11Overlay) drawScene(drawable xproto.Drawable, gc xproto.Gcontext, pixel func(color.RGBA) uint32) {
+	screenWidth, screenHeight := int(o.screen.WidthInPixels), int(o.screen.HeightInPixels)
+	for _, h := range o.scene.Highlights {
+		// The outline is drawn inside the highlighted region
+		inset := highlightWidth / 2
+		rect, ok := clip(h.X+inset, h.Y+inset, max(h.Width-highlightWidth, 1), max(h.Height-highlightWidth, 1), screenWidth, screenHeight)
+		if !ok {
+			continue
+		}
+		xproto.ChangeGC(o.conn, gc, xproto.GcForeground, []uint32{pixel(h.Color)})
+		xproto.PolyRectangle(o.conn, drawable, gc, []xproto.Rectangle{rect})
+	}
+	for _, p := range o.scene.Pointers {
+		if p.X < 0 || p.Y < 0 || p.X >= screenWidth || p.Y >= screenHeight {
+			continue
+		}
+		xproto.ChangeGC(o.conn, gc, xproto.GcForeground, []uint32{pixel(p.Color)})
+		xproto.PolyFillArc(o.conn, drawable, gc, []xproto.Arc{{
+			X:      int16(p.X - pointerRadius),
+			Y:      int16(p.Y - pointerRadius),
+			Width:  2 * pointerRadius,
+			Height: 2 * pointerRadius,
+			Angle2: 360 * 64,
+		}})
+	}
+}
+
+// clip returns the part of a rectangle that lies on the screen, and false if
+// none of it does. X11 coordinates are 16 bits, so coordinates far off the
+// screen would wrap around to somewhere on it if they were cast unchecked.
+func clip(x, y, width, height, screenWidth, screenHeight int) (xproto.Rectangle, bool) {
+	// Bound the values first so adding them cannot overflow
+	const limit = 1 << 24
+	x, y = min(max(x, -limit), limit), min(max(y, -limit), limit)
+	width, height = min(max(width, 0), limit), min(max(height, 0), limit)
+
+	left, top := max(x, 0), max(y, 0)
+	right, bottom := min(x+width, screenWidth), min(y+height, screenHeight)
+	if right <= left || bottom <= top {
+		return xproto.Rectangle{}, false
+	}
+	return xproto.Rectangle{
+		X:      int16(left),
+		Y:      int16(top),
+		Width:  uint16(right - left),
+		Height: uint16(bottom - top),
+	}, true
+}
+
+// pixel converts a color to a pixel value of the root visual
+func (o *x11Overlay) pixel(c color.RGBA) uint32 {
+	if o.visual == nil || o.visual.Class != xproto.VisualClassTrueColor {
+		if c.R|c.G|c.B == 0 {
+			return o.screen.BlackPixel
+		}
+		return o.screen.WhitePixel
+	}
+	return channel(c.R, o.visual.RedMask) | channel(c.G, o.visual.GreenMask) | channel(c.B, o.visual.BlueMask)
+}
+
+// channel scales an 8-bit color channel into the bits of a visual's mask
+func channel(value uint8, mask uint32) uint32 {
+	if mask == 0 {
+		return 0
+	}
+	shift := bits.TrailingZeros32(mask)
+	width := bits.OnesCount32(mask)
+	scaled := uint32(value)
+	if width < 8 {
+		scaled >>= 8 - width
+	} else {
+		scaled <<= width - 8
+	}
+	return (scaled << shift) & mask
+}
+
+// handleEvents redraws the overlay when it is exposed, until the connection closes
+func (o *x11Overlay) handleEvents() {
+	for {
+		event, err := o.conn.WaitForEvent()
+		if event == nil && err == nil {
+			return
+		}
+		if err != nil {
+			log.Printf("X11 overlay error: %v", err)
+			continue
+		}
+		if expose, ok := event.(xproto.ExposeEvent); ok && expose.Count == 0 {
+			o.mu.Lock()
+			if o.mapped {
+				o.drawScene(xproto.Drawable(o.window), o.gc, o.pixel)
+			}
+			o.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,84 @@
+//go:build linux
+// +build linux
+
+package overlay
+
+import (
+	"image/color"
+	"os"
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+func TestChannel(t *testing.T) {
+	if got := channel(0xff, 0xff0000); got != 0xff0000 {
+		t.Errorf("channel(0xff, 0xff0000) = %#x", got)
+	}
+	// 5-bit channel of a 16-bit visual
+	if got := channel(0xff, 0xf800); got != 0xf800 {
+		t.Errorf("channel(0xff, 0xf800) = %#x", got)
+	}
+	if got := channel(0x80, 0x00ff00); got != 0x8000 {
+		t.Errorf("channel(0x80, 0x00ff00) = %#x", got)
+	}
+}
+
+func TestClip(t *testing.T) {
+	tests := []struct {
+		x, y, width, height int
+		want                xproto.Rectangle
+		ok                  bool
+	}{
+		{10, 20, 30, 40, xproto.Rectangle{X: 10, Y: 20, Width: 30, Height: 40}, true},
+		{-10, -20, 30, 40, xproto.Rectangle{X: 0, Y: 0, Width: 20, Height: 20}, true},
+		{1900, 1000, 100000, 100000, xproto.Rectangle{X: 1900, Y: 1000, Width: 20, Height: 80}, true},
+		// Would wrap around to 10,10 if cast to int16 unchecked
+		{65546, 65546, 10, 10, xproto.Rectangle{}, false},
+		{-100, 10, 50, 10, xproto.Rectangle{}, false},
+		{1 << 62, 1 << 62, 1 << 62, 1 << 62, xproto.Rectangle{}, false},
+	}
+	for _, test := range tests {
+		got, ok := clip(test.x, test.y, test.width, test.height, 1920, 1080)
+		if got != test.want || ok != test.ok {
+			t.Errorf("clip(%d, %d, %d, %d) = %+v, %v, expected %+v, %v",
+				test.x, test.y, test.width, test.height, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+// TestX11Overlay runs against the X server in $DISPLAY, e.g. xvfb-run go test ./pkg/overlay/
+func TestX11Overlay(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" {
+		t.Skip("DISPLAY is not set; run under Xvfb to test the X11 overlay")
+	}
+
+	o, err := newX11Overlay("")
+	if err != nil {
+		t.Fatalf("Failed to open the overlay: %v", err)
+	}
+	defer o.Close()
+
+	scene := Scene{
+		Pointers:   []Pointer{{X: 50, Y: 50, Color: color.RGBA{255, 0, 0, 255}}},
+		Highlights: []Highlight{{X: 100, Y: 100, Width: 80, Height: 40, Color: color.RGBA{255, 200, 0, 255}}},
+	}
+	if err := o.Render(scene); err != nil {
+		t.Fatalf("Render() returned an error: %v", err)
+	}
+
+	attrs, err := xproto.GetWindowAttributes(o.conn, o.window).Reply()
+	if err != nil {
+		t.Fatalf("Failed to read overlay window attributes: %v", err)
+	}
+	if attrs.MapState != xproto.MapStateViewable || !attrs.OverrideRedirect {
+		t.Errorf("Expected a mapped override-redirect window, got %+v", attrs)
+	}
+
+	if err := o.Render(Scene{}); err != nil {
+		t.Fatalf("Render() of an empty scene returned an error: %v", err)
+	}
+	if attrs, err := xproto.GetWindowAttributes(o.conn, o.window).Reply(); err != nil || attrs.MapState != xproto.MapStateUnmapped {
+		t.Errorf("Expected the overlay to be hidden for an empty scene, got %+v, %v", attrs, err)
+	}
+}
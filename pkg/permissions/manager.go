@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+	"sync"
 )
 
 // PermissionType represents different types of permissions
@@ -22,6 +23,10 @@ const (
 	ScreenShare PermissionType = "screen_share"
 	// RemoteControl permission for keyboard and mouse control
 	RemoteControl PermissionType = "remote_control"
+	// Clipboard permission for reading and writing the clipboard
+	Clipboard PermissionType = "clipboard"
+	// ProcessControl permission for stopping processes and launching applications
+	ProcessControl PermissionType = "process_control"
 	// Add more permission types as needed
 )
 
@@ -76,6 +81,7 @@ type Manager interface {
 type DefaultManager struct {
 	permissions map[PermissionType]PermissionStatus
 	verbose     bool
+	mu          sync.RWMutex // Guards permissions, which is checked from many goroutines
 }
 
 // NewManager creates a new permission manager
@@ -100,6 +106,10 @@ func (m *DefaultManager) RequestPermission(permType PermissionType) (PermissionS
 		return m.requestScreenSharePermission()
 	case RemoteControl:
 		return m.requestRemoteControlPermission()
+	case Clipboard:
+		return m.requestClipboardPermission()
+	case ProcessControl:
+		return m.checkProcessControlPermission()
 	default:
 		return Unknown, fmt.Errorf("unsupported permission type: %s", permType)
 	}
@@ -108,8 +118,7 @@ func (m *DefaultManager) RequestPermission(permType PermissionType) (PermissionS
 // CheckPermission implements the Manager interface
 func (m *DefaultManager) CheckPermission(permType PermissionType) (PermissionStatus, error) {
 	// First check if we have a cached status
-	status, exists := m.permissions[permType]
-	if exists {
+	if status, exists := m.cached(permType); exists {
 		return status, nil
 	}
 
@@ -119,11 +128,30 @@ func (m *DefaultManager) CheckPermission(permType PermissionType) (PermissionSta
 		return m.checkScreenSharePermission()
 	case RemoteControl:
 		return m.checkRemoteControlPermission()
+	case Clipboard:
+		return m.checkClipboardPermission()
+	case ProcessControl:
+		return m.checkProcessControlPermission()
 	default:
 		return Unknown, nil
 	}
 }
 
+// cached returns the cached status of a permission
+func (m *DefaultManager) cached(permType PermissionType) (PermissionStatus, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status, exists := m.permissions[permType]
+	return status, exists
+}
+
+// remember caches the status of a permission
+func (m *DefaultManager) remember(permType PermissionType, status PermissionStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.permissions[permType] = status
+}
+
 // EnsurePermission implements the Manager interface
 func (m *DefaultManager) EnsurePermission(permType PermissionType) (bool, error) {
 	// First check if we already have the permission
@@ -163,7 +191,7 @@ func (m *DefaultManager) requestScreenSharePermission() (PermissionStatus, error
 	}
 
 	if err == nil {
-		m.permissions[ScreenShare] = status
+		m.remember(ScreenShare, status)
 	}
 	return status, err
 }
@@ -185,7 +213,7 @@ func (m *DefaultManager) checkScreenSharePermission() (PermissionStatus, error)
 	}
 
 	if err == nil {
-		m.permissions[ScreenShare] = status
+		m.remember(ScreenShare, status)
 	}
 	return status, err
 }
@@ -207,7 +235,7 @@ func (m *DefaultManager) requestRemoteControlPermission() (PermissionStatus, err
 	}
 
 	if err == nil {
-		m.permissions[RemoteControl] = status
+		m.remember(RemoteControl, status)
 	}
 	return status, err
 }
@@ -229,11 +257,60 @@ func (m *DefaultManager) checkRemoteControlPermission() (PermissionStatus, error
 	}
 
 	if err == nil {
-		m.permissions[RemoteControl] = status
+		m.remember(RemoteControl, status)
 	}
 	return status, err
 }
 
+// checkClipboardPermission checks that the clipboard can be accessed on this platform
+func (m *DefaultManager) checkClipboardPermission() (PermissionStatus, error) {
+	status := Granted
+
+	// Linux needs a clipboard tool for the X11 or Wayland session
+	if runtime.GOOS == "linux" {
+		status = Denied
+		for _, tool := range []string{"xclip", "wl-paste"} {
+			if _, err := execLookPath(tool); err == nil {
+				status = Granted
+				break
+			}
+		}
+		if status == Denied && m.verbose {
+			log.Println("Neither xclip nor wl-paste was found in PATH")
+		}
+	}
+
+	m.remember(Clipboard, status)
+	return status, nil
+}
+
+// checkProcessControlPermission checks that processes can be managed.
+// Stopping processes and launching applications act on the user's desktop,
+// so they follow the remote control permission, which is never requested here.
+func (m *DefaultManager) checkProcessControlPermission() (PermissionStatus, error) {
+	status, err := m.CheckPermission(RemoteControl)
+	if err != nil {
+		return Unknown, err
+	}
+	if status != Granted && m.verbose {
+		log.Printf("Process control needs the remote control permission, which is %v", status)
+	}
+	m.remember(ProcessControl, status)
+	return status, nil
+}
+
+// requestClipboardPermission explains how to enable clipboard access
+func (m *DefaultManager) requestClipboardPermission() (PermissionStatus, error) {
+	status, _ := m.checkClipboardPermission()
+	if status == Granted {
+		return Granted, nil
+	}
+
+	log.Println("This application requires xclip (X11) or wl-clipboard (Wayland) for clipboard access.")
+	log.Println("Install one of them with your package manager, for example: sudo apt install xclip")
+	return Denied, nil
+}
+
 // macOS permission methods
 func (m *DefaultManager) checkMacOSScreenSharePermission() (PermissionStatus, error) {
 	// Check screen recording permission
@@ -504,6 +581,10 @@ func (m *DefaultManager) RequestPermissionInteractive(permType PermissionType) b
 			"3. Add this application to the list of allowed apps or check its checkbox if already listed\n" +
 			"4. Return to this application after granting permission"
 		preferencesPath = "x-apple.systempreferences:com.apple.preference.security?Privacy_Accessibility"
+	case Clipboard:
+		description = "Clipboard access is required to share copied text and images with the technician."
+		instructions = "On Linux, install xclip (X11) or wl-clipboard (Wayland).\n" +
+			"On macOS and Windows, no additional setup is required."
 	default:
 		fmt.Printf("Unknown permission type: %s\n", permType)
 		return false
@@ -520,7 +601,7 @@ func (m *DefaultManager) RequestPermissionInteractive(permType PermissionType) b
 
 	if input == "y" || input == "Y" {
 		// Open System Preferences
-		if runtime.GOOS == "darwin" {
+		if runtime.GOOS == "darwin" && preferencesPath != "" {
 			cmd := exec.Command("open", preferencesPath)
 			err := cmd.Run()
 			if err != nil {
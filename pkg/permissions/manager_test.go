@@ -3,6 +3,8 @@ package permissions
 import (
 	"errors"
 	"os/exec"
+	"runtime"
+	"sync"
 	"testing"
 )
 
@@ -302,3 +304,65 @@ func TestRequestPermissionInteractive(t *testing.T) {
 		t.Error("RequestPermissionInteractive() should return false for a denied permission")
 	}
 }
+
+func TestDefaultManagerProcessControlPermission(t *testing.T) {
+	manager := &DefaultManager{permissions: map[PermissionType]PermissionStatus{RemoteControl: Granted}}
+	if granted, err := manager.EnsurePermission(ProcessControl); err != nil || !granted {
+		t.Errorf("Expected process control permission, got %v, %v", granted, err)
+	}
+
+	manager = &DefaultManager{permissions: map[PermissionType]PermissionStatus{RemoteControl: Denied}}
+	if granted, err := manager.EnsurePermission(ProcessControl); err != nil || granted {
+		t.Errorf("Expected no process control permission without remote control, got %v, %v", granted, err)
+	}
+}
+
+func TestDefaultManagerClipboardPermission(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("clipboard tools are only checked on Linux")
+	}
+
+	originalLookPath := execLookPath
+	defer func() { execLookPath = originalLookPath }()
+
+	manager := &DefaultManager{permissions: make(map[PermissionType]PermissionStatus)}
+
+	execLookPath = func(file string) (string, error) {
+		return "", errors.New("not found")
+	}
+	if status, _ := manager.CheckPermission(Clipboard); status != Denied {
+		t.Errorf("Expected Denied without clipboard tools, got %v", status)
+	}
+
+	manager.permissions = make(map[PermissionType]PermissionStatus)
+	execLookPath = func(file string) (string, error) {
+		if file == "wl-paste" {
+			return "/usr/bin/wl-paste", nil
+		}
+		return "", errors.New("not found")
+	}
+	if granted, err := manager.EnsurePermission(Clipboard); err != nil || !granted {
+		t.Errorf("Expected clipboard permission with wl-paste, got %v, %v", granted, err)
+	}
+}
+
+func TestDefaultManagerConcurrentChecks(t *testing.T) {
+	manager := NewManager(false).(*DefaultManager)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				manager.CheckPermission(Clipboard)
+				manager.RequestPermission(ProcessControl)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, exists := manager.cached(Clipboard); !exists {
+		t.Error("Expected the clipboard permission to be cached")
+	}
+}
@@ -0,0 +1,97 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Capability is an action the technician may be allowed to take on the machine
+type Capability string
+
+const (
+	// ListProcesses allows listing the running processes
+	ListProcesses Capability = "listProcesses"
+	// KillProcess allows stopping processes
+	KillProcess Capability = "killProcess"
+	// LaunchApplication allows starting applications
+	LaunchApplication Capability = "launchApplication"
+)
+
+// known lists every capability a policy can grant
+var known = []Capability{ListProcesses, KillProcess, LaunchApplication}
+
+// ErrDenied is returned for capabilities the policy does not grant
+var ErrDenied = errors.New("capability is not allowed by policy")
+
+// Policy is the set of capabilities granted to the technician.
+// A nil Policy grants nothing.
+type Policy struct {
+	granted map[Capability]bool
+}
+
+// New creates a policy granting the given capabilities
+func New(capabilities ...Capability) *Policy {
+	p := &Policy{granted: make(map[Capability]bool)}
+	for _, capability := range capabilities {
+		p.granted[capability] = true
+	}
+	return p
+}
+
+// Parse creates a policy from capability names. "all" grants every capability.
+func Parse(names []string) (*Policy, error) {
+	p := New()
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == "all" {
+			return New(known...), nil
+		}
+		capability := Capability(name)
+		if !isKnown(capability) {
+			return nil, fmt.Errorf("unknown capability: %s", name)
+		}
+		p.granted[capability] = true
+	}
+	return p, nil
+}
+
+// Allows reports whether the policy grants a capability
+func (p *Policy) Allows(capability Capability) bool {
+	return p != nil && p.granted[capability]
+}
+
+// Check returns ErrDenied if the policy does not grant a capability
+func (p *Policy) Check(capability Capability) error {
+	if !p.Allows(capability) {
+		return fmt.Errorf("%w: %s", ErrDenied, capability)
+	}
+	return nil
+}
+
+// Capabilities returns the granted capabilities in name order
+func (p *Policy) Capabilities() []Capability {
+	if p == nil {
+		return nil
+	}
+	capabilities := make([]Capability, 0, len(p.granted))
+	for capability := range p.granted {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Slice(capabilities, func(i, j int) bool { return capabilities[i] < capabilities[j] })
+	return capabilities
+}
+
+// isKnown reports whether a capability exists
+func isKnown(capability Capability) bool {
+	for _, k := range known {
+		if k == capability {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]string{"listProcesses", " killProcess ", ""})
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+	if !p.Allows(ListProcesses) || !p.Allows(KillProcess) || p.Allows(LaunchApplication) {
+		t.Errorf("Unexpected capabilities: %v", p.Capabilities())
+	}
+	if err := p.Check(LaunchApplication); !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected ErrDenied, got: %v", err)
+	}
+	if err := p.Check(KillProcess); err != nil {
+		t.Errorf("Expected killProcess to be allowed, got: %v", err)
+	}
+
+	if _, err := Parse([]string{"formatDisk"}); err == nil {
+		t.Error("Expected an error for an unknown capability")
+	}
+
+	all, _ := Parse([]string{"all"})
+	if len(all.Capabilities()) != len(known) {
+		t.Errorf("Expected all capabilities, got %v", all.Capabilities())
+	}
+}
+
+func TestNilPolicy(t *testing.T) {
+	var p *Policy
+	if p.Allows(ListProcesses) || p.Capabilities() != nil {
+		t.Error("A nil policy should grant nothing")
+	}
+	if err := p.Check(ListProcesses); !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected ErrDenied, got: %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+package processes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/audit"
+	"github.com/adamrobbie/go-support/pkg/client"
+	"github.com/adamrobbie/go-support/pkg/diagnostics"
+	"github.com/adamrobbie/go-support/pkg/permissions"
+	"github.com/adamrobbie/go-support/pkg/policy"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// Message types used by process management
+const (
+	MessageTypeListProcesses       = "listProcesses"       // Server requests the process list
+	MessageTypeProcessList         = "processList"         // Reply to listProcesses
+	MessageTypeKillProcess         = "killProcess"         // Server stops a process
+	MessageTypeProcessKilled       = "processKilled"       // Reply to killProcess
+	MessageTypeLaunchApplication   = "launchApplication"   // Server starts an application
+	MessageTypeApplicationLaunched = "applicationLaunched" // Reply to launchApplication
+)
+
+// listTimeout limits how long reading the process list may take
+const listTimeout = 10 * time.Second
+
+var (
+	// ErrPermissionDenied is returned when process control is not permitted
+	ErrPermissionDenied = errors.New("process control permission not granted")
+	// ErrNotAllowed is returned for applications that are not on the allowlist
+	ErrNotAllowed = errors.New("application is not allowed")
+	// ErrProtectedProcess is returned when asked to stop the agent itself or init
+	ErrProtectedProcess = errors.New("process cannot be stopped remotely")
+	// ErrNoSuchProcess is returned for a PID that is not running
+	ErrNoSuchProcess = errors.New("no such process")
+)
+
+// Options configures process management
+type Options struct {
+	Policy       *policy.Policy      // Capabilities granted to the technician
+	PermManager  permissions.Manager // Checks the process control permission (granted if nil)
+	Applications []string            // Applications that may be launched, by name or absolute path
+	Audit        *audit.Logger       // Audit trail for stopped processes and launched applications
+	Verbose      bool
+}
+
+// Request is a process management request from the server
+type Request struct {
+	Type        string   `json:"type"`
+	RequestID   string   `json:"requestId,omitempty"`
+	Filter      string   `json:"filter,omitempty"` // Case-insensitive name filter for listProcesses
+	PID         int32    `json:"pid,omitempty"`
+	Force       bool     `json:"force,omitempty"` // Kill instead of asking the process to terminate
+	Application string   `json:"application,omitempty"`
+	Args        []string `json:"args,omitempty"`
+	ViewerID    string   `json:"viewerId,omitempty"`
+}
+
+// ProcessList is the reply to listProcesses
+type ProcessList struct {
+	Type      string                `json:"type"`
+	RequestID string                `json:"requestId,omitempty"`
+	Processes []diagnostics.Process `json:"processes"` // Sorted by CPU usage
+	Error     string                `json:"error,omitempty"`
+}
+
+// Result is the reply to killProcess and launchApplication
+type Result struct {
+	Type        string `json:"type"`
+	RequestID   string `json:"requestId,omitempty"`
+	PID         int32  `json:"pid,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Application string `json:"application,omitempty"`
+	Success     bool   `json:"success"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Manager answers process management requests from the server
+type Manager struct {
+	transport    client.Transport
+	policy       *policy.Policy
+	permManager  permissions.Manager
+	applications map[string]bool
+	audit        *audit.Logger
+	verbose      bool
+}
+
+// NewManager creates a process manager and registers its handlers on the transport
+func NewManager(transport client.Transport, opts Options) *Manager {
+	m := &Manager{
+		transport:    transport,
+		policy:       opts.Policy,
+		permManager:  opts.PermManager,
+		applications: make(map[string]bool),
+		audit:        opts.Audit,
+		verbose:      opts.Verbose,
+	}
+	for _, name := range opts.Applications {
+		m.applications[name] = true
+	}
+
+	transport.RegisterHandler(MessageTypeListProcesses, m.handleList)
+	transport.RegisterHandler(MessageTypeKillProcess, m.handleKill)
+	transport.RegisterHandler(MessageTypeLaunchApplication, m.handleLaunch)
+
+	return m
+}
+
+// handleList replies with the running processes
+func (m *Manager) handleList(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse process list request: %w", err)
+	}
+	reply := ProcessList{Type: MessageTypeProcessList, RequestID: req.RequestID, Processes: []diagnostics.Process{}}
+
+	err := m.policy.Check(policy.ListProcesses)
+	if err == nil {
+		var all []diagnostics.Process
+		ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+		all, err = diagnostics.Processes(ctx)
+		cancel()
+		reply.Processes = filter(diagnostics.Top(all, len(all)), req.Filter)
+	}
+	if err != nil {
+		reply.Error = err.Error()
+	}
+
+	if sendErr := m.transport.SendJSON(reply); sendErr != nil {
+		log.Printf("Failed to send process list: %v", sendErr)
+	}
+	return err
+}
+
+// handleKill stops a process
+func (m *Manager) handleKill(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse kill request: %w", err)
+	}
+	result := Result{Type: MessageTypeProcessKilled, RequestID: req.RequestID, PID: req.PID}
+
+	err := m.authorize(policy.KillProcess)
+	if err == nil {
+		result.Name, err = m.kill(req.PID, req.Force)
+	}
+
+	m.audit.Record("process.kill", req.ViewerID, map[string]interface{}{
+		"pid":   req.PID,
+		"name":  result.Name,
+		"force": req.Force,
+		"error": errorString(err),
+	})
+	return m.reply(result, err)
+}
+
+// handleLaunch starts an application
+func (m *Manager) handleLaunch(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse launch request: %w", err)
+	}
+	result := Result{Type: MessageTypeApplicationLaunched, RequestID: req.RequestID, Application: req.Application}
+
+	err := m.authorize(policy.LaunchApplication)
+	if err == nil && req.Application == "" {
+		err = fmt.Errorf("launch request needs an application")
+	}
+	if err == nil && !m.Allowed(req.Application) {
+		err = fmt.Errorf("%w: %s", ErrNotAllowed, req.Application)
+	}
+	if err == nil {
+		var cmd *exec.Cmd
+		if cmd, err = launchCommand(req.Application, req.Args); err == nil {
+			result.PID = int32(cmd.Process.Pid)
+			// Reap the process when it exits
+			go cmd.Wait()
+		}
+	}
+
+	m.audit.Record("process.launch", req.ViewerID, map[string]interface{}{
+		"application": req.Application,
+		"args":        req.Args,
+		"pid":         result.PID,
+		"error":       errorString(err),
+	})
+	return m.reply(result, err)
+}
+
+// authorize checks the policy and the process control permission
+func (m *Manager) authorize(capability policy.Capability) error {
+	if err := m.policy.Check(capability); err != nil {
+		return err
+	}
+	if m.permManager == nil {
+		return nil
+	}
+	granted, err := m.permManager.EnsurePermission(permissions.ProcessControl)
+	if err != nil {
+		return fmt.Errorf("failed to check process control permission: %w", err)
+	}
+	if !granted {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// Allowed reports whether an application may be launched. Allowlist entries
+// match the application exactly, so "firefox" does not allow "/tmp/firefox".
+func (m *Manager) Allowed(application string) bool {
+	return m.applications[application]
+}
+
+// kill asks a process to terminate, or kills it if force is set, and returns its name
+func (m *Manager) kill(pid int32, force bool) (string, error) {
+	if pid <= 1 || int(pid) == os.Getpid() {
+		return "", fmt.Errorf("%w: %d", ErrProtectedProcess, pid)
+	}
+
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return "", fmt.Errorf("%w: %d", ErrNoSuchProcess, pid)
+	}
+	name, _ := p.Name()
+
+	// Windows has no graceful termination signal
+	if force || runtime.GOOS == "windows" {
+		err = p.Kill()
+	} else {
+		err = p.Terminate()
+	}
+	if err != nil {
+		return name, fmt.Errorf("failed to stop process %d: %w", pid, err)
+	}
+
+	if m.verbose {
+		log.Printf("DEBUG: Stopped process %d (%s), force=%v", pid, name, force)
+	}
+	return name, nil
+}
+
+// reply sends a result, marking it successful if err is nil
+func (m *Manager) reply(result Result, err error) error {
+	result.Success = err == nil
+	result.Error = errorString(err)
+	if sendErr := m.transport.SendJSON(result); sendErr != nil {
+		log.Printf("Failed to send %s: %v", result.Type, sendErr)
+	}
+	return err
+}
+
+// launchCommand starts an application without a shell. On macOS it is
+// opened with open -a and the returned process is open, not the application.
+func launchCommand(application string, args []string) (*exec.Cmd, error) {
+	cmd := exec.Command(application, args...)
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("open", append([]string{"-a", application, "--args"}, args...)...)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to launch %s: %w", application, err)
+	}
+	return cmd, nil
+}
+
+// filter returns the processes whose name contains the filter, ignoring case
+func filter(processes []diagnostics.Process, name string) []diagnostics.Process {
+	if name == "" {
+		return processes
+	}
+	name = strings.ToLower(name)
+
+	result := []diagnostics.Process{}
+	for _, p := range processes {
+		if strings.Contains(strings.ToLower(p.Name), name) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// errorString returns the error message, or "" for a nil error
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,165 @@
+package processes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/audit"
+	"github.com/adamrobbie/go-support/pkg/client/clienttest"
+	"github.com/adamrobbie/go-support/pkg/permissions"
+	"github.com/adamrobbie/go-support/pkg/policy"
+)
+
+func TestListProcesses(t *testing.T) {
+	transport := clienttest.New()
+	NewManager(transport, Options{Policy: policy.New(policy.ListProcesses)})
+
+	if err := transport.Deliver(t, `{"type":"listProcesses","requestId":"r1"}`); err != nil {
+		t.Fatalf("listProcesses handler returned an error: %v", err)
+	}
+	reply := transport.Last()
+	if reply["type"] != MessageTypeProcessList || reply["requestId"] != "r1" {
+		t.Fatalf("Unexpected reply: %v", reply)
+	}
+
+	found := false
+	for _, p := range reply["processes"].([]interface{}) {
+		if int(p.(map[string]interface{})["pid"].(float64)) == os.Getpid() {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected the test process in the process list")
+	}
+
+	// A filter that matches nothing returns an empty list
+	transport.Deliver(t, `{"type":"listProcesses","filter":"no-such-process-name"}`)
+	if processes := transport.Last()["processes"].([]interface{}); len(processes) != 0 {
+		t.Errorf("Expected no processes, got %d", len(processes))
+	}
+}
+
+func TestPolicyAndPermissionGating(t *testing.T) {
+	transport := clienttest.New()
+	NewManager(transport, Options{Policy: policy.New()})
+
+	if err := transport.Deliver(t, `{"type":"listProcesses"}`); !errors.Is(err, policy.ErrDenied) {
+		t.Errorf("Expected policy.ErrDenied, got: %v", err)
+	}
+	if reply := transport.Last(); reply["error"] == nil || len(reply["processes"].([]interface{})) != 0 {
+		t.Errorf("Expected an error reply without processes, got %v", reply)
+	}
+
+	if err := transport.Deliver(t, `{"type":"killProcess","pid":12345}`); !errors.Is(err, policy.ErrDenied) {
+		t.Errorf("Expected policy.ErrDenied, got: %v", err)
+	}
+	if reply := transport.Last(); reply["success"] != false || reply["type"] != MessageTypeProcessKilled {
+		t.Errorf("Expected a failed processKilled reply, got %v", reply)
+	}
+
+	permManager := permissions.NewMockManager()
+	permManager.SetPermission(permissions.ProcessControl, permissions.Denied)
+	transport = clienttest.New()
+	NewManager(transport, Options{
+		Policy:       policy.New(policy.KillProcess, policy.LaunchApplication),
+		PermManager:  permManager,
+		Applications: []string{"sleep"},
+	})
+
+	for _, message := range []string{
+		`{"type":"killProcess","pid":12345}`,
+		`{"type":"launchApplication","application":"sleep","args":["0"]}`,
+	} {
+		if err := transport.Deliver(t, message); !errors.Is(err, ErrPermissionDenied) {
+			t.Errorf("Expected ErrPermissionDenied for %s, got: %v", message, err)
+		}
+		if reply := transport.Last(); reply["success"] != false {
+			t.Errorf("Expected a failed reply, got %v", reply)
+		}
+	}
+}
+
+func TestKillProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses sleep")
+	}
+
+	var trail bytes.Buffer
+	transport := clienttest.New()
+	NewManager(transport, Options{Policy: policy.New(policy.KillProcess), Audit: audit.New(&trail)})
+
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start sleep: %v", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	if err := transport.Deliver(t, fmt.Sprintf(`{"type":"killProcess","requestId":"k1","pid":%d,"viewerId":"v1"}`, cmd.Process.Pid)); err != nil {
+		t.Fatalf("killProcess handler returned an error: %v", err)
+	}
+	if reply := transport.Last(); reply["success"] != true || reply["name"] != "sleep" || reply["requestId"] != "k1" {
+		t.Errorf("Unexpected reply: %v", reply)
+	}
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The process was not stopped")
+	}
+
+	var event audit.Event
+	json.Unmarshal(trail.Bytes(), &event)
+	if event.Action != "process.kill" || event.Actor != "v1" {
+		t.Errorf("Unexpected audit event: %+v", event)
+	}
+
+	for _, pid := range []int{os.Getpid(), 1} {
+		if err := transport.Deliver(t, fmt.Sprintf(`{"type":"killProcess","pid":%d}`, pid)); !errors.Is(err, ErrProtectedProcess) {
+			t.Errorf("Expected ErrProtectedProcess for pid %d, got: %v", pid, err)
+		}
+	}
+}
+
+func TestLaunchApplication(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Applications are started directly only on Linux")
+	}
+
+	transport := clienttest.New()
+	NewManager(transport, Options{
+		Policy:       policy.New(policy.LaunchApplication),
+		Applications: []string{"sleep", "/no/such/application"},
+	})
+
+	if err := transport.Deliver(t, `{"type":"launchApplication","requestId":"l1","application":"sleep","args":["0"]}`); err != nil {
+		t.Fatalf("launchApplication handler returned an error: %v", err)
+	}
+	if reply := transport.Last(); reply["success"] != true || reply["pid"] == nil {
+		t.Errorf("Unexpected reply: %v", reply)
+	}
+
+	if err := transport.Deliver(t, `{"type":"launchApplication","application":"/no/such/application"}`); err == nil {
+		t.Error("Expected an error for a missing application")
+	}
+	if err := transport.Deliver(t, `{"type":"launchApplication"}`); err == nil {
+		t.Error("Expected an error for a request without an application")
+	}
+
+	// Applications off the allowlist are not started, even with a matching name
+	for _, application := range []string{"true", "/bin/sleep"} {
+		err := transport.Deliver(t, fmt.Sprintf(`{"type":"launchApplication","application":%q}`, application))
+		if !errors.Is(err, ErrNotAllowed) {
+			t.Errorf("Expected ErrNotAllowed for %s, got: %v", application, err)
+		}
+		if reply := transport.Last(); reply["success"] != false || reply["pid"] != nil {
+			t.Errorf("Expected a failed reply without a pid, got %v", reply)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log"
+
+	"github.com/adamrobbie/go-support/pkg/screenshot"
+	"github.com/adamrobbie/go-support/pkg/vision"
+)
+
+// ErrImageNotFound is returned by ClickImage when the template is not on screen
+var ErrImageNotFound = errors.New("image not found on screen")
+
+// ImageMatch is where ClickImage found the template
+type ImageMatch struct {
+	vision.Match
+	ScreenX int `json:"screenX"` // Clicked point in screen coordinates
+	ScreenY int `json:"screenY"`
+}
+
+// SetScreenCapture replaces the screen capture used to find images, e.g. in tests
+func (rc *RemoteController) SetScreenCapture(capture func() (image.Image, error)) {
+	rc.capture = capture
+}
+
+// FindImage captures the screen and returns the best match of a template
+// scoring at least the threshold, with its center in screen coordinates
+func (rc *RemoteController) FindImage(template image.Image, opts vision.Options) (ImageMatch, error) {
+	if err := rc.checkPermissions(); err != nil {
+		return ImageMatch{}, err
+	}
+
+	capture := rc.capture
+	if capture == nil {
+		capture = screenshot.CaptureScreen
+	}
+	img, err := capture()
+	if err != nil {
+		return ImageMatch{}, fmt.Errorf("failed to capture screen: %w", err)
+	}
+
+	matches, err := vision.Find(img, template, opts)
+	if err != nil {
+		return ImageMatch{}, err
+	}
+	best, ok := vision.Best(matches)
+	if !ok {
+		return ImageMatch{}, ErrImageNotFound
+	}
+
+	// Captures are in physical pixels, which differ from screen points on HiDPI displays
+	x, y := best.Center()
+	width, height := robotgoGetScreenSizeFunc()
+	bounds := img.Bounds()
+	if width > 0 && height > 0 && bounds.Dx() > 0 && bounds.Dy() > 0 {
+		x = x * width / bounds.Dx()
+		y = y * height / bounds.Dy()
+	}
+	return ImageMatch{Match: best, ScreenX: x, ScreenY: y}, nil
+}
+
+// ClickImage finds a template on screen and clicks the center of the best match
+func (rc *RemoteController) ClickImage(template image.Image, opts vision.Options, button MouseButton, double bool) (ImageMatch, error) {
+	match, err := rc.FindImage(template, opts)
+	if err != nil {
+		return match, err
+	}
+	if rc.verbose {
+		log.Printf("Found image at (%d,%d) with score %.3f, clicking (%d,%d)", match.X, match.Y, match.Score, match.ScreenX, match.ScreenY)
+	}
+
+	if button == "" {
+		button = LeftButton
+	}
+	err = rc.ExecuteMouseEvent(MouseEvent{Action: MouseClick, X: match.ScreenX, Y: match.ScreenY, Button: button, Double: double})
+	if err != nil {
+		return match, fmt.Errorf("failed to click image: %w", err)
+	}
+	return match, nil
+}
@@ -0,0 +1,89 @@
+package remote
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/adamrobbie/go-support/pkg/vision"
+)
+
+// stubRobotgo replaces the RobotGo functions used for clicking and restores them after the test
+func stubRobotgo(t *testing.T, width, height int) (moves *[]image.Point, clicks *int) {
+	t.Helper()
+	screenSize, getPos, move, click := robotgoGetScreenSizeFunc, robotgoGetMousePosFunc, robotgoMoveMouseFunc, robotgoClickFunc
+	t.Cleanup(func() {
+		robotgoGetScreenSizeFunc, robotgoGetMousePosFunc, robotgoMoveMouseFunc, robotgoClickFunc = screenSize, getPos, move, click
+	})
+
+	moves, clicks = &[]image.Point{}, new(int)
+	var pos image.Point
+	robotgoGetScreenSizeFunc = func() (int, int) { return width, height }
+	robotgoGetMousePosFunc = func() (int, int) { return pos.X, pos.Y }
+	robotgoMoveMouseFunc = func(x, y int) {
+		pos = image.Pt(x, y)
+		*moves = append(*moves, pos)
+	}
+	robotgoClickFunc = func(button string, double bool) { *clicks++ }
+	return moves, clicks
+}
+
+// imageScreen returns a patterned screen with a marker drawn at x, y
+func imageScreen(width, height, x, y int) (*image.RGBA, *image.RGBA) {
+	screen := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range screen.Pix {
+		screen.Pix[i] = uint8((i * 37) % 251)
+	}
+	marker := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	draw.Draw(marker, marker.Bounds(), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	draw.Draw(marker, image.Rect(8, 8, 24, 24), image.NewUniform(color.RGBA{0, 0, 255, 255}), image.Point{}, draw.Src)
+	draw.Draw(screen, image.Rect(x, y, x+32, y+32), marker, image.Point{}, draw.Src)
+	return screen, marker
+}
+
+func TestClickImage(t *testing.T) {
+	// A HiDPI capture at twice the screen size in points
+	moves, clicks := stubRobotgo(t, 200, 150)
+	screen, marker := imageScreen(400, 300, 100, 60)
+
+	rc := NewRemoteController(nil, false)
+	rc.SetScreenCapture(func() (image.Image, error) { return screen, nil })
+
+	match, err := rc.ClickImage(marker, vision.Options{Scales: []float64{1}}, "", false)
+	if err != nil {
+		t.Fatalf("ClickImage() returned an error: %v", err)
+	}
+	if match.X != 100 || match.Y != 60 {
+		t.Errorf("Expected the match at (100,60), got (%d,%d)", match.X, match.Y)
+	}
+	if match.ScreenX != 58 || match.ScreenY != 38 {
+		t.Errorf("Expected the click at (58,38), got (%d,%d)", match.ScreenX, match.ScreenY)
+	}
+	if *clicks != 1 || len(*moves) == 0 || (*moves)[len(*moves)-1] != image.Pt(58, 38) {
+		t.Errorf("Expected one click at (58,38), got %d clicks after moves %v", *clicks, *moves)
+	}
+}
+
+func TestClickImageNotFound(t *testing.T) {
+	_, clicks := stubRobotgo(t, 400, 300)
+	screen, _ := imageScreen(400, 300, 100, 60)
+	_, other := imageScreen(10, 10, 0, 0)
+	draw.Draw(other, image.Rect(0, 0, 16, 32), image.NewUniform(color.RGBA{0, 255, 0, 255}), image.Point{}, draw.Src)
+
+	rc := NewRemoteController(nil, false)
+	rc.SetScreenCapture(func() (image.Image, error) { return screen, nil })
+
+	if _, err := rc.ClickImage(other, vision.Options{Scales: []float64{1}}, LeftButton, false); !errors.Is(err, ErrImageNotFound) {
+		t.Errorf("Expected ErrImageNotFound, got %v", err)
+	}
+	if *clicks != 0 {
+		t.Errorf("Expected no clicks, got %d", *clicks)
+	}
+
+	rc.SetScreenCapture(func() (image.Image, error) { return nil, errors.New("no display") })
+	if _, err := rc.FindImage(other, vision.Options{Scales: []float64{1}}); err == nil || errors.Is(err, ErrImageNotFound) {
+		t.Errorf("Expected a capture error, got %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package remote
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"unicode/utf8"
+)
+
+// DOMEvent is a browser MouseEvent, WheelEvent or KeyboardEvent as JSON, with
+// the property names of the DOM
+type DOMEvent struct {
+	Type string `json:"type"` // mousemove, mousedown, mouseup, wheel, keydown, keyup, ...
+
+	// Mouse events, with coordinates relative to the element showing the screen
+	OffsetX float64 `json:"offsetX"`
+	OffsetY float64 `json:"offsetY"`
+	Button  int     `json:"button"`  // 0 left, 1 middle, 2 right
+	Buttons int     `json:"buttons"` // Pressed buttons: 1 left, 2 right, 4 middle
+
+	// Wheel events
+	DeltaX    float64 `json:"deltaX"`
+	DeltaY    float64 `json:"deltaY"`
+	DeltaMode int     `json:"deltaMode"` // 0 pixels, 1 lines, 2 pages
+
+	// Keyboard events
+	Key         string `json:"key"`
+	Code        string `json:"code"`
+	Repeat      bool   `json:"repeat"`
+	IsComposing bool   `json:"isComposing"`
+
+	// Modifier state of all events
+	ShiftKey bool `json:"shiftKey"`
+	CtrlKey  bool `json:"ctrlKey"`
+	AltKey   bool `json:"altKey"`
+	MetaKey  bool `json:"metaKey"`
+}
+
+// View is the size the viewer displays the screen at, for scaling coordinates
+// to the screen. Coordinates are screen pixels if it is zero.
+type View struct {
+	Width  int `json:"viewWidth"`
+	Height int `json:"viewHeight"`
+}
+
+// DOM button numbers and the bits of the buttons mask
+const (
+	domLeft   = 0
+	domMiddle = 1
+	domRight  = 2
+
+	// linesPerPage converts wheel deltas in pages to lines
+	linesPerPage = 20
+)
+
+// domButtons maps DOM button numbers to mouse buttons and their bit in the buttons mask
+var domButtons = map[int]struct {
+	button MouseButton
+	mask   int
+}{
+	domLeft:   {LeftButton, 1},
+	domMiddle: {MiddleButton, 4},
+	domRight:  {RightButton, 2},
+}
+
+// modifierKeys are the modifiers tracked across events, in the order they are pressed
+var modifierKeys = []string{"ctrl", "alt", "shift", "cmd"}
+
+// InputExecutor executes the events translated from DOM events;
+// RemoteController implements it
+type InputExecutor interface {
+	ExecuteMouseEvent(event MouseEvent) error
+	ExecuteKeyboardEvent(event KeyboardEvent) error
+	GetScreenSize() (int, int, error)
+}
+
+// Translator translates DOM input events into mouse and keyboard events. It
+// keeps the modifiers, keys and buttons it holds down on the host in step with
+// the state each DOM event reports, so a key released while the viewer did
+// not have focus is released on the host with the next event.
+type Translator struct {
+	executor InputExecutor
+	verbose  bool
+
+	mu        sync.Mutex
+	modifiers map[string]bool // Held modifiers by robotgo name
+	keys      map[string]bool // Held keys by code, or key if the event had no code
+	buttons   int             // Held buttons as a DOM buttons mask
+}
+
+// NewTranslator creates a translator that executes events through an executor
+func NewTranslator(executor InputExecutor, verbose bool) *Translator {
+	return &Translator{
+		executor:  executor,
+		verbose:   verbose,
+		modifiers: make(map[string]bool),
+		keys:      make(map[string]bool),
+	}
+}
+
+// SupportsDOMEvent reports whether Handle accepts DOM events of a type
+func SupportsDOMEvent(eventType string) bool {
+	switch eventType {
+	case "mousemove", "mousedown", "mouseup", "wheel", "keydown", "keyup",
+		"click", "dblclick", "contextmenu", "auxclick", "keypress":
+		return true
+	}
+	return false
+}
+
+// Handle translates a DOM event and executes it. Events that follow from
+// others, such as click after mousedown and mouseup, are ignored.
+func (t *Translator) Handle(event DOMEvent, view View) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.verbose {
+		log.Printf("DEBUG: Translating DOM %s event", event.Type)
+	}
+
+	switch event.Type {
+	case "mousemove", "mousedown", "mouseup":
+		return t.handleMouse(event, view)
+	case "wheel":
+		return t.handleWheel(event, view)
+	case "keydown":
+		return t.handleKeyDown(event)
+	case "keyup":
+		return t.handleKeyUp(event)
+	case "click", "dblclick", "contextmenu", "auxclick", "keypress":
+		return nil
+	default:
+		return fmt.Errorf("unsupported DOM event type: %q", event.Type)
+	}
+}
+
+// Reset releases every modifier, key and button the translator holds down
+func (t *Translator) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.releaseButtons(0)
+	for key := range t.keys {
+		t.executor.ExecuteKeyboardEvent(KeyboardEvent{Action: KeyUp, Key: key})
+	}
+	t.keys = make(map[string]bool)
+	t.syncModifiers(map[string]bool{})
+}
+
+// handleMouse moves the pointer and presses or releases a button
+func (t *Translator) handleMouse(event DOMEvent, view View) error {
+	if err := t.syncModifiers(eventModifiers(event)); err != nil {
+		return err
+	}
+	x, y, err := t.position(event, view)
+	if err != nil {
+		return err
+	}
+	if err := t.executor.ExecuteMouseEvent(MouseEvent{Action: MouseMove, X: x, Y: y}); err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case "mousedown":
+		b, ok := domButtons[event.Button]
+		if !ok {
+			return fmt.Errorf("unsupported mouse button %d", event.Button)
+		}
+		if err := t.executor.ExecuteMouseEvent(MouseEvent{Action: MouseDown, Button: b.button}); err != nil {
+			return err
+		}
+		t.buttons |= b.mask
+		return t.releaseButtons(event.Buttons | b.mask)
+	case "mouseup":
+		b, ok := domButtons[event.Button]
+		if !ok {
+			return fmt.Errorf("unsupported mouse button %d", event.Button)
+		}
+		if t.buttons&b.mask != 0 {
+			t.buttons &^= b.mask
+			if err := t.executor.ExecuteMouseEvent(MouseEvent{Action: MouseUp, Button: b.button}); err != nil {
+				return err
+			}
+		}
+	}
+	return t.releaseButtons(event.Buttons)
+}
+
+// releaseButtons releases held buttons missing from a DOM buttons mask
+func (t *Translator) releaseButtons(pressed int) error {
+	for _, b := range domButtons {
+		if t.buttons&b.mask == 0 || pressed&b.mask != 0 {
+			continue
+		}
+		t.buttons &^= b.mask
+		if err := t.executor.ExecuteMouseEvent(MouseEvent{Action: MouseUp, Button: b.button}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleWheel scrolls at the pointer position. Ctrl+wheel, which browsers
+// report for touchpad pinches, zooms since ctrl is held.
+func (t *Translator) handleWheel(event DOMEvent, view View) error {
+	if err := t.syncModifiers(eventModifiers(event)); err != nil {
+		return err
+	}
+	x, y, err := t.position(event, view)
+	if err != nil {
+		return err
+	}
+
+	scroll := MouseEvent{Action: MouseScroll, X: x, Y: y}
+	dx, dy := event.DeltaX, event.DeltaY
+	switch event.DeltaMode {
+	case 0:
+		scroll.Unit = ScrollPixel
+	case 1:
+		scroll.Unit = ScrollLine
+	case 2:
+		scroll.Unit = ScrollLine
+		dx, dy = dx*linesPerPage, dy*linesPerPage
+	default:
+		return fmt.Errorf("unknown wheel delta mode %d", event.DeltaMode)
+	}
+	// Browsers report positive deltaY for scrolling down, we scroll up for positive amounts
+	scroll.AmountX = int(math.Round(dx))
+	scroll.Amount = -int(math.Round(dy))
+	if scroll.Amount == 0 && scroll.AmountX == 0 {
+		return nil
+	}
+	return t.executor.ExecuteMouseEvent(scroll)
+}
+
+// handleKeyDown types the character of a key, or holds the key down if it
+// has no character or is part of a shortcut
+func (t *Translator) handleKeyDown(event DOMEvent) error {
+	// The input method composes text and reports it with its last event
+	if event.IsComposing {
+		return nil
+	}
+	if isModifier(event.Key) {
+		return t.syncModifiers(eventModifiers(event))
+	}
+
+	if text, ok := eventText(event); ok {
+		// The character already includes shift and AltGr, so type it without modifiers
+		if err := t.syncModifiers(map[string]bool{}); err != nil {
+			return err
+		}
+		return t.executor.ExecuteKeyboardEvent(KeyboardEvent{Action: KeyType, Text: text})
+	}
+
+	if err := t.syncModifiers(eventModifiers(event)); err != nil {
+		return err
+	}
+	key := eventKey(event)
+	if err := t.executor.ExecuteKeyboardEvent(KeyboardEvent{Action: KeyDown, Key: key}); err != nil {
+		return err
+	}
+	t.keys[key] = true
+	return nil
+}
+
+// handleKeyUp releases a key held down by handleKeyDown
+func (t *Translator) handleKeyUp(event DOMEvent) error {
+	if isModifier(event.Key) {
+		return t.syncModifiers(eventModifiers(event))
+	}
+	key := eventKey(event)
+	if !t.keys[key] {
+		// Typed characters are not held
+		return nil
+	}
+	delete(t.keys, key)
+	return t.executor.ExecuteKeyboardEvent(KeyboardEvent{Action: KeyUp, Key: key})
+}
+
+// syncModifiers presses and releases modifiers until exactly the wanted ones are held
+func (t *Translator) syncModifiers(want map[string]bool) error {
+	for i := len(modifierKeys) - 1; i >= 0; i-- {
+		key := modifierKeys[i]
+		if t.modifiers[key] && !want[key] {
+			if err := t.executor.ExecuteKeyboardEvent(KeyboardEvent{Action: KeyUp, Key: key}); err != nil {
+				return err
+			}
+			delete(t.modifiers, key)
+		}
+	}
+	for _, key := range modifierKeys {
+		if want[key] && !t.modifiers[key] {
+			if err := t.executor.ExecuteKeyboardEvent(KeyboardEvent{Action: KeyDown, Key: key}); err != nil {
+				return err
+			}
+			t.modifiers[key] = true
+		}
+	}
+	return nil
+}
+
+// position returns the screen position of a mouse event
+func (t *Translator) position(event DOMEvent, view View) (int, int, error) {
+	width, height, err := t.executor.GetScreenSize()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get screen size: %w", err)
+	}
+	x, y := event.OffsetX, event.OffsetY
+	if view.Width > 0 && view.Height > 0 {
+		x = x * float64(width) / float64(view.Width)
+		y = y * float64(height) / float64(view.Height)
+	}
+	clamp := func(v float64, size int) int {
+		return min(max(int(math.Round(v)), 0), max(size-1, 0))
+	}
+	return clamp(x, width), clamp(y, height), nil
+}
+
+// eventModifiers returns the modifiers an event reports as held
+func eventModifiers(event DOMEvent) map[string]bool {
+	return map[string]bool{
+		"shift": event.ShiftKey,
+		"ctrl":  event.CtrlKey,
+		"alt":   event.AltKey,
+		"cmd":   event.MetaKey,
+	}
+}
+
+// isModifier returns true for the key values of modifier keys
+func isModifier(key string) bool {
+	switch key {
+	case "Shift", "Control", "Alt", "AltGraph", "Meta", "OS":
+		return true
+	}
+	return false
+}
+
+// eventText returns the character a key event types, if it types one rather
+// than triggering a shortcut. Ctrl+Alt is AltGr on Windows, so it types too.
+func eventText(event DOMEvent) (string, bool) {
+	if utf8.RuneCountInString(event.Key) != 1 {
+		return "", false
+	}
+	if event.MetaKey || (event.CtrlKey != event.AltKey) {
+		return "", false
+	}
+	return event.Key, true
+}
+
+// eventKey returns the key a key event presses, preferring its physical code
+func eventKey(event DOMEvent) string {
+	if event.Code != "" && event.Code != "Unidentified" {
+		return event.Code
+	}
+	return event.Key
+}
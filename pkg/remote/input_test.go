@@ -0,0 +1,163 @@
+package remote
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// fakeExecutor records the events a translator executes
+type fakeExecutor struct {
+	events []string
+}
+
+func (f *fakeExecutor) ExecuteMouseEvent(event MouseEvent) error {
+	switch event.Action {
+	case MouseMove:
+		f.events = append(f.events, fmt.Sprintf("move %d,%d", event.X, event.Y))
+	case MouseScroll:
+		f.events = append(f.events, fmt.Sprintf("scroll %d,%d %s at %d,%d", event.AmountX, event.Amount, event.Unit, event.X, event.Y))
+	default:
+		f.events = append(f.events, fmt.Sprintf("%s %s", event.Action, event.Button))
+	}
+	return nil
+}
+
+func (f *fakeExecutor) ExecuteKeyboardEvent(event KeyboardEvent) error {
+	if event.Action == KeyType {
+		f.events = append(f.events, "type "+event.Text)
+	} else {
+		f.events = append(f.events, fmt.Sprintf("key%s %s", event.Action, event.Key))
+	}
+	return nil
+}
+
+func (f *fakeExecutor) GetScreenSize() (int, int, error) {
+	return 1920, 1080, nil
+}
+
+// handle sends events to a translator and returns what it executed
+func handle(t *testing.T, tr *Translator, f *fakeExecutor, view View, events ...DOMEvent) []string {
+	t.Helper()
+	f.events = nil
+	for _, event := range events {
+		if err := tr.Handle(event, view); err != nil {
+			t.Fatalf("Handle(%+v) returned an error: %v", event, err)
+		}
+	}
+	return f.events
+}
+
+func TestTranslateMouse(t *testing.T) {
+	f := &fakeExecutor{}
+	tr := NewTranslator(f, false)
+	view := View{Width: 960, Height: 540}
+
+	got := handle(t, tr, f, view,
+		DOMEvent{Type: "mousedown", OffsetX: 100, OffsetY: 50, Button: 0, Buttons: 1},
+		DOMEvent{Type: "mousemove", OffsetX: 200.4, OffsetY: 60, Buttons: 1},
+		DOMEvent{Type: "mouseup", OffsetX: 200, OffsetY: 60, Button: 0},
+		DOMEvent{Type: "click", OffsetX: 200, OffsetY: 60},
+	)
+	want := []string{"move 200,100", "down left", "move 401,120", "move 400,120", "up left"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// A button released outside the viewer is released with the next event
+	got = handle(t, tr, f, View{},
+		DOMEvent{Type: "mousedown", OffsetX: 10, OffsetY: 10, Button: 2, Buttons: 2},
+		DOMEvent{Type: "mousemove", OffsetX: 5000, OffsetY: -5, Buttons: 0},
+	)
+	want = []string{"move 10,10", "down right", "move 1919,0", "up right"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	if err := tr.Handle(DOMEvent{Type: "mousedown", Button: 3}, view); err == nil {
+		t.Error("Expected an unsupported button to be rejected")
+	}
+	if err := tr.Handle(DOMEvent{Type: "pointerdown"}, view); err == nil {
+		t.Error("Expected an unsupported event type to be rejected")
+	}
+}
+
+func TestTranslateWheel(t *testing.T) {
+	f := &fakeExecutor{}
+	tr := NewTranslator(f, false)
+
+	got := handle(t, tr, f, View{},
+		DOMEvent{Type: "wheel", OffsetX: 10, OffsetY: 20, DeltaY: 120.4},
+		DOMEvent{Type: "wheel", OffsetX: 10, OffsetY: 20, DeltaX: -2, DeltaMode: 1},
+		DOMEvent{Type: "wheel", OffsetX: 10, OffsetY: 20, DeltaY: -5, CtrlKey: true},
+	)
+	want := []string{
+		"scroll 0,-120 pixel at 10,20",
+		"scroll -2,0 line at 10,20",
+		"keydown ctrl", "scroll 0,5 pixel at 10,20",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestTranslateKeyboard(t *testing.T) {
+	f := &fakeExecutor{}
+	tr := NewTranslator(f, false)
+
+	// Shift+A types the character, with shift released for the typing
+	got := handle(t, tr, f, View{},
+		DOMEvent{Type: "keydown", Key: "Shift", Code: "ShiftLeft", ShiftKey: true},
+		DOMEvent{Type: "keydown", Key: "A", Code: "KeyA", ShiftKey: true},
+		DOMEvent{Type: "keyup", Key: "A", Code: "KeyA", ShiftKey: true},
+		DOMEvent{Type: "keyup", Key: "Shift", Code: "ShiftLeft"},
+	)
+	want := []string{"keydown shift", "keyup shift", "type A"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// Ctrl+C is a shortcut, pressed by the physical key
+	got = handle(t, tr, f, View{},
+		DOMEvent{Type: "keydown", Key: "Control", Code: "ControlLeft", CtrlKey: true},
+		DOMEvent{Type: "keydown", Key: "c", Code: "KeyC", CtrlKey: true},
+		DOMEvent{Type: "keyup", Key: "c", Code: "KeyC", CtrlKey: true},
+		DOMEvent{Type: "keyup", Key: "Control", Code: "ControlLeft"},
+	)
+	want = []string{"keydown ctrl", "keydown KeyC", "keyup KeyC", "keyup ctrl"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// Ctrl+Alt is AltGr, so it types; composition is left to the final event
+	got = handle(t, tr, f, View{},
+		DOMEvent{Type: "keydown", Key: "@", Code: "KeyQ", CtrlKey: true, AltKey: true},
+		DOMEvent{Type: "keydown", Key: "Process", Code: "KeyN", IsComposing: true},
+	)
+	want = []string{"type @"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestTranslatorReset(t *testing.T) {
+	f := &fakeExecutor{}
+	tr := NewTranslator(f, false)
+	handle(t, tr, f, View{},
+		DOMEvent{Type: "keydown", Key: "ArrowDown", Code: "ArrowDown", MetaKey: true},
+		DOMEvent{Type: "mousedown", Button: 1, Buttons: 4, MetaKey: true},
+	)
+
+	f.events = nil
+	tr.Reset()
+	want := []string{"up middle", "keyup ArrowDown", "keyup cmd"}
+	if !reflect.DeepEqual(f.events, want) {
+		t.Errorf("Expected %q, got %q", want, f.events)
+	}
+
+	f.events = nil
+	tr.Reset()
+	if len(f.events) != 0 {
+		t.Errorf("Expected nothing to release twice, got %q", f.events)
+	}
+}
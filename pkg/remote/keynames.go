@@ -0,0 +1,125 @@
+package remote
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// codeKeys maps the browser KeyboardEvent.code values of character keys to the
+// robotgo name of the key in the same position on a US keyboard
+var codeKeys = map[string]string{
+	"Backquote":    "`",
+	"Minus":        "-",
+	"Equal":        "=",
+	"BracketLeft":  "[",
+	"BracketRight": "]",
+	"Backslash":    "\\",
+	"Semicolon":    ";",
+	"Quote":        "'",
+	"Comma":        ",",
+	"Period":       ".",
+	"Slash":        "/",
+}
+
+// keyNames maps browser KeyboardEvent.code and KeyboardEvent.key values, and
+// common aliases, to robotgo key names
+var keyNames = map[string]string{
+	// Whitespace and editing
+	" ":         "space",
+	"Space":     "space",
+	"Spacebar":  "space",
+	"Enter":     "enter",
+	"Return":    "enter",
+	"Tab":       "tab",
+	"Backspace": "backspace",
+	"Delete":    "delete",
+	"Insert":    "insert",
+	"Escape":    "escape",
+	"Esc":       "escape",
+
+	// Navigation
+	"ArrowUp":    "up",
+	"ArrowDown":  "down",
+	"ArrowLeft":  "left",
+	"ArrowRight": "right",
+	"Home":       "home",
+	"End":        "end",
+	"PageUp":     "pageup",
+	"PageDown":   "pagedown",
+
+	// Modifiers, as codes for one side and as keys for either
+	"ShiftLeft":    "lshift",
+	"ShiftRight":   "rshift",
+	"ControlLeft":  "lctrl",
+	"ControlRight": "rctrl",
+	"AltLeft":      "lalt",
+	"AltRight":     "ralt",
+	"MetaLeft":     "lcmd",
+	"MetaRight":    "rcmd",
+	"OSLeft":       "lcmd",
+	"OSRight":      "rcmd",
+	"Shift":        "shift",
+	"Control":      "ctrl",
+	"Alt":          "alt",
+	"AltGraph":     "ralt",
+	"Meta":         "cmd",
+	"OS":           "cmd",
+	"command":      "cmd",
+	"win":          "cmd",
+	"super":        "cmd",
+	"CapsLock":     "capslock",
+
+	// Numeric keypad
+	"NumLock":        "num_lock",
+	"NumpadEnter":    "num_enter",
+	"NumpadEqual":    "num_equal",
+	"NumpadAdd":      "+",
+	"NumpadSubtract": "-",
+	"NumpadMultiply": "*",
+	"NumpadDivide":   "/",
+	"NumpadDecimal":  ".",
+
+	// System and media
+	"ContextMenu":        "menu",
+	"PrintScreen":        "printscreen",
+	"AudioVolumeMute":    "audio_mute",
+	"AudioVolumeDown":    "audio_vol_down",
+	"AudioVolumeUp":      "audio_vol_up",
+	"MediaPlayPause":     "audio_play",
+	"MediaStop":          "audio_stop",
+	"MediaTrackNext":     "audio_next",
+	"MediaTrackPrevious": "audio_prev",
+}
+
+// NormalizeKey converts a browser KeyboardEvent.code or KeyboardEvent.key
+// value to a robotgo key name. Codes of character keys become the key in the
+// same position on a US keyboard. Names robotgo already knows, and single
+// characters, are returned unchanged apart from case.
+func NormalizeKey(name string) string {
+	if key, ok := codeKey(name); ok {
+		return key
+	}
+	if key, ok := keyNames[name]; ok {
+		return key
+	}
+	if digit, ok := strings.CutPrefix(name, "Numpad"); ok && len(digit) == 1 && digit[0] >= '0' && digit[0] <= '9' {
+		return "num" + digit
+	}
+	// Single characters keep their case, since "A" and "a" differ when typed
+	if utf8.RuneCountInString(name) <= 1 {
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+// codeKey returns the US key name for the KeyboardEvent.code of a character key
+func codeKey(code string) (string, bool) {
+	if letter, ok := strings.CutPrefix(code, "Key"); ok && len(letter) == 1 && letter[0] >= 'A' && letter[0] <= 'Z' {
+		return strings.ToLower(letter), true
+	}
+	if digit, ok := strings.CutPrefix(code, "Digit"); ok && len(digit) == 1 && digit[0] >= '0' && digit[0] <= '9' {
+		return digit, true
+	}
+	key, ok := codeKeys[code]
+	return key, ok
+}
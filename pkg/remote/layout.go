@@ -0,0 +1,289 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// usKeys lists the character keys of a keyboard in rows, by their name on a
+// US keyboard. Layouts give the characters of the same keys in this order.
+const usKeys = "`1234567890-=" + "qwertyuiop[]\\" + "asdfghjkl;'" + "zxcvbnm,./"
+
+// noChar marks a key that has no character at a level, or a dead key
+const noChar = '□'
+
+// keystroke is a key with the modifiers that make it produce a character
+type keystroke struct {
+	key   string // robotgo name of the key
+	shift bool
+	altGr bool
+}
+
+// Layout maps characters to the keys that type them on a keyboard layout
+type Layout struct {
+	Name      string
+	keys      map[rune]keystroke
+	positions map[string]string // US key name to the robotgo name of the key in that position
+}
+
+// newLayout builds a layout from the characters its keys produce unshifted,
+// with shift and with AltGr, in the order of usKeys. robotgo finds keys by the
+// ASCII character they produce unshifted on the active layout, so a key whose
+// unshifted character is not ASCII cannot be pressed and its characters are
+// left out.
+func newLayout(name, base, shift, altGr string) *Layout {
+	l := &Layout{
+		Name:      name,
+		keys:      make(map[rune]keystroke),
+		positions: make(map[string]string),
+	}
+	us := []rune(usKeys)
+	levels := [][]rune{[]rune(base), []rune(shift), []rune(altGr)}
+	if len(levels[0]) != len(us) || len(levels[1]) != len(us) || (altGr != "" && len(levels[2]) != len(us)) {
+		panic(fmt.Sprintf("layout %s does not have %d keys", name, len(us)))
+	}
+
+	// macOS puts the AltGr characters of PC layouts on other Option combinations
+	if runtime.GOOS == "darwin" {
+		levels = levels[:2]
+	}
+
+	for i, r := range levels[0] {
+		if r == noChar || r >= utf8.RuneSelf {
+			continue
+		}
+		key := string(r)
+		l.positions[string(us[i])] = key
+		for level, chars := range levels {
+			if len(chars) == 0 || chars[i] == noChar {
+				continue
+			}
+			if _, ok := l.keys[chars[i]]; ok {
+				continue
+			}
+			l.keys[chars[i]] = keystroke{key: key, shift: level == 1, altGr: level == 2}
+		}
+	}
+	return l
+}
+
+// keystroke returns the key that types a character
+func (l *Layout) keystroke(r rune) (keystroke, bool) {
+	ks, ok := l.keys[r]
+	return ks, ok
+}
+
+// positionKey returns the robotgo name of the key in the position of a key on
+// a US keyboard
+func (l *Layout) positionKey(usKey string) (string, bool) {
+	key, ok := l.positions[usKey]
+	return key, ok
+}
+
+// layouts are the built-in keyboard layouts, the PC variants where they differ
+var layouts = map[string]*Layout{
+	"us": newLayout("us",
+		usKeys,
+		"~!@#$%^&*()_+"+"QWERTYUIOP{}|"+"ASDFGHJKL:\""+"ZXCVBNM<>?",
+		""),
+	"uk": newLayout("uk",
+		"`1234567890-="+"qwertyuiop[]#"+"asdfghjkl;'"+"zxcvbnm,./",
+		"¬!\"£$%^&*()_+"+"QWERTYUIOP{}~"+"ASDFGHJKL:@"+"ZXCVBNM<>?",
+		"¦□□□€□□□□□□□□"+"□□é□□□úíó□□□□"+"á□□□□□□□□□□"+"□□□□□□□□□□"),
+	"de": newLayout("de",
+		"□1234567890ß□"+"qwertzuiopü+#"+"asdfghjklöä"+"yxcvbnm,.-",
+		"°!\"§$%&/()=?□"+"QWERTZUIOPÜ*'"+"ASDFGHJKLÖÄ"+"YXCVBNM;:_",
+		"□□²³□□□{[]}\\□"+"@□€□□□□□□□□~□"+"□□□□□□□□□□□"+"□□□□□□µ□□□"),
+	"fr": newLayout("fr",
+		"²&é\"'(-è_çà)="+"azertyuiop□$*"+"qsdfghjklmù"+"wxcvbn,;:!",
+		"□1234567890°+"+"AZERTYUIOP□£µ"+"QSDFGHJKLM%"+"WXCVBN?./§",
+		"□□□#{[|□\\□@]}"+"□□€□□□□□□□□¤□"+"□□□□□□□□□□□"+"□□□□□□□□□□"),
+	"es": newLayout("es",
+		"º1234567890'¡"+"qwertyuiop□+ç"+"asdfghjklñ□"+"zxcvbnm,.-",
+		"ª!\"·$%&/()=?¿"+"QWERTYUIOP□*Ç"+"ASDFGHJKLÑ□"+"ZXCVBNM;:_",
+		"\\|@#~€¬□□□□□□"+"□□€□□□□□□□[]}"+"□□□□□□□□□□{"+"□□□□□□□□□□"),
+}
+
+// layoutAliases maps other names of the built-in layouts to them
+var layoutAliases = map[string]string{
+	"gb": "uk",
+	"en": "us",
+}
+
+// LookupLayout returns a built-in keyboard layout by name, e.g. us or de
+func LookupLayout(name string) (*Layout, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := layoutAliases[name]; ok {
+		name = alias
+	}
+	l, ok := layouts[name]
+	return l, ok
+}
+
+// LayoutNames returns the names of the built-in keyboard layouts
+func LayoutNames() []string {
+	names := make([]string, 0, len(layouts))
+	for name := range layouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// layoutCommandFunc runs a command that reports the keyboard layout; tests replace it
+var layoutCommandFunc = func(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).Output()
+}
+
+// DetectLayout returns the name of the active keyboard layout of the system
+func DetectLayout() (string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		out, err := layoutCommandFunc("setxkbmap", "-query")
+		if err != nil {
+			return "", fmt.Errorf("failed to query X keyboard layout: %w", err)
+		}
+		return parseXkbLayout(out)
+	case "darwin":
+		out, err := layoutCommandFunc("defaults", "read", "com.apple.HIToolbox", "AppleSelectedInputSources")
+		if err != nil {
+			return "", fmt.Errorf("failed to read macOS input sources: %w", err)
+		}
+		return parseMacLayout(out)
+	case "windows":
+		out, err := layoutCommandFunc("powershell", "-NoProfile", "-Command", "(Get-WinUserLanguageList)[0].InputMethodTips[0]")
+		if err != nil {
+			return "", fmt.Errorf("failed to read Windows input method: %w", err)
+		}
+		return parseWindowsLayout(out)
+	default:
+		return "", fmt.Errorf("keyboard layout detection is not supported on %s", runtime.GOOS)
+	}
+}
+
+// parseXkbLayout returns the first layout from the output of setxkbmap -query
+func parseXkbLayout(out []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "layout:")
+		if !ok {
+			continue
+		}
+		layout, _, _ := strings.Cut(strings.TrimSpace(value), ",")
+		if layout != "" {
+			return layout, nil
+		}
+	}
+	return "", fmt.Errorf("no layout in setxkbmap output")
+}
+
+// macLayoutPattern finds the layout name in the macOS input sources
+var macLayoutPattern = regexp.MustCompile(`"KeyboardLayout Name"\s*=\s*"?([^";]+)"?;`)
+
+// macLayouts maps macOS layout names to the built-in layouts
+var macLayouts = map[string]string{
+	"U.S.":    "us",
+	"US":      "us",
+	"ABC":     "us",
+	"British": "uk",
+	"German":  "de",
+	"French":  "fr",
+	"Spanish": "es",
+}
+
+// parseMacLayout returns the layout from the macOS input sources
+func parseMacLayout(out []byte) (string, error) {
+	m := macLayoutPattern.FindSubmatch(out)
+	if m == nil {
+		return "", fmt.Errorf("no keyboard layout in macOS input sources")
+	}
+	name := strings.TrimSpace(string(m[1]))
+	if layout, ok := macLayouts[name]; ok {
+		return layout, nil
+	}
+	return name, nil
+}
+
+// windowsLayouts maps the language part of Windows keyboard layout IDs to the built-in layouts
+var windowsLayouts = map[string]string{
+	"0409": "us",
+	"0809": "uk",
+	"0407": "de",
+	"040c": "fr",
+	"040a": "es",
+	"0c0a": "es",
+}
+
+// parseWindowsLayout returns the layout from an input method tip such as 0407:00000407
+func parseWindowsLayout(out []byte) (string, error) {
+	tip := strings.ToLower(strings.TrimSpace(string(out)))
+	_, klid, ok := strings.Cut(tip, ":")
+	if !ok || len(klid) < 4 {
+		return "", fmt.Errorf("unexpected input method %q", tip)
+	}
+	id := klid[len(klid)-4:]
+	if layout, ok := windowsLayouts[id]; ok {
+		return layout, nil
+	}
+	return klid, nil
+}
+
+// keyboardState holds the layout text is typed with
+type keyboardState struct {
+	mu       sync.Mutex
+	layout   *Layout
+	resolved bool // Whether the layout was set or detected
+}
+
+// SetKeyboardLayout sets the layout text is typed with, instead of detecting
+// it. An empty name or auto detects the layout again.
+func (rc *RemoteController) SetKeyboardLayout(name string) error {
+	rc.keyboard.mu.Lock()
+	defer rc.keyboard.mu.Unlock()
+	if name == "" || strings.EqualFold(name, "auto") {
+		rc.keyboard.layout, rc.keyboard.resolved = nil, false
+		return nil
+	}
+	l, ok := LookupLayout(name)
+	if !ok {
+		return fmt.Errorf("unknown keyboard layout %q, expected one of %s", name, strings.Join(LayoutNames(), ", "))
+	}
+	rc.keyboard.layout, rc.keyboard.resolved = l, true
+	return nil
+}
+
+// keyboardLayout returns the layout text is typed with, detecting it on first
+// use. It returns nil if the layout is not one of the built-in layouts.
+func (rc *RemoteController) keyboardLayout() *Layout {
+	rc.keyboard.mu.Lock()
+	defer rc.keyboard.mu.Unlock()
+	if rc.keyboard.resolved {
+		return rc.keyboard.layout
+	}
+	rc.keyboard.resolved = true
+
+	name, err := DetectLayout()
+	if err != nil {
+		log.Printf("Failed to detect keyboard layout, typing with Unicode input: %v", err)
+		return nil
+	}
+	l, ok := LookupLayout(name)
+	if !ok {
+		log.Printf("Keyboard layout %s has no key map, typing with Unicode input", name)
+		return nil
+	}
+	if rc.verbose {
+		log.Printf("Detected keyboard layout %s", l.Name)
+	}
+	rc.keyboard.layout = l
+	return l
+}
@@ -0,0 +1,143 @@
+package remote
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestLayoutKeystrokes(t *testing.T) {
+	de, ok := LookupLayout("DE")
+	if !ok {
+		t.Fatal("Expected the German layout")
+	}
+	tests := []struct {
+		char rune
+		want keystroke
+	}{
+		{'z', keystroke{key: "z"}},
+		{'Y', keystroke{key: "y", shift: true}},
+		{'"', keystroke{key: "2", shift: true}},
+		{'-', keystroke{key: "-"}},
+		{'_', keystroke{key: "-", shift: true}},
+		{'#', keystroke{key: "#"}},
+	}
+	if runtime.GOOS != "darwin" {
+		tests = append(tests, struct {
+			char rune
+			want keystroke
+		}{'@', keystroke{key: "q", altGr: true}})
+	}
+	for _, tt := range tests {
+		if got, ok := de.keystroke(tt.char); !ok || got != tt.want {
+			t.Errorf("keystroke(%q) = %+v, %v, want %+v", tt.char, got, ok, tt.want)
+		}
+	}
+
+	// Keys whose unshifted character is not ASCII cannot be named, and dead keys are left out
+	for _, r := range []rune{'ö', 'Ö', 'ß', '?', '^', '´', '😀'} {
+		if ks, ok := de.keystroke(r); ok {
+			t.Errorf("Expected no key for %q, got %+v", r, ks)
+		}
+	}
+
+	if key, ok := de.positionKey("y"); !ok || key != "z" {
+		t.Errorf("Expected the Z key in the position of the US Y key, got %q", key)
+	}
+	if _, ok := LookupLayout("gb"); !ok {
+		t.Error("Expected gb to be an alias of uk")
+	}
+	if _, ok := LookupLayout("dvorak"); ok {
+		t.Error("Expected no dvorak layout")
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	tests := map[string]string{
+		"KeyA":        "a",
+		"Digit7":      "7",
+		"Numpad3":     "num3",
+		"NumpadEnter": "num_enter",
+		"ArrowLeft":   "left",
+		"Enter":       "enter",
+		" ":           "space",
+		"ShiftLeft":   "lshift",
+		"Control":     "ctrl",
+		"Meta":        "cmd",
+		"BracketLeft": "[",
+		"F5":          "f5",
+		"PageDown":    "pagedown",
+		"escape":      "escape",
+		"A":           "A",
+		"é":           "é",
+	}
+	for name, want := range tests {
+		if got := NormalizeKey(name); got != want {
+			t.Errorf("NormalizeKey(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDetectLayout(t *testing.T) {
+	original := layoutCommandFunc
+	t.Cleanup(func() { layoutCommandFunc = original })
+
+	var output string
+	layoutCommandFunc = func(name string, args ...string) ([]byte, error) {
+		return []byte(output), nil
+	}
+	switch runtime.GOOS {
+	case "linux":
+		output = "rules:      evdev\nmodel:      pc105\nlayout:     de,us\nvariant:    nodeadkeys\n"
+	case "darwin":
+		output = "(\n    {\n        InputSourceKind = \"Keyboard Layout\";\n        \"KeyboardLayout ID\" = 3;\n        \"KeyboardLayout Name\" = German;\n    }\n)\n"
+	case "windows":
+		output = "0407:00000407\r\n"
+	default:
+		t.Skipf("Layout detection is not supported on %s", runtime.GOOS)
+	}
+	if name, err := DetectLayout(); err != nil || name != "de" {
+		t.Errorf("DetectLayout() = %q, %v, want de", name, err)
+	}
+
+	layoutCommandFunc = func(name string, args ...string) ([]byte, error) {
+		return nil, errors.New("not found")
+	}
+	if _, err := DetectLayout(); err == nil {
+		t.Error("Expected an error when the layout cannot be queried")
+	}
+}
+
+func TestParseLayouts(t *testing.T) {
+	if name, err := parseXkbLayout([]byte("layout:     gb\n")); err != nil || name != "gb" {
+		t.Errorf("parseXkbLayout() = %q, %v", name, err)
+	}
+	if _, err := parseXkbLayout([]byte("rules: evdev\n")); err == nil {
+		t.Error("Expected an error without a layout line")
+	}
+	if name, err := parseMacLayout([]byte(`"KeyboardLayout Name" = "U.S.";`)); err != nil || name != "us" {
+		t.Errorf("parseMacLayout() = %q, %v", name, err)
+	}
+	if name, err := parseMacLayout([]byte(`"KeyboardLayout Name" = Dvorak;`)); err != nil || name != "Dvorak" {
+		t.Errorf("Expected unknown macOS layouts by name, got %q, %v", name, err)
+	}
+	if name, err := parseWindowsLayout([]byte("040C:0000040C")); err != nil || name != "fr" {
+		t.Errorf("parseWindowsLayout() = %q, %v", name, err)
+	}
+	if _, err := parseWindowsLayout([]byte("garbage")); err == nil {
+		t.Error("Expected an error for an unexpected input method")
+	}
+}
+
+func TestSetKeyboardLayout(t *testing.T) {
+	rc := NewRemoteController(nil, false)
+	if err := rc.SetKeyboardLayout("fr"); err != nil {
+		t.Fatalf("SetKeyboardLayout() returned an error: %v", err)
+	}
+	if l := rc.keyboardLayout(); l == nil || l.Name != "fr" {
+		t.Errorf("Expected the French layout, got %+v", l)
+	}
+	if err := rc.SetKeyboardLayout("klingon"); err == nil {
+		t.Error("Expected an unknown layout to be rejected")
+	}
+}
@@ -0,0 +1,231 @@
+package remote
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// Easing names how the pointer speeds up and slows down during a movement
+type Easing string
+
+const (
+	EaseLinear Easing = "linear"
+	EaseIn     Easing = "easeIn"
+	EaseOut    Easing = "easeOut"
+	EaseInOut  Easing = "easeInOut" // Used when an event gives no easing
+)
+
+const (
+	// DefaultStepDelay is the time between pointer updates of a smooth movement
+	DefaultStepDelay = 10 * time.Millisecond
+	// DefaultDragDuration is how long a drag takes when the event gives no duration
+	DefaultDragDuration = 300 * time.Millisecond
+	// dragHold is the pause after pressing and before releasing the button of a
+	// drag, so applications see the press before the movement and the final
+	// position before the release
+	dragHold = 50 * time.Millisecond
+	// MaxMotionDuration limits the duration of a single movement
+	MaxMotionDuration = 10 * time.Second
+	// MaxPathPoints limits the number of points in a path
+	MaxPathPoints = 1000
+)
+
+// Point is a position on screen
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// sleepFunc pauses between the steps of a movement; tests replace it
+var sleepFunc = time.Sleep
+
+// ease maps progress from 0 to 1 through an easing curve
+func ease(easing Easing, t float64) (float64, error) {
+	switch easing {
+	case EaseLinear:
+		return t, nil
+	case EaseIn:
+		return t * t, nil
+	case EaseOut:
+		return t * (2 - t), nil
+	case EaseInOut, "":
+		// Smoothstep: slow start, fast middle, slow end
+		return t * t * (3 - 2*t), nil
+	default:
+		return 0, fmt.Errorf("unknown easing: %s", easing)
+	}
+}
+
+// motion is a movement through a list of points with its timing
+type motion struct {
+	points    []Point // Starting position first
+	duration  time.Duration
+	stepDelay time.Duration
+	easing    Easing
+}
+
+// newMotion plans a movement from a position through the points of an event.
+// The path, if any, replaces the event's x and y as the destination.
+func newMotion(from Point, event MouseEvent, defaultDuration time.Duration) (*motion, error) {
+	m := &motion{
+		points:    []Point{from},
+		duration:  time.Duration(event.Duration) * time.Millisecond,
+		stepDelay: time.Duration(event.StepDelay) * time.Millisecond,
+		easing:    event.Easing,
+	}
+	if len(event.Path) > MaxPathPoints {
+		return nil, fmt.Errorf("path has %d points, more than the limit of %d", len(event.Path), MaxPathPoints)
+	}
+	if len(event.Path) > 0 {
+		m.points = append(m.points, event.Path...)
+	} else {
+		m.points = append(m.points, Point{X: event.X, Y: event.Y})
+	}
+
+	if m.duration < 0 || m.stepDelay < 0 {
+		return nil, fmt.Errorf("duration and step delay must not be negative")
+	}
+	if m.duration == 0 {
+		m.duration = defaultDuration
+	}
+	if m.duration > MaxMotionDuration {
+		return nil, fmt.Errorf("movement of %s is longer than the limit of %s", m.duration, MaxMotionDuration)
+	}
+	if m.stepDelay == 0 {
+		m.stepDelay = DefaultStepDelay
+	}
+	if _, err := ease(m.easing, 0); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// destination returns the last point of the movement
+func (m *motion) destination() Point {
+	return m.points[len(m.points)-1]
+}
+
+// steps returns the intermediate positions of the movement, ending at the
+// destination. Progress along the path is eased over its total length, so
+// the pointer keeps a steady rhythm through the corners of a path.
+func (m *motion) steps() []Point {
+	lengths := make([]float64, len(m.points))
+	for i := 1; i < len(m.points); i++ {
+		dx := float64(m.points[i].X - m.points[i-1].X)
+		dy := float64(m.points[i].Y - m.points[i-1].Y)
+		lengths[i] = lengths[i-1] + math.Hypot(dx, dy)
+	}
+	total := lengths[len(lengths)-1]
+
+	n := int(m.duration / m.stepDelay)
+	if n < 1 || total == 0 {
+		return m.points[1:]
+	}
+
+	steps := make([]Point, 0, n)
+	segment := 1
+	for i := 1; i <= n; i++ {
+		t, _ := ease(m.easing, float64(i)/float64(n))
+		distance := t * total
+		for segment < len(m.points)-1 && lengths[segment] < distance {
+			segment++
+		}
+		from, to := m.points[segment-1], m.points[segment]
+		span := lengths[segment] - lengths[segment-1]
+		f := 1.0
+		if span > 0 {
+			f = (distance - lengths[segment-1]) / span
+		}
+		p := Point{
+			X: from.X + int(math.Round(f*float64(to.X-from.X))),
+			Y: from.Y + int(math.Round(f*float64(to.Y-from.Y))),
+		}
+		// Skip steps that would not move the pointer
+		if len(steps) > 0 && steps[len(steps)-1] == p {
+			continue
+		}
+		steps = append(steps, p)
+	}
+	if steps[len(steps)-1] != m.destination() {
+		steps = append(steps, m.destination())
+	}
+	return steps
+}
+
+// run moves the pointer through the steps of the movement, ending with a
+// verified move to the destination
+func (m *motion) run() error {
+	steps := m.steps()
+	delay := m.duration / time.Duration(max(len(steps)-1, 1))
+	for _, p := range steps[:len(steps)-1] {
+		robotgoMoveMouseFunc(p.X, p.Y)
+		sleepFunc(delay)
+	}
+	dest := m.destination()
+	return executeMouseMove(dest.X, dest.Y)
+}
+
+// smooth returns true if a move should be interpolated rather than a jump
+func (e MouseEvent) smooth() bool {
+	return e.Duration > 0 || len(e.Path) > 0
+}
+
+// executeSmoothMove moves the pointer along the interpolated path of a move event
+func (rc *RemoteController) executeSmoothMove(event MouseEvent) error {
+	x, y := robotgoGetMousePosFunc()
+	m, err := newMotion(Point{X: x, Y: y}, event, 0)
+	if err != nil {
+		return err
+	}
+	return m.run()
+}
+
+// executeDrag presses a button, moves the pointer smoothly along the path of
+// a drag event and releases the button at the destination
+func (rc *RemoteController) executeDrag(event MouseEvent) error {
+	startX, startY, err := rc.GetMousePosition()
+	if err != nil {
+		return fmt.Errorf("failed to get mouse position: %w", err)
+	}
+	m, err := newMotion(Point{X: startX, Y: startY}, event, DefaultDragDuration)
+	if err != nil {
+		return err
+	}
+
+	// Press mouse button down
+	err = rc.ExecuteMouseEvent(MouseEvent{
+		Action: MouseDown,
+		Button: event.Button,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to press mouse button: %w", err)
+	}
+	sleepFunc(dragHold)
+
+	// Move along the path, releasing the button if that fails
+	if err := m.run(); err != nil {
+		rc.ExecuteMouseEvent(MouseEvent{
+			Action: MouseUp,
+			Button: event.Button,
+		})
+		return fmt.Errorf("failed to move mouse during drag: %w", err)
+	}
+	sleepFunc(dragHold)
+
+	// Release mouse button
+	err = rc.ExecuteMouseEvent(MouseEvent{
+		Action: MouseUp,
+		Button: event.Button,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release mouse button: %w", err)
+	}
+
+	if rc.verbose {
+		dest := m.destination()
+		log.Printf("Dragged from (%d,%d) to (%d,%d) in %s", startX, startY, dest.X, dest.Y, m.duration)
+	}
+	return nil
+}
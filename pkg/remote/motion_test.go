@@ -0,0 +1,137 @@
+package remote
+
+import (
+	"image"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEase(t *testing.T) {
+	for _, easing := range []Easing{EaseLinear, EaseIn, EaseOut, EaseInOut, ""} {
+		start, _ := ease(easing, 0)
+		end, _ := ease(easing, 1)
+		if start != 0 || end != 1 {
+			t.Errorf("%q: expected 0 and 1 at the ends, got %v and %v", easing, start, end)
+		}
+	}
+	if mid, _ := ease(EaseIn, 0.5); mid >= 0.5 {
+		t.Errorf("Expected easeIn to be slow at first, got %v", mid)
+	}
+	if mid, _ := ease(EaseOut, 0.5); mid <= 0.5 {
+		t.Errorf("Expected easeOut to be fast at first, got %v", mid)
+	}
+	if _, err := ease("bounce", 0.5); err == nil {
+		t.Error("Expected an unknown easing to be rejected")
+	}
+}
+
+func TestMotionSteps(t *testing.T) {
+	m, err := newMotion(Point{0, 0}, MouseEvent{X: 100, Y: 0, Duration: 100, Easing: EaseLinear}, 0)
+	if err != nil {
+		t.Fatalf("newMotion() returned an error: %v", err)
+	}
+	steps := m.steps()
+	if len(steps) != 10 || steps[0] != (Point{10, 0}) || steps[9] != (Point{100, 0}) {
+		t.Errorf("Expected 10 even steps to (100,0), got %v", steps)
+	}
+
+	// Eased steps are short at the ends and long in the middle
+	m.easing = EaseInOut
+	steps = m.steps()
+	first, middle := steps[0].X, steps[5].X-steps[4].X
+	if first >= middle {
+		t.Errorf("Expected a slow start, got a first step of %d and a middle step of %d", first, middle)
+	}
+	for i := 1; i < len(steps); i++ {
+		if steps[i].X <= steps[i-1].X {
+			t.Fatalf("Expected the steps to move forward, got %v", steps)
+		}
+	}
+}
+
+func TestMotionPath(t *testing.T) {
+	path := []Point{{100, 0}, {100, 100}}
+	m, err := newMotion(Point{0, 0}, MouseEvent{X: 5, Y: 5, Path: path, Duration: 200, Easing: EaseLinear}, 0)
+	if err != nil {
+		t.Fatalf("newMotion() returned an error: %v", err)
+	}
+	if m.destination() != (Point{100, 100}) {
+		t.Errorf("Expected the path to replace x and y, got %v", m.destination())
+	}
+
+	steps := m.steps()
+	corner := false
+	for _, p := range steps {
+		if p.X > 100 || p.Y > 100 || (p.X < 100 && p.Y > 0) {
+			t.Fatalf("Step %v is off the path", p)
+		}
+		if p == (Point{100, 0}) {
+			corner = true
+		}
+	}
+	if !corner {
+		t.Errorf("Expected the path to pass through the corner, got %v", steps)
+	}
+
+	// Without a duration the pointer jumps from point to point
+	m, _ = newMotion(Point{0, 0}, MouseEvent{Path: path}, 0)
+	if steps := m.steps(); len(steps) != 2 || steps[0] != path[0] || steps[1] != path[1] {
+		t.Errorf("Expected the path points, got %v", steps)
+	}
+}
+
+func TestMotionLimits(t *testing.T) {
+	tests := []MouseEvent{
+		{Duration: -1},
+		{Duration: int(MaxMotionDuration/time.Millisecond) + 1},
+		{Duration: 100, Easing: "bounce"},
+		{Path: make([]Point, MaxPathPoints+1)},
+	}
+	for _, event := range tests {
+		if _, err := newMotion(Point{}, event, 0); err == nil {
+			t.Errorf("Expected %+v to be rejected", event)
+		}
+	}
+}
+
+func TestSmoothDrag(t *testing.T) {
+	moves, _ := stubRobotgo(t, 1920, 1080)
+	toggle, sleep := robotgoMouseToggleFunc, sleepFunc
+	t.Cleanup(func() { robotgoMouseToggleFunc, sleepFunc = toggle, sleep })
+
+	var actions []string
+	var slept time.Duration
+	robotgoMouseToggleFunc = func(button, direction string) {
+		actions = append(actions, direction)
+	}
+	sleepFunc = func(d time.Duration) { slept += d }
+	robotgoMoveMouseFunc(10, 10)
+	*moves = nil
+
+	rc := NewRemoteController(nil, false)
+	err := rc.ExecuteMouseEvent(MouseEvent{Action: MouseDrag, Button: LeftButton, Path: []Point{{50, 10}, {50, 60}}})
+	if err != nil {
+		t.Fatalf("Drag returned an error: %v", err)
+	}
+
+	if len(actions) != 2 || actions[0] != "down" || actions[1] != "up" {
+		t.Errorf("Expected the button to be pressed and released, got %v", actions)
+	}
+	if len(*moves) < 10 {
+		t.Errorf("Expected many small moves, got %v", *moves)
+	}
+	if last := (*moves)[len(*moves)-1]; last != image.Pt(50, 60) {
+		t.Errorf("Expected the drag to end at (50,60), got %v", last)
+	}
+	for i := 1; i < len(*moves); i++ {
+		a, b := (*moves)[i-1], (*moves)[i]
+		if math.Hypot(float64(b.X-a.X), float64(b.Y-a.Y)) > 15 {
+			t.Errorf("Expected no jumps, got a move from %v to %v", a, b)
+		}
+	}
+	// The default drag duration plus the holds around the movement
+	if want := DefaultDragDuration + 2*dragHold; slept < want-time.Millisecond || slept > want {
+		t.Errorf("Expected about %s of pauses, got %s", want, slept)
+	}
+}
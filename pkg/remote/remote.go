@@ -2,12 +2,11 @@ package remote
 
 import (
 	"fmt"
+	"image"
 	"log"
 	"runtime"
-	"time"
 
 	"github.com/adamrobbie/go-support/pkg/permissions"
-	"github.com/go-vgo/robotgo"
 )
 
 // MouseAction represents a mouse action type
@@ -25,6 +24,7 @@ const (
 	MouseScroll   MouseAction = "scroll"
 	MouseDown     MouseAction = "down"
 	MouseUp       MouseAction = "up"
+	MouseZoom     MouseAction = "zoom" // Emulates a pinch by scrolling with the zoom modifier held
 
 	// Keyboard actions
 	KeyPress       KeyboardAction = "press"
@@ -50,13 +50,25 @@ type MouseEvent struct {
 	Y      int         `json:"y"`
 	Button MouseButton `json:"button,omitempty"`
 	Double bool        `json:"double,omitempty"`
-	Amount int         `json:"amount,omitempty"` // For scrolling
+	Amount int         `json:"amount,omitempty"` // For scrolling; positive scrolls up or zooms in
+
+	// Scrolling
+	AmountX   int        `json:"amountX,omitempty"`   // Horizontal amount; positive scrolls right
+	Unit      ScrollUnit `json:"unit,omitempty"`      // line (default) or pixel
+	Modifiers []string   `json:"modifiers,omitempty"` // Keys held while scrolling, e.g. shift
+
+	// Smooth movement, for moves and drags
+	Path      []Point `json:"path,omitempty"`      // Points to pass through; the last one replaces x and y
+	Duration  int     `json:"duration,omitempty"`  // Milliseconds the movement takes; moves without it or a path jump
+	Easing    Easing  `json:"easing,omitempty"`    // linear, easeIn, easeOut or easeInOut (default)
+	StepDelay int     `json:"stepDelay,omitempty"` // Milliseconds between pointer updates (10 if 0)
 }
 
 // KeyboardEvent represents a keyboard event
 type KeyboardEvent struct {
 	Action KeyboardAction `json:"action"`
-	Key    string         `json:"key"`
+	Key    string         `json:"key"`            // robotgo name, browser KeyboardEvent.key or KeyboardEvent.code
+	Code   string         `json:"code,omitempty"` // Browser KeyboardEvent.code, used when key is empty
 	Keys   []string       `json:"keys,omitempty"` // For key combinations
 	Text   string         `json:"text,omitempty"` // For typing text
 }
@@ -65,6 +77,10 @@ type KeyboardEvent struct {
 type RemoteController struct {
 	permManager permissions.Manager
 	verbose     bool
+	windows     windowState
+	scroll      scrollState
+	keyboard    keyboardState
+	capture     func() (image.Image, error) // Screen capture for finding images, nil for the primary display
 }
 
 // NewRemoteController creates a new remote controller
@@ -111,6 +127,9 @@ func (rc *RemoteController) ExecuteMouseEvent(event MouseEvent) error {
 
 	switch event.Action {
 	case MouseMove:
+		if event.smooth() {
+			return rc.executeSmoothMove(event)
+		}
 		log.Printf("Moving mouse to (%d,%d)", event.X, event.Y)
 
 		err := executeMouseMove(event.X, event.Y)
@@ -256,59 +275,13 @@ func (rc *RemoteController) ExecuteMouseEvent(event MouseEvent) error {
 		return err
 
 	case MouseDrag:
-		// Get current position
-		startX, startY, err := rc.GetMousePosition()
-		if err != nil {
-			return fmt.Errorf("failed to get mouse position: %w", err)
-		}
-
-		// Press mouse button down
-		err = rc.ExecuteMouseEvent(MouseEvent{
-			Action: MouseDown,
-			Button: event.Button,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to press mouse button: %w", err)
-		}
-
-		// Move to target position
-		err = rc.ExecuteMouseEvent(MouseEvent{
-			Action: MouseMove,
-			X:      event.X,
-			Y:      event.Y,
-		})
-		if err != nil {
-			// Release mouse button before returning error
-			rc.ExecuteMouseEvent(MouseEvent{
-				Action: MouseUp,
-				Button: event.Button,
-			})
-			return fmt.Errorf("failed to move mouse during drag: %w", err)
-		}
-
-		// Small delay to ensure the drag is registered
-		time.Sleep(50 * time.Millisecond)
-
-		// Release mouse button
-		err = rc.ExecuteMouseEvent(MouseEvent{
-			Action: MouseUp,
-			Button: event.Button,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to release mouse button: %w", err)
-		}
-
-		if rc.verbose {
-			log.Printf("Dragged from (%d,%d) to (%d,%d)", startX, startY, event.X, event.Y)
-		}
-
-		return nil
+		return rc.executeDrag(event)
 
 	case MouseScroll:
-		// Use Scroll for mouse scrolling
-		// TODO: Add wrapper function for Scroll
-		robotgo.Scroll(0, event.Amount)
-		return nil
+		return rc.executeScroll(event, false)
+
+	case MouseZoom:
+		return rc.executeScroll(event, true)
 
 	default:
 		return fmt.Errorf("unknown mouse action: %s", event.Action)
@@ -326,8 +299,24 @@ func (rc *RemoteController) ExecuteKeyboardEvent(event KeyboardEvent) error {
 		log.Printf("Executing keyboard event: %+v", event)
 	}
 
+	// Convert browser and alias key names to the keys of the active layout
+	if event.Key == "" {
+		event.Key = event.Code
+	}
+	event.Key = rc.resolveKey(event.Key)
+	keys := make([]string, len(event.Keys))
+	for i, key := range event.Keys {
+		keys[i] = rc.resolveKey(key)
+	}
+	event.Keys = keys
+
 	switch event.Action {
 	case KeyPress:
+		// Characters that need modifiers or have no key are typed instead
+		if rc.typedKey(event.Key) {
+			return rc.typeText(event.Key)
+		}
+
 		// Try RobotGo first
 		err := executeKeyboardPress(event.Key, nil)
 		if err != nil && rc.verbose {
@@ -344,18 +333,14 @@ func (rc *RemoteController) ExecuteKeyboardEvent(event KeyboardEvent) error {
 		return err
 
 	case KeyDown:
-		// TODO: Add wrapper function for KeyToggle
-		robotgo.KeyToggle(event.Key, "down")
-		return nil
+		return robotgoKeyToggleFunc(event.Key, "down")
 
 	case KeyUp:
-		// TODO: Add wrapper function for KeyToggle
-		robotgo.KeyToggle(event.Key, "up")
-		return nil
+		return robotgoKeyToggleFunc(event.Key, "up")
 
 	case KeyType:
-		// Try RobotGo first
-		err := executeKeyboardType(event.Text)
+		// Type with the keys of the layout, pasting what has no key
+		err := rc.typeText(event.Text)
 		if err != nil && rc.verbose {
 			log.Printf("Key type failed: %v", err)
 		}
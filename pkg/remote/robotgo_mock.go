@@ -36,6 +36,9 @@ var (
 	keyTapCalled        bool
 	getScreenSizeCalled bool
 	getMousePosCalled   bool
+	scrollCalled        bool
+	keyToggleCalled     bool
+	clipboardWritten    bool
 
 	// Call arguments
 	lastMoveMouseX      int
@@ -46,6 +49,10 @@ var (
 	lastToggleDirection string
 	lastTypeString      string
 	lastKeyTap          string
+	lastScrollX         int
+	lastScrollY         int
+	lastKeyToggle       string
+	mockClipboard       string
 
 	// Mock implementations
 	robotgoMoveMouseFunc = func(x, y int) {
@@ -88,6 +95,36 @@ var (
 		lastKeyTap = key
 	}
 
+	robotgoScrollFunc = func(x, y int) {
+		mockMutex.Lock()
+		defer mockMutex.Unlock()
+		scrollCalled = true
+		lastScrollX = x
+		lastScrollY = y
+	}
+
+	robotgoKeyToggleFunc = func(key, direction string) error {
+		mockMutex.Lock()
+		defer mockMutex.Unlock()
+		keyToggleCalled = true
+		lastKeyToggle = key + " " + direction
+		return nil
+	}
+
+	robotgoReadClipboardFunc = func() (string, error) {
+		mockMutex.Lock()
+		defer mockMutex.Unlock()
+		return mockClipboard, nil
+	}
+
+	robotgoWriteClipboardFunc = func(text string) error {
+		mockMutex.Lock()
+		defer mockMutex.Unlock()
+		clipboardWritten = true
+		mockClipboard = text
+		return nil
+	}
+
 	robotgoGetScreenSizeFunc = func() (int, int) {
 		mockMutex.Lock()
 		defer mockMutex.Unlock()
@@ -131,6 +168,9 @@ func ResetMocks() {
 	keyTapCalled = false
 	getScreenSizeCalled = false
 	getMousePosCalled = false
+	scrollCalled = false
+	keyToggleCalled = false
+	clipboardWritten = false
 
 	// Reset call arguments
 	lastMoveMouseX = 0
@@ -141,6 +181,10 @@ func ResetMocks() {
 	lastToggleDirection = ""
 	lastTypeString = ""
 	lastKeyTap = ""
+	lastScrollX = 0
+	lastScrollY = 0
+	lastKeyToggle = ""
+	mockClipboard = ""
 }
 
 // SetMockScreenSize sets the mock screen size
@@ -204,6 +248,12 @@ func GetMockCallCount(function string) bool {
 		return getScreenSizeCalled
 	case "GetMousePos":
 		return getMousePosCalled
+	case "Scroll":
+		return scrollCalled
+	case "KeyToggle":
+		return keyToggleCalled
+	case "WriteClipboard":
+		return clipboardWritten
 	default:
 		panic(fmt.Sprintf("Unknown function: %s", function))
 	}
@@ -29,11 +29,19 @@ var (
 		robotgo.Toggle(button, direction)
 	}
 
+	robotgoScrollFunc = func(x, y int) {
+		robotgo.Scroll(x, y)
+	}
+
 	// Keyboard functions
 	robotgoTypeStrFunc = func(text string) {
 		robotgo.TypeStr(text)
 	}
 
+	robotgoKeyToggleFunc = func(key, direction string) error {
+		return robotgo.KeyToggle(key, direction)
+	}
+
 	robotgoKeyTapFunc = func(key string, modifiers ...string) {
 		// Convert []string to []interface{} for robotgo.KeyTap
 		if len(modifiers) > 0 {
@@ -46,4 +54,13 @@ var (
 			robotgo.KeyTap(key)
 		}
 	}
+
+	// Clipboard functions, for pasting text that has no keys
+	robotgoReadClipboardFunc = func() (string, error) {
+		return robotgo.ReadAll()
+	}
+
+	robotgoWriteClipboardFunc = func(text string) error {
+		return robotgo.WriteAll(text)
+	}
 )
@@ -0,0 +1,162 @@
+package remote
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ScrollUnit is the unit of scroll amounts
+type ScrollUnit string
+
+const (
+	ScrollLine  ScrollUnit = "line"  // Wheel notches, the default
+	ScrollPixel ScrollUnit = "pixel" // Pixels, e.g. the deltas of a touchpad
+)
+
+const (
+	// pixelsPerLine converts between lines and pixels where the platform scrolls in the other unit
+	pixelsPerLine = 40
+	// MaxScrollAmount limits the lines or pixels of a single scroll event
+	MaxScrollAmount = 10000
+)
+
+// scrollState keeps the pixels too small to scroll a whole line, so that
+// many small touchpad deltas add up to the same distance as one large one
+type scrollState struct {
+	mu         sync.Mutex
+	remX, remY int
+}
+
+// platformUnit returns the unit robotgo scrolls in: pixels on macOS and wheel
+// notches elsewhere
+func platformUnit() ScrollUnit {
+	if runtime.GOOS == "darwin" {
+		return ScrollPixel
+	}
+	return ScrollLine
+}
+
+// shortcutModifier returns the key of shortcuts such as zooming and pasting:
+// cmd on macOS and ctrl elsewhere
+func shortcutModifier() string {
+	if runtime.GOOS == "darwin" {
+		return "cmd"
+	}
+	return "ctrl"
+}
+
+// scrollDelta converts the amounts of an event into robotgo's units and
+// directions, where positive x scrolls left and positive y scrolls up
+func (rc *RemoteController) scrollDelta(event MouseEvent, unit ScrollUnit) (int, int, error) {
+	if event.Unit == "" {
+		event.Unit = ScrollLine
+	}
+	if event.Unit != ScrollLine && event.Unit != ScrollPixel {
+		return 0, 0, fmt.Errorf("unknown scroll unit: %s", event.Unit)
+	}
+	if abs(event.Amount) > MaxScrollAmount || abs(event.AmountX) > MaxScrollAmount {
+		return 0, 0, fmt.Errorf("scroll amount is larger than the limit of %d", MaxScrollAmount)
+	}
+
+	// Positive amountX scrolls right, robotgo scrolls right for negative x
+	dx, dy := -event.AmountX, event.Amount
+	switch {
+	case event.Unit == unit:
+		return dx, dy, nil
+	case event.Unit == ScrollLine:
+		return dx * pixelsPerLine, dy * pixelsPerLine, nil
+	}
+
+	// Pixels on a platform that scrolls in lines
+	rc.scroll.mu.Lock()
+	defer rc.scroll.mu.Unlock()
+	rc.scroll.remX += dx
+	rc.scroll.remY += dy
+	lx, ly := rc.scroll.remX/pixelsPerLine, rc.scroll.remY/pixelsPerLine
+	rc.scroll.remX -= lx * pixelsPerLine
+	rc.scroll.remY -= ly * pixelsPerLine
+	return lx, ly, nil
+}
+
+// executeScroll scrolls, or zooms if zoom is set, optionally at a position,
+// with modifier keys held and spread over the duration of the event
+func (rc *RemoteController) executeScroll(event MouseEvent, zoom bool) error {
+	if zoom && event.AmountX != 0 {
+		return fmt.Errorf("zoom only takes a vertical amount")
+	}
+	dx, dy, err := rc.scrollDelta(event, platformUnit())
+	if err != nil {
+		return err
+	}
+	duration := time.Duration(event.Duration) * time.Millisecond
+	if duration < 0 || duration > MaxMotionDuration {
+		return fmt.Errorf("invalid scroll duration of %s", duration)
+	}
+
+	// Scroll at a position
+	if event.X > 0 || event.Y > 0 {
+		if err := executeMouseMove(event.X, event.Y); err != nil {
+			return fmt.Errorf("failed to move mouse before scroll: %w", err)
+		}
+	}
+	if dx == 0 && dy == 0 {
+		return nil
+	}
+
+	modifiers := event.Modifiers
+	if zoom && len(modifiers) == 0 {
+		modifiers = []string{shortcutModifier()}
+	}
+	for i, key := range modifiers {
+		if err := robotgoKeyToggleFunc(key, "down"); err != nil {
+			releaseKeys(modifiers[:i])
+			return fmt.Errorf("failed to press %s: %w", key, err)
+		}
+	}
+	defer releaseKeys(modifiers)
+
+	if rc.verbose {
+		log.Printf("Scrolling by (%d,%d) with modifiers %v over %s", dx, dy, modifiers, duration)
+	}
+	if duration == 0 {
+		robotgoScrollFunc(dx, dy)
+		return nil
+	}
+
+	// Spread the scroll over steps of at most one unit per axis, or fewer
+	// larger steps if the duration does not allow that many
+	stepDelay := time.Duration(event.StepDelay) * time.Millisecond
+	if stepDelay <= 0 {
+		stepDelay = DefaultStepDelay
+	}
+	n := min(max(abs(dx), abs(dy)), max(int(duration/stepDelay), 1))
+	delay := duration / time.Duration(n)
+	sentX, sentY := 0, 0
+	for i := 1; i <= n; i++ {
+		x, y := dx*i/n, dy*i/n
+		robotgoScrollFunc(x-sentX, y-sentY)
+		sentX, sentY = x, y
+		if i < n {
+			sleepFunc(delay)
+		}
+	}
+	return nil
+}
+
+// releaseKeys releases held keys in reverse order
+func releaseKeys(keys []string) {
+	for i := len(keys) - 1; i >= 0; i-- {
+		robotgoKeyToggleFunc(keys[i], "up")
+	}
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,150 @@
+package remote
+
+import (
+	"image"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// scrollRecorder stubs the scroll and key toggle wrappers and records their calls
+type scrollRecorder struct {
+	scrolls []image.Point
+	keys    []string
+	slept   time.Duration
+}
+
+func stubScroll(t *testing.T) *scrollRecorder {
+	t.Helper()
+	stubRobotgo(t, 1920, 1080)
+	scroll, toggle, sleep := robotgoScrollFunc, robotgoKeyToggleFunc, sleepFunc
+	t.Cleanup(func() { robotgoScrollFunc, robotgoKeyToggleFunc, sleepFunc = scroll, toggle, sleep })
+
+	r := &scrollRecorder{}
+	robotgoScrollFunc = func(x, y int) { r.scrolls = append(r.scrolls, image.Pt(x, y)) }
+	robotgoKeyToggleFunc = func(key, direction string) error {
+		r.keys = append(r.keys, key+" "+direction)
+		return nil
+	}
+	sleepFunc = func(d time.Duration) { r.slept += d }
+	return r
+}
+
+// lines converts lines to the platform's scroll unit
+func lines(n int) int {
+	if platformUnit() == ScrollPixel {
+		return n * pixelsPerLine
+	}
+	return n
+}
+
+func TestScroll(t *testing.T) {
+	r := stubScroll(t)
+	rc := NewRemoteController(nil, false)
+
+	if err := rc.ExecuteMouseEvent(MouseEvent{Action: MouseScroll, Amount: 3}); err != nil {
+		t.Fatalf("Scroll returned an error: %v", err)
+	}
+	if err := rc.ExecuteMouseEvent(MouseEvent{Action: MouseScroll, AmountX: 2}); err != nil {
+		t.Fatalf("Horizontal scroll returned an error: %v", err)
+	}
+	want := []image.Point{{0, lines(3)}, {-lines(2), 0}}
+	if len(r.scrolls) != 2 || r.scrolls[0] != want[0] || r.scrolls[1] != want[1] {
+		t.Errorf("Expected scrolls %v, got %v", want, r.scrolls)
+	}
+	if err := rc.ExecuteMouseEvent(MouseEvent{Action: MouseScroll, Amount: 1, Unit: "page"}); err == nil {
+		t.Error("Expected an unknown unit to be rejected")
+	}
+	if err := rc.ExecuteMouseEvent(MouseEvent{Action: MouseScroll, Amount: MaxScrollAmount + 1}); err == nil {
+		t.Error("Expected a huge amount to be rejected")
+	}
+}
+
+func TestScrollPixels(t *testing.T) {
+	if platformUnit() == ScrollPixel {
+		t.Skip("pixel deltas are passed through on this platform")
+	}
+	r := stubScroll(t)
+	rc := NewRemoteController(nil, false)
+
+	// Touchpad deltas too small for a line add up
+	for i := 0; i < 5; i++ {
+		if err := rc.ExecuteMouseEvent(MouseEvent{Action: MouseScroll, Amount: -10, Unit: ScrollPixel}); err != nil {
+			t.Fatalf("Scroll returned an error: %v", err)
+		}
+	}
+	if len(r.scrolls) != 1 || r.scrolls[0] != image.Pt(0, -1) {
+		t.Errorf("Expected one line down after 50 pixels, got %v", r.scrolls)
+	}
+	if rc.scroll.remY != -10 {
+		t.Errorf("Expected 10 pixels to be kept, got %d", rc.scroll.remY)
+	}
+}
+
+func TestScrollAtPosition(t *testing.T) {
+	r := stubScroll(t)
+	rc := NewRemoteController(nil, false)
+
+	if err := rc.ExecuteMouseEvent(MouseEvent{Action: MouseScroll, X: 300, Y: 200, Amount: -1}); err != nil {
+		t.Fatalf("Scroll returned an error: %v", err)
+	}
+	if x, y, _ := rc.GetMousePosition(); x != 300 || y != 200 {
+		t.Errorf("Expected the pointer at (300,200), got (%d,%d)", x, y)
+	}
+	if len(r.scrolls) != 1 {
+		t.Errorf("Expected one scroll, got %v", r.scrolls)
+	}
+}
+
+func TestSmoothScroll(t *testing.T) {
+	r := stubScroll(t)
+	rc := NewRemoteController(nil, false)
+
+	if err := rc.ExecuteMouseEvent(MouseEvent{Action: MouseScroll, Amount: 4, Duration: 200, StepDelay: 50}); err != nil {
+		t.Fatalf("Scroll returned an error: %v", err)
+	}
+	total := 0
+	for _, s := range r.scrolls {
+		total += s.Y
+	}
+	if len(r.scrolls) != 4 || total != lines(4) {
+		t.Errorf("Expected 4 steps adding up to %d, got %v", lines(4), r.scrolls)
+	}
+	if r.slept != 150*time.Millisecond {
+		t.Errorf("Expected 150ms between the steps, got %s", r.slept)
+	}
+}
+
+func TestZoom(t *testing.T) {
+	r := stubScroll(t)
+	rc := NewRemoteController(nil, false)
+
+	if err := rc.ExecuteMouseEvent(MouseEvent{Action: MouseZoom, Amount: 2}); err != nil {
+		t.Fatalf("Zoom returned an error: %v", err)
+	}
+	modifier := "ctrl"
+	if runtime.GOOS == "darwin" {
+		modifier = "cmd"
+	}
+	if len(r.keys) != 2 || r.keys[0] != modifier+" down" || r.keys[1] != modifier+" up" {
+		t.Errorf("Expected %s to be held while scrolling, got %v", modifier, r.keys)
+	}
+	if len(r.scrolls) != 1 || r.scrolls[0].Y != lines(2) {
+		t.Errorf("Expected to scroll up to zoom in, got %v", r.scrolls)
+	}
+
+	r.keys = nil
+	if err := rc.ExecuteMouseEvent(MouseEvent{Action: MouseScroll, Amount: 1, Modifiers: []string{"shift", "alt"}}); err != nil {
+		t.Fatalf("Scroll returned an error: %v", err)
+	}
+	want := []string{"shift down", "alt down", "alt up", "shift up"}
+	for i := range want {
+		if i >= len(r.keys) || r.keys[i] != want[i] {
+			t.Fatalf("Expected keys %v, got %v", want, r.keys)
+		}
+	}
+
+	if err := rc.ExecuteMouseEvent(MouseEvent{Action: MouseZoom, AmountX: 1}); err == nil {
+		t.Error("Expected a horizontal zoom to be rejected")
+	}
+}
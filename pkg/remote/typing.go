@@ -0,0 +1,164 @@
+package remote
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// pasteSettle is how long a pasted text stays on the clipboard before the
+// previous content is restored, so the application has read it
+const pasteSettle = 100 * time.Millisecond
+
+// controlKeys are the characters typed with a named key on every layout
+var controlKeys = map[rune]string{
+	'\n': "enter",
+	'\r': "enter",
+	'\t': "tab",
+	'\b': "backspace",
+	' ':  "space",
+}
+
+// typeMode is how a run of characters is injected
+type typeMode int
+
+const (
+	typeKeys    typeMode = iota // Keystrokes of the keyboard layout
+	typeUnicode                 // Unicode input through robotgo.TypeStr
+	typePaste                   // The clipboard and the paste shortcut
+)
+
+// typeText types text with the keys of the active keyboard layout. Characters
+// without a key, such as emoji or CJK text, are pasted through the clipboard.
+// Without a known layout, characters robotgo can inject as Unicode are typed
+// that way and only the rest is pasted.
+func (rc *RemoteController) typeText(text string) error {
+	layout := rc.keyboardLayout()
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var run strings.Builder
+	mode := typeKeys
+	flush := func() error {
+		defer run.Reset()
+		switch {
+		case run.Len() == 0:
+			return nil
+		case mode == typeUnicode:
+			robotgoTypeStrFunc(run.String())
+			return nil
+		default:
+			return pasteText(run.String())
+		}
+	}
+
+	for _, r := range text {
+		next := typePaste
+		var ks keystroke
+		if key, ok := controlKeys[r]; ok {
+			next, ks = typeKeys, keystroke{key: key}
+		} else if layout != nil {
+			if ks, ok = layout.keystroke(r); ok {
+				next = typeKeys
+			}
+		} else if r <= 0xFFFF {
+			// robotgo injects UTF-16 code units, so only the Basic Multilingual Plane
+			next = typeUnicode
+		}
+
+		if next != mode {
+			if err := flush(); err != nil {
+				return err
+			}
+			mode = next
+		}
+		if mode != typeKeys {
+			run.WriteRune(r)
+			continue
+		}
+		if err := tapKeystroke(ks); err != nil {
+			return err
+		}
+	}
+	return flush()
+}
+
+// altGrKeys returns the keys held for AltGr; Windows treats Ctrl+Alt as AltGr
+func altGrKeys() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"ctrl", "alt"}
+	}
+	return []string{"ralt"}
+}
+
+// tapKeystroke taps a key with the modifiers of a keystroke held
+func tapKeystroke(ks keystroke) error {
+	var modifiers []string
+	if ks.shift {
+		modifiers = append(modifiers, "shift")
+	}
+	if ks.altGr {
+		modifiers = append(modifiers, altGrKeys()...)
+	}
+	for i, key := range modifiers {
+		if err := robotgoKeyToggleFunc(key, "down"); err != nil {
+			releaseKeys(modifiers[:i])
+			return fmt.Errorf("failed to press %s: %w", key, err)
+		}
+	}
+	defer releaseKeys(modifiers)
+	robotgoKeyTapFunc(ks.key)
+	return nil
+}
+
+// pasteText puts text on the clipboard, pastes it and restores the previous
+// clipboard text
+func pasteText(text string) error {
+	previous, readErr := robotgoReadClipboardFunc()
+	if err := robotgoWriteClipboardFunc(text); err != nil {
+		return fmt.Errorf("failed to put text on the clipboard for pasting: %w", err)
+	}
+	robotgoKeyTapFunc("v", shortcutModifier())
+	sleepFunc(pasteSettle)
+	if readErr == nil {
+		if err := robotgoWriteClipboardFunc(previous); err != nil {
+			return fmt.Errorf("failed to restore the clipboard after pasting: %w", err)
+		}
+	}
+	return nil
+}
+
+// resolveKey converts a key name from an event to the robotgo name of the key
+// on the active layout. Codes of character keys name a position on the
+// keyboard, so they become the key in that position.
+func (rc *RemoteController) resolveKey(name string) string {
+	if usKey, ok := codeKey(name); ok {
+		if layout := rc.keyboardLayout(); layout != nil {
+			if key, ok := layout.positionKey(usKey); ok {
+				return key
+			}
+		}
+		return usKey
+	}
+	return NormalizeKey(name)
+}
+
+// typedKey returns true if pressing a key should type its character instead,
+// because it is a single character that needs modifiers or has no key on the
+// active layout
+func (rc *RemoteController) typedKey(key string) bool {
+	r, size := utf8.DecodeRuneInString(key)
+	if size == 0 || size != len(key) {
+		return false
+	}
+	if _, ok := controlKeys[r]; ok {
+		return false
+	}
+	layout := rc.keyboardLayout()
+	if layout == nil {
+		return r >= utf8.RuneSelf
+	}
+	ks, ok := layout.keystroke(r)
+	return !ok || ks.shift || ks.altGr
+}
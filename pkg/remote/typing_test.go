@@ -0,0 +1,142 @@
+package remote
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// typingRecorder stubs the keyboard and clipboard wrappers and records their calls
+type typingRecorder struct {
+	events    []string // Key taps, toggles, Unicode input and pastes in order
+	clipboard string
+}
+
+func stubTyping(t *testing.T) *typingRecorder {
+	t.Helper()
+	tap, toggle, typeStr := robotgoKeyTapFunc, robotgoKeyToggleFunc, robotgoTypeStrFunc
+	read, write, sleep := robotgoReadClipboardFunc, robotgoWriteClipboardFunc, sleepFunc
+	t.Cleanup(func() {
+		robotgoKeyTapFunc, robotgoKeyToggleFunc, robotgoTypeStrFunc = tap, toggle, typeStr
+		robotgoReadClipboardFunc, robotgoWriteClipboardFunc, sleepFunc = read, write, sleep
+	})
+
+	r := &typingRecorder{clipboard: "previous"}
+	robotgoKeyTapFunc = func(key string, modifiers ...string) {
+		if key == "v" && len(modifiers) == 1 && modifiers[0] == shortcutModifier() {
+			r.events = append(r.events, "paste "+r.clipboard)
+			return
+		}
+		r.events = append(r.events, "tap "+key)
+	}
+	robotgoKeyToggleFunc = func(key, direction string) error {
+		r.events = append(r.events, key+" "+direction)
+		return nil
+	}
+	robotgoTypeStrFunc = func(text string) { r.events = append(r.events, "unicode "+text) }
+	robotgoReadClipboardFunc = func() (string, error) { return r.clipboard, nil }
+	robotgoWriteClipboardFunc = func(text string) error {
+		r.clipboard = text
+		return nil
+	}
+	sleepFunc = func(time.Duration) {}
+	return r
+}
+
+func TestTypeTextWithLayout(t *testing.T) {
+	r := stubTyping(t)
+	rc := NewRemoteController(nil, false)
+	if err := rc.SetKeyboardLayout("de"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rc.ExecuteKeyboardEvent(KeyboardEvent{Action: KeyType, Text: "Zy\"ö😀\n"}); err != nil {
+		t.Fatalf("Typing returned an error: %v", err)
+	}
+	want := []string{
+		"shift down", "tap z", "shift up",
+		"tap y",
+		"shift down", "tap 2", "shift up",
+		"paste ö😀",
+		"tap enter",
+	}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Errorf("Expected %q, got %q", want, r.events)
+	}
+	if r.clipboard != "previous" {
+		t.Errorf("Expected the clipboard to be restored, got %q", r.clipboard)
+	}
+
+	if runtime.GOOS != "darwin" {
+		r.events = nil
+		if err := rc.ExecuteKeyboardEvent(KeyboardEvent{Action: KeyType, Text: "@"}); err != nil {
+			t.Fatal(err)
+		}
+		want := append(pressKeys(altGrKeys(), "down"), "tap q")
+		want = append(want, pressKeys(reverse(altGrKeys()), "up")...)
+		if !reflect.DeepEqual(r.events, want) {
+			t.Errorf("Expected @ to be typed with AltGr+Q: %q, got %q", want, r.events)
+		}
+	}
+}
+
+func TestTypeTextWithoutLayout(t *testing.T) {
+	r := stubTyping(t)
+	original := layoutCommandFunc
+	t.Cleanup(func() { layoutCommandFunc = original })
+	layoutCommandFunc = func(name string, args ...string) ([]byte, error) {
+		return []byte("layout: xx\n\"KeyboardLayout Name\" = Unknown;\n0000:00000000"), nil
+	}
+
+	rc := NewRemoteController(nil, false)
+	if err := rc.ExecuteKeyboardEvent(KeyboardEvent{Action: KeyType, Text: "hi 世界👍"}); err != nil {
+		t.Fatalf("Typing returned an error: %v", err)
+	}
+	want := []string{"unicode hi", "tap space", "unicode 世界", "paste 👍"}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Errorf("Expected %q, got %q", want, r.events)
+	}
+}
+
+func TestPressKeyNames(t *testing.T) {
+	r := stubTyping(t)
+	rc := NewRemoteController(nil, false)
+	if err := rc.SetKeyboardLayout("de"); err != nil {
+		t.Fatal(err)
+	}
+
+	events := []KeyboardEvent{
+		{Action: KeyPress, Key: "ArrowLeft"},
+		{Action: KeyPress, Code: "KeyY"}, // The Z key on a German keyboard
+		{Action: KeyDown, Key: "ShiftLeft"},
+		{Action: KeyPress, Key: "?"}, // Shift+ß, typed through the clipboard
+	}
+	for _, event := range events {
+		if err := rc.ExecuteKeyboardEvent(event); err != nil {
+			t.Fatalf("ExecuteKeyboardEvent(%+v) returned an error: %v", event, err)
+		}
+	}
+	want := []string{"tap left", "tap z", "lshift down", "paste ?"}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Errorf("Expected %q, got %q", want, r.events)
+	}
+}
+
+// pressKeys returns the toggle events of keys
+func pressKeys(keys []string, direction string) []string {
+	events := make([]string, len(keys))
+	for i, key := range keys {
+		events[i] = key + " " + direction
+	}
+	return events
+}
+
+// reverse returns keys in reverse order
+func reverse(keys []string) []string {
+	reversed := make([]string, len(keys))
+	for i, key := range keys {
+		reversed[len(keys)-1-i] = key
+	}
+	return reversed
+}
@@ -0,0 +1,161 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// WindowAction represents a window operation
+type WindowAction string
+
+const (
+	WindowFocus    WindowAction = "focus"
+	WindowRaise    WindowAction = "raise"
+	WindowMinimize WindowAction = "minimize"
+	WindowMove     WindowAction = "move"
+	WindowResize   WindowAction = "resize"
+)
+
+// ErrWindowsUnsupported is returned when windows cannot be managed on this platform or display
+var ErrWindowsUnsupported = errors.New("window management is not supported on this platform")
+
+// Window describes a top-level window
+type Window struct {
+	ID        uint64 `json:"id"`
+	Title     string `json:"title"`
+	PID       int    `json:"pid,omitempty"`
+	Process   string `json:"process,omitempty"` // Name of the owner process
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Minimized bool   `json:"minimized"`
+	Focused   bool   `json:"focused"`
+}
+
+// WindowEvent represents a window operation requested by the server
+type WindowEvent struct {
+	Action   WindowAction `json:"action"`
+	WindowID uint64       `json:"windowId"`
+	X        int          `json:"x,omitempty"`      // For moving
+	Y        int          `json:"y,omitempty"`      // For moving
+	Width    int          `json:"width,omitempty"`  // For resizing
+	Height   int          `json:"height,omitempty"` // For resizing
+}
+
+// WindowManager lists and manipulates top-level windows. Each platform has
+// its own implementation: X11 on Linux and the BSDs, System Events on macOS
+// and user32 on Windows.
+type WindowManager interface {
+	List() ([]Window, error)
+	Focus(id uint64) error
+	Raise(id uint64) error
+	Minimize(id uint64) error
+	Move(id uint64, x, y int) error
+	Resize(id uint64, width, height int) error
+}
+
+// windowState holds the window manager of a controller, created on first use
+type windowState struct {
+	manager WindowManager
+	mu      sync.Mutex
+}
+
+// SetWindowManager replaces the platform window manager, e.g. in tests
+func (rc *RemoteController) SetWindowManager(manager WindowManager) {
+	rc.windows.mu.Lock()
+	defer rc.windows.mu.Unlock()
+	rc.windows.manager = manager
+}
+
+// windowManager returns the window manager, connecting to the platform on first
+// use. A failed connection is retried on the next call.
+func (rc *RemoteController) windowManager() (WindowManager, error) {
+	rc.windows.mu.Lock()
+	defer rc.windows.mu.Unlock()
+
+	if rc.windows.manager == nil {
+		manager, err := newWindowManager()
+		if err != nil {
+			return nil, err
+		}
+		rc.windows.manager = manager
+	}
+	return rc.windows.manager, nil
+}
+
+// ListWindows returns the top-level windows
+func (rc *RemoteController) ListWindows() ([]Window, error) {
+	if err := rc.checkPermissions(); err != nil {
+		return nil, err
+	}
+
+	manager, err := rc.windowManager()
+	if err != nil {
+		return nil, err
+	}
+
+	windows, err := manager.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list windows: %w", err)
+	}
+	for i := range windows {
+		if windows[i].Process == "" && windows[i].PID > 0 {
+			windows[i].Process = processName(windows[i].PID)
+		}
+	}
+	return windows, nil
+}
+
+// ExecuteWindowEvent executes a window event
+func (rc *RemoteController) ExecuteWindowEvent(event WindowEvent) error {
+	if err := rc.checkPermissions(); err != nil {
+		log.Printf("Permission check failed: %v", err)
+		return err
+	}
+
+	manager, err := rc.windowManager()
+	if err != nil {
+		return err
+	}
+
+	if rc.verbose {
+		log.Printf("Executing window event: %+v", event)
+	}
+
+	switch event.Action {
+	case WindowFocus:
+		err = manager.Focus(event.WindowID)
+	case WindowRaise:
+		err = manager.Raise(event.WindowID)
+	case WindowMinimize:
+		err = manager.Minimize(event.WindowID)
+	case WindowMove:
+		err = manager.Move(event.WindowID, event.X, event.Y)
+	case WindowResize:
+		if event.Width <= 0 || event.Height <= 0 {
+			return fmt.Errorf("invalid window size: %dx%d", event.Width, event.Height)
+		}
+		err = manager.Resize(event.WindowID, event.Width, event.Height)
+	default:
+		return fmt.Errorf("unsupported window action: %s", event.Action)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s window %d: %w", event.Action, event.WindowID, err)
+	}
+	return nil
+}
+
+// processName returns the name of a process, or "" if it cannot be read
+func processName(pid int) string {
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return ""
+	}
+	name, _ := p.Name()
+	return name
+}
@@ -0,0 +1,141 @@
+//go:build darwin
+// +build darwin
+
+package remote
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// listWindowsScript prints one tab-separated line per window of every
+// foreground application, with the title last because it may contain tabs
+const listWindowsScript = `
+set output to ""
+tell application "System Events"
+	repeat with proc in (every process whose background only is false)
+		set procID to unix id of proc
+		set procName to name of proc
+		set isFront to frontmost of proc
+		set windowIndex to 0
+		repeat with win in windows of proc
+			set windowIndex to windowIndex + 1
+			try
+				set {winX, winY} to position of win
+				set {winWidth, winHeight} to size of win
+				set winTitle to ""
+				try
+					set winTitle to name of win as text
+				end try
+				set isMinimized to false
+				try
+					set isMinimized to value of attribute "AXMinimized" of win
+				end try
+				set isFocused to isFront and windowIndex is 1
+				set output to output & procID & tab & windowIndex & tab & winX & tab & winY & tab & winWidth & tab & winHeight & tab & isMinimized & tab & isFocused & tab & procName & tab & winTitle & linefeed
+			end try
+		end repeat
+	end repeat
+end tell
+return output`
+
+// macOSWindowManager manages windows through System Events, which needs the
+// Accessibility permission. Window IDs combine the owner PID and the window's
+// index in its application, so they change when the application's windows
+// are reordered; list the windows again before acting on an old ID.
+type macOSWindowManager struct{}
+
+// newWindowManager returns the System Events window manager
+func newWindowManager() (WindowManager, error) {
+	return macOSWindowManager{}, nil
+}
+
+// List returns the windows of every foreground application
+func (macOSWindowManager) List() ([]Window, error) {
+	output, err := exec.Command("osascript", "-e", listWindowsScript).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run System Events script: %w", err)
+	}
+	return parseMacOSWindows(string(output)), nil
+}
+
+// Focus brings an application to the front and raises the window
+func (m macOSWindowManager) Focus(id uint64) error {
+	return m.run(id, "set frontmost to true\n\t\tperform action \"AXRaise\" of window %d")
+}
+
+// Raise raises a window above the other windows of its application
+func (m macOSWindowManager) Raise(id uint64) error {
+	return m.run(id, "perform action \"AXRaise\" of window %d")
+}
+
+// Minimize minimizes a window to the Dock
+func (m macOSWindowManager) Minimize(id uint64) error {
+	return m.run(id, "set value of attribute \"AXMinimized\" of window %d to true")
+}
+
+// Move moves a window to a position on the screen
+func (m macOSWindowManager) Move(id uint64, x, y int) error {
+	return m.run(id, fmt.Sprintf("set position of window %%d to {%d, %d}", x, y))
+}
+
+// Resize changes the size of a window
+func (m macOSWindowManager) Resize(id uint64, width, height int) error {
+	return m.run(id, fmt.Sprintf("set size of window %%d to {%d, %d}", width, height))
+}
+
+// run runs a command for one window inside a tell block for its process.
+// The command contains a %d for the window index.
+func (macOSWindowManager) run(id uint64, command string) error {
+	pid, index := int(id>>32), int(id&0xffffffff)
+	if pid <= 0 || index <= 0 {
+		return fmt.Errorf("invalid window ID: %d", id)
+	}
+
+	script := fmt.Sprintf("tell application \"System Events\"\n\ttell (first process whose unix id is %d)\n\t\t%s\n\tend tell\nend tell",
+		pid, fmt.Sprintf(command, index))
+	if output, err := exec.Command("osascript", "-e", script).CombinedOutput(); err != nil {
+		return fmt.Errorf("System Events script failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// parseMacOSWindows parses the output of listWindowsScript
+func parseMacOSWindows(output string) []Window {
+	var windows []Window
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\t", 10)
+		if len(fields) != 10 {
+			continue
+		}
+		numbers := make([]int, 6)
+		valid := true
+		for i := range numbers {
+			n, err := strconv.Atoi(strings.TrimSpace(fields[i]))
+			if err != nil {
+				valid = false
+				break
+			}
+			numbers[i] = n
+		}
+		if !valid {
+			continue
+		}
+
+		windows = append(windows, Window{
+			ID:        uint64(numbers[0])<<32 | uint64(numbers[1]),
+			PID:       numbers[0],
+			X:         numbers[2],
+			Y:         numbers[3],
+			Width:     numbers[4],
+			Height:    numbers[5],
+			Minimized: fields[6] == "true",
+			Focused:   fields[7] == "true",
+			Process:   fields[8],
+			Title:     fields[9],
+		})
+	}
+	return windows
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !freebsd && !openbsd && !netbsd && !dragonfly && !darwin && !windows
+// +build !linux,!freebsd,!openbsd,!netbsd,!dragonfly,!darwin,!windows
+
+package remote
+
+// newWindowManager reports that windows cannot be managed on this platform
+func newWindowManager() (WindowManager, error) {
+	return nil, ErrWindowsUnsupported
+}
@@ -0,0 +1,89 @@
+package remote
+
+import (
+	"os"
+	"testing"
+)
+
+// fakeWindowManager records window operations
+type fakeWindowManager struct {
+	windows []Window
+	calls   []string
+}
+
+func (f *fakeWindowManager) List() ([]Window, error) {
+	return f.windows, nil
+}
+
+func (f *fakeWindowManager) Focus(id uint64) error {
+	f.calls = append(f.calls, "focus")
+	return nil
+}
+
+func (f *fakeWindowManager) Raise(id uint64) error {
+	f.calls = append(f.calls, "raise")
+	return nil
+}
+
+func (f *fakeWindowManager) Minimize(id uint64) error {
+	f.calls = append(f.calls, "minimize")
+	return nil
+}
+
+func (f *fakeWindowManager) Move(id uint64, x, y int) error {
+	f.calls = append(f.calls, "move")
+	return nil
+}
+
+func (f *fakeWindowManager) Resize(id uint64, width, height int) error {
+	f.calls = append(f.calls, "resize")
+	return nil
+}
+
+func TestListWindows(t *testing.T) {
+	manager := &fakeWindowManager{windows: []Window{{ID: 1, Title: "Terminal", PID: os.Getpid()}}}
+	rc := NewRemoteController(&mockPermissionsManager{shouldGrantPermission: true}, false)
+	rc.SetWindowManager(manager)
+
+	windows, err := rc.ListWindows()
+	if err != nil {
+		t.Fatalf("ListWindows() returned an error: %v", err)
+	}
+	if len(windows) != 1 || windows[0].Process == "" {
+		t.Errorf("Expected one window with its process name, got %+v", windows)
+	}
+
+	denied := NewRemoteController(&mockPermissionsManager{shouldGrantPermission: false}, false)
+	denied.SetWindowManager(manager)
+	if _, err := denied.ListWindows(); err == nil {
+		t.Error("Expected an error without the remote control permission")
+	}
+}
+
+func TestExecuteWindowEvent(t *testing.T) {
+	manager := &fakeWindowManager{}
+	rc := NewRemoteController(nil, false)
+	rc.SetWindowManager(manager)
+
+	for _, event := range []WindowEvent{
+		{Action: WindowFocus, WindowID: 1},
+		{Action: WindowRaise, WindowID: 1},
+		{Action: WindowMinimize, WindowID: 1},
+		{Action: WindowMove, WindowID: 1, X: 10, Y: 20},
+		{Action: WindowResize, WindowID: 1, Width: 640, Height: 480},
+	} {
+		if err := rc.ExecuteWindowEvent(event); err != nil {
+			t.Errorf("ExecuteWindowEvent(%s) returned an error: %v", event.Action, err)
+		}
+	}
+	if len(manager.calls) != 5 {
+		t.Errorf("Expected 5 window operations, got %v", manager.calls)
+	}
+
+	if err := rc.ExecuteWindowEvent(WindowEvent{Action: WindowResize, WindowID: 1}); err == nil {
+		t.Error("Expected an error for a resize without a size")
+	}
+	if err := rc.ExecuteWindowEvent(WindowEvent{Action: "close", WindowID: 1}); err == nil {
+		t.Error("Expected an error for an unsupported action")
+	}
+}
@@ -0,0 +1,164 @@
+//go:build windows
+// +build windows
+
+package remote
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32                       = syscall.NewLazyDLL("user32.dll")
+	procEnumWindows              = user32.NewProc("EnumWindows")
+	procIsWindow                 = user32.NewProc("IsWindow")
+	procIsWindowVisible          = user32.NewProc("IsWindowVisible")
+	procIsIconic                 = user32.NewProc("IsIconic")
+	procGetWindowTextW           = user32.NewProc("GetWindowTextW")
+	procGetWindowTextLengthW     = user32.NewProc("GetWindowTextLengthW")
+	procGetWindowRect            = user32.NewProc("GetWindowRect")
+	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
+	procGetForegroundWindow      = user32.NewProc("GetForegroundWindow")
+	procSetForegroundWindow      = user32.NewProc("SetForegroundWindow")
+	procShowWindow               = user32.NewProc("ShowWindow")
+	procSetWindowPos             = user32.NewProc("SetWindowPos")
+)
+
+const (
+	swMinimize = 6
+	swRestore  = 9
+
+	swpNoSize     = 0x0001
+	swpNoMove     = 0x0002
+	swpNoZOrder   = 0x0004
+	swpNoActivate = 0x0010
+)
+
+// rect is the Win32 RECT structure
+type rect struct {
+	Left, Top, Right, Bottom int32
+}
+
+// win32WindowManager manages windows through user32. Window IDs are HWNDs.
+type win32WindowManager struct{}
+
+// newWindowManager returns the user32 window manager
+func newWindowManager() (WindowManager, error) {
+	return win32WindowManager{}, nil
+}
+
+// List returns the visible top-level windows that have a title
+func (m win32WindowManager) List() ([]Window, error) {
+	var handles []uintptr
+	callback := syscall.NewCallback(func(hwnd, lparam uintptr) uintptr {
+		handles = append(handles, hwnd)
+		return 1
+	})
+	if ret, _, err := procEnumWindows.Call(callback, 0); ret == 0 {
+		return nil, fmt.Errorf("EnumWindows failed: %w", err)
+	}
+
+	foreground, _, _ := procGetForegroundWindow.Call()
+	var windows []Window
+	for _, hwnd := range handles {
+		if visible, _, _ := procIsWindowVisible.Call(hwnd); visible == 0 {
+			continue
+		}
+		title := windowText(hwnd)
+		if title == "" {
+			continue
+		}
+
+		var r rect
+		procGetWindowRect.Call(hwnd, uintptr(unsafe.Pointer(&r)))
+		var pid uint32
+		procGetWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
+		iconic, _, _ := procIsIconic.Call(hwnd)
+
+		windows = append(windows, Window{
+			ID:        uint64(hwnd),
+			Title:     title,
+			PID:       int(pid),
+			X:         int(r.Left),
+			Y:         int(r.Top),
+			Width:     int(r.Right - r.Left),
+			Height:    int(r.Bottom - r.Top),
+			Minimized: iconic != 0,
+			Focused:   hwnd == foreground,
+		})
+	}
+	return windows, nil
+}
+
+// Focus restores a minimized window and brings it to the foreground
+func (m win32WindowManager) Focus(id uint64) error {
+	hwnd, err := m.handle(id)
+	if err != nil {
+		return err
+	}
+	if iconic, _, _ := procIsIconic.Call(hwnd); iconic != 0 {
+		procShowWindow.Call(hwnd, swRestore)
+	}
+	if ret, _, _ := procSetForegroundWindow.Call(hwnd); ret == 0 {
+		return fmt.Errorf("SetForegroundWindow was refused")
+	}
+	return nil
+}
+
+// Raise brings a window to the top of the z-order without activating it
+func (m win32WindowManager) Raise(id uint64) error {
+	return m.setPos(id, 0, 0, 0, 0, swpNoMove|swpNoSize|swpNoActivate)
+}
+
+// Minimize minimizes a window
+func (m win32WindowManager) Minimize(id uint64) error {
+	hwnd, err := m.handle(id)
+	if err != nil {
+		return err
+	}
+	procShowWindow.Call(hwnd, swMinimize)
+	return nil
+}
+
+// Move moves a window to a position on the screen
+func (m win32WindowManager) Move(id uint64, x, y int) error {
+	return m.setPos(id, x, y, 0, 0, swpNoSize|swpNoZOrder|swpNoActivate)
+}
+
+// Resize changes the size of a window
+func (m win32WindowManager) Resize(id uint64, width, height int) error {
+	return m.setPos(id, 0, 0, width, height, swpNoMove|swpNoZOrder|swpNoActivate)
+}
+
+// setPos calls SetWindowPos with HWND_TOP as the insert-after window
+func (m win32WindowManager) setPos(id uint64, x, y, width, height int, flags uintptr) error {
+	hwnd, err := m.handle(id)
+	if err != nil {
+		return err
+	}
+	if ret, _, err := procSetWindowPos.Call(hwnd, 0, uintptr(x), uintptr(y), uintptr(width), uintptr(height), flags); ret == 0 {
+		return fmt.Errorf("SetWindowPos failed: %w", err)
+	}
+	return nil
+}
+
+// handle checks that a window ID is an existing window
+func (win32WindowManager) handle(id uint64) (uintptr, error) {
+	hwnd := uintptr(id)
+	if ret, _, _ := procIsWindow.Call(hwnd); ret == 0 {
+		return 0, fmt.Errorf("no window with ID %d", id)
+	}
+	return hwnd, nil
+}
+
+// windowText returns the title of a window
+func windowText(hwnd uintptr) string {
+	length, _, _ := procGetWindowTextLengthW.Call(hwnd)
+	if length == 0 {
+		return ""
+	}
+	buffer := make([]uint16, length+1)
+	procGetWindowTextW.Call(hwnd, uintptr(unsafe.Pointer(&buffer[0])), length+1)
+	return syscall.UTF16ToString(buffer)
+}
@@ -0,0 +1,279 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+// +build linux freebsd openbsd netbsd dragonfly
+
+package remote
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// ICCCM WM_STATE value of a minimized window
+const iconicState = 3
+
+// x11WindowManager manages windows through the X server. It uses the EWMH
+// hints of the window manager when there is one and falls back to plain X11
+// requests, e.g. on a bare Xvfb display.
+type x11WindowManager struct {
+	conn  *xgb.Conn
+	root  xproto.Window
+	atoms map[string]xproto.Atom
+	mu    sync.Mutex
+}
+
+// newWindowManager connects to the X server in $DISPLAY
+func newWindowManager() (WindowManager, error) {
+	if os.Getenv("DISPLAY") == "" {
+		return nil, fmt.Errorf("%w: DISPLAY is not set", ErrWindowsUnsupported)
+	}
+	return newX11WindowManager("")
+}
+
+// newX11WindowManager connects to an X display ("" for $DISPLAY)
+func newX11WindowManager(display string) (*x11WindowManager, error) {
+	conn, err := xgb.NewConnDisplay(display)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X server: %w", err)
+	}
+	return &x11WindowManager{
+		conn:  conn,
+		root:  xproto.Setup(conn).DefaultScreen(conn).Root,
+		atoms: make(map[string]xproto.Atom),
+	}, nil
+}
+
+// Close closes the connection to the X server
+func (m *x11WindowManager) Close() {
+	m.conn.Close()
+}
+
+// List returns the managed windows, or the mapped top-level windows if no
+// window manager publishes a client list
+func (m *x11WindowManager) List() ([]Window, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids, err := m.clientList()
+	if err != nil {
+		return nil, err
+	}
+	focused := m.activeWindow()
+
+	windows := make([]Window, 0, len(ids))
+	for _, id := range ids {
+		window, err := m.describe(id)
+		if err != nil {
+			// The window was closed while listing
+			continue
+		}
+		window.Focused = id == focused
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// Focus activates a window through the window manager, or sets the input focus directly
+func (m *x11WindowManager) Focus(id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	win := xproto.Window(id)
+	if m.hasWindowManager() {
+		// Source indication 2 marks the request as coming from a pager, which
+		// window managers honor without focus stealing prevention
+		return m.sendClientMessage(win, "_NET_ACTIVE_WINDOW", 2, uint32(xproto.TimeCurrentTime))
+	}
+	if err := m.raise(win); err != nil {
+		return err
+	}
+	return xproto.SetInputFocus(m.conn, xproto.InputFocusPointerRoot, win, xproto.TimeCurrentTime).Check()
+}
+
+// Raise brings a window to the top of the stacking order
+func (m *x11WindowManager) Raise(id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.raise(xproto.Window(id))
+}
+
+// Minimize asks the window manager to iconify a window
+func (m *x11WindowManager) Minimize(id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.hasWindowManager() {
+		return fmt.Errorf("%w: minimizing needs a window manager", ErrWindowsUnsupported)
+	}
+	return m.sendClientMessage(xproto.Window(id), "WM_CHANGE_STATE", iconicState)
+}
+
+// Move moves a window to a position on the screen
+func (m *x11WindowManager) Move(id uint64, x, y int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return xproto.ConfigureWindowChecked(m.conn, xproto.Window(id),
+		xproto.ConfigWindowX|xproto.ConfigWindowY,
+		[]uint32{uint32(int32(x)), uint32(int32(y))}).Check()
+}
+
+// Resize changes the size of a window
+func (m *x11WindowManager) Resize(id uint64, width, height int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return xproto.ConfigureWindowChecked(m.conn, xproto.Window(id),
+		xproto.ConfigWindowWidth|xproto.ConfigWindowHeight,
+		[]uint32{uint32(width), uint32(height)}).Check()
+}
+
+// raise restacks a window above its siblings
+func (m *x11WindowManager) raise(win xproto.Window) error {
+	return xproto.ConfigureWindowChecked(m.conn, win, xproto.ConfigWindowStackMode,
+		[]uint32{xproto.StackModeAbove}).Check()
+}
+
+// clientList returns the windows managed by the window manager, or the
+// viewable children of the root window without one
+func (m *x11WindowManager) clientList() ([]xproto.Window, error) {
+	if value, err := m.property(m.root, "_NET_CLIENT_LIST"); err == nil && len(value) > 0 {
+		ids := make([]xproto.Window, 0, len(value)/4)
+		for i := 0; i+4 <= len(value); i += 4 {
+			ids = append(ids, xproto.Window(xgb.Get32(value[i:])))
+		}
+		return ids, nil
+	}
+
+	tree, err := xproto.QueryTree(m.conn, m.root).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query window tree: %w", err)
+	}
+	var ids []xproto.Window
+	for _, child := range tree.Children {
+		attrs, err := xproto.GetWindowAttributes(m.conn, child).Reply()
+		if err != nil || attrs.OverrideRedirect || attrs.MapState != xproto.MapStateViewable {
+			continue
+		}
+		ids = append(ids, child)
+	}
+	return ids, nil
+}
+
+// describe reads the title, owner, bounds and state of a window
+func (m *x11WindowManager) describe(win xproto.Window) (Window, error) {
+	geometry, err := xproto.GetGeometry(m.conn, xproto.Drawable(win)).Reply()
+	if err != nil {
+		return Window{}, err
+	}
+	// Window managers reparent windows into frames, so translate to root coordinates
+	position, err := xproto.TranslateCoordinates(m.conn, win, m.root, 0, 0).Reply()
+	if err != nil {
+		return Window{}, err
+	}
+
+	window := Window{
+		ID:     uint64(win),
+		Title:  m.title(win),
+		X:      int(position.DstX),
+		Y:      int(position.DstY),
+		Width:  int(geometry.Width),
+		Height: int(geometry.Height),
+	}
+	if value, err := m.property(win, "_NET_WM_PID"); err == nil && len(value) >= 4 {
+		window.PID = int(xgb.Get32(value))
+	}
+	window.Minimized = m.minimized(win)
+	return window, nil
+}
+
+// title returns the EWMH title of a window, or its ICCCM name
+func (m *x11WindowManager) title(win xproto.Window) string {
+	if value, err := m.property(win, "_NET_WM_NAME"); err == nil && len(value) > 0 {
+		return string(value)
+	}
+	if value, err := m.property(win, "WM_NAME"); err == nil {
+		return string(value)
+	}
+	return ""
+}
+
+// minimized reports whether a window is hidden or iconified
+func (m *x11WindowManager) minimized(win xproto.Window) bool {
+	if value, err := m.property(win, "_NET_WM_STATE"); err == nil {
+		hidden := m.atom("_NET_WM_STATE_HIDDEN")
+		for i := 0; i+4 <= len(value); i += 4 {
+			if xproto.Atom(xgb.Get32(value[i:])) == hidden {
+				return true
+			}
+		}
+	}
+	if value, err := m.property(win, "WM_STATE"); err == nil && len(value) >= 4 {
+		return xgb.Get32(value) == iconicState
+	}
+	return false
+}
+
+// activeWindow returns the focused window
+func (m *x11WindowManager) activeWindow() xproto.Window {
+	if value, err := m.property(m.root, "_NET_ACTIVE_WINDOW"); err == nil && len(value) >= 4 {
+		return xproto.Window(xgb.Get32(value))
+	}
+	if focus, err := xproto.GetInputFocus(m.conn).Reply(); err == nil {
+		return focus.Focus
+	}
+	return xproto.WindowNone
+}
+
+// hasWindowManager reports whether an EWMH window manager is running
+func (m *x11WindowManager) hasWindowManager() bool {
+	value, err := m.property(m.root, "_NET_SUPPORTING_WM_CHECK")
+	return err == nil && len(value) >= 4
+}
+
+// sendClientMessage sends a client message about a window to the window manager
+func (m *x11WindowManager) sendClientMessage(win xproto.Window, messageType string, data ...uint32) error {
+	values := make([]uint32, 5)
+	copy(values, data)
+
+	event := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: win,
+		Type:   m.atom(messageType),
+		Data:   xproto.ClientMessageDataUnionData32New(values),
+	}
+	mask := uint32(xproto.EventMaskSubstructureRedirect | xproto.EventMaskSubstructureNotify)
+	return xproto.SendEventChecked(m.conn, false, m.root, mask, string(event.Bytes())).Check()
+}
+
+// property reads the whole value of a window property
+func (m *x11WindowManager) property(win xproto.Window, name string) ([]byte, error) {
+	atom := m.atom(name)
+	if atom == xproto.AtomNone {
+		return nil, fmt.Errorf("unknown atom %s", name)
+	}
+	reply, err := xproto.GetProperty(m.conn, false, win, atom, xproto.GetPropertyTypeAny, 0, 1<<16).Reply()
+	if err != nil {
+		return nil, err
+	}
+	if reply.Format == 0 {
+		return nil, fmt.Errorf("window %d has no %s property", win, name)
+	}
+	return reply.Value, nil
+}
+
+// atom returns the atom for a name, caching it
+func (m *x11WindowManager) atom(name string) xproto.Atom {
+	if atom, ok := m.atoms[name]; ok {
+		return atom
+	}
+	reply, err := xproto.InternAtom(m.conn, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return xproto.AtomNone
+	}
+	m.atoms[name] = reply.Atom
+	return reply.Atom
+}
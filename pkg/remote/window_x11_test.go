@@ -0,0 +1,84 @@
+//go:build linux
+// +build linux
+
+package remote
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// TestX11Windows runs against the X server in $DISPLAY, e.g. xvfb-run go test ./pkg/remote/
+func TestX11Windows(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" {
+		t.Skip("DISPLAY is not set; run under Xvfb to test X11 window management")
+	}
+
+	manager, err := newX11WindowManager("")
+	if err != nil {
+		t.Fatalf("Failed to connect to X server: %v", err)
+	}
+	defer manager.Close()
+
+	// Create and map a titled top-level window
+	conn := manager.conn
+	screen := xproto.Setup(conn).DefaultScreen(conn)
+	win, err := xproto.NewWindowId(conn)
+	if err != nil {
+		t.Fatalf("Failed to allocate a window ID: %v", err)
+	}
+	xproto.CreateWindow(conn, screen.RootDepth, win, screen.Root, 10, 20, 200, 100, 0,
+		xproto.WindowClassInputOutput, screen.RootVisual, 0, nil)
+	title := "go-support window test"
+	xproto.ChangeProperty(conn, xproto.PropModeReplace, win, xproto.AtomWmName, xproto.AtomString, 8, uint32(len(title)), []byte(title))
+	xproto.MapWindow(conn, win)
+	defer xproto.DestroyWindow(conn, win)
+
+	find := func() Window {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			windows, err := manager.List()
+			if err != nil {
+				t.Fatalf("List() returned an error: %v", err)
+			}
+			for _, w := range windows {
+				if w.ID == uint64(win) {
+					return w
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Fatal("The test window was not listed")
+		return Window{}
+	}
+
+	if w := find(); w.Title != title || w.Width != 200 || w.Height != 100 {
+		t.Errorf("Unexpected window: %+v", w)
+	}
+
+	if err := manager.Resize(uint64(win), 320, 240); err != nil {
+		t.Fatalf("Resize() returned an error: %v", err)
+	}
+	if err := manager.Raise(uint64(win)); err != nil {
+		t.Fatalf("Raise() returned an error: %v", err)
+	}
+	if err := manager.Focus(uint64(win)); err != nil {
+		t.Fatalf("Focus() returned an error: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w := find()
+		if w.Width == 320 && w.Height == 240 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Window was not resized: %+v", w)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
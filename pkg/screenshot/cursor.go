@@ -0,0 +1,152 @@
+package screenshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// ErrCursorUnsupported is returned when the cursor cannot be read on this platform or display
+var ErrCursorUnsupported = errors.New("reading the cursor is not supported on this platform")
+
+// CursorShape is the image of the mouse pointer
+type CursorShape struct {
+	Serial uint64      // Changes whenever the shape changes
+	HotX   int         // X offset of the hotspot in the image
+	HotY   int         // Y offset of the hotspot in the image
+	Image  *image.RGBA // Premultiplied pixels of the pointer
+}
+
+// Cursor is the position and shape of the mouse pointer
+type Cursor struct {
+	X       int             // X coordinate of the hotspot on the screen
+	Y       int             // Y coordinate of the hotspot on the screen
+	Visible bool            // Whether the pointer is shown
+	Shape   *CursorShape    // Image of the pointer
+	Screen  image.Rectangle // Bounds of the screen the coordinates refer to
+}
+
+// arrowBitmap is the default pointer, drawn when the platform cursor image
+// is unavailable. '#' is the outline and '.' the fill.
+var arrowBitmap = []string{
+	"#",
+	"##",
+	"#.#",
+	"#..#",
+	"#...#",
+	"#....#",
+	"#.....#",
+	"#......#",
+	"#.......#",
+	"#........#",
+	"#.........#",
+	"#......#####",
+	"#...#..#",
+	"#..##..#",
+	"#.#  #..#",
+	"##   #..#",
+	"#     #..#",
+	"      #..#",
+	"       ##",
+}
+
+// DefaultCursorShape returns a plain arrow pointer with its hotspot at the tip
+func DefaultCursorShape() *CursorShape {
+	width := 0
+	for _, row := range arrowBitmap {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, len(arrowBitmap)))
+	for y, row := range arrowBitmap {
+		for x, pixel := range row {
+			switch pixel {
+			case '#':
+				img.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+			case '.':
+				img.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+			}
+		}
+	}
+	return &CursorShape{Image: img}
+}
+
+// PNG encodes the pointer image as PNG
+func (s *CursorShape) PNG() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, s.Image); err != nil {
+		return nil, fmt.Errorf("failed to encode cursor image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// CompositeCursor draws the pointer over an image. If the cursor has screen
+// bounds, its position is scaled from the screen to the image, e.g. for
+// captures at a different resolution than the screen coordinates.
+func CompositeCursor(img image.Image, cursor *Cursor) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+
+	if cursor == nil || !cursor.Visible {
+		return dst
+	}
+	shape := cursor.Shape
+	if shape == nil || shape.Image == nil {
+		shape = DefaultCursorShape()
+	}
+
+	x, y := cursor.X, cursor.Y
+	scaleX, scaleY := 1.0, 1.0
+	if screen := cursor.Screen; !screen.Empty() {
+		scaleX = float64(bounds.Dx()) / float64(screen.Dx())
+		scaleY = float64(bounds.Dy()) / float64(screen.Dy())
+		x = int(float64(x-screen.Min.X) * scaleX)
+		y = int(float64(y-screen.Min.Y) * scaleY)
+	}
+
+	// Scale the pointer with the screen so it keeps its apparent size
+	size := shape.Image.Bounds()
+	width := int(float64(size.Dx())*scaleX + 0.5)
+	height := int(float64(size.Dy())*scaleY + 0.5)
+	origin := image.Pt(bounds.Min.X+x-int(float64(shape.HotX)*scaleX), bounds.Min.Y+y-int(float64(shape.HotY)*scaleY))
+	target := image.Rect(origin.X, origin.Y, origin.X+width, origin.Y+height)
+
+	if width == size.Dx() && height == size.Dy() {
+		draw.Draw(dst, target, shape.Image, size.Min, draw.Over)
+	} else {
+		draw.BiLinear.Scale(dst, target, shape.Image, size, draw.Over, nil)
+	}
+	return dst
+}
+
+// DrawCursor draws the pointer into the screenshot, keeping its format
+func (s *Screenshot) DrawCursor(cursor *Cursor) error {
+	img, _, err := image.Decode(bytes.NewReader(s.Data))
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	composited := CompositeCursor(img, cursor)
+
+	var buf bytes.Buffer
+	if s.Format == "jpeg" {
+		err = jpeg.Encode(&buf, composited, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(&buf, composited)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	s.Data = buf.Bytes()
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !freebsd && !openbsd && !netbsd && !dragonfly
+// +build !linux,!freebsd,!openbsd,!netbsd,!dragonfly
+
+package screenshot
+
+// CaptureCursor is not implemented on this platform; callers fall back to
+// the pointer position from the input backend and the default shape
+func CaptureCursor() (*Cursor, error) {
+	return nil, ErrCursorUnsupported
+}
@@ -0,0 +1,75 @@
+package screenshot
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestDefaultCursorShape(t *testing.T) {
+	shape := DefaultCursorShape()
+	if shape.Image.Bounds().Dx() == 0 || shape.Image.Bounds().Dy() == 0 {
+		t.Fatal("Expected a non-empty default cursor")
+	}
+	if _, _, _, a := shape.Image.At(0, 0).RGBA(); a == 0 {
+		t.Error("Expected the tip of the arrow at the hotspot")
+	}
+
+	data, err := shape.PNG()
+	if err != nil {
+		t.Fatalf("PNG() returned an error: %v", err)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("Failed to decode cursor PNG: %v", err)
+	}
+}
+
+func TestCompositeCursor(t *testing.T) {
+	shape := &CursorShape{HotX: 1, HotY: 1, Image: image.NewRGBA(image.Rect(0, 0, 2, 2))}
+	for i := range shape.Image.Pix {
+		shape.Image.Pix[i] = 255
+	}
+	white := color.RGBA{255, 255, 255, 255}
+
+	frame := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	result := CompositeCursor(frame, &Cursor{X: 10, Y: 20, Visible: true, Shape: shape})
+	if result.RGBAAt(9, 19) != white || result.RGBAAt(10, 20) != white {
+		t.Error("Expected the cursor drawn around its hotspot")
+	}
+	if result.RGBAAt(11, 21) == white || frame.RGBAAt(10, 20) == white {
+		t.Error("Expected only the cursor pixels to change in a copy of the frame")
+	}
+
+	// The frame is half the size of the screen
+	result = CompositeCursor(frame, &Cursor{X: 100, Y: 60, Visible: true, Shape: shape, Screen: image.Rect(0, 0, 200, 100)})
+	if result.RGBAAt(50, 30) != white {
+		t.Error("Expected the cursor position scaled to the frame")
+	}
+
+	result = CompositeCursor(frame, &Cursor{X: 10, Y: 20, Visible: false, Shape: shape})
+	if result.RGBAAt(10, 20) == white {
+		t.Error("Expected a hidden cursor not to be drawn")
+	}
+}
+
+func TestDrawCursor(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 40))); err != nil {
+		t.Fatal(err)
+	}
+	ss := &Screenshot{Data: buf.Bytes(), Width: 40, Height: 40, Format: "png"}
+
+	if err := ss.DrawCursor(&Cursor{X: 5, Y: 5, Visible: true}); err != nil {
+		t.Fatalf("DrawCursor() returned an error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(ss.Data))
+	if err != nil {
+		t.Fatalf("Failed to decode the screenshot: %v", err)
+	}
+	// The default arrow has its black tip at the hotspot
+	if _, _, _, a := img.At(5, 5).RGBA(); a == 0 {
+		t.Error("Expected the default cursor drawn at the pointer position")
+	}
+}
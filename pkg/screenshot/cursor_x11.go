@@ -0,0 +1,102 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+// +build linux freebsd openbsd netbsd dragonfly
+
+package screenshot
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xfixes"
+	"github.com/jezek/xgb/xproto"
+)
+
+// cursorConn is the X server connection used to read the cursor, opened on first use
+var cursorConn struct {
+	conn  *xgb.Conn
+	shape *CursorShape // Last shape read, reused while its serial is unchanged
+	mu    sync.Mutex
+}
+
+// CaptureCursor reads the position and image of the pointer through the
+// XFixes extension of the X server in $DISPLAY
+func CaptureCursor() (*Cursor, error) {
+	cursorConn.mu.Lock()
+	defer cursorConn.mu.Unlock()
+
+	if cursorConn.conn == nil {
+		if os.Getenv("DISPLAY") == "" {
+			return nil, fmt.Errorf("%w: DISPLAY is not set", ErrCursorUnsupported)
+		}
+		conn, err := openXFixes()
+		if err != nil {
+			return nil, err
+		}
+		cursorConn.conn = conn
+	}
+	conn := cursorConn.conn
+
+	reply, err := xfixes.GetCursorImage(conn).Reply()
+	if err != nil {
+		// The server may have gone away; reconnect on the next call
+		conn.Close()
+		cursorConn.conn = nil
+		return nil, fmt.Errorf("failed to read cursor image: %w", err)
+	}
+
+	root := xproto.Setup(conn).DefaultScreen(conn)
+	cursor := &Cursor{
+		X:       int(reply.X),
+		Y:       int(reply.Y),
+		Visible: reply.Width > 0 && reply.Height > 0,
+		Screen:  image.Rect(0, 0, int(root.WidthInPixels), int(root.HeightInPixels)),
+	}
+	if !cursor.Visible {
+		return cursor, nil
+	}
+
+	if cursorConn.shape == nil || cursorConn.shape.Serial != uint64(reply.CursorSerial) {
+		cursorConn.shape = &CursorShape{
+			Serial: uint64(reply.CursorSerial),
+			HotX:   int(reply.Xhot),
+			HotY:   int(reply.Yhot),
+			Image:  argbImage(int(reply.Width), int(reply.Height), reply.CursorImage),
+		}
+	}
+	cursor.Shape = cursorConn.shape
+	return cursor, nil
+}
+
+// openXFixes connects to the X server and initializes the XFixes extension
+func openXFixes() (*xgb.Conn, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X server: %w", err)
+	}
+	if err := xfixes.Init(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %v", ErrCursorUnsupported, err)
+	}
+	// The cursor image request needs XFixes 2 or later
+	if _, err := xfixes.QueryVersion(conn, 4, 0).Reply(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to query XFixes version: %w", err)
+	}
+	return conn, nil
+}
+
+// argbImage converts premultiplied 32-bit ARGB pixels to an RGBA image
+func argbImage(width, height int, pixels []uint32) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < width*height && i < len(pixels); i++ {
+		p := pixels[i]
+		img.Pix[i*4] = uint8(p >> 16)
+		img.Pix[i*4+1] = uint8(p >> 8)
+		img.Pix[i*4+2] = uint8(p)
+		img.Pix[i*4+3] = uint8(p >> 24)
+	}
+	return img
+}
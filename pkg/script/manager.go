@@ -0,0 +1,144 @@
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+)
+
+// Message types used by scripts
+const (
+	MessageTypeRunScript      = "runScript"      // Server runs a script
+	MessageTypeStopScript     = "stopScript"     // Server stops the running script
+	MessageTypeScriptFinished = "scriptFinished" // Sent when a script ends
+)
+
+// ErrAlreadyRunning is returned when a script is started while another one is running
+var ErrAlreadyRunning = errors.New("a script is already running")
+
+// Request is a runScript message from the server
+type Request struct {
+	Type      string `json:"type"`
+	RequestID string `json:"requestId,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Script    string `json:"script"`
+}
+
+// Result is sent when a script ends
+type Result struct {
+	Type      string `json:"type"`
+	RequestID string `json:"requestId,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Commands  int    `json:"commands"`       // Commands in the script
+	Executed  int    `json:"executed"`       // Commands that completed
+	Line      int    `json:"line,omitempty"` // Line of the error
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Manager runs scripts sent by the server, one at a time
+type Manager struct {
+	transport client.Transport
+	env       Env
+	cancel    context.CancelFunc // Stops the running script, nil when idle
+	mu        sync.Mutex
+}
+
+// NewManager creates a script manager and registers its handlers on the transport
+func NewManager(transport client.Transport, env Env) *Manager {
+	m := &Manager{transport: transport, env: env}
+
+	transport.RegisterHandler(MessageTypeRunScript, m.handleRun)
+	transport.RegisterHandler(MessageTypeStopScript, m.handleStop)
+
+	return m
+}
+
+// Start runs a script in the background and calls done when it ends
+func (m *Manager) Start(s *Script, done func(executed int, err error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		return ErrAlreadyRunning
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	go func() {
+		executed, err := Run(ctx, s, m.env)
+
+		m.mu.Lock()
+		m.cancel = nil
+		m.mu.Unlock()
+		cancel()
+
+		if done != nil {
+			done(executed, err)
+		}
+	}()
+	return nil
+}
+
+// Stop stops the running script, if any
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// handleRun parses and starts a script, reporting parse errors right away
+func (m *Manager) handleRun(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse run script request: %w", err)
+	}
+	result := Result{Type: MessageTypeScriptFinished, RequestID: req.RequestID, Name: req.Name}
+
+	s, err := Parse(req.Script)
+	if err != nil {
+		return m.reply(result, err)
+	}
+	result.Commands = len(s.Commands)
+	if m.env.Verbose {
+		log.Printf("DEBUG: Running script %q with %d commands", req.Name, len(s.Commands))
+	}
+
+	err = m.Start(s, func(executed int, err error) {
+		result.Executed = executed
+		m.reply(result, err)
+	})
+	if err != nil {
+		return m.reply(result, err)
+	}
+	return nil
+}
+
+// handleStop stops the running script
+func (m *Manager) handleStop(data []byte) error {
+	m.Stop()
+	return nil
+}
+
+// reply sends a result, marking it successful if err is nil
+func (m *Manager) reply(result Result, err error) error {
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
+		var scriptErr *Error
+		if errors.As(err, &scriptErr) {
+			result.Line = scriptErr.Line
+		}
+	}
+	if sendErr := m.transport.SendJSON(result); sendErr != nil {
+		log.Printf("Failed to send %s: %v", result.Type, sendErr)
+	}
+	return err
+}
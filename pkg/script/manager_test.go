@@ -0,0 +1,57 @@
+package script
+
+import (
+	"testing"
+
+	"github.com/adamrobbie/go-support/pkg/client/clienttest"
+)
+
+func TestManagerRunScript(t *testing.T) {
+	transport := clienttest.New()
+	controller := &fakeController{}
+	NewManager(transport, Env{Controller: controller})
+
+	if err := transport.Deliver(t, `{"type":"runScript","requestId":"r1","name":"demo","script":"move 1 2\nclick"}`); err != nil {
+		t.Fatalf("runScript returned an error: %v", err)
+	}
+	result := transport.WaitSent(t, 1)[0]
+	if result["type"] != MessageTypeScriptFinished || result["requestId"] != "r1" || result["success"] != true {
+		t.Errorf("Unexpected result: %v", result)
+	}
+	if result["commands"] != float64(2) || result["executed"] != float64(2) {
+		t.Errorf("Expected 2 of 2 commands executed, got %v", result)
+	}
+}
+
+func TestManagerReportsParseErrors(t *testing.T) {
+	transport := clienttest.New()
+	NewManager(transport, Env{Controller: &fakeController{}})
+
+	if err := transport.Deliver(t, `{"type":"runScript","requestId":"r2","script":"move 1 2\njump"}`); err == nil {
+		t.Error("Expected a parse error")
+	}
+	result := transport.WaitSent(t, 1)[0]
+	if result["success"] != false || result["line"] != float64(2) || result["error"] == "" {
+		t.Errorf("Expected a failure on line 2, got %v", result)
+	}
+}
+
+func TestManagerStop(t *testing.T) {
+	transport := clienttest.New()
+	m := NewManager(transport, Env{Controller: &fakeController{}})
+
+	if err := transport.Deliver(t, `{"type":"runScript","requestId":"r3","script":"wait 1m"}`); err != nil {
+		t.Fatalf("runScript returned an error: %v", err)
+	}
+	if err := m.Start(&Script{}, nil); err != ErrAlreadyRunning {
+		t.Errorf("Expected ErrAlreadyRunning, got %v", err)
+	}
+	if err := transport.Deliver(t, `{"type":"stopScript"}`); err != nil {
+		t.Fatalf("stopScript returned an error: %v", err)
+	}
+
+	result := transport.WaitSent(t, 1)[0]
+	if result["success"] != false || result["line"] != float64(1) {
+		t.Errorf("Expected the script to stop on line 1, got %v", result)
+	}
+}
@@ -0,0 +1,61 @@
+package script
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os/exec"
+	"strings"
+)
+
+// ErrNoOCR is returned by waitForText when no text recognizer is available
+var ErrNoOCR = errors.New("text recognition is not available; install tesseract to use waitForText")
+
+// TextRecognizer reads the text in an image
+type TextRecognizer interface {
+	Recognize(ctx context.Context, img image.Image) (string, error)
+}
+
+// Tesseract recognizes text with the tesseract command line tool
+type Tesseract struct {
+	Path string // Path of the tesseract binary
+}
+
+// NewTesseract finds tesseract in PATH, returning ErrNoOCR if it is not installed
+func NewTesseract() (*Tesseract, error) {
+	path, err := exec.LookPath("tesseract")
+	if err != nil {
+		return nil, ErrNoOCR
+	}
+	return &Tesseract{Path: path}, nil
+}
+
+// Recognize passes the image to tesseract as PNG and returns the text it finds
+func (t *Tesseract) Recognize(ctx context.Context, img image.Image) (string, error) {
+	var input bytes.Buffer
+	if err := png.Encode(&input, img); err != nil {
+		return "", fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	var output, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.Path, "stdin", "stdout")
+	cmd.Stdin = &input
+	cmd.Stdout = &output
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return output.String(), nil
+}
+
+// ContainsText reports whether recognized text contains the wanted text,
+// ignoring case and differences in whitespace
+func ContainsText(recognized, text string) bool {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+	return strings.Contains(normalize(recognized), normalize(text))
+}
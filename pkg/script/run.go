@@ -0,0 +1,137 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/remote"
+)
+
+// Controller executes input events; RemoteController implements it
+type Controller interface {
+	ExecuteMouseEvent(event remote.MouseEvent) error
+	ExecuteKeyboardEvent(event remote.KeyboardEvent) error
+}
+
+// Env is what a script runs against
+type Env struct {
+	Controller   Controller
+	Capture      func() (image.Image, error)                // Captures the screen for waitForText
+	OCR          TextRecognizer                             // Reads text from captures; waitForText fails without it
+	Screenshot   func(name string) error                    // Handles the screenshot command
+	ClickImage   func(path string, threshold float64) error // Finds a template image on screen and clicks it
+	PollInterval time.Duration                              // How often waitForText checks the screen (500ms if 0)
+	Verbose      bool
+}
+
+// Run executes the commands of a script in order. It returns the number of
+// commands executed and, if one failed, an *Error with its line.
+func Run(ctx context.Context, s *Script, env Env) (int, error) {
+	if env.PollInterval <= 0 {
+		env.PollInterval = 500 * time.Millisecond
+	}
+
+	for i := range s.Commands {
+		cmd := &s.Commands[i]
+		if err := ctx.Err(); err != nil {
+			return i, &Error{Line: cmd.Line, Err: err}
+		}
+		if env.Verbose {
+			log.Printf("DEBUG: Script line %d: %s %s", cmd.Line, cmd.Name, strings.Join(cmd.Args, " "))
+		}
+		if err := env.execute(ctx, cmd); err != nil {
+			return i, &Error{Line: cmd.Line, Err: err}
+		}
+	}
+	return len(s.Commands), nil
+}
+
+// execute runs one command
+func (env Env) execute(ctx context.Context, cmd *Command) error {
+	switch {
+	case cmd.mouse != nil:
+		if env.Controller == nil {
+			return errors.New("remote control is not available")
+		}
+		return env.Controller.ExecuteMouseEvent(*cmd.mouse)
+
+	case cmd.keyboard != nil:
+		if env.Controller == nil {
+			return errors.New("remote control is not available")
+		}
+		return env.Controller.ExecuteKeyboardEvent(*cmd.keyboard)
+	}
+
+	switch strings.ToLower(cmd.Name) {
+	case "wait":
+		return sleep(ctx, cmd.duration)
+
+	case "waitfortext":
+		return env.waitForText(ctx, cmd.text, cmd.duration)
+
+	case "clickimage":
+		if env.ClickImage == nil {
+			return errors.New("clicking images is not available")
+		}
+		return env.ClickImage(cmd.text, cmd.threshold)
+
+	case "screenshot":
+		if env.Screenshot == nil {
+			return errors.New("screenshots are not available")
+		}
+		return env.Screenshot(cmd.text)
+
+	default:
+		return fmt.Errorf("unknown command %q", cmd.Name)
+	}
+}
+
+// waitForText captures the screen until the text shows up or the timeout passes
+func (env Env) waitForText(ctx context.Context, text string, timeout time.Duration) error {
+	if env.Capture == nil {
+		return errors.New("screen capture is not available")
+	}
+	if env.OCR == nil {
+		return ErrNoOCR
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		img, err := env.Capture()
+		if err != nil {
+			return fmt.Errorf("failed to capture screen: %w", err)
+		}
+		recognized, err := env.OCR.Recognize(ctx, img)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		if err == nil && ContainsText(recognized, text) {
+			return nil
+		}
+
+		if err := sleep(ctx, env.PollInterval); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("text %q did not appear within %s", text, timeout)
+			}
+			return err
+		}
+	}
+}
+
+// sleep waits for a duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,376 @@
+package script
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/adamrobbie/go-support/pkg/remote"
+)
+
+const (
+	// MaxCommands limits the number of commands in a script
+	MaxCommands = 10000
+	// DefaultTextTimeout is how long waitForText waits without an explicit timeout
+	DefaultTextTimeout = 10 * time.Second
+)
+
+// Error is an error in a script, with the line it occurred on
+type Error struct {
+	Line int
+	Err  error
+}
+
+// Error returns the error prefixed with its line number
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Command is one line of a script
+type Command struct {
+	Line int
+	Name string   // Command name as written in the script
+	Args []string // Arguments with quotes removed
+
+	mouse     *remote.MouseEvent
+	keyboard  *remote.KeyboardEvent
+	duration  time.Duration // For wait, and the timeout of waitForText
+	text      string        // For waitForText, the name of a screenshot and the template of clickImage
+	threshold float64       // For clickImage, 0 for the default
+}
+
+// Script is a parsed automation script
+type Script struct {
+	Commands []Command
+}
+
+// Parse parses a script. Each line holds one command; blank lines and
+// everything after a # outside quotes are ignored.
+func Parse(source string) (*Script, error) {
+	s := &Script{}
+	scanner := bufio.NewScanner(strings.NewReader(source))
+	line := 0
+	for scanner.Scan() {
+		line++
+		words, err := split(scanner.Text())
+		if err != nil {
+			return nil, &Error{Line: line, Err: err}
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if len(s.Commands) >= MaxCommands {
+			return nil, &Error{Line: line, Err: fmt.Errorf("script has more than %d commands", MaxCommands)}
+		}
+
+		cmd := Command{Line: line, Name: words[0], Args: words[1:]}
+		if err := cmd.compile(); err != nil {
+			return nil, &Error{Line: line, Err: err}
+		}
+		s.Commands = append(s.Commands, cmd)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	return s, nil
+}
+
+// Load reads and parses a script file
+func Load(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	return Parse(string(data))
+}
+
+// split splits a line into words, honouring double quotes and comments
+func split(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, quoted, escaped := false, false, false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			switch r {
+			case 'n':
+				word.WriteRune('\n')
+			case 't':
+				word.WriteRune('\t')
+			default:
+				word.WriteRune(r)
+			}
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case quoted:
+			word.WriteRune(r)
+		case r == '#':
+			if inWord {
+				words = append(words, word.String())
+			}
+			return words, nil
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated string")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// compile checks the arguments of a command and prepares what it executes
+func (c *Command) compile() error {
+	switch strings.ToLower(c.Name) {
+	case "move":
+		x, y, err := c.point(0)
+		if err != nil || len(c.Args) < 2 || len(c.Args) > 3 {
+			return c.usage("move <x> <y> [duration]")
+		}
+		c.mouse = &remote.MouseEvent{Action: remote.MouseMove, X: x, Y: y}
+		if len(c.Args) == 3 {
+			if c.mouse.Duration, err = parseMillis(c.Args[2]); err != nil {
+				return err
+			}
+		}
+
+	case "click", "doubleclick", "mousedown", "mouseup":
+		actions := map[string]remote.MouseAction{
+			"click":       remote.MouseClick,
+			"doubleclick": remote.MouseDblClick,
+			"mousedown":   remote.MouseDown,
+			"mouseup":     remote.MouseUp,
+		}
+		event := remote.MouseEvent{Action: actions[strings.ToLower(c.Name)], Button: remote.LeftButton}
+		args := c.Args
+		if len(args) == 1 || len(args) == 3 {
+			button, err := parseButton(args[0])
+			if err != nil {
+				return err
+			}
+			event.Button = button
+			args = args[1:]
+		}
+		switch len(args) {
+		case 0:
+		case 2:
+			x, y, err := c.point(len(c.Args) - 2)
+			if err != nil {
+				return err
+			}
+			event.X, event.Y = x, y
+		default:
+			return c.usage(c.Name + " [left|right|middle] [<x> <y>]")
+		}
+		c.mouse = &event
+
+	case "drag":
+		if len(c.Args) < 2 || len(c.Args) > 4 {
+			return c.usage("drag <x> <y> [left|right|middle] [duration]")
+		}
+		x, y, err := c.point(0)
+		if err != nil {
+			return err
+		}
+		event := remote.MouseEvent{Action: remote.MouseDrag, X: x, Y: y, Button: remote.LeftButton}
+		for _, arg := range c.Args[2:] {
+			if button, err := parseButton(arg); err == nil {
+				event.Button = button
+			} else if event.Duration, err = parseMillis(arg); err != nil {
+				return fmt.Errorf("expected a mouse button or a duration, got %q", arg)
+			}
+		}
+		c.mouse = &event
+
+	case "scroll":
+		if len(c.Args) != 1 && len(c.Args) != 2 {
+			return c.usage("scroll <lines up> [lines right]")
+		}
+		amounts := make([]int, len(c.Args))
+		for i, arg := range c.Args {
+			amount, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("invalid scroll amount %q", arg)
+			}
+			amounts[i] = amount
+		}
+		c.mouse = &remote.MouseEvent{Action: remote.MouseScroll, Amount: amounts[0]}
+		if len(amounts) == 2 {
+			c.mouse.AmountX = amounts[1]
+		}
+
+	case "zoom":
+		if len(c.Args) != 1 {
+			return c.usage("zoom <steps>, positive to zoom in")
+		}
+		amount, err := strconv.Atoi(c.Args[0])
+		if err != nil {
+			return fmt.Errorf("invalid zoom amount %q", c.Args[0])
+		}
+		c.mouse = &remote.MouseEvent{Action: remote.MouseZoom, Amount: amount}
+
+	case "type":
+		if len(c.Args) != 1 {
+			return c.usage(`type "<text>"`)
+		}
+		c.keyboard = &remote.KeyboardEvent{Action: remote.KeyType, Text: c.Args[0]}
+
+	case "press":
+		if len(c.Args) != 1 {
+			return c.usage("press <key> or press <modifier>+<key>")
+		}
+		keys := strings.Split(c.Args[0], "+")
+		for _, key := range keys {
+			if key == "" {
+				return fmt.Errorf("invalid key combination %q", c.Args[0])
+			}
+		}
+		if len(keys) == 1 {
+			c.keyboard = &remote.KeyboardEvent{Action: remote.KeyPress, Key: keys[0]}
+		} else {
+			c.keyboard = &remote.KeyboardEvent{Action: remote.KeyCombination, Keys: keys}
+		}
+
+	case "keydown", "keyup":
+		if len(c.Args) != 1 {
+			return c.usage(c.Name + " <key>")
+		}
+		action := remote.KeyDown
+		if strings.ToLower(c.Name) == "keyup" {
+			action = remote.KeyUp
+		}
+		c.keyboard = &remote.KeyboardEvent{Action: action, Key: c.Args[0]}
+
+	case "wait":
+		if len(c.Args) != 1 {
+			return c.usage("wait <duration>, e.g. wait 500ms")
+		}
+		d, err := parseDuration(c.Args[0])
+		if err != nil {
+			return err
+		}
+		c.duration = d
+
+	case "waitfortext":
+		if len(c.Args) != 1 && len(c.Args) != 2 {
+			return c.usage(`waitForText "<text>" [timeout]`)
+		}
+		if strings.TrimSpace(c.Args[0]) == "" {
+			return errors.New("waitForText needs some text to wait for")
+		}
+		c.text = c.Args[0]
+		c.duration = DefaultTextTimeout
+		if len(c.Args) == 2 {
+			d, err := parseDuration(c.Args[1])
+			if err != nil {
+				return err
+			}
+			c.duration = d
+		}
+
+	case "clickimage":
+		if len(c.Args) != 1 && len(c.Args) != 2 {
+			return c.usage(`clickImage "<file>" [threshold]`)
+		}
+		c.text = c.Args[0]
+		if len(c.Args) == 2 {
+			threshold, err := strconv.ParseFloat(c.Args[1], 64)
+			if err != nil || threshold <= 0 || threshold > 1 {
+				return fmt.Errorf("invalid threshold %q, expected a number between 0 and 1", c.Args[1])
+			}
+			c.threshold = threshold
+		}
+
+	case "screenshot":
+		if len(c.Args) > 1 {
+			return c.usage("screenshot [name]")
+		}
+		if len(c.Args) == 1 {
+			c.text = c.Args[0]
+		}
+
+	default:
+		return fmt.Errorf("unknown command %q", c.Name)
+	}
+	return nil
+}
+
+// point parses the x and y coordinates starting at argument i
+func (c *Command) point(i int) (int, int, error) {
+	if len(c.Args) < i+2 {
+		return 0, 0, errors.New("missing coordinates")
+	}
+	x, err := strconv.Atoi(c.Args[i])
+	if err != nil || x < 0 {
+		return 0, 0, fmt.Errorf("invalid x coordinate %q", c.Args[i])
+	}
+	y, err := strconv.Atoi(c.Args[i+1])
+	if err != nil || y < 0 {
+		return 0, 0, fmt.Errorf("invalid y coordinate %q", c.Args[i+1])
+	}
+	return x, y, nil
+}
+
+// usage returns an error describing how a command is used
+func (c *Command) usage(syntax string) error {
+	return fmt.Errorf("wrong arguments for %s, expected: %s", c.Name, syntax)
+}
+
+// parseButton parses a mouse button name
+func parseButton(name string) (remote.MouseButton, error) {
+	switch button := remote.MouseButton(strings.ToLower(name)); button {
+	case remote.LeftButton, remote.RightButton, remote.MiddleButton:
+		return button, nil
+	default:
+		return "", fmt.Errorf("unknown mouse button %q", name)
+	}
+}
+
+// parseMillis parses the duration of a movement into milliseconds
+func parseMillis(value string) (int, error) {
+	d, err := parseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d > remote.MaxMotionDuration {
+		return 0, fmt.Errorf("duration %s is longer than the limit of %s", d, remote.MaxMotionDuration)
+	}
+	return int(d / time.Millisecond), nil
+}
+
+// parseDuration parses a duration such as 500ms or 2s; a bare number is milliseconds
+func parseDuration(value string) (time.Duration, error) {
+	if ms, err := strconv.Atoi(value); err == nil {
+		value = strconv.Itoa(ms) + "ms"
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
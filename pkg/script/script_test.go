@@ -0,0 +1,249 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"image"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/remote"
+)
+
+// fakeController records the events it executes
+type fakeController struct {
+	events []string
+	err    error
+}
+
+func (f *fakeController) ExecuteMouseEvent(event remote.MouseEvent) error {
+	f.events = append(f.events, "mouse:"+string(event.Action)+":"+string(event.Button))
+	return f.err
+}
+
+func (f *fakeController) ExecuteKeyboardEvent(event remote.KeyboardEvent) error {
+	f.events = append(f.events, "key:"+string(event.Action)+":"+event.Key+event.Text+strings.Join(event.Keys, "+"))
+	return f.err
+}
+
+// fakeOCR returns one text per call, repeating the last one
+type fakeOCR struct {
+	texts []string
+	calls int
+}
+
+func (f *fakeOCR) Recognize(ctx context.Context, img image.Image) (string, error) {
+	text := f.texts[min(f.calls, len(f.texts)-1)]
+	f.calls++
+	return text, nil
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse(`
+# Open the installer
+move 100 200
+click right
+click middle 5 6
+doubleClick 10 20
+type "hello \"world\""   # greet
+press ctrl+shift+t
+wait 500ms
+wait 250
+waitForText "Install" 5s
+screenshot done
+`)
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+	if len(s.Commands) != 10 {
+		t.Fatalf("Expected 10 commands, got %d", len(s.Commands))
+	}
+
+	move := s.Commands[0]
+	if move.Line != 3 || move.mouse == nil || move.mouse.X != 100 || move.mouse.Y != 200 {
+		t.Errorf("Unexpected move command: %+v", move)
+	}
+	if click := s.Commands[1].mouse; click.Button != remote.RightButton || click.X != 0 {
+		t.Errorf("Unexpected click: %+v", click)
+	}
+	if click := s.Commands[2].mouse; click.Button != remote.MiddleButton || click.X != 5 || click.Y != 6 {
+		t.Errorf("Unexpected click with coordinates: %+v", click)
+	}
+	if typed := s.Commands[4].keyboard; typed.Text != `hello "world"` {
+		t.Errorf("Expected the quoted text, got %q", typed.Text)
+	}
+	if combo := s.Commands[5].keyboard; combo.Action != remote.KeyCombination || len(combo.Keys) != 3 {
+		t.Errorf("Unexpected key combination: %+v", combo)
+	}
+	if s.Commands[6].duration != 500*time.Millisecond || s.Commands[7].duration != 250*time.Millisecond {
+		t.Errorf("Unexpected wait durations: %v, %v", s.Commands[6].duration, s.Commands[7].duration)
+	}
+	if wait := s.Commands[8]; wait.text != "Install" || wait.duration != 5*time.Second {
+		t.Errorf("Unexpected waitForText: %+v", wait)
+	}
+	if shot := s.Commands[9]; shot.text != "done" {
+		t.Errorf("Unexpected screenshot name %q", shot.text)
+	}
+}
+
+func TestParseMotion(t *testing.T) {
+	s, err := Parse("move 10 20 250ms\ndrag 30 40 right 1s\ndrag 50 60 400")
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+	if move := s.Commands[0].mouse; move.Duration != 250 {
+		t.Errorf("Expected a 250ms move, got %+v", move)
+	}
+	if drag := s.Commands[1].mouse; drag.Button != remote.RightButton || drag.Duration != 1000 {
+		t.Errorf("Expected a 1s right drag, got %+v", drag)
+	}
+	if drag := s.Commands[2].mouse; drag.Button != remote.LeftButton || drag.Duration != 400 {
+		t.Errorf("Expected a 400ms left drag, got %+v", drag)
+	}
+	s, err = Parse("scroll -3 2\nzoom 1")
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+	if scroll := s.Commands[0].mouse; scroll.Amount != -3 || scroll.AmountX != 2 {
+		t.Errorf("Unexpected scroll: %+v", scroll)
+	}
+	if zoom := s.Commands[1].mouse; zoom.Action != remote.MouseZoom || zoom.Amount != 1 {
+		t.Errorf("Unexpected zoom: %+v", zoom)
+	}
+	if _, err := Parse("drag 1 2 sideways"); err == nil {
+		t.Error("Expected an invalid drag argument to be rejected")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		line   int
+	}{
+		{"move 100", 1},
+		{"move 1 2\nclick sideways", 2},
+		{"\n\ntype \"unterminated", 3},
+		{"wait soon", 1},
+		{"fly 1 2", 1},
+		{`waitForText ""`, 1},
+		{"press ctrl+", 1},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.source)
+		var scriptErr *Error
+		if !errors.As(err, &scriptErr) {
+			t.Errorf("Parse(%q) returned %v, expected a script error", test.source, err)
+			continue
+		}
+		if scriptErr.Line != test.line {
+			t.Errorf("Parse(%q) reported line %d, expected %d", test.source, scriptErr.Line, test.line)
+		}
+		if !strings.HasPrefix(err.Error(), "line ") {
+			t.Errorf("Expected the error to start with the line number, got %q", err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	s, err := Parse("move 1 2\nclick\ntype hi\nwait 1ms\nscreenshot shot")
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+
+	controller := &fakeController{}
+	var shots []string
+	executed, err := Run(context.Background(), s, Env{
+		Controller: controller,
+		Screenshot: func(name string) error {
+			shots = append(shots, name)
+			return nil
+		},
+	})
+	if err != nil || executed != 5 {
+		t.Fatalf("Run() = %d, %v", executed, err)
+	}
+	want := []string{"mouse:move:", "mouse:click:left", "key:type:hi"}
+	if strings.Join(controller.events, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected events %v, got %v", want, controller.events)
+	}
+	if len(shots) != 1 || shots[0] != "shot" {
+		t.Errorf("Expected one screenshot named shot, got %v", shots)
+	}
+}
+
+func TestRunReportsLine(t *testing.T) {
+	s, _ := Parse("move 1 2\n\nclick")
+	controller := &fakeController{}
+	if _, err := Run(context.Background(), s, Env{Controller: controller}); err != nil {
+		t.Fatalf("Run() returned an error: %v", err)
+	}
+
+	controller.err = errors.New("no display")
+	executed, err := Run(context.Background(), s, Env{Controller: controller})
+	var scriptErr *Error
+	if !errors.As(err, &scriptErr) || scriptErr.Line != 1 || executed != 0 {
+		t.Errorf("Expected an error on line 1 after 0 commands, got %d, %v", executed, err)
+	}
+}
+
+func TestClickImage(t *testing.T) {
+	s, err := Parse("clickImage \"buttons/ok.png\" 0.8\nclickImage cancel.png")
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+
+	var clicked []string
+	var thresholds []float64
+	env := Env{ClickImage: func(path string, threshold float64) error {
+		clicked = append(clicked, path)
+		thresholds = append(thresholds, threshold)
+		return nil
+	}}
+	if _, err := Run(context.Background(), s, env); err != nil {
+		t.Fatalf("Run() returned an error: %v", err)
+	}
+	if strings.Join(clicked, ",") != "buttons/ok.png,cancel.png" || thresholds[0] != 0.8 || thresholds[1] != 0 {
+		t.Errorf("Unexpected clicks %v with thresholds %v", clicked, thresholds)
+	}
+
+	if _, err := Parse("clickImage ok.png 2"); err == nil {
+		t.Error("Expected a threshold above 1 to be rejected")
+	}
+}
+
+func TestWaitForText(t *testing.T) {
+	s, _ := Parse(`waitForText "Install  now" 1s`)
+	capture := func() (image.Image, error) { return image.NewRGBA(image.Rect(0, 0, 1, 1)), nil }
+
+	ocr := &fakeOCR{texts: []string{"Loading...", "Click INSTALL\nNow to continue"}}
+	env := Env{Capture: capture, OCR: ocr, PollInterval: time.Millisecond}
+	if _, err := Run(context.Background(), s, env); err != nil {
+		t.Fatalf("Expected the text to be found, got %v", err)
+	}
+	if ocr.calls != 2 {
+		t.Errorf("Expected 2 captures, got %d", ocr.calls)
+	}
+
+	s, _ = Parse(`waitForText "Install" 20ms`)
+	env.OCR = &fakeOCR{texts: []string{"Loading..."}}
+	if _, err := Run(context.Background(), s, env); err == nil || !strings.Contains(err.Error(), "did not appear") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+
+	env.OCR = nil
+	if _, err := Run(context.Background(), s, env); !errors.Is(err, ErrNoOCR) {
+		t.Errorf("Expected ErrNoOCR, got %v", err)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	s, _ := Parse("wait 1m\nclick")
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	executed, err := Run(ctx, s, Env{Controller: &fakeController{}})
+	if !errors.Is(err, context.Canceled) || executed != 0 {
+		t.Errorf("Expected the script to be cancelled on the wait, got %d, %v", executed, err)
+	}
+}
@@ -0,0 +1,340 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+)
+
+// Message types used by the pairing flow
+const (
+	MessageTypePairingRequest   = "pairingRequest"   // Agent asks the server for a pairing code
+	MessageTypePairingCode      = "pairingCode"      // Server assigns a pairing code
+	MessageTypeTechnicianJoined = "technicianJoined" // Server reports a technician joined with the code
+	MessageTypePairingExpired   = "pairingExpired"   // Server reports the code expired
+	MessageTypePairingEnded     = "pairingEnded"     // Either side ends the paired session
+)
+
+// DefaultCodeLifetime is used when the server does not say when a code expires
+const DefaultCodeLifetime = 10 * time.Minute
+
+// State represents the state of the pairing state machine
+type State int
+
+const (
+	// Idle means no pairing has been requested
+	Idle State = iota
+	// Requesting means a code has been requested from the server
+	Requesting
+	// Waiting means a code is displayed and no technician has joined yet
+	Waiting
+	// Paired means a technician joined with the current code
+	Paired
+	// Expired means the code expired before a technician joined
+	Expired
+	// Ended means the paired session was ended
+	Ended
+)
+
+// String returns the string representation of State
+func (s State) String() string {
+	switch s {
+	case Idle:
+		return "Idle"
+	case Requesting:
+		return "Requesting"
+	case Waiting:
+		return "Waiting"
+	case Paired:
+		return "Paired"
+	case Expired:
+		return "Expired"
+	case Ended:
+		return "Ended"
+	default:
+		return fmt.Sprintf("Unknown State: %d", s)
+	}
+}
+
+// Technician describes the technician that joined the session
+type Technician struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// pairingMessage is the union of the pairing messages received from the server
+type pairingMessage struct {
+	Type       string     `json:"type"`
+	Code       string     `json:"code"`
+	ExpiresAt  string     `json:"expiresAt,omitempty"` // RFC3339 expiry time
+	ExpiresIn  int        `json:"expiresIn,omitempty"` // Seconds until expiry
+	Technician Technician `json:"technician"`
+	Reason     string     `json:"reason,omitempty"`
+}
+
+// Pairing runs the pairing-code state machine on top of a client.Transport
+type Pairing struct {
+	transport     client.Transport
+	state         State
+	code          string
+	expiresAt     time.Time
+	technician    Technician
+	expiryTimer   *time.Timer
+	onStateChange func(State)
+	now           func() time.Time
+	verbose       bool
+	mu            sync.Mutex
+}
+
+// NewPairing creates a pairing state machine and registers its handlers on the transport
+func NewPairing(transport client.Transport, verbose bool) *Pairing {
+	p := &Pairing{
+		transport: transport,
+		state:     Idle,
+		now:       time.Now,
+		verbose:   verbose,
+	}
+
+	transport.RegisterHandler(MessageTypePairingCode, p.handleCode)
+	transport.RegisterHandler(MessageTypeTechnicianJoined, p.handleJoined)
+	transport.RegisterHandler(MessageTypePairingExpired, p.handleExpired)
+	transport.RegisterHandler(MessageTypePairingEnded, p.handleEnded)
+
+	return p
+}
+
+// OnStateChange sets a callback invoked after every state transition.
+// The callback runs without internal locks held, so it may call back into the Pairing.
+func (p *Pairing) OnStateChange(callback func(State)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onStateChange = callback
+}
+
+// Start requests a new pairing code from the server. The state changes to
+// Requesting before the request is sent, so a code the server sends straight
+// back is accepted; it changes back if the request cannot be sent.
+func (p *Pairing) Start() error {
+	p.mu.Lock()
+	if p.state == Paired {
+		p.mu.Unlock()
+		return fmt.Errorf("already paired with technician %s", p.technician.ID)
+	}
+	previous := p.state
+	p.stopTimerLocked()
+	p.code = ""
+	p.mu.Unlock()
+
+	p.transition(Requesting)
+
+	if err := p.transport.SendJSON(map[string]interface{}{
+		"type":      MessageTypePairingRequest,
+		"timestamp": time.Now().Format(time.RFC3339),
+	}); err != nil {
+		p.mu.Lock()
+		requesting := p.state == Requesting
+		p.mu.Unlock()
+		if requesting {
+			p.transition(previous)
+		}
+		return fmt.Errorf("failed to request pairing code: %w", err)
+	}
+	return nil
+}
+
+// End ends the paired session and notifies the server
+func (p *Pairing) End() error {
+	p.mu.Lock()
+	if p.state != Paired && p.state != Waiting {
+		p.mu.Unlock()
+		return nil
+	}
+	code := p.code
+	p.stopTimerLocked()
+	p.mu.Unlock()
+
+	p.transition(Ended)
+
+	return p.transport.SendJSON(map[string]interface{}{
+		"type":      MessageTypePairingEnded,
+		"code":      code,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// State returns the current state
+func (p *Pairing) State() State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// IsPaired returns true if a technician has joined with the current code
+func (p *Pairing) IsPaired() bool {
+	return p.State() == Paired
+}
+
+// Code returns the current pairing code and when it expires
+func (p *Pairing) Code() (string, time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.code, p.expiresAt
+}
+
+// Technician returns the technician that joined the session
+func (p *Pairing) Technician() Technician {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.technician
+}
+
+// handleCode handles a pairing code assigned by the server
+func (p *Pairing) handleCode(data []byte) error {
+	var msg pairingMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to parse pairing code: %w", err)
+	}
+	if msg.Code == "" {
+		return fmt.Errorf("pairing code message has no code")
+	}
+
+	p.mu.Lock()
+	if p.state != Requesting {
+		state := p.state
+		p.mu.Unlock()
+		return fmt.Errorf("unexpected pairing code in state %s", state)
+	}
+
+	expiresAt := p.now().Add(DefaultCodeLifetime)
+	if msg.ExpiresAt != "" {
+		parsed, err := time.Parse(time.RFC3339, msg.ExpiresAt)
+		if err != nil {
+			p.mu.Unlock()
+			return fmt.Errorf("invalid pairing code expiry: %w", err)
+		}
+		expiresAt = parsed
+	} else if msg.ExpiresIn > 0 {
+		expiresAt = p.now().Add(time.Duration(msg.ExpiresIn) * time.Second)
+	}
+
+	p.code = msg.Code
+	p.expiresAt = expiresAt
+	p.expiryTimer = time.AfterFunc(expiresAt.Sub(p.now()), p.expire)
+	p.mu.Unlock()
+
+	if p.verbose {
+		log.Printf("DEBUG: Received pairing code %s, expires at %s", msg.Code, expiresAt.Format(time.RFC3339))
+	}
+
+	p.transition(Waiting)
+	return nil
+}
+
+// handleJoined handles a technician joining with the pairing code
+func (p *Pairing) handleJoined(data []byte) error {
+	var msg pairingMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to parse technician joined message: %w", err)
+	}
+
+	p.mu.Lock()
+	switch {
+	case p.state != Waiting:
+		state := p.state
+		p.mu.Unlock()
+		return fmt.Errorf("unexpected technician join in state %s", state)
+	case msg.Code != p.code:
+		p.mu.Unlock()
+		return fmt.Errorf("technician joined with a different pairing code")
+	case !p.now().Before(p.expiresAt):
+		p.mu.Unlock()
+		p.expire()
+		return fmt.Errorf("technician joined with an expired pairing code")
+	}
+
+	p.stopTimerLocked()
+	p.technician = msg.Technician
+	p.mu.Unlock()
+
+	p.transition(Paired)
+	return nil
+}
+
+// handleExpired handles the server expiring the pairing code
+func (p *Pairing) handleExpired(data []byte) error {
+	var msg pairingMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to parse pairing expired message: %w", err)
+	}
+
+	p.mu.Lock()
+	current := p.code
+	p.mu.Unlock()
+
+	if msg.Code != "" && msg.Code != current {
+		return nil
+	}
+	p.expire()
+	return nil
+}
+
+// handleEnded handles the server or technician ending the session
+func (p *Pairing) handleEnded(data []byte) error {
+	p.mu.Lock()
+	if p.state != Paired && p.state != Waiting {
+		p.mu.Unlock()
+		return nil
+	}
+	p.stopTimerLocked()
+	p.mu.Unlock()
+
+	p.transition(Ended)
+	return nil
+}
+
+// expire moves a waiting pairing to the Expired state
+func (p *Pairing) expire() {
+	p.mu.Lock()
+	if p.state != Waiting {
+		p.mu.Unlock()
+		return
+	}
+	p.stopTimerLocked()
+	p.mu.Unlock()
+
+	p.transition(Expired)
+}
+
+// transition changes the state and invokes the state change callback
+func (p *Pairing) transition(state State) {
+	p.mu.Lock()
+	if state != Paired {
+		p.technician = Technician{}
+	}
+	if state == Expired || state == Ended {
+		p.code = ""
+	}
+	p.state = state
+	callback := p.onStateChange
+	p.mu.Unlock()
+
+	if p.verbose {
+		log.Printf("DEBUG: Pairing state changed to %s", state)
+	}
+
+	if callback != nil {
+		callback(state)
+	}
+}
+
+// stopTimerLocked stops the expiry timer. Must be called with the lock held.
+func (p *Pairing) stopTimerLocked() {
+	if p.expiryTimer != nil {
+		p.expiryTimer.Stop()
+		p.expiryTimer = nil
+	}
+}
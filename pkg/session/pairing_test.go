@@ -0,0 +1,190 @@
+package session
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client/clienttest"
+)
+
+func TestPairingFlow(t *testing.T) {
+	transport := clienttest.New()
+	pairing := NewPairing(transport, false)
+
+	var states []State
+	pairing.OnStateChange(func(s State) { states = append(states, s) })
+
+	if pairing.State() != Idle {
+		t.Fatalf("Expected initial state Idle, got %s", pairing.State())
+	}
+
+	if err := pairing.Start(); err != nil {
+		t.Fatalf("Start() returned an error: %v", err)
+	}
+	if transport.LastType() != MessageTypePairingRequest {
+		t.Errorf("Expected a %s message, got %s", MessageTypePairingRequest, transport.LastType())
+	}
+
+	if err := transport.Deliver(t, `{"type":"pairingCode","code":"482-913","expiresIn":60}`); err != nil {
+		t.Fatalf("pairingCode handler returned an error: %v", err)
+	}
+	code, expiresAt := pairing.Code()
+	if code != "482-913" || time.Until(expiresAt) > time.Minute {
+		t.Errorf("Code() = %s, %v", code, expiresAt)
+	}
+	if pairing.IsPaired() {
+		t.Error("Should not be paired before a technician joins")
+	}
+
+	// A join with the wrong code is refused
+	if err := transport.Deliver(t, `{"type":"technicianJoined","code":"000-000","technician":{"id":"t1"}}`); err == nil {
+		t.Error("Expected an error for a mismatched code")
+	}
+
+	if err := transport.Deliver(t, `{"type":"technicianJoined","code":"482-913","technician":{"id":"t1","name":"Alice"}}`); err != nil {
+		t.Fatalf("technicianJoined handler returned an error: %v", err)
+	}
+	if !pairing.IsPaired() || pairing.Technician().Name != "Alice" {
+		t.Errorf("Expected to be paired with Alice, state %s, technician %+v", pairing.State(), pairing.Technician())
+	}
+
+	if err := pairing.End(); err != nil {
+		t.Fatalf("End() returned an error: %v", err)
+	}
+	if transport.LastType() != MessageTypePairingEnded {
+		t.Errorf("Expected a %s message, got %s", MessageTypePairingEnded, transport.LastType())
+	}
+
+	want := []State{Requesting, Waiting, Paired, Ended}
+	if len(states) != len(want) {
+		t.Fatalf("State changes = %v, want %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("State change %d = %s, want %s", i, states[i], want[i])
+		}
+	}
+}
+
+func TestPairingExpiry(t *testing.T) {
+	transport := clienttest.New()
+	pairing := NewPairing(transport, false)
+
+	expired := make(chan struct{})
+	pairing.OnStateChange(func(s State) {
+		if s == Expired {
+			close(expired)
+		}
+	})
+
+	pairing.Start()
+	expiresAt := time.Now().Add(50 * time.Millisecond).Format(time.RFC3339Nano)
+	if err := transport.Deliver(t, `{"type":"pairingCode","code":"111-222","expiresAt":"`+expiresAt+`"}`); err != nil {
+		t.Fatalf("pairingCode handler returned an error: %v", err)
+	}
+
+	select {
+	case <-expired:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Pairing code did not expire")
+	}
+
+	if code, _ := pairing.Code(); code != "" {
+		t.Errorf("Expected the code to be cleared after expiry, got %s", code)
+	}
+
+	// A late join is refused
+	if err := transport.Deliver(t, `{"type":"technicianJoined","code":"111-222","technician":{"id":"t1"}}`); err == nil {
+		t.Error("Expected an error for a join after expiry")
+	}
+
+	// A new code can be requested
+	if err := pairing.Start(); err != nil || pairing.State() != Requesting {
+		t.Errorf("Start() after expiry = %v, state %s", err, pairing.State())
+	}
+}
+
+func TestPairingServerExpiredAndEnded(t *testing.T) {
+	transport := clienttest.New()
+	pairing := NewPairing(transport, false)
+
+	pairing.Start()
+	transport.Deliver(t, `{"type":"pairingCode","code":"333-444"}`)
+
+	// Expiry for another code is ignored
+	transport.Deliver(t, `{"type":"pairingExpired","code":"999-999"}`)
+	if pairing.State() != Waiting {
+		t.Fatalf("Expected state Waiting, got %s", pairing.State())
+	}
+
+	transport.Deliver(t, `{"type":"pairingExpired","code":"333-444"}`)
+	if pairing.State() != Expired {
+		t.Fatalf("Expected state Expired, got %s", pairing.State())
+	}
+
+	pairing.Start()
+	transport.Deliver(t, `{"type":"pairingCode","code":"555-666"}`)
+	transport.Deliver(t, `{"type":"technicianJoined","code":"555-666","technician":{"id":"t2"}}`)
+	transport.Deliver(t, `{"type":"pairingEnded"}`)
+	if pairing.State() != Ended || pairing.IsPaired() {
+		t.Errorf("Expected state Ended, got %s", pairing.State())
+	}
+}
+
+func TestPairingUnexpectedCode(t *testing.T) {
+	transport := clienttest.New()
+	NewPairing(transport, false)
+
+	if err := transport.Deliver(t, `{"type":"pairingCode","code":"123-456"}`); err == nil {
+		t.Error("Expected an error for a code that was not requested")
+	}
+}
+
+// instantTransport answers a pairing request with a code before SendJSON returns
+type instantTransport struct {
+	*clienttest.Transport
+	t *testing.T
+}
+
+func (f instantTransport) SendJSON(message interface{}) error {
+	if err := f.Transport.SendJSON(message); err != nil {
+		return err
+	}
+	return f.Deliver(f.t, `{"type":"pairingCode","code":"123-456"}`)
+}
+
+func TestPairingStart(t *testing.T) {
+	// A code that arrives while the request is still being sent is accepted
+	transport := instantTransport{clienttest.New(), t}
+	pairing := NewPairing(transport, false)
+	if err := pairing.Start(); err != nil {
+		t.Fatalf("Start() returned an error: %v", err)
+	}
+	if code, _ := pairing.Code(); pairing.State() != Waiting || code != "123-456" {
+		t.Errorf("Expected to wait with code 123-456, got %s with %q", pairing.State(), code)
+	}
+
+	// The state changes back when the request cannot be sent
+	failing := clienttest.New()
+	failing.SetSendError(errors.New("connection lost"))
+	pairing = NewPairing(failing, false)
+	var states []State
+	pairing.OnStateChange(func(state State) { states = append(states, state) })
+	if err := pairing.Start(); err == nil {
+		t.Error("Expected Start() to fail when the request cannot be sent")
+	}
+	if pairing.State() != Idle || !reflect.DeepEqual(states, []State{Requesting, Idle}) {
+		t.Errorf("Expected to return to Idle, got %s after %v", pairing.State(), states)
+	}
+}
+
+func TestStateString(t *testing.T) {
+	if Paired.String() != "Paired" {
+		t.Errorf("Expected 'Paired', got '%s'", Paired.String())
+	}
+	if State(99).String() != "Unknown State: 99" {
+		t.Errorf("Unexpected string for unknown state: %s", State(99).String())
+	}
+}
@@ -0,0 +1,436 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+)
+
+// Message types used by the multi-viewer session
+const (
+	MessageTypeViewerJoined   = "viewerJoined"   // Server reports a viewer joined
+	MessageTypeViewerLeft     = "viewerLeft"     // Server reports a viewer left
+	MessageTypeRequestControl = "requestControl" // Viewer asks for the controller role
+	MessageTypeReleaseControl = "releaseControl" // Controller gives up the controller role
+	MessageTypeGrantControl   = "grantControl"   // Controller hands the role to another viewer
+	MessageTypeControlState   = "controlState"   // Agent broadcasts the roles of all viewers
+)
+
+// Role is the role of a viewer in the session
+type Role string
+
+const (
+	// RoleViewer can watch the screen
+	RoleViewer Role = "viewer"
+	// RoleController can watch the screen and send input
+	RoleController Role = "controller"
+)
+
+var (
+	// ErrNoViewerID is returned for control messages that do not say which viewer sent them
+	ErrNoViewerID = errors.New("message has no viewerId")
+	// ErrUnknownViewer is returned for messages from a viewer that has not joined
+	ErrUnknownViewer = errors.New("unknown viewer")
+	// ErrNotController is returned when a viewer without the controller role sends input
+	ErrNotController = errors.New("viewer does not hold the controller role")
+)
+
+// Viewer describes a viewer connected to the session
+type Viewer struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	Role     Role      `json:"role"`
+	Watching bool      `json:"watching"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// Change describes a change of the roster to the change callback. The
+// previous values are those passed to the callback the last time, so each
+// change can be handled on its own even when changes happen concurrently.
+type Change struct {
+	PreviousController string // Controller before the change, or ""
+	Controller         string // Controller after the change, or ""
+	PreviousWatching   int    // Viewers watching the stream before the change
+	Watching           int    // Viewers watching the stream after the change
+}
+
+// ControllerChanged returns true if the controller role moved
+func (c Change) ControllerChanged() bool {
+	return c.PreviousController != c.Controller
+}
+
+// viewerMessage is the union of the viewer messages received from the server
+type viewerMessage struct {
+	Type           string `json:"type"`
+	ViewerID       string `json:"viewerId"`
+	Name           string `json:"name,omitempty"`
+	TargetViewerID string `json:"targetViewerId,omitempty"`
+}
+
+// Roster tracks the viewers of a session and arbitrates the controller role.
+// At most one viewer holds the controller role; other viewers may queue for it.
+type Roster struct {
+	transport  client.Transport
+	viewers    map[string]*Viewer
+	controller string
+	pending    []string // Viewers waiting for control, in request order
+	onChange   func(Change)
+	reported   Change // State passed to the last change callback
+	verbose    bool
+	mu         sync.Mutex
+}
+
+// NewRoster creates a roster and registers its handlers on the transport
+func NewRoster(transport client.Transport, verbose bool) *Roster {
+	r := &Roster{
+		transport: transport,
+		viewers:   make(map[string]*Viewer),
+		verbose:   verbose,
+	}
+
+	transport.RegisterHandler(MessageTypeViewerJoined, r.handleJoined)
+	transport.RegisterHandler(MessageTypeViewerLeft, r.handleLeft)
+	transport.RegisterHandler(MessageTypeRequestControl, r.handleRequest)
+	transport.RegisterHandler(MessageTypeReleaseControl, r.handleRelease)
+	transport.RegisterHandler(MessageTypeGrantControl, r.handleGrant)
+
+	return r
+}
+
+// OnChange sets a callback invoked after the roster changes
+func (r *Roster) OnChange(callback func(Change)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = callback
+}
+
+// Controller returns the ID of the viewer holding the controller role, or ""
+func (r *Roster) Controller() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.controller
+}
+
+// Viewers returns the connected viewers ordered by join time
+func (r *Roster) Viewers() []Viewer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.viewersLocked()
+}
+
+// AuthorizeInput checks that a raw input message was sent by the controller
+func (r *Roster) AuthorizeInput(data []byte) error {
+	return r.authorize(data, true)
+}
+
+// AuthorizeViewer checks that a raw message was sent by a connected viewer,
+// for messages that do not need the controller role
+func (r *Roster) AuthorizeViewer(data []byte) error {
+	return r.authorize(data, false)
+}
+
+// authorize checks the viewer that sent a raw message
+func (r *Roster) authorize(data []byte, controller bool) error {
+	var msg viewerMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to parse message: %w", err)
+	}
+	if msg.ViewerID == "" {
+		return ErrNoViewerID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.viewers[msg.ViewerID]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownViewer, msg.ViewerID)
+	}
+	if controller && r.controller != msg.ViewerID {
+		return fmt.Errorf("%w: %s", ErrNotController, msg.ViewerID)
+	}
+	return nil
+}
+
+// Grant gives the controller role to a viewer on behalf of the local user
+func (r *Roster) Grant(viewerID string) error {
+	r.mu.Lock()
+	if _, ok := r.viewers[viewerID]; !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrUnknownViewer, viewerID)
+	}
+	r.setControllerLocked(viewerID)
+	r.mu.Unlock()
+
+	return r.changed("granted by local user")
+}
+
+// Revoke takes the controller role away on behalf of the local user.
+// Pending requests are not granted automatically.
+func (r *Roster) Revoke() error {
+	r.mu.Lock()
+	if r.controller == "" {
+		r.mu.Unlock()
+		return nil
+	}
+	r.setControllerLocked("")
+	r.mu.Unlock()
+
+	return r.changed("revoked by local user")
+}
+
+// SetWatching records whether a viewer is watching the video stream and
+// returns the number of viewers watching afterwards
+func (r *Roster) SetWatching(viewerID string, watching bool) (int, error) {
+	r.mu.Lock()
+	viewer, ok := r.viewers[viewerID]
+	if !ok {
+		r.mu.Unlock()
+		return 0, fmt.Errorf("%w: %s", ErrUnknownViewer, viewerID)
+	}
+	if viewer.Watching == watching {
+		count := r.watchingLocked()
+		r.mu.Unlock()
+		return count, nil
+	}
+	viewer.Watching = watching
+	r.mu.Unlock()
+
+	reason := "viewer stopped watching"
+	if watching {
+		reason = "viewer started watching"
+	}
+	err := r.changed(reason)
+	return r.Watching(), err
+}
+
+// Watching returns the number of viewers watching the video stream
+func (r *Roster) Watching() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.watchingLocked()
+}
+
+// handleJoined adds a viewer to the roster
+func (r *Roster) handleJoined(data []byte) error {
+	msg, err := parseViewerMessage(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if _, ok := r.viewers[msg.ViewerID]; !ok {
+		r.viewers[msg.ViewerID] = &Viewer{
+			ID:       msg.ViewerID,
+			Name:     msg.Name,
+			Role:     RoleViewer,
+			JoinedAt: time.Now(),
+		}
+	}
+	r.mu.Unlock()
+
+	return r.changed("viewer joined")
+}
+
+// handleLeft removes a viewer and hands control to the next pending viewer if needed
+func (r *Roster) handleLeft(data []byte) error {
+	msg, err := parseViewerMessage(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if _, ok := r.viewers[msg.ViewerID]; !ok {
+		r.mu.Unlock()
+		return nil
+	}
+	delete(r.viewers, msg.ViewerID)
+	r.removePendingLocked(msg.ViewerID)
+	if r.controller == msg.ViewerID {
+		r.setControllerLocked(r.nextPendingLocked())
+	}
+	r.mu.Unlock()
+
+	return r.changed("viewer left")
+}
+
+// handleRequest grants control if nobody holds it, otherwise queues the request
+func (r *Roster) handleRequest(data []byte) error {
+	msg, err := parseViewerMessage(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if _, ok := r.viewers[msg.ViewerID]; !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrUnknownViewer, msg.ViewerID)
+	}
+
+	reason := "control requested"
+	switch {
+	case r.controller == msg.ViewerID:
+		r.mu.Unlock()
+		return nil
+	case r.controller == "":
+		r.setControllerLocked(msg.ViewerID)
+		reason = "control granted"
+	default:
+		r.removePendingLocked(msg.ViewerID)
+		r.pending = append(r.pending, msg.ViewerID)
+	}
+	r.mu.Unlock()
+
+	return r.changed(reason)
+}
+
+// handleRelease lets the controller give up control to the next pending viewer
+func (r *Roster) handleRelease(data []byte) error {
+	msg, err := parseViewerMessage(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if r.controller != msg.ViewerID {
+		r.removePendingLocked(msg.ViewerID)
+		r.mu.Unlock()
+		return r.changed("control request withdrawn")
+	}
+	r.setControllerLocked(r.nextPendingLocked())
+	r.mu.Unlock()
+
+	return r.changed("control released")
+}
+
+// handleGrant lets the controller hand control to another viewer
+func (r *Roster) handleGrant(data []byte) error {
+	msg, err := parseViewerMessage(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if r.controller != msg.ViewerID {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrNotController, msg.ViewerID)
+	}
+	if _, ok := r.viewers[msg.TargetViewerID]; !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrUnknownViewer, msg.TargetViewerID)
+	}
+	r.removePendingLocked(msg.TargetViewerID)
+	r.setControllerLocked(msg.TargetViewerID)
+	r.mu.Unlock()
+
+	return r.changed("control handed over")
+}
+
+// changed broadcasts the control state to all viewers and invokes the change callback
+func (r *Roster) changed(reason string) error {
+	r.mu.Lock()
+	state := map[string]interface{}{
+		"type":         MessageTypeControlState,
+		"controllerId": r.controller,
+		"viewers":      r.viewersLocked(),
+		"pending":      append([]string{}, r.pending...),
+		"reason":       reason,
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}
+	callback := r.onChange
+	change := Change{
+		PreviousController: r.reported.Controller,
+		Controller:         r.controller,
+		PreviousWatching:   r.reported.Watching,
+		Watching:           r.watchingLocked(),
+	}
+	r.reported = change
+	r.mu.Unlock()
+
+	if r.verbose {
+		log.Printf("DEBUG: Control state changed (%s): controller=%q", reason, state["controllerId"])
+	}
+
+	if callback != nil {
+		callback(change)
+	}
+
+	return r.transport.SendJSON(state)
+}
+
+// setControllerLocked moves the controller role. Must be called with the lock held.
+func (r *Roster) setControllerLocked(viewerID string) {
+	if viewer, ok := r.viewers[r.controller]; ok {
+		viewer.Role = RoleViewer
+	}
+	r.controller = viewerID
+	if viewer, ok := r.viewers[viewerID]; ok {
+		viewer.Role = RoleController
+	}
+}
+
+// nextPendingLocked pops the next pending viewer. Must be called with the lock held.
+func (r *Roster) nextPendingLocked() string {
+	for len(r.pending) > 0 {
+		next := r.pending[0]
+		r.pending = r.pending[1:]
+		if _, ok := r.viewers[next]; ok {
+			return next
+		}
+	}
+	return ""
+}
+
+// removePendingLocked drops a viewer from the pending queue. Must be called with the lock held.
+func (r *Roster) removePendingLocked(viewerID string) {
+	kept := r.pending[:0]
+	for _, id := range r.pending {
+		if id != viewerID {
+			kept = append(kept, id)
+		}
+	}
+	r.pending = kept
+}
+
+// viewersLocked returns a snapshot of the viewers. Must be called with the lock held.
+func (r *Roster) viewersLocked() []Viewer {
+	viewers := make([]Viewer, 0, len(r.viewers))
+	for _, viewer := range r.viewers {
+		viewers = append(viewers, *viewer)
+	}
+	sort.Slice(viewers, func(i, j int) bool {
+		if viewers[i].JoinedAt.Equal(viewers[j].JoinedAt) {
+			return viewers[i].ID < viewers[j].ID
+		}
+		return viewers[i].JoinedAt.Before(viewers[j].JoinedAt)
+	})
+	return viewers
+}
+
+// watchingLocked counts viewers watching the stream. Must be called with the lock held.
+func (r *Roster) watchingLocked() int {
+	count := 0
+	for _, viewer := range r.viewers {
+		if viewer.Watching {
+			count++
+		}
+	}
+	return count
+}
+
+// parseViewerMessage parses a viewer message and checks it names a viewer
+func parseViewerMessage(data []byte) (viewerMessage, error) {
+	var msg viewerMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return msg, fmt.Errorf("failed to parse viewer message: %w", err)
+	}
+	if msg.ViewerID == "" {
+		return msg, ErrNoViewerID
+	}
+	return msg, nil
+}
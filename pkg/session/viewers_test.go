@@ -0,0 +1,153 @@
+package session
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/adamrobbie/go-support/pkg/client/clienttest"
+)
+
+func TestRosterControlHandoff(t *testing.T) {
+	transport := clienttest.New()
+	roster := NewRoster(transport, false)
+
+	transport.Deliver(t, `{"type":"viewerJoined","viewerId":"v1","name":"Alice"}`)
+	transport.Deliver(t, `{"type":"viewerJoined","viewerId":"v2","name":"Bob"}`)
+	if len(roster.Viewers()) != 2 || roster.Controller() != "" {
+		t.Fatalf("Expected two viewers and no controller, got %+v", roster.Viewers())
+	}
+	if transport.LastType() != MessageTypeControlState {
+		t.Errorf("Expected a %s broadcast, got %s", MessageTypeControlState, transport.LastType())
+	}
+
+	// The first request is granted, the second is queued
+	transport.Deliver(t, `{"type":"requestControl","viewerId":"v1"}`)
+	transport.Deliver(t, `{"type":"requestControl","viewerId":"v2"}`)
+	if roster.Controller() != "v1" {
+		t.Fatalf("Expected v1 to be controller, got %q", roster.Controller())
+	}
+	if pending := transport.Last()["pending"].([]interface{}); len(pending) != 1 || pending[0] != "v2" {
+		t.Errorf("Expected v2 to be pending, got %v", pending)
+	}
+
+	if err := roster.AuthorizeInput([]byte(`{"type":"mouseEvent","viewerId":"v1"}`)); err != nil {
+		t.Errorf("Controller input was rejected: %v", err)
+	}
+	if err := roster.AuthorizeInput([]byte(`{"type":"mouseEvent","viewerId":"v2"}`)); !errors.Is(err, ErrNotController) {
+		t.Errorf("Expected ErrNotController, got: %v", err)
+	}
+	if err := roster.AuthorizeInput([]byte(`{"type":"mouseEvent"}`)); !errors.Is(err, ErrNoViewerID) {
+		t.Errorf("Expected ErrNoViewerID, got: %v", err)
+	}
+	if err := roster.AuthorizeInput([]byte(`{"type":"mouseEvent","viewerId":"v9"}`)); !errors.Is(err, ErrUnknownViewer) {
+		t.Errorf("Expected ErrUnknownViewer, got: %v", err)
+	}
+	if err := roster.AuthorizeViewer([]byte(`{"type":"showPointer","viewerId":"v2"}`)); err != nil {
+		t.Errorf("Viewer message from a non-controller was rejected: %v", err)
+	}
+	if err := roster.AuthorizeViewer([]byte(`{"type":"showPointer","viewerId":"v9"}`)); !errors.Is(err, ErrUnknownViewer) {
+		t.Errorf("Expected ErrUnknownViewer, got: %v", err)
+	}
+
+	// Releasing hands control to the next pending viewer
+	transport.Deliver(t, `{"type":"releaseControl","viewerId":"v1"}`)
+	if roster.Controller() != "v2" {
+		t.Fatalf("Expected v2 to be controller after release, got %q", roster.Controller())
+	}
+
+	// Only the controller can hand control over
+	if err := transport.Deliver(t, `{"type":"grantControl","viewerId":"v1","targetViewerId":"v1"}`); !errors.Is(err, ErrNotController) {
+		t.Errorf("Expected ErrNotController, got: %v", err)
+	}
+	if err := transport.Deliver(t, `{"type":"grantControl","viewerId":"v2","targetViewerId":"v1"}`); err != nil {
+		t.Fatalf("grantControl handler returned an error: %v", err)
+	}
+	if roster.Controller() != "v1" {
+		t.Errorf("Expected v1 to be controller after grant, got %q", roster.Controller())
+	}
+
+	for _, viewer := range roster.Viewers() {
+		want := RoleViewer
+		if viewer.ID == "v1" {
+			want = RoleController
+		}
+		if viewer.Role != want {
+			t.Errorf("Viewer %s has role %s, want %s", viewer.ID, viewer.Role, want)
+		}
+	}
+}
+
+func TestRosterControllerLeaves(t *testing.T) {
+	transport := clienttest.New()
+	roster := NewRoster(transport, false)
+
+	transport.Deliver(t, `{"type":"viewerJoined","viewerId":"v1"}`)
+	transport.Deliver(t, `{"type":"viewerJoined","viewerId":"v2"}`)
+	transport.Deliver(t, `{"type":"viewerJoined","viewerId":"v3"}`)
+	transport.Deliver(t, `{"type":"requestControl","viewerId":"v1"}`)
+	transport.Deliver(t, `{"type":"requestControl","viewerId":"v2"}`)
+	transport.Deliver(t, `{"type":"requestControl","viewerId":"v3"}`)
+
+	// A pending viewer that leaves is skipped
+	transport.Deliver(t, `{"type":"viewerLeft","viewerId":"v2"}`)
+	transport.Deliver(t, `{"type":"viewerLeft","viewerId":"v1"}`)
+	if roster.Controller() != "v3" {
+		t.Errorf("Expected v3 to be controller, got %q", roster.Controller())
+	}
+}
+
+func TestRosterLocalGrantAndRevoke(t *testing.T) {
+	transport := clienttest.New()
+	roster := NewRoster(transport, false)
+
+	var changes []Change
+	roster.OnChange(func(change Change) { changes = append(changes, change) })
+
+	transport.Deliver(t, `{"type":"viewerJoined","viewerId":"v1"}`)
+	if err := roster.Grant("v9"); !errors.Is(err, ErrUnknownViewer) {
+		t.Errorf("Expected ErrUnknownViewer, got: %v", err)
+	}
+	if err := roster.Grant("v1"); err != nil || roster.Controller() != "v1" {
+		t.Fatalf("Grant() = %v, controller %q", err, roster.Controller())
+	}
+	if err := roster.Revoke(); err != nil || roster.Controller() != "" {
+		t.Fatalf("Revoke() = %v, controller %q", err, roster.Controller())
+	}
+	if transport.Last()["reason"] != "revoked by local user" {
+		t.Errorf("Unexpected broadcast: %v", transport.Last())
+	}
+	want := []Change{{}, {Controller: "v1"}, {PreviousController: "v1"}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Expected changes %+v, got %+v", want, changes)
+	}
+	if !changes[1].ControllerChanged() || changes[0].ControllerChanged() {
+		t.Error("Expected only the grant and the revoke to change the controller")
+	}
+}
+
+func TestRosterWatching(t *testing.T) {
+	transport := clienttest.New()
+	roster := NewRoster(transport, false)
+
+	transport.Deliver(t, `{"type":"viewerJoined","viewerId":"v1"}`)
+	transport.Deliver(t, `{"type":"viewerJoined","viewerId":"v2"}`)
+
+	if n, _ := roster.SetWatching("v1", true); n != 1 {
+		t.Errorf("Expected 1 watcher, got %d", n)
+	}
+	if n, _ := roster.SetWatching("v2", true); n != 2 {
+		t.Errorf("Expected 2 watchers, got %d", n)
+	}
+	if n, _ := roster.SetWatching("v1", false); n != 1 {
+		t.Errorf("Expected 1 watcher, got %d", n)
+	}
+	if _, err := roster.SetWatching("v9", true); !errors.Is(err, ErrUnknownViewer) {
+		t.Errorf("Expected ErrUnknownViewer, got: %v", err)
+	}
+
+	transport.Deliver(t, `{"type":"viewerLeft","viewerId":"v2"}`)
+	if n := roster.Watching(); n != 0 {
+		t.Errorf("Expected no watchers after the last one left, got %d", n)
+	}
+}
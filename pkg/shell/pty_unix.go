@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/creack/pty"
+)
+
+// startTerminal starts an interactive shell attached to a new pseudo-terminal
+func startTerminal(shell string, cols, rows uint16) (*exec.Cmd, *os.File, error) {
+	cmd := exec.Command(shell)
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	if home, err := os.UserHomeDir(); err == nil {
+		cmd.Dir = home
+	}
+
+	f, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: cols, Rows: rows})
+	if err != nil {
+		return nil, nil, err
+	}
+	return cmd, f, nil
+}
+
+// resizeTerminal changes the size of a pseudo-terminal
+func resizeTerminal(f *os.File, cols, rows uint16) error {
+	return pty.Setsize(f, &pty.Winsize{Cols: cols, Rows: rows})
+}
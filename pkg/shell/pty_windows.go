@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+)
+
+// startTerminal is not supported on Windows
+func startTerminal(shell string, cols, rows uint16) (*exec.Cmd, *os.File, error) {
+	return nil, nil, ErrUnsupported
+}
+
+// resizeTerminal is not supported on Windows
+func resizeTerminal(f *os.File, cols, rows uint16) error {
+	return ErrUnsupported
+}
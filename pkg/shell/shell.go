@@ -0,0 +1,345 @@
+package shell
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/adamrobbie/go-support/pkg/audit"
+	"github.com/adamrobbie/go-support/pkg/client"
+)
+
+// Message types used by remote shells
+const (
+	MessageTypeShellOpen   = "shellOpen"   // Server opens a shell
+	MessageTypeShellOpened = "shellOpened" // Agent started a shell
+	MessageTypeShellInput  = "shellInput"  // Server sends keystrokes to a shell
+	MessageTypeShellResize = "shellResize" // Server changes the terminal size
+	MessageTypeShellClose  = "shellClose"  // Server closes a shell
+	MessageTypeShellExit   = "shellExit"   // Agent reports that a shell ended
+)
+
+// FrameShellOutput is the kind byte of a binary frame carrying terminal output
+const FrameShellOutput byte = 0x01
+
+const (
+	// DefaultMaxShells is the default limit for simultaneous shells
+	DefaultMaxShells = 4
+	// DefaultCols and DefaultRows are the terminal size used when a request has none
+	DefaultCols = 80
+	DefaultRows = 24
+	// readSize is the largest output chunk sent in one frame
+	readSize = 4096
+	// maxIDLength is the longest shell ID that fits in a frame header
+	maxIDLength = 255
+)
+
+var (
+	// ErrUnsupported is returned on platforms without pseudo-terminals
+	ErrUnsupported = errors.New("remote shells are not supported on this platform")
+	// ErrTooManyShells is returned when the shell limit is reached
+	ErrTooManyShells = errors.New("too many open shells")
+	// ErrShellExists is returned when a shell ID is reused while its shell runs
+	ErrShellExists = errors.New("shell ID already in use")
+	// ErrUnknownShell is returned for messages about a shell that is not open
+	ErrUnknownShell = errors.New("no open shell with this ID")
+	// ErrInvalidFrame is returned when a binary frame cannot be decoded
+	ErrInvalidFrame = errors.New("invalid shell frame")
+)
+
+// Transport is the part of the WebSocket client used by remote shells
+type Transport interface {
+	client.Transport
+	SendBinary(data []byte) error
+}
+
+// Options configures remote shells
+type Options struct {
+	Shell     string        // Shell to run ($SHELL or /bin/sh if empty)
+	MaxShells int           // Simultaneous shells (DefaultMaxShells if zero)
+	Audit     *audit.Logger // Audit trail for opened and closed shells
+	Verbose   bool
+}
+
+// Request is a message from the server about a shell
+type Request struct {
+	Type     string `json:"type"`
+	ShellID  string `json:"shellId"`
+	Data     string `json:"data,omitempty"` // Base64-encoded keystrokes for shellInput
+	Cols     uint16 `json:"cols,omitempty"`
+	Rows     uint16 `json:"rows,omitempty"`
+	ViewerID string `json:"viewerId,omitempty"`
+}
+
+// Opened reports that a shell started
+type Opened struct {
+	Type    string `json:"type"`
+	ShellID string `json:"shellId"`
+	Shell   string `json:"shell"`
+	PID     int    `json:"pid"`
+}
+
+// Exit reports that a shell ended
+type Exit struct {
+	Type     string `json:"type"`
+	ShellID  string `json:"shellId"`
+	ExitCode int    `json:"exitCode"` // -1 if the shell was killed
+	Error    string `json:"error,omitempty"`
+}
+
+// terminal is a shell attached to a pseudo-terminal
+type terminal struct {
+	id        string
+	viewerID  string
+	cmd       *exec.Cmd
+	pty       *os.File
+	closeOnce sync.Once
+}
+
+// close closes the pseudo-terminal, which hangs up the shell, and kills it
+func (t *terminal) close() {
+	t.closeOnce.Do(func() {
+		t.pty.Close()
+		t.cmd.Process.Kill()
+	})
+}
+
+// Manager runs the shells opened by the server
+type Manager struct {
+	transport Transport
+	shell     string
+	maxShells int
+	audit     *audit.Logger
+	shells    map[string]*terminal
+	verbose   bool
+	mu        sync.Mutex
+}
+
+// NewManager creates a shell manager and registers its handlers on the transport
+func NewManager(transport Transport, opts Options) *Manager {
+	m := &Manager{
+		transport: transport,
+		shell:     opts.Shell,
+		maxShells: opts.MaxShells,
+		audit:     opts.Audit,
+		shells:    make(map[string]*terminal),
+		verbose:   opts.Verbose,
+	}
+	if m.shell == "" {
+		m.shell = os.Getenv("SHELL")
+	}
+	if m.shell == "" {
+		m.shell = "/bin/sh"
+	}
+	if m.maxShells <= 0 {
+		m.maxShells = DefaultMaxShells
+	}
+
+	transport.RegisterHandler(MessageTypeShellOpen, m.handleOpen)
+	transport.RegisterHandler(MessageTypeShellInput, m.handleInput)
+	transport.RegisterHandler(MessageTypeShellResize, m.handleResize)
+	transport.RegisterHandler(MessageTypeShellClose, m.handleClose)
+
+	return m
+}
+
+// Count returns the number of open shells
+func (m *Manager) Count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.shells)
+}
+
+// Close closes every open shell
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.shells {
+		t.close()
+	}
+}
+
+// EncodeFrame builds a binary frame of the given kind for a shell
+func EncodeFrame(kind byte, shellID string, data []byte) []byte {
+	frame := make([]byte, 0, 2+len(shellID)+len(data))
+	frame = append(frame, kind, byte(len(shellID)))
+	frame = append(frame, shellID...)
+	return append(frame, data...)
+}
+
+// DecodeFrame splits a binary frame into its kind, shell ID and data
+func DecodeFrame(frame []byte) (byte, string, []byte, error) {
+	if len(frame) < 2 || len(frame) < 2+int(frame[1]) {
+		return 0, "", nil, ErrInvalidFrame
+	}
+	end := 2 + int(frame[1])
+	return frame[0], string(frame[2:end]), frame[end:], nil
+}
+
+// handleOpen starts a shell
+func (m *Manager) handleOpen(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse shell open request: %w", err)
+	}
+	if req.ShellID == "" || len(req.ShellID) > maxIDLength {
+		return fmt.Errorf("shell open request needs a shellId of at most %d bytes", maxIDLength)
+	}
+	if req.Cols == 0 || req.Rows == 0 {
+		req.Cols, req.Rows = DefaultCols, DefaultRows
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.shells[req.ShellID]; exists {
+		return m.reject(req, fmt.Errorf("%w: %s", ErrShellExists, req.ShellID))
+	}
+	if len(m.shells) >= m.maxShells {
+		return m.reject(req, ErrTooManyShells)
+	}
+
+	cmd, pty, err := startTerminal(m.shell, req.Cols, req.Rows)
+	if err != nil {
+		return m.reject(req, fmt.Errorf("failed to start shell: %w", err))
+	}
+	t := &terminal{id: req.ShellID, viewerID: req.ViewerID, cmd: cmd, pty: pty}
+	m.shells[req.ShellID] = t
+
+	m.audit.Record("shell.opened", req.ViewerID, map[string]interface{}{
+		"shellId": req.ShellID,
+		"shell":   m.shell,
+		"pid":     cmd.Process.Pid,
+	})
+	if m.verbose {
+		log.Printf("DEBUG: Opened shell %s (%s, pid %d)", req.ShellID, m.shell, cmd.Process.Pid)
+	}
+
+	if err := m.transport.SendJSON(Opened{Type: MessageTypeShellOpened, ShellID: req.ShellID, Shell: m.shell, PID: cmd.Process.Pid}); err != nil {
+		log.Printf("Failed to send shell opened: %v", err)
+	}
+
+	go m.pump(t)
+	return nil
+}
+
+// reject reports a shell that could not be opened
+func (m *Manager) reject(req Request, err error) error {
+	if sendErr := m.transport.SendJSON(Exit{Type: MessageTypeShellExit, ShellID: req.ShellID, ExitCode: -1, Error: err.Error()}); sendErr != nil {
+		log.Printf("Failed to send shell exit: %v", sendErr)
+	}
+	return err
+}
+
+// handleInput writes keystrokes to a shell
+func (m *Manager) handleInput(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse shell input: %w", err)
+	}
+	input, err := base64.StdEncoding.DecodeString(req.Data)
+	if err != nil {
+		return fmt.Errorf("failed to decode shell input: %w", err)
+	}
+
+	t, err := m.lookup(req.ShellID)
+	if err != nil {
+		return err
+	}
+	if _, err := t.pty.Write(input); err != nil {
+		return fmt.Errorf("failed to write to shell %s: %w", req.ShellID, err)
+	}
+	return nil
+}
+
+// handleResize changes the terminal size of a shell
+func (m *Manager) handleResize(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse shell resize: %w", err)
+	}
+	if req.Cols == 0 || req.Rows == 0 {
+		return fmt.Errorf("shell resize needs cols and rows")
+	}
+
+	t, err := m.lookup(req.ShellID)
+	if err != nil {
+		return err
+	}
+	if err := resizeTerminal(t.pty, req.Cols, req.Rows); err != nil {
+		return fmt.Errorf("failed to resize shell %s: %w", req.ShellID, err)
+	}
+	return nil
+}
+
+// handleClose closes a shell. Its exit is reported once its output is drained.
+func (m *Manager) handleClose(data []byte) error {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to parse shell close request: %w", err)
+	}
+
+	t, err := m.lookup(req.ShellID)
+	if err != nil {
+		return err
+	}
+	t.close()
+	return nil
+}
+
+// lookup returns an open shell
+func (m *Manager) lookup(id string) (*terminal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.shells[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownShell, id)
+	}
+	return t, nil
+}
+
+// pump sends a shell's output as binary frames until it ends, then reports its exit
+func (m *Manager) pump(t *terminal) {
+	buffer := make([]byte, readSize)
+	for {
+		n, err := t.pty.Read(buffer)
+		if n > 0 {
+			if sendErr := m.transport.SendBinary(EncodeFrame(FrameShellOutput, t.id, buffer[:n])); sendErr != nil {
+				log.Printf("Failed to send shell output: %v", sendErr)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	t.close()
+	exit := Exit{Type: MessageTypeShellExit, ShellID: t.id}
+	if err := t.cmd.Wait(); err != nil && t.cmd.ProcessState == nil {
+		exit.Error = err.Error()
+	}
+	exit.ExitCode = t.cmd.ProcessState.ExitCode()
+
+	m.mu.Lock()
+	delete(m.shells, t.id)
+	m.mu.Unlock()
+
+	m.audit.Record("shell.closed", t.viewerID, map[string]interface{}{
+		"shellId":  t.id,
+		"exitCode": exit.ExitCode,
+	})
+	if m.verbose {
+		log.Printf("DEBUG: Shell %s exited with code %d", t.id, exit.ExitCode)
+	}
+
+	if err := m.transport.SendJSON(exit); err != nil {
+		log.Printf("Failed to send shell exit: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package shell
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/adamrobbie/go-support/pkg/client/clienttest"
+)
+
+// input sends keystrokes to a shell
+func input(t *testing.T, transport *clienttest.Transport, id, keys string) {
+	t.Helper()
+	message := fmt.Sprintf(`{"type":"shellInput","shellId":%q,"data":%q}`, id, base64.StdEncoding.EncodeToString([]byte(keys)))
+	if err := transport.Deliver(t, message); err != nil {
+		t.Fatalf("shellInput handler returned an error: %v", err)
+	}
+}
+
+// waitOutput waits until a shell's output contains want
+func waitOutput(t *testing.T, transport *clienttest.Transport, id, want string) {
+	t.Helper()
+	transport.WaitBinary(t, fmt.Sprintf("%q in the output of shell %s", want, id), func(frames [][]byte) bool {
+		var output bytes.Buffer
+		for _, frame := range frames {
+			if kind, shellID, data, err := DecodeFrame(frame); err == nil && kind == FrameShellOutput && shellID == id {
+				output.Write(data)
+			}
+		}
+		return strings.Contains(output.String(), want)
+	})
+}
+
+func skipWithoutPTY(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Pseudo-terminals are not supported on Windows")
+	}
+}
+
+func TestFrames(t *testing.T) {
+	frame := EncodeFrame(FrameShellOutput, "s1", []byte("hello"))
+	kind, id, data, err := DecodeFrame(frame)
+	if err != nil || kind != FrameShellOutput || id != "s1" || string(data) != "hello" {
+		t.Errorf("DecodeFrame() = %d, %q, %q, %v", kind, id, data, err)
+	}
+
+	for _, frame := range [][]byte{nil, {FrameShellOutput}, {FrameShellOutput, 5, 'a'}} {
+		if _, _, _, err := DecodeFrame(frame); !errors.Is(err, ErrInvalidFrame) {
+			t.Errorf("Expected ErrInvalidFrame for %v, got %v", frame, err)
+		}
+	}
+}
+
+func TestShellSession(t *testing.T) {
+	skipWithoutPTY(t)
+
+	transport := clienttest.New()
+	manager := NewManager(transport, Options{Shell: "/bin/sh"})
+	defer manager.Close()
+
+	if err := transport.Deliver(t, `{"type":"shellOpen","shellId":"s1","cols":100,"rows":30}`); err != nil {
+		t.Fatalf("shellOpen handler returned an error: %v", err)
+	}
+	if transport.Sent()[0]["type"] != MessageTypeShellOpened {
+		t.Errorf("Expected shellOpened, got %v", transport.Sent()[0])
+	}
+
+	input(t, transport, "s1", "stty size\n")
+	waitOutput(t, transport, "s1", "30 100")
+
+	if err := transport.Deliver(t, `{"type":"shellResize","shellId":"s1","cols":120,"rows":40}`); err != nil {
+		t.Fatalf("shellResize handler returned an error: %v", err)
+	}
+	input(t, transport, "s1", "stty size\n")
+	waitOutput(t, transport, "s1", "40 120")
+
+	input(t, transport, "s1", "exit 5\n")
+	exit := transport.Next(t, MessageTypeShellExit)
+	if exit["shellId"] != "s1" || exit["exitCode"].(float64) != 5 {
+		t.Errorf("Unexpected exit: %v", exit)
+	}
+	if manager.Count() != 0 {
+		t.Errorf("Expected no open shells, got %d", manager.Count())
+	}
+
+	if err := transport.Deliver(t, `{"type":"shellResize","shellId":"s1","cols":80,"rows":24}`); !errors.Is(err, ErrUnknownShell) {
+		t.Errorf("Expected ErrUnknownShell, got: %v", err)
+	}
+}
+
+func TestMultipleShells(t *testing.T) {
+	skipWithoutPTY(t)
+
+	transport := clienttest.New()
+	manager := NewManager(transport, Options{Shell: "/bin/sh", MaxShells: 2})
+
+	transport.Deliver(t, `{"type":"shellOpen","shellId":"a"}`)
+	transport.Deliver(t, `{"type":"shellOpen","shellId":"b"}`)
+	if err := transport.Deliver(t, `{"type":"shellOpen","shellId":"a"}`); !errors.Is(err, ErrShellExists) {
+		t.Errorf("Expected ErrShellExists, got: %v", err)
+	}
+	if err := transport.Deliver(t, `{"type":"shellOpen","shellId":"c"}`); !errors.Is(err, ErrTooManyShells) {
+		t.Errorf("Expected ErrTooManyShells, got: %v", err)
+	}
+	for range 2 {
+		transport.Next(t, MessageTypeShellExit) // Rejections are reported as exits
+	}
+
+	// Output is kept apart per shell
+	input(t, transport, "a", "echo from-$((1+1))\n")
+	input(t, transport, "b", "echo from-$((2+2))\n")
+	waitOutput(t, transport, "a", "from-2")
+	waitOutput(t, transport, "b", "from-4")
+
+	if err := transport.Deliver(t, `{"type":"shellClose","shellId":"a"}`); err != nil {
+		t.Fatalf("shellClose handler returned an error: %v", err)
+	}
+	if exit := transport.Next(t, MessageTypeShellExit); exit["shellId"] != "a" {
+		t.Errorf("Expected shell a to exit, got %v", exit)
+	}
+
+	// Closing the manager closes the remaining shells
+	manager.Close()
+	if exit := transport.Next(t, MessageTypeShellExit); exit["shellId"] != "b" {
+		t.Errorf("Expected shell b to exit, got %v", exit)
+	}
+}
@@ -0,0 +1,214 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Field names used by signed messages
+const (
+	FieldKeyID     = "keyId"
+	FieldNonce     = "nonce"
+	FieldTimestamp = "timestamp"
+	FieldSignature = "signature"
+)
+
+// DefaultMaxSkew is the default allowed difference between the message timestamp and the local clock
+const DefaultMaxSkew = 30 * time.Second
+
+var (
+	// ErrMissingSignature is returned when a message carries no signature fields
+	ErrMissingSignature = errors.New("message is not signed")
+	// ErrUnknownKey is returned when the signing key is not configured
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrBadSignature is returned when the signature does not verify
+	ErrBadSignature = errors.New("invalid signature")
+	// ErrStaleTimestamp is returned when the timestamp is outside the allowed skew
+	ErrStaleTimestamp = errors.New("stale or future timestamp")
+	// ErrReplay is returned when a nonce has already been seen
+	ErrReplay = errors.New("replayed nonce")
+)
+
+// Verifier checks Ed25519 signatures on command messages and rejects replays
+type Verifier struct {
+	keys    map[string]ed25519.PublicKey
+	maxSkew time.Duration
+	seen    map[string]time.Time // nonce -> time after which it can be forgotten
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// NewVerifier creates a verifier for the given public keys, indexed by key ID
+func NewVerifier(keys map[string]ed25519.PublicKey, maxSkew time.Duration) *Verifier {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+	return &Verifier{
+		keys:    keys,
+		maxSkew: maxSkew,
+		seen:    make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Verify checks the signature, timestamp and nonce of a raw JSON message
+func (v *Verifier) Verify(data []byte) error {
+	msg, err := decode(data)
+	if err != nil {
+		return err
+	}
+
+	keyID, _ := msg[FieldKeyID].(string)
+	nonce, _ := msg[FieldNonce].(string)
+	timestamp, _ := msg[FieldTimestamp].(string)
+	signature, _ := msg[FieldSignature].(string)
+	if signature == "" || keyID == "" {
+		return ErrMissingSignature
+	}
+	if nonce == "" || timestamp == "" {
+		return fmt.Errorf("%w: nonce and timestamp are required", ErrBadSignature)
+	}
+
+	key, ok := v.keys[keyID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: signature is not valid base64", ErrBadSignature)
+	}
+
+	delete(msg, FieldSignature)
+	payload, err := Canonicalize(msg)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, payload, sig) {
+		return ErrBadSignature
+	}
+
+	// Only check freshness once the signature is known to be genuine, so
+	// unauthenticated messages cannot fill the nonce cache
+	ts, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStaleTimestamp, err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	if ts.Before(now.Add(-v.maxSkew)) || ts.After(now.Add(v.maxSkew)) {
+		return fmt.Errorf("%w: %s", ErrStaleTimestamp, timestamp)
+	}
+
+	// Forget nonces whose messages would now be rejected as stale anyway
+	for n, expiry := range v.seen {
+		if now.After(expiry) {
+			delete(v.seen, n)
+		}
+	}
+
+	cacheKey := keyID + "/" + nonce
+	if _, replayed := v.seen[cacheKey]; replayed {
+		return fmt.Errorf("%w: %s", ErrReplay, nonce)
+	}
+	v.seen[cacheKey] = ts.Add(v.maxSkew)
+
+	return nil
+}
+
+// Sign adds a key ID, nonce, timestamp and Ed25519 signature to a JSON message
+func Sign(data []byte, keyID string, key ed25519.PrivateKey) ([]byte, error) {
+	msg, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	delete(msg, FieldSignature)
+	msg[FieldKeyID] = keyID
+	msg[FieldNonce] = hex.EncodeToString(nonce)
+	msg[FieldTimestamp] = time.Now().UTC().Format(time.RFC3339Nano)
+
+	payload, err := Canonicalize(msg)
+	if err != nil {
+		return nil, err
+	}
+	msg[FieldSignature] = base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))
+
+	return Canonicalize(msg)
+}
+
+// Canonicalize encodes a message as compact JSON with sorted keys and no HTML escaping.
+// Numbers must be json.Number values (as produced by decode) to round-trip exactly.
+func Canonicalize(msg map[string]any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(msg); err != nil {
+		return nil, fmt.Errorf("failed to canonicalize message: %w", err)
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// decode parses a JSON object, keeping numbers in their original textual form
+func decode(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var msg map[string]any
+	if err := dec.Decode(&msg); err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+	if msg == nil {
+		return nil, fmt.Errorf("failed to parse message: not a JSON object")
+	}
+	return msg, nil
+}
+
+// ParsePublicKeys parses a comma-separated list of "keyId:base64-public-key" entries
+func ParsePublicKeys(spec string) (map[string]ed25519.PublicKey, error) {
+	keys := make(map[string]ed25519.PublicKey)
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || strings.TrimSpace(id) == "" {
+			return nil, fmt.Errorf("invalid public key entry %q: expected keyId:base64", entry)
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 public key for %s", id)
+		}
+		keys[strings.TrimSpace(id)] = ed25519.PublicKey(raw)
+	}
+	return keys, nil
+}
+
+// LoadPublicKeys reads public keys from a file containing one "keyId:base64" entry per line
+func LoadPublicKeys(path string) (map[string]ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public keys: %w", err)
+	}
+	return ParsePublicKeys(string(data))
+}
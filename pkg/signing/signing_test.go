@@ -0,0 +1,137 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return pub, priv
+}
+
+func TestSignAndVerify(t *testing.T) {
+	pub, priv := newTestKey(t)
+	verifier := NewVerifier(map[string]ed25519.PublicKey{"tech": pub}, time.Minute)
+
+	signed, err := Sign([]byte(`{"type":"mouseEvent","action":"move","x":100,"y":200.5}`), "tech", priv)
+	if err != nil {
+		t.Fatalf("Sign() returned an error: %v", err)
+	}
+
+	if err := verifier.Verify(signed); err != nil {
+		t.Fatalf("Verify() returned an error for a valid message: %v", err)
+	}
+
+	// The same message must not be accepted twice
+	if err := verifier.Verify(signed); !errors.Is(err, ErrReplay) {
+		t.Errorf("Expected ErrReplay, got: %v", err)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	pub, priv := newTestKey(t)
+	verifier := NewVerifier(map[string]ed25519.PublicKey{"tech": pub}, time.Minute)
+
+	signed, _ := Sign([]byte(`{"type":"keyboardEvent","action":"type","text":"ls"}`), "tech", priv)
+	tampered := []byte(strings.Replace(string(signed), `"ls"`, `"rm -rf /"`, 1))
+
+	if err := verifier.Verify(tampered); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Expected ErrBadSignature, got: %v", err)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	pub, priv := newTestKey(t)
+	_, otherPriv := newTestKey(t)
+	verifier := NewVerifier(map[string]ed25519.PublicKey{"tech": pub}, time.Minute)
+
+	if err := verifier.Verify([]byte(`{"type":"mouseEvent"}`)); !errors.Is(err, ErrMissingSignature) {
+		t.Errorf("Expected ErrMissingSignature, got: %v", err)
+	}
+
+	signed, _ := Sign([]byte(`{"type":"mouseEvent"}`), "unknown", priv)
+	if err := verifier.Verify(signed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got: %v", err)
+	}
+
+	signed, _ = Sign([]byte(`{"type":"mouseEvent"}`), "tech", otherPriv)
+	if err := verifier.Verify(signed); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Expected ErrBadSignature for wrong key, got: %v", err)
+	}
+
+	if err := verifier.Verify([]byte(`not json`)); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestVerifyStaleTimestamp(t *testing.T) {
+	pub, priv := newTestKey(t)
+	verifier := NewVerifier(map[string]ed25519.PublicKey{"tech": pub}, time.Minute)
+
+	signed, _ := Sign([]byte(`{"type":"mouseEvent"}`), "tech", priv)
+
+	verifier.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if err := verifier.Verify(signed); !errors.Is(err, ErrStaleTimestamp) {
+		t.Errorf("Expected ErrStaleTimestamp for old message, got: %v", err)
+	}
+
+	verifier.now = func() time.Time { return time.Now().Add(-2 * time.Minute) }
+	if err := verifier.Verify(signed); !errors.Is(err, ErrStaleTimestamp) {
+		t.Errorf("Expected ErrStaleTimestamp for future message, got: %v", err)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	msg, err := decode([]byte(`{ "b": 1.50, "a": {"z": "<x>", "y": [1, 2]} }`))
+	if err != nil {
+		t.Fatalf("decode() returned an error: %v", err)
+	}
+
+	got, err := Canonicalize(msg)
+	if err != nil {
+		t.Fatalf("Canonicalize() returned an error: %v", err)
+	}
+
+	want := `{"a":{"y":[1,2],"z":"<x>"},"b":1.50}`
+	if string(got) != want {
+		t.Errorf("Canonicalize() = %s, want %s", got, want)
+	}
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	pub, _ := newTestKey(t)
+	encoded := base64.StdEncoding.EncodeToString(pub)
+
+	keys, err := ParsePublicKeys("alice:" + encoded + ", bob:" + encoded)
+	if err != nil {
+		t.Fatalf("ParsePublicKeys() returned an error: %v", err)
+	}
+	if len(keys) != 2 || !keys["bob"].Equal(pub) {
+		t.Errorf("Expected two keys including bob, got %v", keys)
+	}
+
+	if _, err := ParsePublicKeys("alice:not-base64"); err == nil {
+		t.Error("Expected an error for an invalid key")
+	}
+	if _, err := ParsePublicKeys(encoded); err == nil {
+		t.Error("Expected an error for an entry without a key ID")
+	}
+
+	path := filepath.Join(t.TempDir(), "keys")
+	os.WriteFile(path, []byte("# technicians\nalice:"+encoded+"\n"), 0600)
+	keys, err = LoadPublicKeys(path)
+	if err != nil || len(keys) != 1 {
+		t.Errorf("LoadPublicKeys() = %v, %v; want one key", keys, err)
+	}
+}
@@ -0,0 +1,689 @@
+package transfer
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+)
+
+// Message types used by file transfers
+const (
+	MessageTypeUploadStart     = "fileUploadStart"      // Server offers a file to the agent
+	MessageTypeUploadChunk     = "fileUploadChunk"      // Server sends a chunk of an upload
+	MessageTypeUploadEnd       = "fileUploadEnd"        // Server has sent every chunk of an upload
+	MessageTypeDownloadRequest = "fileDownloadRequest"  // Server asks for a file from the agent
+	MessageTypeDownloadStart   = "fileDownloadStart"    // Agent describes the file it is about to send
+	MessageTypeDownloadChunk   = "fileDownloadChunk"    // Agent sends a chunk of a download
+	MessageTypeDownloadEnd     = "fileDownloadEnd"      // Agent has sent every chunk of a download
+	MessageTypeReady           = "fileTransferReady"    // Agent accepted an upload and says where to resume
+	MessageTypeProgress        = "fileTransferProgress" // Agent reports transfer progress
+	MessageTypeComplete        = "fileTransferComplete" // Agent stored and verified an upload
+	MessageTypeError           = "fileTransferError"    // A transfer failed
+	MessageTypeCancel          = "fileTransferCancel"   // Server cancels a transfer
+)
+
+const (
+	// DefaultChunkSize is the default size of a chunk in bytes
+	DefaultChunkSize = 256 << 10
+	// DefaultMaxFileSize is the default limit for a transferred file in bytes
+	DefaultMaxFileSize = 1 << 30
+	// partSuffix is appended to files that are still being uploaded
+	partSuffix = ".part"
+)
+
+var (
+	// ErrNotAllowed is returned for paths outside the allowed directories
+	ErrNotAllowed = errors.New("path is outside the allowed directories")
+	// ErrDeclined is returned when the local user declines a transfer
+	ErrDeclined = errors.New("transfer declined by the local user")
+	// ErrChecksum is returned when a file does not match its SHA-256 hash
+	ErrChecksum = errors.New("checksum mismatch")
+	// ErrUnknownTransfer is returned for messages about a transfer that is not in progress
+	ErrUnknownTransfer = errors.New("unknown transfer")
+	// ErrTooLarge is returned for files larger than the size limit
+	ErrTooLarge = errors.New("file exceeds the size limit")
+)
+
+// Direction is the direction of a transfer as seen from the server
+type Direction string
+
+const (
+	// Upload sends a file from the server to the agent
+	Upload Direction = "upload"
+	// Download sends a file from the agent to the server
+	Download Direction = "download"
+)
+
+// Request describes a transfer awaiting local consent
+type Request struct {
+	ID        string
+	Direction Direction
+	Path      string // Destination of an upload or source of a download
+	Size      int64
+}
+
+// Transport is the part of the WebSocket client used by file transfers
+type Transport interface {
+	client.Transport
+	SendJSONWithPriority(message interface{}, priority client.Priority) error
+}
+
+// Options configures file transfers
+type Options struct {
+	AllowedDirs []string           // Directories files may be written to and read from
+	ChunkSize   int                // Size of download chunks (DefaultChunkSize if zero)
+	MaxFileSize int64              // Largest file accepted (DefaultMaxFileSize if zero)
+	Consent     func(Request) bool // Asks the local user to approve a transfer; nil approves all
+	Verbose     bool
+}
+
+// Message is the union of the file transfer messages
+type Message struct {
+	Type       string `json:"type"`
+	TransferID string `json:"transferId"`
+	Name       string `json:"name,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Directory  string `json:"directory,omitempty"`
+	Size       int64  `json:"size,omitempty"`
+	SHA256     string `json:"sha256,omitempty"` // Hex-encoded SHA-256 of the whole file
+	ChunkSize  int    `json:"chunkSize,omitempty"`
+	Offset     int64  `json:"offset,omitempty"`
+	Data       string `json:"data,omitempty"`  // Base64-encoded chunk
+	Bytes      int64  `json:"bytes,omitempty"` // Bytes transferred so far
+	Error      string `json:"error,omitempty"`
+}
+
+// upload is an upload in progress
+type upload struct {
+	request      Request
+	sha256       string
+	partPath     string
+	file         *os.File
+	written      int64
+	lastProgress int64
+	ready        bool
+}
+
+// Manager runs file transfers on top of a Transport
+type Manager struct {
+	transport   Transport
+	allowedDirs []string
+	chunkSize   int
+	maxFileSize int64
+	consent     func(Request) bool
+	uploads     map[string]*upload
+	downloads   map[string]chan struct{} // Closed to cancel a download
+	verbose     bool
+	mu          sync.Mutex
+}
+
+// NewManager creates a transfer manager and registers its handlers on the transport
+func NewManager(transport Transport, opts Options) (*Manager, error) {
+	if len(opts.AllowedDirs) == 0 {
+		return nil, fmt.Errorf("no allowed directories configured")
+	}
+
+	m := &Manager{
+		transport:   transport,
+		chunkSize:   opts.ChunkSize,
+		maxFileSize: opts.MaxFileSize,
+		consent:     opts.Consent,
+		uploads:     make(map[string]*upload),
+		downloads:   make(map[string]chan struct{}),
+		verbose:     opts.Verbose,
+	}
+	if m.chunkSize <= 0 {
+		m.chunkSize = DefaultChunkSize
+	}
+	if m.maxFileSize <= 0 {
+		m.maxFileSize = DefaultMaxFileSize
+	}
+
+	for _, dir := range opts.AllowedDirs {
+		resolved, err := resolveDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		m.allowedDirs = append(m.allowedDirs, resolved)
+	}
+
+	transport.RegisterHandler(MessageTypeUploadStart, m.handleUploadStart)
+	transport.RegisterHandler(MessageTypeUploadChunk, m.handleUploadChunk)
+	transport.RegisterHandler(MessageTypeUploadEnd, m.handleUploadEnd)
+	transport.RegisterHandler(MessageTypeDownloadRequest, m.handleDownloadRequest)
+	transport.RegisterHandler(MessageTypeCancel, m.handleCancel)
+
+	return m, nil
+}
+
+// AllowedDirs returns the resolved allowed directories
+func (m *Manager) AllowedDirs() []string {
+	return append([]string{}, m.allowedDirs...)
+}
+
+// Close cancels all transfers in progress. Partial uploads are kept so they can be resumed.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, u := range m.uploads {
+		if u.file != nil {
+			u.file.Close()
+		}
+		delete(m.uploads, id)
+	}
+	for id, cancel := range m.downloads {
+		close(cancel)
+		delete(m.downloads, id)
+	}
+}
+
+// handleUploadStart validates an upload offer and asks for consent
+func (m *Manager) handleUploadStart(data []byte) error {
+	msg, err := parseMessage(data)
+	if err != nil {
+		return err
+	}
+
+	name, err := safeName(msg.Name)
+	if err != nil {
+		return m.fail(msg.TransferID, err)
+	}
+	if msg.Size < 0 || msg.Size > m.maxFileSize {
+		return m.fail(msg.TransferID, fmt.Errorf("%w: %d bytes, limit %d", ErrTooLarge, msg.Size, m.maxFileSize))
+	}
+	if sum, err := hex.DecodeString(msg.SHA256); err != nil || len(sum) != sha256.Size {
+		return m.fail(msg.TransferID, fmt.Errorf("upload has no valid sha256"))
+	}
+
+	dir := m.allowedDirs[0]
+	if msg.Directory != "" {
+		if dir, err = m.allowedPath(msg.Directory); err != nil {
+			return m.fail(msg.TransferID, err)
+		}
+	}
+
+	u := &upload{
+		request: Request{
+			ID:        msg.TransferID,
+			Direction: Upload,
+			Path:      filepath.Join(dir, name),
+			Size:      msg.Size,
+		},
+		sha256:   strings.ToLower(msg.SHA256),
+		partPath: filepath.Join(dir, "."+name+"."+strings.ToLower(msg.SHA256[:12])+partSuffix),
+	}
+
+	m.mu.Lock()
+	if _, exists := m.uploads[msg.TransferID]; exists {
+		m.mu.Unlock()
+		return m.fail(msg.TransferID, fmt.Errorf("transfer %s already in progress", msg.TransferID))
+	}
+	m.uploads[msg.TransferID] = u
+	m.mu.Unlock()
+
+	// Consent may wait for the local user, so it must not block message dispatch.
+	// The server does not send chunks before fileTransferReady.
+	go m.acceptUpload(u)
+	return nil
+}
+
+// acceptUpload asks for consent and opens the partial file for an upload
+func (m *Manager) acceptUpload(u *upload) {
+	if m.consent != nil && !m.consent(u.request) {
+		m.abortUpload(u.request.ID, ErrDeclined)
+		return
+	}
+
+	file, err := os.OpenFile(u.partPath, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		m.abortUpload(u.request.ID, fmt.Errorf("failed to create file: %w", err))
+		return
+	}
+
+	// Resume from a partial file left by an earlier attempt
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		m.abortUpload(u.request.ID, fmt.Errorf("failed to stat file: %w", err))
+		return
+	}
+	offset := info.Size()
+	if offset > u.request.Size {
+		offset = 0
+	}
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		m.abortUpload(u.request.ID, fmt.Errorf("failed to truncate file: %w", err))
+		return
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		m.abortUpload(u.request.ID, fmt.Errorf("failed to seek file: %w", err))
+		return
+	}
+
+	m.mu.Lock()
+	if m.uploads[u.request.ID] != u {
+		// Cancelled while waiting for consent
+		m.mu.Unlock()
+		file.Close()
+		return
+	}
+	u.file = file
+	u.written = offset
+	u.lastProgress = offset
+	u.ready = true
+	m.mu.Unlock()
+
+	if m.verbose {
+		log.Printf("DEBUG: Accepted upload %s to %s at offset %d", u.request.ID, u.request.Path, offset)
+	}
+
+	if err := m.transport.SendJSON(Message{
+		Type:       MessageTypeReady,
+		TransferID: u.request.ID,
+		Offset:     offset,
+	}); err != nil {
+		log.Printf("Failed to send transfer ready: %v", err)
+	}
+}
+
+// handleUploadChunk writes a chunk of an upload
+func (m *Manager) handleUploadChunk(data []byte) error {
+	msg, err := parseMessage(data)
+	if err != nil {
+		return err
+	}
+
+	chunk, err := base64.StdEncoding.DecodeString(msg.Data)
+	if err != nil {
+		return m.abortUpload(msg.TransferID, fmt.Errorf("invalid chunk encoding: %w", err))
+	}
+
+	m.mu.Lock()
+	u, ok := m.uploads[msg.TransferID]
+	switch {
+	case !ok || !u.ready:
+		m.mu.Unlock()
+		return m.fail(msg.TransferID, fmt.Errorf("%w: %s", ErrUnknownTransfer, msg.TransferID))
+	case msg.Offset != u.written:
+		written := u.written
+		m.mu.Unlock()
+		return m.abortUpload(msg.TransferID, fmt.Errorf("chunk at offset %d, expected %d", msg.Offset, written))
+	case u.written+int64(len(chunk)) > u.request.Size:
+		m.mu.Unlock()
+		return m.abortUpload(msg.TransferID, fmt.Errorf("upload is larger than the announced %d bytes", u.request.Size))
+	}
+
+	if _, err := u.file.Write(chunk); err != nil {
+		m.mu.Unlock()
+		return m.abortUpload(msg.TransferID, fmt.Errorf("failed to write file: %w", err))
+	}
+	u.written += int64(len(chunk))
+	report := m.shouldReport(u.written, u.lastProgress, u.request.Size)
+	if report {
+		u.lastProgress = u.written
+	}
+	written := u.written
+	m.mu.Unlock()
+
+	if report {
+		return m.sendProgress(msg.TransferID, written, u.request.Size)
+	}
+	return nil
+}
+
+// handleUploadEnd verifies an upload and moves it into place
+func (m *Manager) handleUploadEnd(data []byte) error {
+	msg, err := parseMessage(data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	u, ok := m.uploads[msg.TransferID]
+	if !ok || !u.ready {
+		m.mu.Unlock()
+		return m.fail(msg.TransferID, fmt.Errorf("%w: %s", ErrUnknownTransfer, msg.TransferID))
+	}
+	if u.written != u.request.Size {
+		written := u.written
+		m.mu.Unlock()
+		return m.abortUpload(msg.TransferID, fmt.Errorf("upload ended after %d of %d bytes", written, u.request.Size))
+	}
+	delete(m.uploads, msg.TransferID)
+	m.mu.Unlock()
+
+	if err := u.file.Close(); err != nil {
+		return m.fail(msg.TransferID, fmt.Errorf("failed to close file: %w", err))
+	}
+
+	sum, err := hashFile(u.partPath)
+	if err != nil {
+		return m.fail(msg.TransferID, err)
+	}
+	if sum != u.sha256 {
+		os.Remove(u.partPath)
+		return m.fail(msg.TransferID, fmt.Errorf("%w: got %s, want %s", ErrChecksum, sum, u.sha256))
+	}
+
+	path := uniquePath(u.request.Path)
+	if err := os.Rename(u.partPath, path); err != nil {
+		return m.fail(msg.TransferID, fmt.Errorf("failed to move file into place: %w", err))
+	}
+
+	log.Printf("Received file %s (%d bytes)", path, u.request.Size)
+	return m.transport.SendJSON(Message{
+		Type:       MessageTypeComplete,
+		TransferID: msg.TransferID,
+		Path:       path,
+		Size:       u.request.Size,
+		SHA256:     sum,
+	})
+}
+
+// handleDownloadRequest validates a download request and starts sending the file
+func (m *Manager) handleDownloadRequest(data []byte) error {
+	msg, err := parseMessage(data)
+	if err != nil {
+		return err
+	}
+
+	path, err := m.allowedPath(msg.Path)
+	if err != nil {
+		return m.fail(msg.TransferID, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return m.fail(msg.TransferID, fmt.Errorf("failed to stat file: %w", err))
+	}
+	if !info.Mode().IsRegular() {
+		return m.fail(msg.TransferID, fmt.Errorf("%s is not a regular file", path))
+	}
+	if info.Size() > m.maxFileSize {
+		return m.fail(msg.TransferID, fmt.Errorf("%w: %d bytes, limit %d", ErrTooLarge, info.Size(), m.maxFileSize))
+	}
+	if msg.Offset < 0 || msg.Offset > info.Size() {
+		return m.fail(msg.TransferID, fmt.Errorf("invalid offset %d", msg.Offset))
+	}
+
+	cancel := make(chan struct{})
+	m.mu.Lock()
+	if _, exists := m.downloads[msg.TransferID]; exists {
+		m.mu.Unlock()
+		return m.fail(msg.TransferID, fmt.Errorf("transfer %s already in progress", msg.TransferID))
+	}
+	m.downloads[msg.TransferID] = cancel
+	m.mu.Unlock()
+
+	request := Request{ID: msg.TransferID, Direction: Download, Path: path, Size: info.Size()}
+	go m.sendFile(request, msg.Offset, cancel)
+	return nil
+}
+
+// sendFile asks for consent and sends a file in chunks starting at offset
+func (m *Manager) sendFile(request Request, offset int64, cancel chan struct{}) {
+	defer func() {
+		m.mu.Lock()
+		if m.downloads[request.ID] == cancel {
+			delete(m.downloads, request.ID)
+		}
+		m.mu.Unlock()
+	}()
+
+	if m.consent != nil && !m.consent(request) {
+		m.fail(request.ID, ErrDeclined)
+		return
+	}
+
+	sum, err := hashFile(request.Path)
+	if err != nil {
+		m.fail(request.ID, err)
+		return
+	}
+
+	file, err := os.Open(request.Path)
+	if err != nil {
+		m.fail(request.ID, fmt.Errorf("failed to open file: %w", err))
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		m.fail(request.ID, fmt.Errorf("failed to seek file: %w", err))
+		return
+	}
+
+	if err := m.transport.SendJSON(Message{
+		Type:       MessageTypeDownloadStart,
+		TransferID: request.ID,
+		Name:       filepath.Base(request.Path),
+		Path:       request.Path,
+		Size:       request.Size,
+		SHA256:     sum,
+		ChunkSize:  m.chunkSize,
+		Offset:     offset,
+	}); err != nil {
+		log.Printf("Failed to start download %s: %v", request.ID, err)
+		return
+	}
+
+	buffer := make([]byte, m.chunkSize)
+	sent, lastProgress := offset, offset
+	for {
+		select {
+		case <-cancel:
+			if m.verbose {
+				log.Printf("DEBUG: Download %s cancelled at %d bytes", request.ID, sent)
+			}
+			return
+		default:
+		}
+
+		n, err := file.Read(buffer)
+		if n > 0 {
+			// Chunks wait behind control messages, so a large download does
+			// not hold up replies and input
+			if err := m.transport.SendJSONWithPriority(Message{
+				Type:       MessageTypeDownloadChunk,
+				TransferID: request.ID,
+				Offset:     sent,
+				Data:       base64.StdEncoding.EncodeToString(buffer[:n]),
+			}, client.PriorityBulk); err != nil {
+				log.Printf("Failed to send download chunk: %v", err)
+				return
+			}
+			sent += int64(n)
+			if m.shouldReport(sent, lastProgress, request.Size) {
+				lastProgress = sent
+				m.sendProgress(request.ID, sent, request.Size)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			m.fail(request.ID, fmt.Errorf("failed to read file: %w", err))
+			return
+		}
+	}
+
+	log.Printf("Sent file %s (%d bytes)", request.Path, request.Size)
+	if err := m.transport.SendJSON(Message{
+		Type:       MessageTypeDownloadEnd,
+		TransferID: request.ID,
+		Size:       request.Size,
+		SHA256:     sum,
+	}); err != nil {
+		log.Printf("Failed to end download %s: %v", request.ID, err)
+	}
+}
+
+// handleCancel cancels an upload or download
+func (m *Manager) handleCancel(data []byte) error {
+	msg, err := parseMessage(data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, ok := m.uploads[msg.TransferID]; ok {
+		// The partial file is kept so the upload can be resumed
+		if u.file != nil {
+			u.file.Close()
+		}
+		delete(m.uploads, msg.TransferID)
+	}
+	if cancel, ok := m.downloads[msg.TransferID]; ok {
+		close(cancel)
+		delete(m.downloads, msg.TransferID)
+	}
+
+	if m.verbose {
+		log.Printf("DEBUG: Cancelled transfer %s", msg.TransferID)
+	}
+	return nil
+}
+
+// abortUpload stops an upload, keeping its partial file, and reports the error
+func (m *Manager) abortUpload(id string, err error) error {
+	m.mu.Lock()
+	if u, ok := m.uploads[id]; ok {
+		if u.file != nil {
+			u.file.Close()
+		}
+		delete(m.uploads, id)
+	}
+	m.mu.Unlock()
+
+	return m.fail(id, err)
+}
+
+// fail reports a failed transfer to the server and returns the error
+func (m *Manager) fail(id string, err error) error {
+	log.Printf("File transfer %s failed: %v", id, err)
+	if sendErr := m.transport.SendJSON(Message{
+		Type:       MessageTypeError,
+		TransferID: id,
+		Error:      err.Error(),
+	}); sendErr != nil {
+		log.Printf("Failed to send transfer error: %v", sendErr)
+	}
+	return fmt.Errorf("transfer %s: %w", id, err)
+}
+
+// sendProgress reports how many bytes of a transfer are done
+func (m *Manager) sendProgress(id string, bytes, size int64) error {
+	return m.transport.SendJSON(Message{
+		Type:       MessageTypeProgress,
+		TransferID: id,
+		Bytes:      bytes,
+		Size:       size,
+	})
+}
+
+// shouldReport returns true when progress has advanced by at least 5% or the transfer is done
+func (m *Manager) shouldReport(done, last, size int64) bool {
+	return done == size || (done-last)*20 >= size
+}
+
+// allowedPath resolves a path and checks it is inside an allowed directory
+func (m *Manager) allowedPath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("%w: %s is not absolute", ErrNotAllowed, path)
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	for _, dir := range m.allowedDirs {
+		rel, err := filepath.Rel(dir, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotAllowed, path)
+}
+
+// parseMessage parses a transfer message and checks it has a transfer ID
+func parseMessage(data []byte) (Message, error) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return msg, fmt.Errorf("failed to parse file transfer message: %w", err)
+	}
+	if msg.TransferID == "" {
+		return msg, fmt.Errorf("file transfer message has no transferId")
+	}
+	return msg, nil
+}
+
+// resolveDir makes a directory absolute and resolves symlinks
+func resolveDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid directory %s: %w", dir, err)
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("invalid directory %s: %w", dir, err)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	return resolved, nil
+}
+
+// safeName checks that a file name has no directory components
+func safeName(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || strings.ContainsRune(name, 0) {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	return name, nil
+}
+
+// uniquePath returns path, or path with a number added if the file already exists
+func uniquePath(path string) string {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return path
+	}
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// hashFile returns the hex-encoded SHA-256 of a file
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,346 @@
+package transfer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/client"
+)
+
+// memoryServer plays the server side of the protocol against a Manager.
+// It implements Transport for the agent and keeps everything in memory.
+type memoryServer struct {
+	t        *testing.T
+	mu       sync.Mutex
+	handlers map[string]client.MessageHandler
+	inbox    chan Message
+	bulk     int // Messages sent with PriorityBulk
+}
+
+func newMemoryServer(t *testing.T) *memoryServer {
+	return &memoryServer{
+		t:        t,
+		handlers: make(map[string]client.MessageHandler),
+		inbox:    make(chan Message, 1024),
+	}
+}
+
+func (s *memoryServer) SendJSON(message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	s.inbox <- msg
+	return nil
+}
+
+func (s *memoryServer) SendJSONWithPriority(message interface{}, priority client.Priority) error {
+	if priority == client.PriorityBulk {
+		s.mu.Lock()
+		s.bulk++
+		s.mu.Unlock()
+	}
+	return s.SendJSON(message)
+}
+
+func (s *memoryServer) RegisterHandler(messageType string, handler client.MessageHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[messageType] = handler
+}
+
+// send delivers a message to the agent
+func (s *memoryServer) send(msg Message) error {
+	s.t.Helper()
+	data, _ := json.Marshal(msg)
+	s.mu.Lock()
+	handler := s.handlers[msg.Type]
+	s.mu.Unlock()
+	if handler == nil {
+		s.t.Fatalf("no handler registered for %s", msg.Type)
+	}
+	return handler(data)
+}
+
+// receive waits for the next message of one of the given types, skipping progress
+func (s *memoryServer) receive(types ...string) Message {
+	s.t.Helper()
+	for {
+		select {
+		case msg := <-s.inbox:
+			for _, want := range types {
+				if msg.Type == want {
+					return msg
+				}
+			}
+			if msg.Type != MessageTypeProgress {
+				s.t.Fatalf("unexpected %s message: %+v", msg.Type, msg)
+			}
+		case <-time.After(2 * time.Second):
+			s.t.Fatalf("timed out waiting for %v", types)
+		}
+	}
+}
+
+// upload pushes a file to the agent, stopping after limit bytes if limit >= 0
+func (s *memoryServer) upload(id, name string, content []byte, chunkSize int, limit int) Message {
+	s.t.Helper()
+	sum := sha256.Sum256(content)
+	s.send(Message{
+		Type:       MessageTypeUploadStart,
+		TransferID: id,
+		Name:       name,
+		Size:       int64(len(content)),
+		SHA256:     hex.EncodeToString(sum[:]),
+	})
+
+	reply := s.receive(MessageTypeReady, MessageTypeError)
+	if reply.Type == MessageTypeError {
+		return reply
+	}
+
+	for offset := int(reply.Offset); offset < len(content); offset += chunkSize {
+		if limit >= 0 && offset >= limit {
+			s.send(Message{Type: MessageTypeCancel, TransferID: id})
+			return reply
+		}
+		end := offset + chunkSize
+		if end > len(content) {
+			end = len(content)
+		}
+		s.send(Message{
+			Type:       MessageTypeUploadChunk,
+			TransferID: id,
+			Offset:     int64(offset),
+			Data:       base64.StdEncoding.EncodeToString(content[offset:end]),
+		})
+	}
+
+	s.send(Message{Type: MessageTypeUploadEnd, TransferID: id})
+	return s.receive(MessageTypeComplete, MessageTypeError)
+}
+
+// download pulls a file from the agent and verifies it
+func (s *memoryServer) download(id, path string) ([]byte, Message) {
+	s.t.Helper()
+	s.mu.Lock()
+	bulk := s.bulk
+	s.mu.Unlock()
+	s.send(Message{Type: MessageTypeDownloadRequest, TransferID: id, Path: path})
+
+	start := s.receive(MessageTypeDownloadStart, MessageTypeError)
+	if start.Type == MessageTypeError {
+		return nil, start
+	}
+
+	var buffer bytes.Buffer
+	for chunks := 0; ; chunks++ {
+		msg := s.receive(MessageTypeDownloadChunk, MessageTypeDownloadEnd)
+		if msg.Type == MessageTypeDownloadEnd {
+			sum := sha256.Sum256(buffer.Bytes())
+			if hex.EncodeToString(sum[:]) != start.SHA256 {
+				s.t.Errorf("downloaded file does not match sha256 %s", start.SHA256)
+			}
+			s.mu.Lock()
+			if s.bulk-bulk != chunks {
+				s.t.Errorf("%d of %d chunks were sent as bulk messages", s.bulk-bulk, chunks)
+			}
+			s.mu.Unlock()
+			return buffer.Bytes(), msg
+		}
+		if msg.Offset != int64(buffer.Len()) {
+			s.t.Fatalf("chunk at offset %d, expected %d", msg.Offset, buffer.Len())
+		}
+		chunk, _ := base64.StdEncoding.DecodeString(msg.Data)
+		buffer.Write(chunk)
+	}
+}
+
+func newTestManager(t *testing.T, opts Options) (*Manager, *memoryServer, string) {
+	t.Helper()
+	dir := t.TempDir()
+	server := newMemoryServer(t)
+	opts.AllowedDirs = append([]string{dir}, opts.AllowedDirs...)
+	manager, err := NewManager(server, opts)
+	if err != nil {
+		t.Fatalf("NewManager() returned an error: %v", err)
+	}
+	return manager, server, manager.AllowedDirs()[0]
+}
+
+func TestUploadAndDownload(t *testing.T) {
+	_, server, dir := newTestManager(t, Options{ChunkSize: 7})
+	content := []byte(strings.Repeat("crash dump line\n", 20))
+
+	reply := server.upload("u1", "collector.sh", content, 10, -1)
+	if reply.Type != MessageTypeComplete {
+		t.Fatalf("Upload failed: %+v", reply)
+	}
+	if reply.Path != filepath.Join(dir, "collector.sh") {
+		t.Errorf("Upload stored at %s", reply.Path)
+	}
+	if stored, _ := os.ReadFile(reply.Path); !bytes.Equal(stored, content) {
+		t.Error("Stored file does not match the upload")
+	}
+
+	// A second upload with the same name does not overwrite the first
+	reply = server.upload("u2", "collector.sh", []byte("v2"), 10, -1)
+	if reply.Path != filepath.Join(dir, "collector (1).sh") {
+		t.Errorf("Second upload stored at %s", reply.Path)
+	}
+
+	downloaded, end := server.download("d1", filepath.Join(dir, "collector.sh"))
+	if end.Type != MessageTypeDownloadEnd || !bytes.Equal(downloaded, content) {
+		t.Errorf("Download returned %d bytes, end %+v", len(downloaded), end)
+	}
+}
+
+func TestResumeUpload(t *testing.T) {
+	_, server, dir := newTestManager(t, Options{})
+	content := bytes.Repeat([]byte("0123456789"), 10)
+
+	server.upload("u1", "tool.bin", content, 16, 48)
+	time.Sleep(10 * time.Millisecond)
+
+	sum := sha256.Sum256(content)
+	server.send(Message{
+		Type:       MessageTypeUploadStart,
+		TransferID: "u2",
+		Name:       "tool.bin",
+		Size:       int64(len(content)),
+		SHA256:     hex.EncodeToString(sum[:]),
+	})
+	if ready := server.receive(MessageTypeReady); ready.Offset != 48 {
+		t.Fatalf("Expected to resume at offset 48, got %d", ready.Offset)
+	}
+	server.send(Message{Type: MessageTypeCancel, TransferID: "u2"})
+
+	reply := server.upload("u3", "tool.bin", content, 16, -1)
+	if reply.Type != MessageTypeComplete {
+		t.Fatalf("Resumed upload failed: %+v", reply)
+	}
+	if stored, _ := os.ReadFile(filepath.Join(dir, "tool.bin")); !bytes.Equal(stored, content) {
+		t.Error("Resumed file does not match the upload")
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*"+partSuffix)); len(matches) != 0 {
+		t.Errorf("Partial files left behind: %v", matches)
+	}
+}
+
+func TestUploadChecksumMismatch(t *testing.T) {
+	_, server, dir := newTestManager(t, Options{})
+
+	server.send(Message{
+		Type:       MessageTypeUploadStart,
+		TransferID: "u1",
+		Name:       "bad.txt",
+		Size:       5,
+		SHA256:     strings.Repeat("ab", 32),
+	})
+	server.receive(MessageTypeReady)
+	server.send(Message{Type: MessageTypeUploadChunk, TransferID: "u1", Data: base64.StdEncoding.EncodeToString([]byte("hello"))})
+
+	if err := server.send(Message{Type: MessageTypeUploadEnd, TransferID: "u1"}); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum, got: %v", err)
+	}
+	server.receive(MessageTypeError)
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no files after a checksum mismatch, got %d", len(entries))
+	}
+}
+
+func TestPathRestrictions(t *testing.T) {
+	_, server, _ := newTestManager(t, Options{})
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(outside, []byte("secret"), 0600)
+
+	if _, msg := server.download("d1", outside); msg.Type != MessageTypeError || !strings.Contains(msg.Error, ErrNotAllowed.Error()) {
+		t.Errorf("Expected download outside allowed dirs to fail, got %+v", msg)
+	}
+	if _, msg := server.download("d2", "relative.txt"); msg.Type != MessageTypeError {
+		t.Errorf("Expected relative path to fail, got %+v", msg)
+	}
+
+	for _, name := range []string{"../escape.txt", "a/b.txt", ".."} {
+		if reply := server.upload("u-"+name, name, []byte("x"), 10, -1); reply.Type != MessageTypeError {
+			t.Errorf("Expected upload named %q to fail, got %+v", name, reply)
+		}
+	}
+
+	sum := sha256.Sum256([]byte("x"))
+	server.send(Message{
+		Type:       MessageTypeUploadStart,
+		TransferID: "u-dir",
+		Name:       "x.txt",
+		Directory:  filepath.Dir(outside),
+		Size:       1,
+		SHA256:     hex.EncodeToString(sum[:]),
+	})
+	if msg := server.receive(MessageTypeError, MessageTypeReady); msg.Type != MessageTypeError {
+		t.Errorf("Expected upload to a directory outside allowed dirs to fail, got %+v", msg)
+	}
+}
+
+func TestConsent(t *testing.T) {
+	var requests []Request
+	var mu sync.Mutex
+	_, server, dir := newTestManager(t, Options{Consent: func(r Request) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		return r.Direction == Download
+	}})
+
+	if reply := server.upload("u1", "declined.txt", []byte("x"), 10, -1); reply.Type != MessageTypeError || reply.Error != ErrDeclined.Error() {
+		t.Errorf("Expected a declined upload, got %+v", reply)
+	}
+
+	path := filepath.Join(dir, "log.txt")
+	os.WriteFile(path, []byte("log"), 0600)
+	if data, _ := server.download("d1", path); string(data) != "log" {
+		t.Errorf("Download returned %q", data)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 2 || requests[0].Path != filepath.Join(dir, "declined.txt") || requests[1].Size != 3 {
+		t.Errorf("Unexpected consent requests: %+v", requests)
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	_, server, dir := newTestManager(t, Options{MaxFileSize: 4})
+
+	if reply := server.upload("u1", "big.bin", []byte("12345"), 10, -1); reply.Type != MessageTypeError {
+		t.Errorf("Expected an oversized upload to fail, got %+v", reply)
+	}
+
+	path := filepath.Join(dir, "big.log")
+	os.WriteFile(path, []byte("12345"), 0600)
+	if _, msg := server.download("d1", path); msg.Type != MessageTypeError {
+		t.Errorf("Expected an oversized download to fail, got %+v", msg)
+	}
+}
+
+func TestNewManagerRequiresDirectories(t *testing.T) {
+	if _, err := NewManager(newMemoryServer(t), Options{}); err == nil {
+		t.Error("Expected an error without allowed directories")
+	}
+	if _, err := NewManager(newMemoryServer(t), Options{AllowedDirs: []string{"/does/not/exist"}}); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,138 @@
+package video
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/screenshot"
+)
+
+// MaxCursorRate is the most cursor updates per second a stream polls for
+const MaxCursorRate = 240
+
+// CursorUpdate reports a change of the pointer position, visibility or shape
+type CursorUpdate struct {
+	Cursor       *screenshot.Cursor
+	ShapeChanged bool // Whether the shape differs from the previous update
+}
+
+// cursorState holds the cursor options of a stream
+type cursorState struct {
+	source    func() (*screenshot.Cursor, error)
+	rate      int // Updates per second, 0 disables cursor updates
+	onUpdate  func(CursorUpdate) error
+	composite bool
+	latest    *screenshot.Cursor // Last cursor read by the cursor loop
+}
+
+// SetCursorSource sets the function that reads the pointer and how many times
+// per second it is polled while streaming. A rate of 0 disables cursor updates,
+// and rates above MaxCursorRate are lowered to it.
+func (v *VideoStream) SetCursorSource(source func() (*screenshot.Cursor, error), rate int) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.cursor.source = source
+	v.cursor.rate = min(rate, MaxCursorRate)
+}
+
+// SetOnCursorUpdate sets the callback function to be called when the pointer moves or changes shape
+func (v *VideoStream) SetOnCursorUpdate(callback func(CursorUpdate) error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.cursor.onUpdate = callback
+}
+
+// SetCompositeCursor sets whether the pointer is drawn into captured frames
+func (v *VideoStream) SetCompositeCursor(enabled bool) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.cursor.composite = enabled
+}
+
+// cursorLoop polls the pointer and reports changes until the context is cancelled
+func (v *VideoStream) cursorLoop(ctx context.Context) {
+	v.mutex.Lock()
+	source, rate := v.cursor.source, v.cursor.rate
+	v.mutex.Unlock()
+	if source == nil || rate <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+
+	var last *screenshot.Cursor
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cursor, err := source()
+			if err != nil {
+				if v.verbose {
+					log.Printf("Error reading cursor: %v", err)
+				}
+				continue
+			}
+
+			v.mutex.Lock()
+			v.cursor.latest = cursor
+			callback := v.cursor.onUpdate
+			v.mutex.Unlock()
+
+			update, changed := cursorChange(last, cursor)
+			if !changed {
+				continue
+			}
+			last = cursor
+
+			if callback != nil {
+				if err := callback(update); err != nil && v.verbose {
+					log.Printf("Error in cursor update callback: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// cursorChange compares a cursor with the previously reported one
+func cursorChange(last, cursor *screenshot.Cursor) (CursorUpdate, bool) {
+	update := CursorUpdate{Cursor: cursor, ShapeChanged: last == nil || shapeSerial(last) != shapeSerial(cursor)}
+	if update.ShapeChanged {
+		return update, true
+	}
+	return update, last.X != cursor.X || last.Y != cursor.Y || last.Visible != cursor.Visible
+}
+
+// shapeSerial returns the serial of a cursor's shape, or 0 without one
+func shapeSerial(cursor *screenshot.Cursor) uint64 {
+	if cursor.Shape == nil {
+		return 0
+	}
+	return cursor.Shape.Serial
+}
+
+// frameCursor returns the cursor to draw into a frame, or nil if compositing is off
+func (v *VideoStream) frameCursor() *screenshot.Cursor {
+	v.mutex.Lock()
+	composite, source, latest := v.cursor.composite, v.cursor.source, v.cursor.latest
+	v.mutex.Unlock()
+
+	if !composite || source == nil {
+		return nil
+	}
+	if latest != nil {
+		return latest
+	}
+
+	// Cursor updates are disabled, so read the pointer for this frame
+	cursor, err := source()
+	if err != nil {
+		if v.verbose {
+			log.Printf("Error reading cursor: %v", err)
+		}
+		return nil
+	}
+	return cursor
+}
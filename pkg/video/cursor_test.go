@@ -0,0 +1,86 @@
+package video
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adamrobbie/go-support/pkg/screenshot"
+)
+
+func TestCursorChange(t *testing.T) {
+	shape := &screenshot.CursorShape{Serial: 1}
+	cursor := &screenshot.Cursor{X: 1, Y: 2, Visible: true, Shape: shape}
+
+	if update, changed := cursorChange(nil, cursor); !changed || !update.ShapeChanged {
+		t.Error("Expected the first cursor to be reported with its shape")
+	}
+	if _, changed := cursorChange(cursor, &screenshot.Cursor{X: 1, Y: 2, Visible: true, Shape: shape}); changed {
+		t.Error("Expected an unchanged cursor not to be reported")
+	}
+	if update, changed := cursorChange(cursor, &screenshot.Cursor{X: 3, Y: 2, Visible: true, Shape: shape}); !changed || update.ShapeChanged {
+		t.Error("Expected a move to be reported without the shape")
+	}
+	if update, changed := cursorChange(cursor, &screenshot.Cursor{X: 1, Y: 2, Visible: true, Shape: &screenshot.CursorShape{Serial: 2}}); !changed || !update.ShapeChanged {
+		t.Error("Expected a new shape to be reported")
+	}
+}
+
+func TestCursorRateLimit(t *testing.T) {
+	v := NewVideoStream(Low, 1, false)
+	v.SetCursorSource(func() (*screenshot.Cursor, error) { return nil, nil }, 2000000000)
+	if v.cursor.rate != MaxCursorRate {
+		t.Errorf("Expected the rate to be lowered to %d, got %d", MaxCursorRate, v.cursor.rate)
+	}
+}
+
+func TestCursorLoop(t *testing.T) {
+	v := NewVideoStream(Low, 1, false)
+
+	var mu sync.Mutex
+	positions := []int{1, 1, 1, 2, 2, 3}
+	reads := 0
+	v.SetCursorSource(func() (*screenshot.Cursor, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		x := positions[len(positions)-1]
+		if reads < len(positions) {
+			x = positions[reads]
+		}
+		reads++
+		return &screenshot.Cursor{X: x, Visible: true}, nil
+	}, 1000)
+
+	updates := make(chan CursorUpdate, 10)
+	v.SetOnCursorUpdate(func(update CursorUpdate) error {
+		updates <- update
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go v.cursorLoop(ctx)
+
+	for _, want := range []int{1, 2, 3} {
+		select {
+		case update := <-updates:
+			if update.Cursor.X != want {
+				t.Fatalf("Expected an update at x=%d, got %d", want, update.Cursor.X)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for the update at x=%d", want)
+		}
+	}
+
+	select {
+	case update := <-updates:
+		t.Errorf("Unexpected update for an unchanged cursor: %+v", update.Cursor)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	v.SetCompositeCursor(true)
+	if cursor := v.frameCursor(); cursor == nil || cursor.X != 3 {
+		t.Errorf("Expected the latest cursor for frames, got %+v", cursor)
+	}
+}
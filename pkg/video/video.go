@@ -34,6 +34,7 @@ type VideoStream struct {
 	mutex          sync.Mutex
 	frames         [][]byte
 	onFrameCapture func([]byte) error
+	cursor         cursorState
 	verbose        bool
 }
 
@@ -69,7 +70,7 @@ func (v *VideoStream) StartStreaming() error {
 	}
 
 	v.isStreaming = true
-	go v.streamLoop()
+	go v.streamLoop(v.ctx)
 
 	if v.verbose {
 		log.Printf("Started video streaming at %d FPS with quality %d", v.fps, v.quality)
@@ -113,7 +114,7 @@ func (v *VideoStream) StartRecording() error {
 
 	// Start streaming if not already streaming
 	if !v.isStreaming {
-		go v.streamLoop()
+		go v.streamLoop(v.ctx)
 		v.isStreaming = true
 	}
 
@@ -164,15 +165,18 @@ func (v *VideoStream) IsRecording() bool {
 	return v.isRecording
 }
 
-// streamLoop captures frames at the specified FPS
-func (v *VideoStream) streamLoop() {
+// streamLoop captures frames at the specified FPS, and polls the cursor
+// alongside when a cursor source is set
+func (v *VideoStream) streamLoop(ctx context.Context) {
+	go v.cursorLoop(ctx)
+
 	interval := time.Second / time.Duration(v.fps)
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-v.ctx.Done():
+		case <-ctx.Done():
 			return
 		case <-ticker.C:
 			frame, err := v.captureFrame()
@@ -227,6 +231,13 @@ func (v *VideoStream) captureFrame() ([]byte, error) {
 		return nil, fmt.Errorf("failed to capture screenshot: %w", err)
 	}
 
+	// Most capture tools leave the pointer out of the image
+	if cursor := v.frameCursor(); cursor != nil {
+		if err := ss.DrawCursor(cursor); err != nil && v.verbose {
+			log.Printf("Error drawing cursor into frame: %v", err)
+		}
+	}
+
 	return ss.Data, nil
 }
 
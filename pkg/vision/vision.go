@@ -0,0 +1,367 @@
+package vision
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/adamrobbie/go-support/pkg/screenshot"
+	"golang.org/x/image/draw"
+)
+
+const (
+	// DefaultThreshold is the minimum score of a match when none is given
+	DefaultThreshold = 0.9
+	// DefaultMaxMatches limits the number of matches returned when no limit is given
+	DefaultMaxMatches = 10
+	// coarseSize is the smaller side of the template in the coarse search
+	coarseSize = 8
+	// maxCoarseFactor limits how much the coarse search downsamples
+	maxCoarseFactor = 8
+	// coarseSlack is how far below the threshold a coarse score may be and still be refined
+	coarseSlack = 0.2
+	// maxCandidates limits the coarse candidates refined per scale
+	maxCandidates = 32
+)
+
+// DefaultScales are the template scales searched when none are given. They
+// cover the same element rendered at slightly different sizes, e.g. after a
+// change of display scaling.
+var DefaultScales = []float64{0.8, 0.9, 1, 1.1, 1.25}
+
+// ErrNoContrast is returned for templates of a single color, which match everywhere
+var ErrNoContrast = errors.New("template has no contrast")
+
+// Options configures a search
+type Options struct {
+	Threshold  float64   // Minimum score from 0 to 1 (DefaultThreshold if 0)
+	Scales     []float64 // Template scales to search (DefaultScales if empty)
+	MaxMatches int       // Maximum number of matches (DefaultMaxMatches if 0)
+}
+
+// Match is a location where the template was found
+type Match struct {
+	X      int     `json:"x"` // Top-left corner in image pixels
+	Y      int     `json:"y"`
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	Score  float64 `json:"score"` // Normalized cross-correlation from -1 to 1
+	Scale  float64 `json:"scale"` // Scale of the template that matched
+}
+
+// Center returns the center of the match
+func (m Match) Center() (int, int) {
+	return m.X + m.Width/2, m.Y + m.Height/2
+}
+
+// Rect returns the matched rectangle
+func (m Match) Rect() image.Rectangle {
+	return image.Rect(m.X, m.Y, m.X+m.Width, m.Y+m.Height)
+}
+
+// LoadTemplate reads a template image from a PNG or JPEG file
+func LoadTemplate(path string) (image.Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	return DecodeTemplate(data)
+}
+
+// DecodeTemplate decodes a PNG or JPEG template image
+func DecodeTemplate(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode template: %w", err)
+	}
+	return img, nil
+}
+
+// FindInScreenshot finds a template in a screenshot
+func FindInScreenshot(ss *screenshot.Screenshot, template image.Image, opts Options) ([]Match, error) {
+	img, _, err := image.Decode(bytes.NewReader(ss.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+	return Find(img, template, opts)
+}
+
+// Find finds a template in an image and returns the matches scoring at least
+// the threshold, best first. Overlapping matches are reduced to the best one.
+func Find(img, template image.Image, opts Options) ([]Match, error) {
+	if opts.Threshold == 0 {
+		opts.Threshold = DefaultThreshold
+	}
+	if len(opts.Scales) == 0 {
+		opts.Scales = DefaultScales
+	}
+	if opts.MaxMatches <= 0 {
+		opts.MaxMatches = DefaultMaxMatches
+	}
+
+	bounds := template.Bounds()
+	if bounds.Dx() < 2 || bounds.Dy() < 2 {
+		return nil, fmt.Errorf("template is too small: %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	screen := grayPlane(img)
+	var matches []Match
+	searched := false
+	for _, scale := range opts.Scales {
+		width := int(math.Round(float64(bounds.Dx()) * scale))
+		height := int(math.Round(float64(bounds.Dy()) * scale))
+		if width < 2 || height < 2 || width > screen.width || height > screen.height {
+			continue
+		}
+		searched = true
+
+		t := grayPlane(resize(template, width, height))
+		found, err := search(screen, t, opts.Threshold)
+		if err != nil {
+			return nil, err
+		}
+		for i := range found {
+			found[i].Scale = scale
+		}
+		matches = append(matches, found...)
+	}
+	if !searched {
+		return nil, fmt.Errorf("template is larger than the image at every scale")
+	}
+
+	matches = suppress(matches)
+	if len(matches) > opts.MaxMatches {
+		matches = matches[:opts.MaxMatches]
+	}
+	return matches, nil
+}
+
+// Best returns the best match, or false if there is none
+func Best(matches []Match) (Match, bool) {
+	if len(matches) == 0 {
+		return Match{}, false
+	}
+	return matches[0], true
+}
+
+// plane is a grayscale image with float pixels
+type plane struct {
+	width, height int
+	pix           []float64
+}
+
+// at returns the pixel at x, y
+func (p *plane) at(x, y int) float64 {
+	return p.pix[y*p.width+x]
+}
+
+// grayPlane converts an image to a grayscale plane
+func grayPlane(img image.Image) *plane {
+	b := img.Bounds()
+	p := &plane{width: b.Dx(), height: b.Dy(), pix: make([]float64, b.Dx()*b.Dy())}
+
+	if rgba, ok := img.(*image.RGBA); ok {
+		for y := 0; y < p.height; y++ {
+			row := rgba.Pix[(y+b.Min.Y-rgba.Rect.Min.Y)*rgba.Stride+(b.Min.X-rgba.Rect.Min.X)*4:]
+			for x := 0; x < p.width; x++ {
+				r, g, bl := row[x*4], row[x*4+1], row[x*4+2]
+				p.pix[y*p.width+x] = 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+			}
+		}
+		return p
+	}
+
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			p.pix[y*p.width+x] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)) / 257
+		}
+	}
+	return p
+}
+
+// resize scales an image to the given size
+func resize(img image.Image, width, height int) image.Image {
+	if img.Bounds().Dx() == width && img.Bounds().Dy() == height {
+		return img
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// downsample averages factor×factor blocks of a plane
+func downsample(p *plane, factor int) *plane {
+	if factor <= 1 {
+		return p
+	}
+	d := &plane{width: p.width / factor, height: p.height / factor}
+	d.pix = make([]float64, d.width*d.height)
+	area := float64(factor * factor)
+	for y := 0; y < d.height; y++ {
+		for x := 0; x < d.width; x++ {
+			var sum float64
+			for dy := 0; dy < factor; dy++ {
+				row := p.pix[(y*factor+dy)*p.width+x*factor:]
+				for dx := 0; dx < factor; dx++ {
+					sum += row[dx]
+				}
+			}
+			d.pix[y*d.width+x] = sum / area
+		}
+	}
+	return d
+}
+
+// correlator computes the normalized cross-correlation of a template at
+// positions of an image, using summed-area tables for the image statistics
+type correlator struct {
+	img      *plane
+	template []float64 // Template pixels minus their mean
+	tw, th   int
+	tNorm    float64 // Square root of the template's sum of squared deviations
+	sum, sq  []float64
+}
+
+// newCorrelator prepares a template for correlation with an image
+func newCorrelator(img, t *plane) (*correlator, error) {
+	c := &correlator{img: img, tw: t.width, th: t.height, template: make([]float64, len(t.pix))}
+
+	var mean float64
+	for _, v := range t.pix {
+		mean += v
+	}
+	mean /= float64(len(t.pix))
+	var ss float64
+	for i, v := range t.pix {
+		c.template[i] = v - mean
+		ss += c.template[i] * c.template[i]
+	}
+	if ss < 1e-6*float64(len(t.pix)) {
+		return nil, ErrNoContrast
+	}
+	c.tNorm = math.Sqrt(ss)
+
+	// Summed-area tables with a zero row and column
+	w := img.width + 1
+	c.sum = make([]float64, w*(img.height+1))
+	c.sq = make([]float64, w*(img.height+1))
+	for y := 0; y < img.height; y++ {
+		var rowSum, rowSq float64
+		for x := 0; x < img.width; x++ {
+			v := img.at(x, y)
+			rowSum += v
+			rowSq += v * v
+			c.sum[(y+1)*w+x+1] = c.sum[y*w+x+1] + rowSum
+			c.sq[(y+1)*w+x+1] = c.sq[y*w+x+1] + rowSq
+		}
+	}
+	return c, nil
+}
+
+// score returns the correlation of the template with the image at x, y
+func (c *correlator) score(x, y int) float64 {
+	w := c.img.width + 1
+	area := func(table []float64) float64 {
+		return table[(y+c.th)*w+x+c.tw] - table[y*w+x+c.tw] - table[(y+c.th)*w+x] + table[y*w+x]
+	}
+	n := float64(c.tw * c.th)
+	sum := area(c.sum)
+	variance := area(c.sq) - sum*sum/n
+	if variance < 1e-6*n {
+		return 0
+	}
+
+	// The template has zero mean, so the image mean drops out of the numerator
+	var dot float64
+	for ty := 0; ty < c.th; ty++ {
+		row := c.img.pix[(y+ty)*c.img.width+x:]
+		trow := c.template[ty*c.tw:]
+		for tx := 0; tx < c.tw; tx++ {
+			dot += row[tx] * trow[tx]
+		}
+	}
+	return dot / (c.tNorm * math.Sqrt(variance))
+}
+
+// search finds a template of one scale. It correlates downsampled copies
+// first and refines the promising positions at full resolution.
+func search(img, t *plane, threshold float64) ([]Match, error) {
+	factor := min(t.width, t.height) / coarseSize
+	factor = max(1, min(factor, maxCoarseFactor))
+
+	fine, err := newCorrelator(img, t)
+	if err != nil {
+		return nil, err
+	}
+	if factor == 1 {
+		return peaks(fine, 0, 0, img.width-t.width, img.height-t.height, threshold, 1), nil
+	}
+
+	coarse, err := newCorrelator(downsample(img, factor), downsample(t, factor))
+	if err != nil {
+		// Fine detail can vanish when downsampling; fall back to the slow path
+		return peaks(fine, 0, 0, img.width-t.width, img.height-t.height, threshold, 1), nil
+	}
+	candidates := peaks(coarse, 0, 0, coarse.img.width-coarse.tw, coarse.img.height-coarse.th, threshold-coarseSlack, maxCandidates)
+
+	var matches []Match
+	for _, c := range candidates {
+		x0, y0 := max(c.X*factor-factor, 0), max(c.Y*factor-factor, 0)
+		x1, y1 := min(c.X*factor+factor, img.width-t.width), min(c.Y*factor+factor, img.height-t.height)
+		matches = append(matches, peaks(fine, x0, y0, x1, y1, threshold, 1)...)
+	}
+	return matches, nil
+}
+
+// peaks scores every position in a range and returns the best ones at or above
+// the threshold, at most limit of them or all of them if limit is 0
+func peaks(c *correlator, x0, y0, x1, y1 int, threshold float64, limit int) []Match {
+	var found []Match
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			if s := c.score(x, y); s >= threshold {
+				found = append(found, Match{X: x, Y: y, Width: c.tw, Height: c.th, Score: s})
+			}
+		}
+	}
+	found = suppress(found)
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	return found
+}
+
+// suppress sorts matches by score and drops those overlapping a better match by more than half
+func suppress(matches []Match) []Match {
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+
+	var kept []Match
+	for _, m := range matches {
+		overlaps := false
+		for _, k := range kept {
+			if overlap(m.Rect(), k.Rect()) > 0.5 {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// overlap returns the intersection of two rectangles divided by the smaller one's area
+func overlap(a, b image.Rectangle) float64 {
+	inter := a.Intersect(b)
+	if inter.Empty() {
+		return 0
+	}
+	smaller := min(a.Dx()*a.Dy(), b.Dx()*b.Dy())
+	return float64(inter.Dx()*inter.Dy()) / float64(smaller)
+}
@@ -0,0 +1,160 @@
+package vision
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	"github.com/adamrobbie/go-support/pkg/screenshot"
+	"golang.org/x/image/draw"
+)
+
+// testScreen returns a noisy background with a few flat panels, like a desktop
+func testScreen(width, height int) *image.RGBA {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.Intn(256))
+		if i%4 == 3 {
+			img.Pix[i] = 255
+		}
+	}
+	draw.Draw(img, image.Rect(0, 0, width, 30), image.NewUniform(color.RGBA{40, 40, 40, 255}), image.Point{}, draw.Src)
+	return img
+}
+
+// testButton returns a button-like template with a border and a label
+func testButton(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{20, 90, 200, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(3, 3, width-3, height-3), image.NewUniform(color.RGBA{230, 230, 230, 255}), image.Point{}, draw.Src)
+	for x := width / 4; x < 3*width/4; x += 4 {
+		draw.Draw(img, image.Rect(x, height/3, x+2, 2*height/3), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func TestFind(t *testing.T) {
+	screen := testScreen(640, 480)
+	button := testButton(60, 24)
+	draw.Draw(screen, image.Rect(300, 200, 360, 224), button, image.Point{}, draw.Src)
+
+	matches, err := Find(screen, button, Options{})
+	if err != nil {
+		t.Fatalf("Find() returned an error: %v", err)
+	}
+	best, ok := Best(matches)
+	if !ok {
+		t.Fatal("Expected the button to be found")
+	}
+	if best.X != 300 || best.Y != 200 || best.Scale != 1 || best.Score < 0.99 {
+		t.Errorf("Unexpected best match: %+v", best)
+	}
+	if x, y := best.Center(); x != 330 || y != 212 {
+		t.Errorf("Expected the center at (330,212), got (%d,%d)", x, y)
+	}
+	if len(matches) != 1 {
+		t.Errorf("Expected one match after suppressing overlaps, got %+v", matches)
+	}
+}
+
+func TestFindScaled(t *testing.T) {
+	screen := testScreen(640, 480)
+	button := testButton(60, 24)
+	// The same button rendered 25% larger, e.g. at a higher display scale
+	large := image.NewRGBA(image.Rect(0, 0, 75, 30))
+	draw.CatmullRom.Scale(large, large.Bounds(), button, button.Bounds(), draw.Src, nil)
+	draw.Draw(screen, image.Rect(100, 300, 175, 330), large, image.Point{}, draw.Src)
+
+	matches, err := Find(screen, button, Options{Threshold: 0.8})
+	if err != nil {
+		t.Fatalf("Find() returned an error: %v", err)
+	}
+	best, ok := Best(matches)
+	if !ok {
+		t.Fatal("Expected the scaled button to be found")
+	}
+	if best.Scale != 1.25 || abs(best.X-100) > 1 || abs(best.Y-300) > 1 {
+		t.Errorf("Unexpected best match: %+v", best)
+	}
+}
+
+func TestFindMultiple(t *testing.T) {
+	screen := testScreen(640, 480)
+	button := testButton(40, 20)
+	for _, p := range []image.Point{{50, 50}, {400, 100}, {200, 400}} {
+		draw.Draw(screen, image.Rect(p.X, p.Y, p.X+40, p.Y+20), button, image.Point{}, draw.Src)
+	}
+
+	matches, err := Find(screen, button, Options{Scales: []float64{1}})
+	if err != nil {
+		t.Fatalf("Find() returned an error: %v", err)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("Expected 3 matches, got %+v", matches)
+	}
+
+	matches, _ = Find(screen, button, Options{Scales: []float64{1}, MaxMatches: 2})
+	if len(matches) != 2 {
+		t.Errorf("Expected MaxMatches to limit the matches to 2, got %d", len(matches))
+	}
+}
+
+func TestFindNoMatch(t *testing.T) {
+	screen := testScreen(320, 240)
+	matches, err := Find(screen, testButton(40, 20), Options{})
+	if err != nil {
+		t.Fatalf("Find() returned an error: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("Expected no matches in noise, got %+v", matches)
+	}
+}
+
+func TestFindErrors(t *testing.T) {
+	screen := testScreen(100, 100)
+
+	flat := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	if _, err := Find(screen, flat, Options{}); !errors.Is(err, ErrNoContrast) {
+		t.Errorf("Expected ErrNoContrast, got %v", err)
+	}
+	if _, err := Find(screen, testButton(200, 50), Options{}); err == nil {
+		t.Error("Expected an error for a template larger than the image")
+	}
+}
+
+func TestFindInScreenshot(t *testing.T) {
+	screen := testScreen(320, 240)
+	button := testButton(40, 20)
+	draw.Draw(screen, image.Rect(10, 150, 50, 170), button, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	png.Encode(&buf, screen)
+	ss := &screenshot.Screenshot{Data: buf.Bytes(), Width: 320, Height: 240, Format: "png"}
+
+	var template bytes.Buffer
+	png.Encode(&template, button)
+	decoded, err := DecodeTemplate(template.Bytes())
+	if err != nil {
+		t.Fatalf("DecodeTemplate() returned an error: %v", err)
+	}
+
+	matches, err := FindInScreenshot(ss, decoded, Options{})
+	if err != nil {
+		t.Fatalf("FindInScreenshot() returned an error: %v", err)
+	}
+	if best, ok := Best(matches); !ok || best.X != 10 || best.Y != 150 {
+		t.Errorf("Unexpected matches: %+v", matches)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}